@@ -33,6 +33,8 @@ iptables -t nat -F APISIX_REDIRECT
 iptables -t nat -X APISIX_REDIRECT
 iptables -t nat -F APISIX_INBOUND_REDIRECT
 iptables -t nat -X APISIX_INBOUND_REDIRECT
+iptables -t nat -F APISIX_STREAM_REDIRECT
+iptables -t nat -X APISIX_STREAM_REDIRECT
 `
 	assert.Equal(t, expect, string(data))
 }
@@ -13,16 +13,18 @@ import (
 )
 
 type iptablesConstructor struct {
-	iptables *builder.IptablesBuilderImpl
-	cfg      *config.Config
-	dep      dependencies.Dependencies
+	iptables    *builder.IptablesBuilderImpl
+	cfg         *config.Config
+	dep         dependencies.Dependencies
+	streamPorts string
 }
 
 // NewSetupCommand creates the iptables sub-command object.
 func NewSetupCommand() *cobra.Command {
 	var (
-		cfg       config.Config
-		proxyUser string
+		cfg         config.Config
+		proxyUser   string
+		streamPorts string
 	)
 	cmd := &cobra.Command{
 		Use:   "iptables [flags]",
@@ -36,6 +38,11 @@ To intercept all inbound TCP traffic, just use "*" as the value of --inbound-por
 if outbound TCP traffic (say the destination port is 80) is desired to be intercepted, just run:
 	apisix-mesh-agent iptables --apisix-port 9080 --inbound-ports 80 --outbound-ports 80
 
+Outbound TCP traffic which should be handled by the APISIX stream proxy (say the destination port is 3306)
+can be intercepted by the --stream-ports option, the destination port will be kept, so the port should also
+be configured in the stream_proxy section of APISIX:
+	apisix-mesh-agent iptables --apisix-port 9080 --outbound-ports 80 --stream-ports 3306
+
 --dry-run option can be specified if you just want to see which rules will be generated (but no effects).
 `,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -54,9 +61,10 @@ if outbound TCP traffic (say the destination port is 80) is desired to be interc
 			cfg.ProxyGID = usr.Gid
 
 			ic := &iptablesConstructor{
-				iptables: builder.NewIptablesBuilder(),
-				cfg:      &cfg,
-				dep:      dep,
+				iptables:    builder.NewIptablesBuilder(),
+				cfg:         &cfg,
+				dep:         dep,
+				streamPorts: streamPorts,
 			}
 
 			ic.run()
@@ -70,6 +78,7 @@ if outbound TCP traffic (say the destination port is 80) is desired to be interc
 	cmd.PersistentFlags().StringVar(&cfg.InboundPortsInclude, "inbound-ports", "",
 		"comma separated list of inbound ports for which traffic is to be redirected, the wildcard character \"*\" can be used to configure redirection for all ports, empty list will disable the redirection")
 	cmd.PersistentFlags().StringVar(&cfg.OutboundPortsInclude, "outbound-ports", "", "comma separated list of outbound ports for which traffic is to be redirected")
+	cmd.PersistentFlags().StringVar(&streamPorts, "stream-ports", "", "comma separated list of outbound ports for which traffic is to be redirected to the APISIX stream proxy, the destination port is kept")
 	cmd.PersistentFlags().StringVar(&cfg.InboundPortsExclude, "inbound-exclude-ports", "", "comma separated list of inbound ports to be excluded from forwarding to APISIX, only in effective if value of --inbound-ports option is \"*\"")
 	cmd.PersistentFlags().StringVar(&cfg.OutboundPortsExclude, "outbound-exclude-ports", "", "comma separated list of outbound ports to be excluded from forwarding to APISIX, only in effective if value of --outbound-ports option is \"*\"")
 
@@ -87,10 +96,18 @@ func (ic *iptablesConstructor) run() {
 		types.InboundRedirectChain, "nat", "-p", "tcp",
 		"-j", "REDIRECT", "--to-ports", ic.cfg.InboundCapturePort,
 	)
+	if ic.streamPorts != "" {
+		ic.iptables.AppendRuleV4(
+			types.StreamRedirectChain, "nat", "-p", "tcp", "-j", "REDIRECT",
+		)
+	}
 
 	// Should first insert these skipping rules.
 	ic.insertSkipRules()
 	ic.insertInboundRules()
+	// Stream rules should be inserted before the outbound rules, so
+	// that they won't be shadowed by the wildcard outbound rule.
+	ic.insertStreamRules()
 	ic.insertOutboundRules()
 	ic.executeCommand()
 }
@@ -144,6 +161,14 @@ func (ic *iptablesConstructor) insertOutboundRules() {
 	}
 }
 
+func (ic *iptablesConstructor) insertStreamRules() {
+	for _, port := range split(ic.streamPorts) {
+		ic.iptables.AppendRuleV4(
+			types.OutputChain, "nat", "-p", "tcp", "--dport", port, "-j", types.StreamRedirectChain,
+		)
+	}
+}
+
 func (ic *iptablesConstructor) insertSkipRules() {
 	ic.iptables.AppendRuleV4(types.OutputChain, "nat", "-o", "lo", "!", "-d",
 		"127.0.0.1/32", "-m", "owner", "--uid-owner", ic.cfg.ProxyUID, "-j", "RETURN")
@@ -251,3 +251,46 @@ func TestCaptureBothInboundAndOutboundTraffic(t *testing.T) {
 	actual := strings.Split(strings.TrimSpace(string(data)), "\n")
 	assert.Equal(t, expect, actual)
 }
+
+func TestCaptureStreamTraffic(t *testing.T) {
+	f, err := ioutil.TempFile("./", "iptables.*")
+	assert.Nil(t, err)
+	defer func() {
+		assert.Nil(t, f.Close())
+		assert.Nil(t, os.Remove(f.Name()))
+	}()
+	rawStdout := os.Stdout
+	os.Stdout = f
+	cmd := NewSetupCommand()
+	cmd.SetArgs([]string{
+		"--apisix-port",
+		"9080",
+		"--outbound-ports",
+		"*",
+		"--stream-ports",
+		"3306,6379",
+		"--dry-run",
+		"--apisix-user",
+		"root",
+	})
+	err = cmd.Execute()
+	os.Stdout = rawStdout
+	assert.Nil(t, err)
+	expect := []string{
+		"iptables -t nat -N APISIX_REDIRECT",
+		"iptables -t nat -N APISIX_INBOUND_REDIRECT",
+		"iptables -t nat -N APISIX_STREAM_REDIRECT",
+		"iptables -t nat -A APISIX_REDIRECT -p tcp -j REDIRECT --to-ports 9080",
+		"iptables -t nat -A APISIX_INBOUND_REDIRECT -p tcp -j REDIRECT --to-ports 9081",
+		"iptables -t nat -A APISIX_STREAM_REDIRECT -p tcp -j REDIRECT",
+		"iptables -t nat -A OUTPUT -o lo ! -d 127.0.0.1/32 -m owner --uid-owner 0 -j RETURN",
+		"iptables -t nat -A OUTPUT -m owner --gid-owner 0 -j RETURN",
+		"iptables -t nat -A OUTPUT -p tcp --dport 3306 -j APISIX_STREAM_REDIRECT",
+		"iptables -t nat -A OUTPUT -p tcp --dport 6379 -j APISIX_STREAM_REDIRECT",
+		"iptables -t nat -A OUTPUT -p tcp -j APISIX_REDIRECT",
+	}
+	data, err := ioutil.ReadFile(f.Name())
+	assert.Nil(t, err)
+	actual := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, expect, actual)
+}
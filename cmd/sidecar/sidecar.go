@@ -79,13 +79,43 @@ func NewCommand() *cobra.Command {
 
 	cmd.PersistentFlags().StringVar(&cfg.LogOutput, "log-output", "stderr", "the output file path of error log")
 	cmd.PersistentFlags().StringVar(&cfg.LogLevel, "log-level", "info", "the error log level")
-	cmd.PersistentFlags().StringVar(&cfg.Provisioner, "provisioner", config.XDSV3FileProvisioner, "the provisioner to use, option can be \"xds-v3-file\", \"xds-v3-grpc\"")
+	cmd.PersistentFlags().StringVar(&cfg.Provisioner, "provisioner", config.XDSV3FileProvisioner, "the provisioner to use, option can be \"xds-v3-file\", \"xds-v3-grpc\", \"xds-v3-rest\", \"apisix-file\", \"kubernetes\", \"replay\"")
+	cmd.PersistentFlags().StringSliceVar(&cfg.Provisioners, "provisioners", nil, "the provisioners to run together, objects from the former one take precedence if ids are conflicted, --provisioner is ignored if it's specified")
 	cmd.PersistentFlags().StringSliceVar(&cfg.XDSWatchFiles, "xds-watch-files", nil, "file paths watched by xds-v3-file provisioner")
+	cmd.PersistentFlags().StringSliceVar(&cfg.APISIXWatchFiles, "apisix-watch-files", nil, "APISIX declarative config files (apisix.yaml style) watched by apisix-file provisioner")
+	cmd.PersistentFlags().StringSliceVar(&cfg.PatchFiles, "patch-files", nil, "watched files of patches (JSON merge patch or JSON patch) which are applied to the routes and upstreams generated by the provisioner")
+	cmd.PersistentFlags().StringVar(&cfg.RecordFile, "record-file", "", "the file to record the events emitted by the provisioner, in the JSON lines format")
+	cmd.PersistentFlags().BoolVar(&cfg.RecordRedactKeys, "record-redact-keys", false, "remove the private keys of SSLs from the recording file, SSLs will be skipped when replaying it")
+	cmd.PersistentFlags().StringVar(&cfg.ReplayFile, "replay-file", "", "the recording file played back by replay provisioner")
+	cmd.PersistentFlags().Float64Var(&cfg.ReplaySpeed, "replay-speed", config.DefaultReplaySpeed, "the speed to play back the recording file for replay provisioner, e.g. 2 means twice as fast as the original timing, 0 means no waiting")
+	cmd.PersistentFlags().StringVar(&cfg.Kubeconfig, "kubeconfig", "", "the kubeconfig file to access the Kubernetes API server for kubernetes provisioner, the in-cluster config will be used if it's empty")
+	cmd.PersistentFlags().StringVar(&cfg.KubernetesNamespace, "kubernetes-namespace", "", "the namespace watched by kubernetes provisioner, all namespaces will be watched if it's empty")
+	cmd.PersistentFlags().DurationVar(&cfg.XDSWatchDebounce, "xds-watch-debounce", config.DefaultXDSWatchDebounce, "the debounce window of file changes for xds-v3-file provisioner, zero means no debounce")
 	cmd.PersistentFlags().StringVar(&cfg.GRPCListen, "grpc-listen", config.DefaultGRPCListen, "grpc server listen address")
 	cmd.PersistentFlags().StringVar(&cfg.EtcdKeyPrefix, "etcd-key-prefix", config.DefaultEtcdKeyPrefix, "the key prefix in the mimicking etcd v3 server")
-	cmd.PersistentFlags().StringVar(&cfg.XDSConfigSource, "xds-config-source", "", "the xds config source address, required if provisioner is \"xds-v3-grpc\"")
+	cmd.PersistentFlags().StringVar(&cfg.XDSConfigSource, "xds-config-source", "", "the xds config source address, required if provisioner is \"xds-v3-grpc\" or \"xds-v3-rest\"")
+	cmd.PersistentFlags().DurationVar(&cfg.XDSRefreshInterval, "xds-refresh-interval", config.DefaultXDSRefreshInterval, "the interval to poll the xds config source for xds-v3-rest provisioner")
+	cmd.PersistentFlags().DurationVar(&cfg.XDSRequestTimeout, "xds-request-timeout", config.DefaultXDSRequestTimeout, "the timeout of each request to the xds config source for xds-v3-rest provisioner")
+	cmd.PersistentFlags().BoolVar(&cfg.XDSDelta, "xds-delta", false, "use the incremental (delta) xds protocol, it's only concerned if provisioner is \"xds-v3-grpc\"")
+	cmd.PersistentFlags().StringVar(&cfg.XDSCAFile, "xds-ca-file", "", "the CA bundle to verify the xds config source, it's only concerned if the xds config source is \"grpcs://\"")
+	cmd.PersistentFlags().StringVar(&cfg.XDSClientCertFile, "xds-client-cert-file", "", "the client certificate to connect the xds config source with mTLS")
+	cmd.PersistentFlags().StringVar(&cfg.XDSClientKeyFile, "xds-client-key-file", "", "the client private key to connect the xds config source with mTLS")
+	cmd.PersistentFlags().StringVar(&cfg.XDSServerName, "xds-server-name", "", "the server name to verify the xds config source, the host of the config source is used by default")
+	cmd.PersistentFlags().StringVar(&cfg.XDSTokenFile, "xds-token-file", "", "the bearer token file to authenticate to the xds config source, e.g. /var/run/secrets/tokens/istio-token")
+	cmd.PersistentFlags().StringSliceVar(&cfg.XDSTokenAudiences, "xds-token-audiences", nil, "the token should be issued for one of these audiences if specified")
+	cmd.PersistentFlags().StringVar(&cfg.XDSNodeIdTemplate, "xds-node-id-template", config.DefaultXDSNodeIdTemplate, "the template to generate the xds node id")
+	cmd.PersistentFlags().StringVar(&cfg.DNSDomain, "dns-domain", "", "the dns domain of the resident pod, \"<namespace>.svc.cluster.local\" is used if it's empty")
+	cmd.PersistentFlags().StringVar(&cfg.PodLabelsFile, "pod-labels-file", config.DefaultPodLabelsFile, "the file which contains labels of the resident pod, it's usually mounted by the downward API")
+	cmd.PersistentFlags().StringVar(&cfg.CAAddress, "ca-address", "", "the Istio CA address to request the workload certificate, e.g. grpcs://istiod.istio-system.svc:15012, it's only concerned if provisioner is \"xds-v3-grpc\"")
+	cmd.PersistentFlags().StringVar(&cfg.CARootCertFile, "ca-root-cert-file", "", "the root certificate to verify the CA, e.g. /var/run/secrets/istio/root-cert.pem")
+	cmd.PersistentFlags().StringVar(&cfg.CATokenFile, "ca-token-file", "", "the bearer token file to authenticate to the CA, e.g. /var/run/secrets/tokens/istio-token")
+	cmd.PersistentFlags().StringVar(&cfg.TrustDomain, "trust-domain", config.DefaultTrustDomain, "the SPIFFE trust domain of the workload certificate")
+	cmd.PersistentFlags().StringVar(&cfg.ClusterId, "cluster-id", config.DefaultClusterId, "the cluster id reported to the CA")
+	cmd.PersistentFlags().DurationVar(&cfg.WorkloadCertTTL, "workload-cert-ttl", config.DefaultWorkloadCertTTL, "the requested TTL of the workload certificate")
 	cmd.PersistentFlags().StringVar(&cfg.RunMode, "run-mode", config.StandaloneMode, "run mode for apisix-mesh-agent, can be \"standalone\" or \"bundle\"")
 	cmd.PersistentFlags().StringVar(&cfg.APISIXBinPath, "apisix-bin-path", config.DefaultAPISIXBinPath, "executable binary file path for Apache APISIX, it's not concerned if run mode is \"standalone\"")
 	cmd.PersistentFlags().StringVar(&cfg.APISIXHomePath, "apisix-home-path", config.DefaultAPISIXHomePath, "home path for Apache APISIX, it's not concerned if run mode is \"standalone\"")
+	cmd.PersistentFlags().IntVar(&cfg.InboundPort, "inbound-port", 0, "the port that Apache APISIX listens on for the intercepted inbound traffic, inbound listeners will be translated if it's specified, it should be same to the inbound capture port of iptables and differs from the passthrough port 9081, e.g. 9082")
+	cmd.PersistentFlags().IntSliceVar(&cfg.StreamPorts, "stream-ports", nil, "TCP ports that the stream proxy of Apache APISIX listens on, it's not concerned if run mode is \"standalone\"")
 	return cmd
 }
@@ -4,6 +4,7 @@ import (
 	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"go.uber.org/zap"
 
 	"github.com/api7/apisix-mesh-agent/pkg/id"
@@ -22,6 +23,9 @@ func (adaptor *adaptor) TranslateCluster(c *clusterv3.Cluster) (*apisix.Upstream
 	if err := adaptor.translateClusterTimeoutSettings(c, ups); err != nil {
 		return nil, err
 	}
+	if err := adaptor.translateClusterTransportSocket(c, ups); err != nil {
+		return nil, err
+	}
 	if err := adaptor.translateClusterLoadAssignments(c, ups); err != nil {
 		if err == ErrRequireFurtherEDS {
 			return ups, err
@@ -69,6 +73,32 @@ func (adaptor *adaptor) translateClusterTimeoutSettings(c *clusterv3.Cluster, up
 	return nil
 }
 
+// translateClusterTransportSocket enables the TLS if the cluster uses the TLS transport
+// socket, the client certificate from SDS is filled by the caller once the secret is
+// received, see TranslateClientCertificate.
+func (adaptor *adaptor) translateClusterTransportSocket(c *clusterv3.Cluster, ups *apisix.Upstream) error {
+	var ctx tlsv3.UpstreamTlsContext
+	ok, err := adaptor.unmarshalTlsContext(c.GetTransportSocket(), _upstreamTlsContextv3, &ctx)
+	if err != nil || !ok {
+		return err
+	}
+	ups.Scheme = "https"
+	common := ctx.GetCommonTlsContext()
+	if len(common.GetTlsCertificateSdsSecretConfigs()) > 1 {
+		adaptor.logger.Warnw("only the first client certificate is used",
+			zap.String("cluster_name", c.Name),
+		)
+	}
+	// Apache APISIX cannot verify the upstream certificate, so the
+	// validation context (like the Istio ROOTCA) is not enforced.
+	if common.GetValidationContextType() != nil {
+		adaptor.logger.Warnw("upstream certificate verification is not supported, the validation context is ignored",
+			zap.String("cluster_name", c.Name),
+		)
+	}
+	return nil
+}
+
 func (adaptor *adaptor) translateClusterLoadAssignments(c *clusterv3.Cluster, ups *apisix.Upstream) error {
 	if c.GetClusterType() != nil {
 		return ErrFeatureNotSupportedYet
@@ -0,0 +1,266 @@
+package v3
+
+import (
+	"fmt"
+	"net/url"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	extauthzv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+const (
+	// The maximum timeout that the forward-auth plugin accepts.
+	_maxForwardAuthTimeout = 60000
+	// The HTTP status code used when the authorization service cannot be used.
+	_defaultExtAuthzDeniedStatus = 403
+)
+
+var (
+	_extAuthzv3         = "type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthz"
+	_extAuthzPerRoutev3 = "type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthzPerRoute"
+)
+
+// extAuthz is the translated ext_authz filter.
+type extAuthz struct {
+	// filterName is the name of the ext_authz filter, which
+	// is used to find the per route config.
+	filterName string
+	plugins    map[string]*structpb.Struct
+}
+
+// ExtAuthzClusters returns the clusters that used by the ext_authz filters
+// (only the HTTP service variant), the caller should re-translate the routes
+// once the state of these clusters changed.
+func ExtAuthzClusters(filters map[string][]*hcmv3.HttpFilter) set.StringSet {
+	clusters := set.StringSet{}
+	for _, fs := range filters {
+		for _, f := range fs {
+			if f.GetTypedConfig().GetTypeUrl() != _extAuthzv3 {
+				continue
+			}
+			var ext extauthzv3.ExtAuthz
+			if err := anypb.UnmarshalTo(f.GetTypedConfig(), &ext, proto.UnmarshalOptions{}); err != nil {
+				continue
+			}
+			if cluster := ext.GetHttpService().GetServerUri().GetCluster(); cluster != "" {
+				clusters.Add(cluster)
+			}
+		}
+	}
+	return clusters
+}
+
+// translateExtAuthz translates the ext_authz filter (if any) that applied to the
+// RouteConfiguration to APISIX plugins, nil will be returned if there is no
+// ext_authz filter or it's bypassed.
+func (adaptor *adaptor) translateExtAuthz(rcName string, opts *TranslateOptions) *extAuthz {
+	if opts == nil || opts.RouteHTTPFilters == nil {
+		return nil
+	}
+	var (
+		name string
+		ext  *extauthzv3.ExtAuthz
+	)
+	for _, f := range opts.RouteHTTPFilters[rcName] {
+		if f.GetTypedConfig().GetTypeUrl() != _extAuthzv3 {
+			continue
+		}
+		if ext != nil {
+			adaptor.logger.Warnw("ignore redundant ext_authz filter",
+				zap.String("route_configuration", rcName),
+				zap.Any("filter", f),
+			)
+			continue
+		}
+		var cfg extauthzv3.ExtAuthz
+		if err := anypb.UnmarshalTo(f.GetTypedConfig(), &cfg, proto.UnmarshalOptions{}); err != nil {
+			adaptor.logger.Errorw("failed to unmarshal ext_authz config, requests will be denied",
+				zap.Error(err),
+				zap.String("route_configuration", rcName),
+				zap.Any("filter", f),
+			)
+			return &extAuthz{
+				filterName: f.GetName(),
+				plugins:    denyAllPlugins(_defaultExtAuthzDeniedStatus),
+			}
+		}
+		name = f.GetName()
+		ext = &cfg
+	}
+	if ext == nil {
+		return nil
+	}
+
+	deniedStatus := _defaultExtAuthzDeniedStatus
+	if code := int(ext.GetStatusOnError().GetCode()); code >= 200 {
+		deniedStatus = code
+	}
+
+	if ext.GetGrpcService() != nil {
+		// Apache APISIX doesn't have a plugin which talks to the
+		// envoy.service.auth.v3.Authorization service.
+		adaptor.logger.Warnw("ext_authz with gRPC service is not supported yet, requests will be denied",
+			zap.String("route_configuration", rcName),
+			zap.Any("ext_authz", ext),
+		)
+		return &extAuthz{
+			filterName: name,
+			plugins:    denyAllPlugins(deniedStatus),
+		}
+	}
+
+	fa, err := adaptor.translateExtAuthzHttpService(ext, opts)
+	if err != nil {
+		if ext.GetFailureModeAllow() {
+			adaptor.logger.Warnw("ext_authz is bypassed since failure_mode_allow is enabled",
+				zap.Error(err),
+				zap.String("route_configuration", rcName),
+			)
+			return nil
+		}
+		adaptor.logger.Warnw("failed to translate ext_authz, requests will be denied",
+			zap.Error(err),
+			zap.String("route_configuration", rcName),
+		)
+		return &extAuthz{
+			filterName: name,
+			plugins:    denyAllPlugins(deniedStatus),
+		}
+	}
+	return &extAuthz{
+		filterName: name,
+		plugins:    newPlugins(apisix.ForwardAuthPlugin, fa),
+	}
+}
+
+func (adaptor *adaptor) translateExtAuthzHttpService(ext *extauthzv3.ExtAuthz, opts *TranslateOptions) (*apisix.ForwardAuth, error) {
+	svc := ext.GetHttpService()
+	cluster := svc.GetServerUri().GetCluster()
+	ups, ok := opts.Upstreams[cluster]
+	if !ok {
+		return nil, fmt.Errorf("unknown authorization cluster %s", cluster)
+	}
+	// The forward-auth plugin only accepts a URI, so the first available
+	// endpoint is used, the caller re-translates routes once the endpoints
+	// of the authorization cluster change, see ExtAuthzClusters.
+	var node *apisix.Node
+	for _, n := range ups.GetNodes() {
+		if n.GetWeight() > 0 {
+			node = n
+			break
+		}
+	}
+	if node == nil {
+		return nil, fmt.Errorf("no available endpoints in authorization cluster %s", cluster)
+	}
+
+	scheme := "http"
+	if u, err := url.Parse(svc.GetServerUri().GetUri()); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	fa := &apisix.ForwardAuth{
+		Uri:              fmt.Sprintf("%s://%s:%d%s", scheme, node.Host, node.Port, svc.GetPathPrefix()),
+		AllowDegradation: ext.GetFailureModeAllow(),
+	}
+
+	// The Authorization header is always sent to the authorization
+	// service by Envoy.
+	reqHeaders := set.StringSet{"Authorization": {}}
+	for _, h := range adaptor.getExactHeaderNames(svc.GetAuthorizationRequest().GetAllowedHeaders()) {
+		reqHeaders.Add(h)
+	}
+	fa.RequestHeaders = reqHeaders.OrderedStrings()
+	fa.UpstreamHeaders = adaptor.getExactHeaderNames(svc.GetAuthorizationResponse().GetAllowedUpstreamHeaders())
+	fa.ClientHeaders = adaptor.getExactHeaderNames(svc.GetAuthorizationResponse().GetAllowedClientHeaders())
+
+	if timeout := svc.GetServerUri().GetTimeout(); timeout != nil {
+		ms := timeout.AsDuration().Milliseconds()
+		if ms > _maxForwardAuthTimeout {
+			ms = _maxForwardAuthTimeout
+		}
+		fa.Timeout = int32(ms)
+	}
+	return fa, nil
+}
+
+// getExactHeaderNames extracts the header names from the ListStringMatcher,
+// only the exact matchers can be used since the forward-auth plugin requires
+// concrete header names.
+func (adaptor *adaptor) getExactHeaderNames(matcher *matcherv3.ListStringMatcher) []string {
+	headers := set.StringSet{}
+	for _, pattern := range matcher.GetPatterns() {
+		exact, ok := pattern.GetMatchPattern().(*matcherv3.StringMatcher_Exact)
+		if !ok {
+			adaptor.logger.Warnw("ignore non-exact header matcher in ext_authz",
+				zap.Any("matcher", pattern),
+			)
+			continue
+		}
+		headers.Add(exact.Exact)
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers.OrderedStrings()
+}
+
+// isExtAuthzDisabled checks whether the ext_authz is disabled by the
+// per filter config, config on route has higher priority than the one
+// on virtual host.
+func (adaptor *adaptor) isExtAuthzDisabled(filterName string, vhost *routev3.VirtualHost, route *routev3.Route) bool {
+	for _, cfgs := range []map[string]*anypb.Any{route.GetTypedPerFilterConfig(), vhost.GetTypedPerFilterConfig()} {
+		cfg, ok := cfgs[filterName]
+		if !ok || cfg.GetTypeUrl() != _extAuthzPerRoutev3 {
+			continue
+		}
+		var perRoute extauthzv3.ExtAuthzPerRoute
+		if err := anypb.UnmarshalTo(cfg, &perRoute, proto.UnmarshalOptions{}); err != nil {
+			adaptor.logger.Warnw("failed to unmarshal ExtAuthzPerRoute config",
+				zap.Error(err),
+				zap.Any("route", route),
+			)
+			continue
+		}
+		return perRoute.GetDisabled()
+	}
+	return false
+}
+
+func denyAllPlugins(status int) map[string]*structpb.Struct {
+	return newPlugins(apisix.FaultInjectionPlugin, &apisix.FaultInjection{
+		Abort: &apisix.FaultInjection_Abort{
+			HttpStatus: int32(status),
+		},
+	})
+}
+
+// newPlugins encodes the plugin configuration which is generated by the adaptor.
+func newPlugins(name string, conf proto.Message) map[string]*structpb.Struct {
+	s, err := apisix.NewPluginConfig(conf)
+	if err != nil {
+		// Configurations generated by the adaptor are always encodable.
+		panic(err)
+	}
+	return map[string]*structpb.Struct{name: s}
+}
+
+// clonePlugins deeply copies the plugins so that routes don't share them.
+func clonePlugins(plugins map[string]*structpb.Struct) map[string]*structpb.Struct {
+	if plugins == nil {
+		return nil
+	}
+	cloned := make(map[string]*structpb.Struct, len(plugins))
+	for name, conf := range plugins {
+		cloned[name] = proto.Clone(conf).(*structpb.Struct)
+	}
+	return cloned
+}
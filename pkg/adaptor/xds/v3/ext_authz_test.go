@@ -0,0 +1,235 @@
+package v3
+
+import (
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	extauthzv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func newExtAuthzFilter(t *testing.T, ext *extauthzv3.ExtAuthz) *hcmv3.HttpFilter {
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, ext, proto.MarshalOptions{}))
+	return &hcmv3.HttpFilter{
+		Name: xdswellknown.HTTPExternalAuthorization,
+		ConfigType: &hcmv3.HttpFilter_TypedConfig{
+			TypedConfig: &opaque,
+		},
+	}
+}
+
+func newHttpServiceExtAuthz(failureModeAllow bool) *extauthzv3.ExtAuthz {
+	return &extauthzv3.ExtAuthz{
+		FailureModeAllow: failureModeAllow,
+		Services: &extauthzv3.ExtAuthz_HttpService{
+			HttpService: &extauthzv3.HttpService{
+				ServerUri: &corev3.HttpUri{
+					Uri: "http://opa.default.svc.cluster.local:8181",
+					HttpUpstreamType: &corev3.HttpUri_Cluster{
+						Cluster: "outbound|8181||opa.default.svc.cluster.local",
+					},
+					Timeout: durationpb.New(90 * time.Second),
+				},
+				PathPrefix: "/authz",
+				AuthorizationRequest: &extauthzv3.AuthorizationRequest{
+					AllowedHeaders: &matcherv3.ListStringMatcher{
+						Patterns: []*matcherv3.StringMatcher{
+							{
+								MatchPattern: &matcherv3.StringMatcher_Exact{
+									Exact: "X-User",
+								},
+							},
+							{
+								MatchPattern: &matcherv3.StringMatcher_Prefix{
+									Prefix: "X-Ignored-",
+								},
+							},
+						},
+					},
+				},
+				AuthorizationResponse: &extauthzv3.AuthorizationResponse{
+					AllowedUpstreamHeaders: &matcherv3.ListStringMatcher{
+						Patterns: []*matcherv3.StringMatcher{
+							{
+								MatchPattern: &matcherv3.StringMatcher_Exact{
+									Exact: "X-User-Id",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestTranslateExtAuthz(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	assert.Nil(t, a.translateExtAuthz("rc1", nil))
+
+	opts := &TranslateOptions{
+		RouteHTTPFilters: map[string][]*hcmv3.HttpFilter{
+			"rc1": {newExtAuthzFilter(t, newHttpServiceExtAuthz(false))},
+		},
+		Upstreams: map[string]*apisix.Upstream{
+			"outbound|8181||opa.default.svc.cluster.local": {
+				Nodes: []*apisix.Node{
+					{
+						Host:   "10.0.5.3",
+						Port:   8181,
+						Weight: 0,
+					},
+					{
+						Host:   "10.0.5.4",
+						Port:   8181,
+						Weight: 100,
+					},
+				},
+			},
+		},
+	}
+	assert.Nil(t, a.translateExtAuthz("rc2", opts))
+
+	authz := a.translateExtAuthz("rc1", opts)
+	assert.NotNil(t, authz)
+	assert.Equal(t, authz.filterName, xdswellknown.HTTPExternalAuthorization)
+	assert.Len(t, authz.plugins, 1)
+	var fa apisix.ForwardAuth
+	assert.Nil(t, apisix.DecodePluginConfig(authz.plugins[apisix.ForwardAuthPlugin], &fa))
+	assert.True(t, proto.Equal(&fa, &apisix.ForwardAuth{
+		Uri:             "http://10.0.5.4:8181/authz",
+		RequestHeaders:  []string{"Authorization", "X-User"},
+		UpstreamHeaders: []string{"X-User-Id"},
+		Timeout:         60000,
+	}))
+	assert.Nil(t, fa.Validate())
+	assert.Equal(t, authz.plugins[apisix.ForwardAuthPlugin].AsMap()["request_headers"], []interface{}{"Authorization", "X-User"})
+
+	// Unresolvable authorization cluster, fail closed.
+	opts.Upstreams = nil
+	authz = a.translateExtAuthz("rc1", opts)
+	assert.NotNil(t, authz)
+	assert.Equal(t, authz.plugins[apisix.FaultInjectionPlugin].AsMap(), map[string]interface{}{
+		"abort": map[string]interface{}{"http_status": float64(403)},
+	})
+	assert.Nil(t, authz.plugins[apisix.ForwardAuthPlugin])
+
+	// Unresolvable authorization cluster with failure_mode_allow.
+	opts.RouteHTTPFilters["rc1"] = []*hcmv3.HttpFilter{newExtAuthzFilter(t, newHttpServiceExtAuthz(true))}
+	assert.Nil(t, a.translateExtAuthz("rc1", opts))
+
+	// gRPC service is not supported.
+	opts.RouteHTTPFilters["rc1"] = []*hcmv3.HttpFilter{
+		newExtAuthzFilter(t, &extauthzv3.ExtAuthz{
+			FailureModeAllow: true,
+			Services: &extauthzv3.ExtAuthz_GrpcService{
+				GrpcService: &corev3.GrpcService{},
+			},
+		}),
+	}
+	authz = a.translateExtAuthz("rc1", opts)
+	assert.NotNil(t, authz)
+	assert.Equal(t, authz.plugins[apisix.FaultInjectionPlugin].AsMap(), map[string]interface{}{
+		"abort": map[string]interface{}{"http_status": float64(403)},
+	})
+	assert.Nil(t, authz.plugins[apisix.ForwardAuthPlugin])
+}
+
+func TestIsExtAuthzDisabled(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	var (
+		disabled anypb.Any
+		enabled  anypb.Any
+	)
+	assert.Nil(t, anypb.MarshalFrom(&disabled, &extauthzv3.ExtAuthzPerRoute{
+		Override: &extauthzv3.ExtAuthzPerRoute_Disabled{
+			Disabled: true,
+		},
+	}, proto.MarshalOptions{}))
+	assert.Nil(t, anypb.MarshalFrom(&enabled, &extauthzv3.ExtAuthzPerRoute{
+		Override: &extauthzv3.ExtAuthzPerRoute_CheckSettings{
+			CheckSettings: &extauthzv3.CheckSettings{},
+		},
+	}, proto.MarshalOptions{}))
+
+	name := xdswellknown.HTTPExternalAuthorization
+	vhost := &routev3.VirtualHost{}
+	route := &routev3.Route{}
+	assert.False(t, a.isExtAuthzDisabled(name, vhost, route))
+
+	vhost.TypedPerFilterConfig = map[string]*anypb.Any{name: &disabled}
+	assert.True(t, a.isExtAuthzDisabled(name, vhost, route))
+
+	// Config on route has higher priority.
+	route.TypedPerFilterConfig = map[string]*anypb.Any{name: &enabled}
+	assert.False(t, a.isExtAuthzDisabled(name, vhost, route))
+}
+
+func TestTranslateRouteConfigurationWithExtAuthz(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	var disabled anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&disabled, &extauthzv3.ExtAuthzPerRoute{
+		Override: &extauthzv3.ExtAuthzPerRoute_Disabled{
+			Disabled: true,
+		},
+	}, proto.MarshalOptions{}))
+
+	newRoute := func(name string) *routev3.Route {
+		return &routev3.Route{
+			Name: name,
+			Match: &routev3.RouteMatch{
+				PathSpecifier: &routev3.RouteMatch_Prefix{
+					Prefix: "/" + name,
+				},
+			},
+			Action: &routev3.Route_Route{
+				Route: &routev3.RouteAction{
+					ClusterSpecifier: &routev3.RouteAction_Cluster{
+						Cluster: "httpbin.default.svc.cluster.local",
+					},
+				},
+			},
+		}
+	}
+	route2 := newRoute("route2")
+	route2.TypedPerFilterConfig = map[string]*anypb.Any{
+		xdswellknown.HTTPExternalAuthorization: &disabled,
+	}
+	rc := &routev3.RouteConfiguration{
+		Name: "rc1",
+		VirtualHosts: []*routev3.VirtualHost{
+			{
+				Name:    "vhost1",
+				Domains: []string{"*"},
+				Routes:  []*routev3.Route{newRoute("route1"), route2},
+			},
+		},
+	}
+	opts := &TranslateOptions{
+		RouteHTTPFilters: map[string][]*hcmv3.HttpFilter{
+			"rc1": {newExtAuthzFilter(t, newHttpServiceExtAuthz(false))},
+		},
+	}
+	routes, err := a.TranslateRouteConfiguration(rc, opts)
+	assert.Nil(t, err)
+	assert.Len(t, routes, 2)
+	assert.Equal(t, routes[0].Plugins[apisix.FaultInjectionPlugin].AsMap(), map[string]interface{}{
+		"abort": map[string]interface{}{"http_status": float64(403)},
+	})
+	assert.Nil(t, routes[1].Plugins)
+}
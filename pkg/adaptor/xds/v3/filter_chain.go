@@ -0,0 +1,168 @@
+package v3
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+// patchRoutesWithFilterChains carries the match criteria of the filter chains
+// which use the routes. If there are several filter chains, each of them has
+// its own copy of routes, the filter chain name is suffixed to the route names
+// (and so the ids). Routes of the filter chain without match criteria have a
+// lower priority, since Envoy selects it only if no other filter chain matches.
+func (adaptor *adaptor) patchRoutesWithFilterChains(routes []*apisix.Route, chains []*FilterChain) []*apisix.Route {
+	if len(chains) == 1 {
+		if chains[0].Match != nil {
+			adaptor.patchRoutesWithFilterChainMatch(routes, chains[0].Match)
+		}
+		return routes
+	}
+	var all []*apisix.Route
+	for _, fc := range chains {
+		partial := make([]*apisix.Route, 0, len(routes))
+		for _, r := range routes {
+			cloned := proto.Clone(r).(*apisix.Route)
+			cloned.Name = r.Name + "#" + fc.Name
+			cloned.Id = id.GenID(cloned.Name)
+			if fc.Match == nil {
+				cloned.Priority--
+			}
+			partial = append(partial, cloned)
+		}
+		if fc.Match != nil {
+			adaptor.patchRoutesWithFilterChainMatch(partial, fc.Match)
+		}
+		all = append(all, partial...)
+	}
+	return all
+}
+
+// patchRoutesWithFilterChainMatch translates the match criteria of the filter
+// chain to the match conditions of routes. Source prefix ranges are translated
+// to the remote_addrs; destination prefix ranges, destination port, source ports,
+// server names and transport protocol are translated to the vars.
+// Conditions that cannot be expressed in APISIX are ignored, which means
+// routes will be matched more loosely than the filter chain.
+func (adaptor *adaptor) patchRoutesWithFilterChainMatch(routes []*apisix.Route, fcm *listenerv3.FilterChainMatch) {
+	var vars []*apisix.Var
+
+	// See https://github.com/api7/lua-resty-expr
+	// for the translation details.
+	var dstIPs []string
+	for _, cidr := range fcm.GetPrefixRanges() {
+		ip, ok := adaptor.getCidrRangeHost(cidr)
+		if !ok {
+			// The connection_original_dst is a plain string, so
+			// only the exact address can be matched.
+			adaptor.logger.Warnw("ignore non-host destination prefix range in filter chain match",
+				zap.Any("prefix_range", cidr),
+			)
+			dstIPs = nil
+			break
+		}
+		dstIPs = append(dstIPs, regexp.QuoteMeta(ip))
+	}
+	if len(dstIPs) > 0 {
+		vars = append(vars, &apisix.Var{
+			Vars: []string{"connection_original_dst", "~~", "^(" + strings.Join(dstIPs, "|") + "):"},
+		})
+	}
+	if port := fcm.GetDestinationPort(); port != nil {
+		vars = append(vars, &apisix.Var{
+			Vars: []string{"connection_original_dst", "~~", fmt.Sprintf(":%d$", port.GetValue())},
+		})
+	}
+	if len(fcm.GetSourcePorts()) > 0 {
+		ports := make([]string, 0, len(fcm.GetSourcePorts()))
+		for _, port := range fcm.GetSourcePorts() {
+			ports = append(ports, strconv.Itoa(int(port)))
+		}
+		vars = append(vars, &apisix.Var{
+			Vars: []string{"remote_port", "~~", "^(" + strings.Join(ports, "|") + ")$"},
+		})
+	}
+	if len(fcm.GetServerNames()) > 0 {
+		names := make([]string, 0, len(fcm.GetServerNames()))
+		for _, name := range fcm.GetServerNames() {
+			names = append(names, serverNameToRegex(name))
+		}
+		vars = append(vars, &apisix.Var{
+			Vars: []string{"ssl_server_name", "~~", "^(" + strings.Join(names, "|") + ")$"},
+		})
+	}
+	switch fcm.GetTransportProtocol() {
+	case "":
+	case "tls":
+		vars = append(vars, &apisix.Var{
+			Vars: []string{"scheme", "==", "https"},
+		})
+	case "raw_buffer":
+		vars = append(vars, &apisix.Var{
+			Vars: []string{"scheme", "==", "http"},
+		})
+	default:
+		adaptor.logger.Warnw("ignore unknown transport protocol in filter chain match",
+			zap.String("transport_protocol", fcm.GetTransportProtocol()),
+		)
+	}
+
+	remoteAddrs := set.StringSet{}
+	for _, cidr := range fcm.GetSourcePrefixRanges() {
+		prefixLen := cidr.GetPrefixLen().GetValue()
+		if prefixLen == 0 {
+			// Matches all addresses.
+			remoteAddrs = set.StringSet{}
+			break
+		}
+		remoteAddrs.Add(fmt.Sprintf("%s/%d", cidr.GetAddressPrefix(), prefixLen))
+	}
+
+	for _, r := range routes {
+		if len(remoteAddrs) > 0 {
+			r.RemoteAddrs = remoteAddrs.OrderedStrings()
+		}
+		for _, v := range vars {
+			r.Vars = append(r.Vars, &apisix.Var{
+				Vars: v.Vars,
+			})
+		}
+	}
+}
+
+// getCidrRangeHost returns the host address if the CidrRange
+// contains only one address.
+func (adaptor *adaptor) getCidrRangeHost(cidr *corev3.CidrRange) (string, bool) {
+	ip := net.ParseIP(cidr.GetAddressPrefix())
+	if ip == nil {
+		return "", false
+	}
+	bits := 128
+	if ip.To4() != nil {
+		bits = 32
+	}
+	if int(cidr.GetPrefixLen().GetValue()) != bits {
+		return "", false
+	}
+	return cidr.GetAddressPrefix(), true
+}
+
+// serverNameToRegex converts the server name (might be a wildcard
+// one like "*.apache.org") to the regular expression.
+func serverNameToRegex(name string) string {
+	if strings.HasPrefix(name, "*.") {
+		return ".+" + regexp.QuoteMeta(name[1:])
+	}
+	return regexp.QuoteMeta(name)
+}
@@ -0,0 +1,220 @@
+package v3
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func TestPatchRoutesWithFilterChainMatch(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+	routes := []*apisix.Route{
+		{
+			Name: "route1",
+			Vars: []*apisix.Var{
+				{
+					Vars: []string{"request_method", "==", "GET"},
+				},
+			},
+		},
+		{
+			Name: "route2",
+		},
+	}
+	fcm := &listenerv3.FilterChainMatch{
+		DestinationPort: &wrappers.UInt32Value{
+			Value: 8080,
+		},
+		PrefixRanges: []*corev3.CidrRange{
+			{
+				AddressPrefix: "10.0.5.3",
+				PrefixLen: &wrappers.UInt32Value{
+					Value: 32,
+				},
+			},
+		},
+		SourcePrefixRanges: []*corev3.CidrRange{
+			{
+				AddressPrefix: "10.0.0.0",
+				PrefixLen: &wrappers.UInt32Value{
+					Value: 16,
+				},
+			},
+			{
+				AddressPrefix: "192.168.1.0",
+				PrefixLen: &wrappers.UInt32Value{
+					Value: 24,
+				},
+			},
+		},
+		SourcePorts:       []uint32{10001, 10002},
+		ServerNames:       []string{"apisix.apache.org", "*.apache.org"},
+		TransportProtocol: "tls",
+	}
+	a.patchRoutesWithFilterChainMatch(routes, fcm)
+
+	expectedVars := []*apisix.Var{
+		{
+			Vars: []string{"connection_original_dst", "~~", "^(10\\.0\\.5\\.3):"},
+		},
+		{
+			Vars: []string{"connection_original_dst", "~~", ":8080$"},
+		},
+		{
+			Vars: []string{"remote_port", "~~", "^(10001|10002)$"},
+		},
+		{
+			Vars: []string{"ssl_server_name", "~~", "^(apisix\\.apache\\.org|.+\\.apache\\.org)$"},
+		},
+		{
+			Vars: []string{"scheme", "==", "https"},
+		},
+	}
+	assert.Equal(t, routes[0].Vars[1:], expectedVars)
+	assert.Equal(t, routes[0].Vars[0].Vars, []string{"request_method", "==", "GET"})
+	assert.Equal(t, routes[1].Vars, expectedVars)
+	for _, r := range routes {
+		assert.Equal(t, r.RemoteAddrs, []string{"10.0.0.0/16", "192.168.1.0/24"})
+	}
+
+	// Non-host destination prefix range and catch-all source
+	// prefix range are ignored.
+	routes = []*apisix.Route{
+		{
+			Name: "route1",
+		},
+	}
+	fcm = &listenerv3.FilterChainMatch{
+		PrefixRanges: []*corev3.CidrRange{
+			{
+				AddressPrefix: "10.0.5.0",
+				PrefixLen: &wrappers.UInt32Value{
+					Value: 24,
+				},
+			},
+		},
+		SourcePrefixRanges: []*corev3.CidrRange{
+			{
+				AddressPrefix: "0.0.0.0",
+			},
+		},
+		TransportProtocol: "raw_buffer",
+	}
+	a.patchRoutesWithFilterChainMatch(routes, fcm)
+	assert.Nil(t, routes[0].RemoteAddrs)
+	assert.Equal(t, routes[0].Vars, []*apisix.Var{
+		{
+			Vars: []string{"scheme", "==", "http"},
+		},
+	})
+}
+
+func TestTranslateRouteConfigurationWithFilterChains(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, &hcmv3.HttpConnectionManager{
+		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+			Rds: &hcmv3.Rds{
+				RouteConfigName: "route1",
+			},
+		},
+	}, proto.MarshalOptions{}))
+	newFilterChain := func(name string, port uint32) *listenerv3.FilterChain {
+		return &listenerv3.FilterChain{
+			Name: name,
+			FilterChainMatch: &listenerv3.FilterChainMatch{
+				DestinationPort: &wrappers.UInt32Value{Value: port},
+			},
+			Filters: []*listenerv3.Filter{
+				{
+					Name: xdswellknown.HTTPConnectionManager,
+					ConfigType: &listenerv3.Filter_TypedConfig{
+						TypedConfig: &opaque,
+					},
+				},
+			},
+		}
+	}
+	listener := &listenerv3.Listener{
+		Name: "listener1",
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Address: "0.0.0.0",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: 15006,
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerv3.FilterChain{
+			newFilterChain("chain1", 8080),
+			newFilterChain("chain2", 9090),
+		},
+	}
+	states, err := a.CollectListenerStates([]*listenerv3.Listener{listener}, 0)
+	assert.Nil(t, err)
+	assert.Len(t, states.RouteFilterChains["route1"], 2)
+
+	rc := &routev3.RouteConfiguration{
+		Name: "route1",
+		VirtualHosts: []*routev3.VirtualHost{
+			{
+				Name:    "vhost1",
+				Domains: []string{"*"},
+				Routes: []*routev3.Route{
+					{
+						Name: "route1",
+						Match: &routev3.RouteMatch{
+							PathSpecifier: &routev3.RouteMatch_Prefix{
+								Prefix: "/",
+							},
+						},
+						Action: &routev3.Route_Route{
+							Route: &routev3.RouteAction{
+								ClusterSpecifier: &routev3.RouteAction_Cluster{
+									Cluster: "kubernetes.default.svc.cluster.local",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	routes, err := a.TranslateRouteConfiguration(rc, &TranslateOptions{
+		RouteFilterChains: states.RouteFilterChains,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, routes, 2)
+	assert.Equal(t, routes[0].Name, "route1#vhost1#route1#chain1")
+	assert.Equal(t, routes[1].Name, "route1#vhost1#route1#chain2")
+	assert.NotEqual(t, routes[0].Id, routes[1].Id)
+	assert.Equal(t, routes[0].Vars[0].Vars, []string{"connection_original_dst", "~~", ":8080$"})
+	assert.Equal(t, routes[1].Vars[0].Vars, []string{"connection_original_dst", "~~", ":9090$"})
+
+	// The filter chain which matches all connections has a lower priority.
+	listener.FilterChains[1].FilterChainMatch = nil
+	states, err = a.CollectListenerStates([]*listenerv3.Listener{listener}, 0)
+	assert.Nil(t, err)
+	routes, err = a.TranslateRouteConfiguration(rc, &TranslateOptions{
+		RouteFilterChains: states.RouteFilterChains,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, routes, 2)
+	assert.Len(t, routes[0].Vars, 1)
+	assert.Len(t, routes[1].Vars, 0)
+	assert.Greater(t, routes[0].Priority, routes[1].Priority)
+}
@@ -1,6 +1,9 @@
 package v3
 
 import (
+	"fmt"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
@@ -10,6 +13,7 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
 )
 
 var (
@@ -22,24 +26,16 @@ func (adaptor *adaptor) CollectRouteNamesAndConfigs(l *listenerv3.Listener) ([]s
 		staticConfigs []*routev3.RouteConfiguration
 	)
 
-	for _, fc := range l.FilterChains {
-		for _, f := range fc.Filters {
-			if f.Name == xdswellknown.HTTPConnectionManager && f.GetTypedConfig().GetTypeUrl() == _hcmv3 {
-				var hcm hcmv3.HttpConnectionManager
-				if err := anypb.UnmarshalTo(f.GetTypedConfig(), &hcm, proto.UnmarshalOptions{}); err != nil {
-					log.Errorw("failed to unmarshal HttpConnectionManager config",
-						zap.Error(err),
-						zap.Any("listener", l),
-					)
-					return nil, nil, err
-				}
-				if hcm.GetRds() != nil {
-					rdsNames = append(rdsNames, hcm.GetRds().GetRouteConfigName())
-				} else if hcm.GetRouteConfig() != nil {
-					// TODO deep copy?
-					staticConfigs = append(staticConfigs, hcm.GetRouteConfig())
-				}
-			}
+	hcms, err := collectHttpConnectionManagers(l)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, hcm := range hcms {
+		if hcm.GetRds() != nil {
+			rdsNames = append(rdsNames, hcm.GetRds().GetRouteConfigName())
+		} else if hcm.GetRouteConfig() != nil {
+			// TODO deep copy?
+			staticConfigs = append(staticConfigs, hcm.GetRouteConfig())
 		}
 	}
 	adaptor.logger.Debugw("got route names and config from listener",
@@ -49,3 +45,247 @@ func (adaptor *adaptor) CollectRouteNamesAndConfigs(l *listenerv3.Listener) ([]s
 	)
 	return rdsNames, staticConfigs, nil
 }
+
+func (adaptor *adaptor) CollectRouteHTTPFilters(l *listenerv3.Listener) (map[string][]*hcmv3.HttpFilter, error) {
+	hcms, err := collectHttpConnectionManagers(l)
+	if err != nil {
+		return nil, err
+	}
+	filters := make(map[string][]*hcmv3.HttpFilter)
+	for _, hcm := range hcms {
+		var name string
+		if hcm.GetRds() != nil {
+			name = hcm.GetRds().GetRouteConfigName()
+		} else if hcm.GetRouteConfig() != nil {
+			name = hcm.GetRouteConfig().GetName()
+		} else {
+			continue
+		}
+		if len(hcm.GetHttpFilters()) > 0 {
+			filters[name] = hcm.GetHttpFilters()
+		}
+	}
+	return filters, nil
+}
+
+func (adaptor *adaptor) CollectRouteUpgradeConfigs(l *listenerv3.Listener) (map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig, error) {
+	hcms, err := collectHttpConnectionManagers(l)
+	if err != nil {
+		return nil, err
+	}
+	upgrades := make(map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig)
+	for _, hcm := range hcms {
+		var name string
+		if hcm.GetRds() != nil {
+			name = hcm.GetRds().GetRouteConfigName()
+		} else if hcm.GetRouteConfig() != nil {
+			name = hcm.GetRouteConfig().GetName()
+		} else {
+			continue
+		}
+		if len(hcm.GetUpgradeConfigs()) > 0 {
+			upgrades[name] = hcm.GetUpgradeConfigs()
+		}
+	}
+	return upgrades, nil
+}
+
+func (adaptor *adaptor) CollectRouteFilterChains(l *listenerv3.Listener) (map[string][]*FilterChain, error) {
+	chains := make(map[string][]*FilterChain)
+	for i, fc := range l.FilterChains {
+		hcms, err := filterChainHttpConnectionManagers(l, fc)
+		if err != nil {
+			return nil, err
+		}
+		chainName := fc.GetName()
+		if chainName == "" {
+			chainName = fmt.Sprintf("%s#%d", l.GetName(), i)
+		}
+		for _, hcm := range hcms {
+			var name string
+			if hcm.GetRds() != nil {
+				name = hcm.GetRds().GetRouteConfigName()
+			} else if hcm.GetRouteConfig() != nil {
+				name = hcm.GetRouteConfig().GetName()
+			} else {
+				continue
+			}
+			// Like the inbound listener of Istio, the plain text and TLS
+			// filter chains of the same port share the route configuration.
+			chains[name] = appendFilterChain(chains[name], &FilterChain{
+				Name:  chainName,
+				Match: fc.GetFilterChainMatch(),
+			})
+		}
+	}
+	return chains, nil
+}
+
+// appendFilterChain appends the filter chain if there is no filter chain with
+// the same match criteria, names are made unique so that they can be suffixed
+// to the route names.
+func appendFilterChain(chains []*FilterChain, fc *FilterChain) []*FilterChain {
+	match := fc.Match
+	if match != nil && proto.Size(match) == 0 {
+		match = nil
+	}
+	for _, c := range chains {
+		if proto.Equal(c.Match, match) {
+			return chains
+		}
+	}
+	name := fc.Name
+	for _, c := range chains {
+		if c.Name == name {
+			name = fmt.Sprintf("%s#%d", fc.Name, len(chains))
+			break
+		}
+	}
+	return append(chains, &FilterChain{
+		Name:  name,
+		Match: match,
+	})
+}
+
+func (adaptor *adaptor) CollectListenerStates(all []*listenerv3.Listener, inboundPort int) (*ListenerStates, error) {
+	states := &ListenerStates{
+		RouteOwnership:      make(map[string]string),
+		RouteHTTPFilters:    make(map[string][]*hcmv3.HttpFilter),
+		RouteUpgradeConfigs: make(map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig),
+		RouteFilterChains:   make(map[string][]*FilterChain),
+		InboundRoutes:       set.StringSet{},
+		SecretServerNames:   make(map[string][]string),
+	}
+	rdsNames := set.StringSet{}
+	staticNames := set.StringSet{}
+	serverNames := make(map[string]set.StringSet)
+	for _, l := range all {
+		sockAddr := l.GetAddress().GetSocketAddress()
+		if sockAddr == nil || sockAddr.GetPortValue() == 0 {
+			// Only use listener which listens on socket.
+			// TODO Support named port.
+			continue
+		}
+		inbound := l.GetTrafficDirection() == corev3.TrafficDirection_INBOUND
+		if inbound && inboundPort == 0 {
+			adaptor.logger.Debugw("ignore inbound listener since the inbound port is not specified",
+				zap.String("listener", l.GetName()),
+			)
+			continue
+		}
+		addr := fmt.Sprintf("%s:%d", sockAddr.GetAddress(), sockAddr.GetPortValue())
+		names, cfgs, err := adaptor.CollectRouteNamesAndConfigs(l)
+		if err != nil {
+			return nil, fmt.Errorf("listener %s: %s", l.GetName(), err)
+		}
+		owned := append([]string{}, names...)
+		for _, name := range names {
+			if _, ok := rdsNames[name]; ok {
+				continue
+			}
+			rdsNames.Add(name)
+			states.RdsNames = append(states.RdsNames, name)
+		}
+		for _, cfg := range cfgs {
+			owned = append(owned, cfg.GetName())
+			// Several filter chains might share the same route
+			// configuration, e.g. the TLS and plaintext chains
+			// of the inbound listener.
+			if _, ok := staticNames[cfg.GetName()]; ok {
+				continue
+			}
+			staticNames.Add(cfg.GetName())
+			states.StaticRouteConfigurations = append(states.StaticRouteConfigurations, cfg)
+		}
+		for _, name := range owned {
+			// The original destination of the inbound traffic is the
+			// address of the application, not the inbound listener,
+			// so the destination port in filter chain match is used.
+			if inbound {
+				states.InboundRoutes.Add(name)
+				continue
+			}
+			states.RouteOwnership[name] = addr
+		}
+		filters, err := adaptor.CollectRouteHTTPFilters(l)
+		if err != nil {
+			return nil, fmt.Errorf("listener %s: %s", l.GetName(), err)
+		}
+		for name, fs := range filters {
+			states.RouteHTTPFilters[name] = fs
+		}
+		upgrades, err := adaptor.CollectRouteUpgradeConfigs(l)
+		if err != nil {
+			return nil, fmt.Errorf("listener %s: %s", l.GetName(), err)
+		}
+		for name, ucs := range upgrades {
+			states.RouteUpgradeConfigs[name] = ucs
+		}
+		chains, err := adaptor.CollectRouteFilterChains(l)
+		if err != nil {
+			return nil, fmt.Errorf("listener %s: %s", l.GetName(), err)
+		}
+		// The same route configuration might be used by several listeners,
+		// each of them has its own routes.
+		for name, fcs := range chains {
+			for _, fc := range fcs {
+				states.RouteFilterChains[name] = appendFilterChain(states.RouteFilterChains[name], fc)
+			}
+		}
+		secrets, err := adaptor.CollectListenerSecrets(l)
+		if err != nil {
+			return nil, fmt.Errorf("listener %s: %s", l.GetName(), err)
+		}
+		for name, sns := range secrets {
+			if _, ok := serverNames[name]; !ok {
+				serverNames[name] = set.StringSet{}
+			}
+			for _, sn := range sns {
+				serverNames[name].Add(sn)
+			}
+		}
+		states.Listeners = append(states.Listeners, l)
+	}
+	// Routes used by a filter chain that matches all connections
+	// need no extra match conditions.
+	for name, fcs := range states.RouteFilterChains {
+		if len(fcs) == 1 && fcs[0].Match == nil {
+			delete(states.RouteFilterChains, name)
+		}
+	}
+	// The same secret might be used by several listeners.
+	for name, sns := range serverNames {
+		states.SecretServerNames[name] = sns.OrderedStrings()
+	}
+	return states, nil
+}
+
+func collectHttpConnectionManagers(l *listenerv3.Listener) ([]*hcmv3.HttpConnectionManager, error) {
+	var hcms []*hcmv3.HttpConnectionManager
+	for _, fc := range l.FilterChains {
+		partial, err := filterChainHttpConnectionManagers(l, fc)
+		if err != nil {
+			return nil, err
+		}
+		hcms = append(hcms, partial...)
+	}
+	return hcms, nil
+}
+
+func filterChainHttpConnectionManagers(l *listenerv3.Listener, fc *listenerv3.FilterChain) ([]*hcmv3.HttpConnectionManager, error) {
+	var hcms []*hcmv3.HttpConnectionManager
+	for _, f := range fc.Filters {
+		if f.Name == xdswellknown.HTTPConnectionManager && f.GetTypedConfig().GetTypeUrl() == _hcmv3 {
+			var hcm hcmv3.HttpConnectionManager
+			if err := anypb.UnmarshalTo(f.GetTypedConfig(), &hcm, proto.UnmarshalOptions{}); err != nil {
+				log.Errorw("failed to unmarshal HttpConnectionManager config",
+					zap.Error(err),
+					zap.Any("listener", l),
+				)
+				return nil, err
+			}
+			hcms = append(hcms, &hcm)
+		}
+	}
+	return hcms, nil
+}
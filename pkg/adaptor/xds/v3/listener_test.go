@@ -3,10 +3,12 @@ package v3
 import (
 	"testing"
 
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
@@ -94,3 +96,284 @@ func TestCollectRouteNamesAndConfigs(t *testing.T) {
 	assert.Len(t, staticConfigs[0].VirtualHosts, 1)
 	assert.Equal(t, staticConfigs[0].VirtualHosts[0].Name, "v1")
 }
+
+func TestCollectRouteHTTPFilters(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	var (
+		any1 anypb.Any
+		any2 anypb.Any
+	)
+
+	f1 := &hcmv3.HttpConnectionManager{
+		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+			Rds: &hcmv3.Rds{
+				RouteConfigName: "route1",
+			},
+		},
+		HttpFilters: []*hcmv3.HttpFilter{
+			{
+				Name: xdswellknown.HTTPExternalAuthorization,
+			},
+			{
+				Name: xdswellknown.Router,
+			},
+		},
+	}
+	f2 := &hcmv3.HttpConnectionManager{
+		RouteSpecifier: &hcmv3.HttpConnectionManager_RouteConfig{
+			RouteConfig: &routev3.RouteConfiguration{
+				Name: "route2",
+			},
+		},
+	}
+	assert.Nil(t, anypb.MarshalFrom(&any1, f1, proto.MarshalOptions{}))
+	assert.Nil(t, anypb.MarshalFrom(&any2, f2, proto.MarshalOptions{}))
+
+	listener := &listenerv3.Listener{
+		Name: "listener1",
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				Filters: []*listenerv3.Filter{
+					{
+						Name: xdswellknown.HTTPConnectionManager,
+						ConfigType: &listenerv3.Filter_TypedConfig{
+							TypedConfig: &any1,
+						},
+					},
+					{
+						Name: xdswellknown.HTTPConnectionManager,
+						ConfigType: &listenerv3.Filter_TypedConfig{
+							TypedConfig: &any2,
+						},
+					},
+				},
+			},
+		},
+	}
+	filters, err := a.CollectRouteHTTPFilters(listener)
+	assert.Nil(t, err)
+	assert.Len(t, filters, 1)
+	assert.Len(t, filters["route1"], 2)
+	assert.Equal(t, filters["route1"][0].Name, xdswellknown.HTTPExternalAuthorization)
+}
+
+func TestCollectRouteUpgradeConfigs(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	var (
+		any1 anypb.Any
+		any2 anypb.Any
+	)
+
+	f1 := &hcmv3.HttpConnectionManager{
+		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+			Rds: &hcmv3.Rds{
+				RouteConfigName: "route1",
+			},
+		},
+		UpgradeConfigs: []*hcmv3.HttpConnectionManager_UpgradeConfig{
+			{
+				UpgradeType: "websocket",
+			},
+		},
+	}
+	f2 := &hcmv3.HttpConnectionManager{
+		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+			Rds: &hcmv3.Rds{
+				RouteConfigName: "route2",
+			},
+		},
+	}
+	assert.Nil(t, anypb.MarshalFrom(&any1, f1, proto.MarshalOptions{}))
+	assert.Nil(t, anypb.MarshalFrom(&any2, f2, proto.MarshalOptions{}))
+
+	listener := &listenerv3.Listener{
+		Name: "listener1",
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				Filters: []*listenerv3.Filter{
+					{
+						Name: xdswellknown.HTTPConnectionManager,
+						ConfigType: &listenerv3.Filter_TypedConfig{
+							TypedConfig: &any1,
+						},
+					},
+					{
+						Name: xdswellknown.HTTPConnectionManager,
+						ConfigType: &listenerv3.Filter_TypedConfig{
+							TypedConfig: &any2,
+						},
+					},
+				},
+			},
+		},
+	}
+	upgrades, err := a.CollectRouteUpgradeConfigs(listener)
+	assert.Nil(t, err)
+	assert.Len(t, upgrades, 1)
+	assert.Len(t, upgrades["route1"], 1)
+	assert.Equal(t, upgrades["route1"][0].UpgradeType, "websocket")
+}
+
+func TestCollectRouteFilterChains(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	newHCM := func(rdsName string) *anypb.Any {
+		var opaque anypb.Any
+		hcm := &hcmv3.HttpConnectionManager{
+			RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+				Rds: &hcmv3.Rds{
+					RouteConfigName: rdsName,
+				},
+			},
+		}
+		assert.Nil(t, anypb.MarshalFrom(&opaque, hcm, proto.MarshalOptions{}))
+		return &opaque
+	}
+	newFilterChain := func(rdsName string, fcm *listenerv3.FilterChainMatch) *listenerv3.FilterChain {
+		return &listenerv3.FilterChain{
+			FilterChainMatch: fcm,
+			Filters: []*listenerv3.Filter{
+				{
+					Name: xdswellknown.HTTPConnectionManager,
+					ConfigType: &listenerv3.Filter_TypedConfig{
+						TypedConfig: newHCM(rdsName),
+					},
+				},
+			},
+		}
+	}
+
+	listener := &listenerv3.Listener{
+		Name: "listener1",
+		FilterChains: []*listenerv3.FilterChain{
+			newFilterChain("route1", &listenerv3.FilterChainMatch{
+				ServerNames: []string{"a.apache.org"},
+			}),
+			newFilterChain("route2", &listenerv3.FilterChainMatch{
+				ServerNames: []string{"b.apache.org"},
+			}),
+			// route3 is shared by filter chains with different match criteria.
+			newFilterChain("route3", &listenerv3.FilterChainMatch{
+				TransportProtocol: "tls",
+			}),
+			newFilterChain("route3", &listenerv3.FilterChainMatch{
+				TransportProtocol: "raw_buffer",
+			}),
+			newFilterChain("route4", nil),
+			// route5 is shared by the plain text and TLS filter chains of
+			// the same port, like the inbound listener of Istio.
+			newFilterChain("route5", &listenerv3.FilterChainMatch{
+				DestinationPort:      &wrappers.UInt32Value{Value: 8080},
+				TransportProtocol:    "tls",
+				ApplicationProtocols: []string{"istio-http/1.0", "istio-http/1.1"},
+			}),
+			newFilterChain("route5", &listenerv3.FilterChainMatch{
+				DestinationPort:      &wrappers.UInt32Value{Value: 8080},
+				TransportProtocol:    "raw_buffer",
+				ApplicationProtocols: []string{"http/1.0", "http/1.1"},
+			}),
+		},
+	}
+	chains, err := a.CollectRouteFilterChains(listener)
+	assert.Nil(t, err)
+	assert.Len(t, chains, 5)
+	assert.Len(t, chains["route1"], 1)
+	assert.Equal(t, chains["route1"][0].Name, "listener1#0")
+	assert.Equal(t, chains["route1"][0].Match.ServerNames, []string{"a.apache.org"})
+	assert.Equal(t, chains["route2"][0].Match.ServerNames, []string{"b.apache.org"})
+	assert.Len(t, chains["route3"], 2)
+	assert.Equal(t, chains["route3"][0].Match.TransportProtocol, "tls")
+	assert.Equal(t, chains["route3"][1].Match.TransportProtocol, "raw_buffer")
+	assert.Len(t, chains["route4"], 1)
+	assert.Nil(t, chains["route4"][0].Match)
+	// Criteria are kept as is rather than intersected.
+	assert.Len(t, chains["route5"], 2)
+	assert.Equal(t, chains["route5"][0].Name, "listener1#5")
+	assert.Equal(t, chains["route5"][1].Name, "listener1#6")
+	assert.Equal(t, chains["route5"][1].Match.ApplicationProtocols, []string{"http/1.0", "http/1.1"})
+
+	// Filter chains with the same criteria are collected once.
+	listener.FilterChains = append(listener.FilterChains, newFilterChain("route1", &listenerv3.FilterChainMatch{
+		ServerNames: []string{"a.apache.org"},
+	}))
+	chains, err = a.CollectRouteFilterChains(listener)
+	assert.Nil(t, err)
+	assert.Len(t, chains["route1"], 1)
+}
+
+func TestCollectListenerStates(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	newListener := func(name string, port uint32, dir corev3.TrafficDirection, hcm *hcmv3.HttpConnectionManager) *listenerv3.Listener {
+		var typed anypb.Any
+		assert.Nil(t, anypb.MarshalFrom(&typed, hcm, proto.MarshalOptions{}))
+		l := &listenerv3.Listener{
+			Name:             name,
+			TrafficDirection: dir,
+			FilterChains: []*listenerv3.FilterChain{
+				{
+					Filters: []*listenerv3.Filter{
+						{
+							Name: xdswellknown.HTTPConnectionManager,
+							ConfigType: &listenerv3.Filter_TypedConfig{
+								TypedConfig: &typed,
+							},
+						},
+					},
+				},
+			},
+		}
+		if port > 0 {
+			l.Address = &corev3.Address{
+				Address: &corev3.Address_SocketAddress{
+					SocketAddress: &corev3.SocketAddress{
+						Address: "0.0.0.0",
+						PortSpecifier: &corev3.SocketAddress_PortValue{
+							PortValue: port,
+						},
+					},
+				},
+			}
+		}
+		return l
+	}
+	rds := func(name string) *hcmv3.HttpConnectionManager {
+		return &hcmv3.HttpConnectionManager{
+			RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+				Rds: &hcmv3.Rds{
+					RouteConfigName: name,
+				},
+			},
+		}
+	}
+	listeners := []*listenerv3.Listener{
+		newListener("l1", 8080, corev3.TrafficDirection_OUTBOUND, rds("route1")),
+		newListener("l2", 9080, corev3.TrafficDirection_OUTBOUND, rds("route1")),
+		newListener("l3", 0, corev3.TrafficDirection_OUTBOUND, rds("route2")),
+		newListener("virtualInbound", 15006, corev3.TrafficDirection_INBOUND, &hcmv3.HttpConnectionManager{
+			RouteSpecifier: &hcmv3.HttpConnectionManager_RouteConfig{
+				RouteConfig: &routev3.RouteConfiguration{
+					Name: "inbound|80",
+				},
+			},
+		}),
+	}
+
+	states, err := a.CollectListenerStates(listeners, 0)
+	assert.Nil(t, err)
+	assert.Len(t, states.Listeners, 2)
+	assert.Equal(t, states.RdsNames, []string{"route1"})
+	assert.Len(t, states.StaticRouteConfigurations, 0)
+	assert.Equal(t, states.RouteOwnership, map[string]string{"route1": "0.0.0.0:9080"})
+	assert.Len(t, states.InboundRoutes, 0)
+
+	states, err = a.CollectListenerStates(listeners, 9082)
+	assert.Nil(t, err)
+	assert.Len(t, states.Listeners, 3)
+	assert.Len(t, states.StaticRouteConfigurations, 1)
+	assert.Equal(t, states.StaticRouteConfigurations[0].Name, "inbound|80")
+	assert.Equal(t, states.InboundRoutes.Strings(), []string{"inbound|80"})
+	assert.NotContains(t, states.RouteOwnership, "inbound|80")
+}
@@ -0,0 +1,121 @@
+package v3
+
+import (
+	rbacconfigv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// The HTTP status code that Envoy responds when the RBAC filter denies
+	// the request.
+	_rbacDeniedStatus = 403
+)
+
+var (
+	_rbacv3         = "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC"
+	_rbacPerRoutev3 = "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBACPerRoute"
+)
+
+// rbac is the translated RBAC filter.
+type rbac struct {
+	// filterName is the name of the RBAC filter, which
+	// is used to find the per route config.
+	filterName string
+	// denied means requests are denied by the filter.
+	denied bool
+}
+
+// translateRBAC translates the RBAC filters that applied to the RouteConfiguration.
+// Apache APISIX doesn't have a plugin which evaluates the RBAC policies, so requests
+// are denied unless the policies are not enforced or allow all requests, otherwise
+// the policies (e.g. the AuthorizationPolicy of Istio) are bypassed silently.
+func (adaptor *adaptor) translateRBAC(rcName string, opts *TranslateOptions) []*rbac {
+	if opts == nil || opts.RouteHTTPFilters == nil {
+		return nil
+	}
+	var filters []*rbac
+	for _, f := range opts.RouteHTTPFilters[rcName] {
+		if f.GetTypedConfig().GetTypeUrl() != _rbacv3 {
+			continue
+		}
+		var cfg rbacv3.RBAC
+		if err := anypb.UnmarshalTo(f.GetTypedConfig(), &cfg, proto.UnmarshalOptions{}); err != nil {
+			adaptor.logger.Errorw("failed to unmarshal RBAC config, requests will be denied",
+				zap.Error(err),
+				zap.String("route_configuration", rcName),
+				zap.Any("filter", f),
+			)
+			filters = append(filters, &rbac{
+				filterName: f.GetName(),
+				denied:     true,
+			})
+			continue
+		}
+		filters = append(filters, &rbac{
+			filterName: f.GetName(),
+			denied:     adaptor.isRBACDenied(rcName, &cfg),
+		})
+	}
+	return filters
+}
+
+// isRBACDenied checks whether requests are denied by the RBAC config, only
+// the policies which are not enforced or match all requests can be evaluated.
+func (adaptor *adaptor) isRBACDenied(rcName string, cfg *rbacv3.RBAC) bool {
+	rules := cfg.GetRules()
+	if rules == nil {
+		// Shadow rules are not enforced.
+		return false
+	}
+	switch rules.GetAction() {
+	case rbacconfigv3.RBAC_LOG:
+		return false
+	case rbacconfigv3.RBAC_DENY:
+		if len(rules.GetPolicies()) == 0 {
+			return false
+		}
+	case rbacconfigv3.RBAC_ALLOW:
+		if len(rules.GetPolicies()) == 0 {
+			return true
+		}
+	}
+	adaptor.logger.Warnw("RBAC policies are not supported yet, requests will be denied",
+		zap.String("route_configuration", rcName),
+		zap.Any("rules", rules),
+	)
+	return true
+}
+
+// isDeniedByRBAC checks whether the route is denied by the RBAC filters, the
+// per filter config overrides the filter config, config on route has higher
+// priority than the one on virtual host.
+func (adaptor *adaptor) isDeniedByRBAC(rcName string, filters []*rbac, vhost *routev3.VirtualHost, route *routev3.Route) bool {
+	for _, f := range filters {
+		denied := f.denied
+		for _, cfgs := range []map[string]*anypb.Any{route.GetTypedPerFilterConfig(), vhost.GetTypedPerFilterConfig()} {
+			cfg, ok := cfgs[f.filterName]
+			if !ok || cfg.GetTypeUrl() != _rbacPerRoutev3 {
+				continue
+			}
+			var perRoute rbacv3.RBACPerRoute
+			if err := anypb.UnmarshalTo(cfg, &perRoute, proto.UnmarshalOptions{}); err != nil {
+				adaptor.logger.Warnw("failed to unmarshal RBACPerRoute config",
+					zap.Error(err),
+					zap.Any("route", route),
+				)
+				continue
+			}
+			// The filter is disabled if the override config is absent.
+			denied = adaptor.isRBACDenied(rcName, perRoute.GetRbac())
+			break
+		}
+		if denied {
+			return true
+		}
+	}
+	return false
+}
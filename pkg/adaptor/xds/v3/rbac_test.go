@@ -0,0 +1,176 @@
+package v3
+
+import (
+	"testing"
+
+	rbacconfigv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func newRBACFilter(t *testing.T, cfg *rbacv3.RBAC) *hcmv3.HttpFilter {
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, cfg, proto.MarshalOptions{}))
+	return &hcmv3.HttpFilter{
+		Name: xdswellknown.HTTPRoleBasedAccessControl,
+		ConfigType: &hcmv3.HttpFilter_TypedConfig{
+			TypedConfig: &opaque,
+		},
+	}
+}
+
+func newRBAC(action rbacconfigv3.RBAC_Action, withPolicy bool) *rbacv3.RBAC {
+	rules := &rbacconfigv3.RBAC{
+		Action: action,
+	}
+	if withPolicy {
+		rules.Policies = map[string]*rbacconfigv3.Policy{
+			"ns[default]-policy[httpbin]-rule[0]": {
+				Permissions: []*rbacconfigv3.Permission{
+					{
+						Rule: &rbacconfigv3.Permission_Any{Any: true},
+					},
+				},
+				Principals: []*rbacconfigv3.Principal{
+					{
+						Identifier: &rbacconfigv3.Principal_Any{Any: true},
+					},
+				},
+			},
+		}
+	}
+	return &rbacv3.RBAC{Rules: rules}
+}
+
+func TestTranslateRBAC(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+	assert.Nil(t, a.translateRBAC("rc1", nil))
+
+	opts := &TranslateOptions{
+		RouteHTTPFilters: map[string][]*hcmv3.HttpFilter{
+			"rc1": {
+				newRBACFilter(t, &rbacv3.RBAC{
+					ShadowRules: newRBAC(rbacconfigv3.RBAC_ALLOW, true).Rules,
+				}),
+				newRBACFilter(t, newRBAC(rbacconfigv3.RBAC_LOG, true)),
+				newRBACFilter(t, newRBAC(rbacconfigv3.RBAC_DENY, false)),
+				newRBACFilter(t, newRBAC(rbacconfigv3.RBAC_ALLOW, false)),
+				newRBACFilter(t, newRBAC(rbacconfigv3.RBAC_ALLOW, true)),
+				newRBACFilter(t, newRBAC(rbacconfigv3.RBAC_DENY, true)),
+			},
+		},
+	}
+	filters := a.translateRBAC("rc1", opts)
+	assert.Len(t, filters, 6)
+	var denied []bool
+	for _, f := range filters {
+		assert.Equal(t, f.filterName, xdswellknown.HTTPRoleBasedAccessControl)
+		denied = append(denied, f.denied)
+	}
+	assert.Equal(t, denied, []bool{false, false, false, true, true, true})
+
+	// Bad config.
+	opts.RouteHTTPFilters["rc1"] = []*hcmv3.HttpFilter{
+		{
+			Name: xdswellknown.HTTPRoleBasedAccessControl,
+			ConfigType: &hcmv3.HttpFilter_TypedConfig{
+				TypedConfig: &anypb.Any{
+					TypeUrl: _rbacv3,
+					Value:   []byte("bad"),
+				},
+			},
+		},
+	}
+	filters = a.translateRBAC("rc1", opts)
+	assert.Len(t, filters, 1)
+	assert.True(t, filters[0].denied)
+}
+
+func TestIsDeniedByRBAC(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	var (
+		disabled anypb.Any
+		enforced anypb.Any
+	)
+	assert.Nil(t, anypb.MarshalFrom(&disabled, &rbacv3.RBACPerRoute{}, proto.MarshalOptions{}))
+	assert.Nil(t, anypb.MarshalFrom(&enforced, &rbacv3.RBACPerRoute{
+		Rbac: newRBAC(rbacconfigv3.RBAC_ALLOW, true),
+	}, proto.MarshalOptions{}))
+
+	name := xdswellknown.HTTPRoleBasedAccessControl
+	filters := []*rbac{{filterName: name, denied: true}}
+	vhost := &routev3.VirtualHost{}
+	route := &routev3.Route{}
+	assert.False(t, a.isDeniedByRBAC("rc1", nil, vhost, route))
+	assert.True(t, a.isDeniedByRBAC("rc1", filters, vhost, route))
+
+	vhost.TypedPerFilterConfig = map[string]*anypb.Any{name: &disabled}
+	assert.False(t, a.isDeniedByRBAC("rc1", filters, vhost, route))
+
+	// Config on route has higher priority.
+	route.TypedPerFilterConfig = map[string]*anypb.Any{name: &enforced}
+	assert.True(t, a.isDeniedByRBAC("rc1", filters, vhost, route))
+}
+
+func TestTranslateRouteConfigurationWithRBAC(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	rc := &routev3.RouteConfiguration{
+		Name: "inbound|80||",
+		VirtualHosts: []*routev3.VirtualHost{
+			{
+				Name:    "inbound|http|80",
+				Domains: []string{"*"},
+				Routes: []*routev3.Route{
+					{
+						Name: "default",
+						Match: &routev3.RouteMatch{
+							PathSpecifier: &routev3.RouteMatch_Prefix{
+								Prefix: "/",
+							},
+						},
+						Action: &routev3.Route_Route{
+							Route: &routev3.RouteAction{
+								ClusterSpecifier: &routev3.RouteAction_Cluster{
+									Cluster: "inbound|80||",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	opts := &TranslateOptions{
+		RouteHTTPFilters: map[string][]*hcmv3.HttpFilter{
+			"inbound|80||": {
+				newExtAuthzFilter(t, newHttpServiceExtAuthz(true)),
+				newRBACFilter(t, newRBAC(rbacconfigv3.RBAC_DENY, true)),
+			},
+		},
+	}
+	routes, err := a.TranslateRouteConfiguration(rc, opts)
+	assert.Nil(t, err)
+	assert.Len(t, routes, 1)
+	assert.Len(t, routes[0].Plugins, 1)
+	assert.Equal(t, routes[0].Plugins[apisix.FaultInjectionPlugin].AsMap(), map[string]interface{}{
+		"abort": map[string]interface{}{"http_status": float64(403)},
+	})
+
+	opts.RouteHTTPFilters["inbound|80||"] = []*hcmv3.HttpFilter{
+		newRBACFilter(t, newRBAC(rbacconfigv3.RBAC_DENY, false)),
+	}
+	routes, err = a.TranslateRouteConfiguration(rc, opts)
+	assert.Nil(t, err)
+	assert.Len(t, routes, 1)
+	assert.Nil(t, routes[0].Plugins)
+}
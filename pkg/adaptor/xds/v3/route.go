@@ -2,9 +2,11 @@ package v3
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"go.uber.org/zap"
 
@@ -19,8 +21,15 @@ const (
 
 func (adaptor *adaptor) TranslateRouteConfiguration(r *routev3.RouteConfiguration, opts *TranslateOptions) ([]*apisix.Route, error) {
 	var routes []*apisix.Route
-	for _, vhost := range r.GetVirtualHosts() {
-		partial, err := adaptor.translateVirtualHost(r.Name, vhost, opts)
+	authz := adaptor.translateExtAuthz(r.Name, opts)
+	rbacs := adaptor.translateRBAC(r.Name, opts)
+	vhosts := r.GetVirtualHosts()
+	if r.GetVhds() != nil && opts != nil && opts.RouteVirtualHosts != nil {
+		// Virtual hosts from VHDS are merged into the route configuration.
+		vhosts = append(vhosts[:len(vhosts):len(vhosts)], opts.RouteVirtualHosts[r.Name]...)
+	}
+	for _, vhost := range vhosts {
+		partial, err := adaptor.translateVirtualHost(r.Name, vhost, opts, authz, rbacs)
 		if err != nil {
 			adaptor.logger.Errorw("failed to translate VirtualHost",
 				zap.Error(err),
@@ -29,17 +38,29 @@ func (adaptor *adaptor) TranslateRouteConfiguration(r *routev3.RouteConfiguratio
 		}
 		routes = append(routes, partial...)
 	}
+	if opts != nil && opts.RouteFilterChains != nil {
+		if chains, ok := opts.RouteFilterChains[r.Name]; ok {
+			routes = adaptor.patchRoutesWithFilterChains(routes, chains)
+		}
+	}
 	if opts != nil && opts.RouteOriginalDestination != nil {
 		origDst, ok := opts.RouteOriginalDestination[r.Name]
 		if ok {
 			patchRoutesWithOriginalDestination(routes, origDst)
 		}
 	}
-	// TODO support Vhds.
+	if opts != nil && opts.InboundPort != 0 {
+		_, inbound := opts.InboundRoutes[r.Name]
+		patchRoutesWithInboundPort(routes, opts.InboundPort, inbound)
+	}
 	return routes, nil
 }
 
-func (adaptor *adaptor) translateVirtualHost(prefix string, vhost *routev3.VirtualHost, opts *TranslateOptions) ([]*apisix.Route, error) {
+func (adaptor *adaptor) translateVirtualHost(prefix string, vhost *routev3.VirtualHost, opts *TranslateOptions, authz *extAuthz, rbacs []*rbac) ([]*apisix.Route, error) {
+	var upgrades []*hcmv3.HttpConnectionManager_UpgradeConfig
+	if opts != nil && opts.RouteUpgradeConfigs != nil {
+		upgrades = opts.RouteUpgradeConfigs[prefix]
+	}
 	if prefix == "" {
 		prefix = "<anon>"
 	}
@@ -106,14 +127,21 @@ func (adaptor *adaptor) translateVirtualHost(prefix string, vhost *routev3.Virtu
 		name = fmt.Sprintf("%s#%s#%s", name, vhost.GetName(), prefix)
 		name = strings.Replace(name, ".svc.cluster.local", "", -1) // avoid name too long
 		r := &apisix.Route{
-			Name:       name,
-			Priority:   int32(priority),
-			Status:     1,
-			Id:         id.GenID(name),
-			Hosts:      hosts,
-			Uris:       []string{uri},
-			UpstreamId: id.GenID(cluster),
-			Vars:       vars,
+			Name:            name,
+			Priority:        int32(priority),
+			Status:          1,
+			Id:              id.GenID(name),
+			Hosts:           hosts,
+			Uris:            []string{uri},
+			UpstreamId:      id.GenID(cluster),
+			Vars:            vars,
+			EnableWebsocket: adaptor.isWebSocketEnabled(upgrades, route),
+		}
+		if authz != nil && !adaptor.isExtAuthzDisabled(authz.filterName, vhost, route) {
+			r.Plugins = clonePlugins(authz.plugins)
+		}
+		if adaptor.isDeniedByRBAC(prefix, rbacs, vhost, route) {
+			r.Plugins = denyAllPlugins(_rbacDeniedStatus)
 		}
 		routes = append(routes, r)
 	}
@@ -263,3 +291,19 @@ func patchRoutesWithOriginalDestination(routes []*apisix.Route, origDst string)
 		}
 	}
 }
+
+// patchRoutesWithInboundPort binds the inbound routes to the port which
+// accepts the intercepted inbound traffic, other (outbound) routes are
+// excluded from that port, so that the inbound and outbound traffic to
+// the same original destination port won't be mixed.
+func patchRoutesWithInboundPort(routes []*apisix.Route, port int, inbound bool) {
+	op := "~="
+	if inbound {
+		op = "=="
+	}
+	for _, r := range routes {
+		r.Vars = append(r.Vars, &apisix.Var{
+			Vars: []string{"server_port", op, strconv.Itoa(port)},
+		})
+	}
+}
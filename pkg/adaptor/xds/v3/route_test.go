@@ -11,6 +11,7 @@ import (
 	apisixutil "github.com/api7/apisix-mesh-agent/pkg/apisix"
 	"github.com/api7/apisix-mesh-agent/pkg/id"
 	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
 	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
 	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 )
@@ -302,7 +303,7 @@ func TestTranslateVirtualHost(t *testing.T) {
 			},
 		},
 	}
-	routes, err := a.translateVirtualHost("test", vhost, nil)
+	routes, err := a.translateVirtualHost("test", vhost, nil, nil, nil)
 	assert.Nil(t, err)
 	assert.Len(t, routes, 1)
 	assert.Equal(t, routes[0].Name, "route1#test#test")
@@ -344,6 +345,71 @@ func TestPatchRoutesWithOriginalDestination(t *testing.T) {
 	})
 }
 
+func TestTranslateRouteConfigurationWithInboundPort(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+	newRouteConfiguration := func(name string) *routev3.RouteConfiguration {
+		return &routev3.RouteConfiguration{
+			Name: name,
+			VirtualHosts: []*routev3.VirtualHost{
+				{
+					Name:    "inbound|http|8080",
+					Domains: []string{"*"},
+					Routes: []*routev3.Route{
+						{
+							Name: "default",
+							Match: &routev3.RouteMatch{
+								PathSpecifier: &routev3.RouteMatch_Prefix{
+									Prefix: "/",
+								},
+							},
+							Action: &routev3.Route_Route{
+								Route: &routev3.RouteAction{
+									ClusterSpecifier: &routev3.RouteAction_Cluster{
+										Cluster: "inbound|8080||",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	opts := &TranslateOptions{
+		RouteOriginalDestination: map[string]string{
+			"8080": "0.0.0.0:8080",
+		},
+		InboundPort:   9082,
+		InboundRoutes: set.StringSet{"inbound|8080||": {}},
+	}
+	routes, err := a.TranslateRouteConfiguration(newRouteConfiguration("inbound|8080||"), opts)
+	assert.Nil(t, err)
+	assert.Len(t, routes, 1)
+	assert.Equal(t, routes[0].Vars, []*apisix.Var{
+		{
+			Vars: []string{"server_port", "==", "9082"},
+		},
+	})
+
+	routes, err = a.TranslateRouteConfiguration(newRouteConfiguration("8080"), opts)
+	assert.Nil(t, err)
+	assert.Len(t, routes, 1)
+	assert.Equal(t, routes[0].Vars, []*apisix.Var{
+		{
+			Vars: []string{"connection_original_dst", "~~", "8080$"},
+		},
+		{
+			Vars: []string{"server_port", "~=", "9082"},
+		},
+	})
+
+	// Inbound port is not enabled.
+	opts.InboundPort = 0
+	routes, err = a.TranslateRouteConfiguration(newRouteConfiguration("inbound|8080||"), opts)
+	assert.Nil(t, err)
+	assert.Len(t, routes[0].Vars, 0)
+}
+
 func TestUnstableHostsRouteDiff(t *testing.T) {
 	a := &adaptor{logger: log.DefaultLogger}
 	vhost1 := &routev3.VirtualHost{
@@ -412,9 +478,9 @@ func TestUnstableHostsRouteDiff(t *testing.T) {
 			},
 		},
 	}
-	routes1, err := a.translateVirtualHost("test", vhost1, nil)
+	routes1, err := a.translateVirtualHost("test", vhost1, nil, nil, nil)
 	assert.Nil(t, err)
-	routes2, err := a.translateVirtualHost("test", vhost2, nil)
+	routes2, err := a.translateVirtualHost("test", vhost2, nil, nil, nil)
 	assert.Nil(t, err)
 
 	assert.NotNil(t, routes1)
@@ -425,3 +491,54 @@ func TestUnstableHostsRouteDiff(t *testing.T) {
 	assert.Nil(t, deleted)
 	assert.Nil(t, updated)
 }
+
+func TestTranslateRouteConfigurationWithVhds(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	newVirtualHost := func(name string) *routev3.VirtualHost {
+		return &routev3.VirtualHost{
+			Name:    name,
+			Domains: []string{name},
+			Routes: []*routev3.Route{
+				{
+					Name: "route1",
+					Match: &routev3.RouteMatch{
+						PathSpecifier: &routev3.RouteMatch_Path{
+							Path: "/get",
+						},
+					},
+					Action: &routev3.Route_Route{
+						Route: &routev3.RouteAction{
+							ClusterSpecifier: &routev3.RouteAction_Cluster{
+								Cluster: "httpbin.default.svc.cluster.local",
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	rc := &routev3.RouteConfiguration{
+		Name:         "rc1",
+		VirtualHosts: []*routev3.VirtualHost{newVirtualHost("httpbin.org")},
+	}
+	opts := &TranslateOptions{
+		RouteVirtualHosts: map[string][]*routev3.VirtualHost{
+			"rc1": {newVirtualHost("apisix.apache.org")},
+		},
+	}
+
+	// VHDS is not enabled.
+	routes, err := a.TranslateRouteConfiguration(rc, opts)
+	assert.Nil(t, err)
+	assert.Len(t, routes, 1)
+
+	rc.Vhds = &routev3.Vhds{}
+	routes, err = a.TranslateRouteConfiguration(rc, opts)
+	assert.Nil(t, err)
+	assert.Len(t, routes, 2)
+	assert.Equal(t, routes[0].Hosts, []string{"httpbin.org"})
+	assert.Equal(t, routes[1].Hosts, []string{"apisix.apache.org"})
+	// The original route configuration is not changed.
+	assert.Len(t, rc.VirtualHosts, 1)
+}
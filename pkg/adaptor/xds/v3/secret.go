@@ -0,0 +1,164 @@
+package v3
+
+import (
+	"errors"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+var (
+	_downstreamTlsContextv3 = "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext"
+	_upstreamTlsContextv3   = "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext"
+
+	_errIncompleteTlsCertificate = errors.New("incomplete tls certificate")
+)
+
+// serverSSLId returns the id of the SSL object translated from the secret.
+func serverSSLId(secretName string) string {
+	return id.GenID(secretName)
+}
+
+func (adaptor *adaptor) CollectListenerSecrets(l *listenerv3.Listener) (map[string][]string, error) {
+	serverNames := make(map[string]set.StringSet)
+	for _, fc := range l.GetFilterChains() {
+		var ctx tlsv3.DownstreamTlsContext
+		ok, err := adaptor.unmarshalTlsContext(fc.GetTransportSocket(), _downstreamTlsContextv3, &ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for _, sds := range ctx.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs() {
+			names, ok := serverNames[sds.GetName()]
+			if !ok {
+				names = set.StringSet{}
+				serverNames[sds.GetName()] = names
+			}
+			for _, name := range fc.GetFilterChainMatch().GetServerNames() {
+				names.Add(name)
+			}
+		}
+	}
+	secrets := make(map[string][]string, len(serverNames))
+	for name, names := range serverNames {
+		secrets[name] = names.OrderedStrings()
+	}
+	return secrets, nil
+}
+
+func (adaptor *adaptor) CollectClusterSecrets(c *clusterv3.Cluster) ([]string, error) {
+	var ctx tlsv3.UpstreamTlsContext
+	ok, err := adaptor.unmarshalTlsContext(c.GetTransportSocket(), _upstreamTlsContextv3, &ctx)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var names []string
+	for _, sds := range ctx.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs() {
+		names = append(names, sds.GetName())
+	}
+	return names, nil
+}
+
+func (adaptor *adaptor) TranslateSecret(secret *tlsv3.Secret, opts *TranslateOptions) (*apisix.SSL, error) {
+	cert, key, err := adaptor.translateTlsCertificate(secret)
+	if err != nil {
+		return nil, err
+	}
+	snis, ok := opts.SecretServerNames[secret.GetName()]
+	if !ok {
+		return nil, nil
+	}
+	if len(snis) == 0 {
+		// Apache APISIX selects SSLs by the server name indication, SSLs
+		// without snis will be rejected.
+		adaptor.logger.Errorw("ignore server secret which is not restricted by server names",
+			zap.String("secret", secret.GetName()),
+		)
+		return nil, nil
+	}
+	return &apisix.SSL{
+		Id:   serverSSLId(secret.GetName()),
+		Cert: cert,
+		Key:  key,
+		Snis: snis,
+	}, nil
+}
+
+func (adaptor *adaptor) TranslateClientCertificate(secret *tlsv3.Secret) (*apisix.Upstream_TLS, error) {
+	cert, key, err := adaptor.translateTlsCertificate(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &apisix.Upstream_TLS{
+		ClientCert: cert,
+		ClientKey:  key,
+	}, nil
+}
+
+// translateTlsCertificate reads the PEM encoded certificate chain and private
+// key from the secret.
+func (adaptor *adaptor) translateTlsCertificate(secret *tlsv3.Secret) (string, string, error) {
+	tc := secret.GetTlsCertificate()
+	if tc == nil {
+		// Secrets like the validation context are not supported.
+		return "", "", ErrFeatureNotSupportedYet
+	}
+	cert, err := adaptor.translateDataSource(tc.GetCertificateChain())
+	if err != nil {
+		return "", "", err
+	}
+	key, err := adaptor.translateDataSource(tc.GetPrivateKey())
+	if err != nil {
+		return "", "", err
+	}
+	if cert == "" || key == "" {
+		return "", "", _errIncompleteTlsCertificate
+	}
+	return cert, key, nil
+}
+
+// translateDataSource reads the content of data source, only the inline
+// data source is supported, which is used by the SDS server.
+func (adaptor *adaptor) translateDataSource(ds *corev3.DataSource) (string, error) {
+	switch spec := ds.GetSpecifier().(type) {
+	case nil:
+		return "", nil
+	case *corev3.DataSource_InlineBytes:
+		return string(spec.InlineBytes), nil
+	case *corev3.DataSource_InlineString:
+		return spec.InlineString, nil
+	default:
+		adaptor.logger.Warnw("ignore secret with unsupported data source",
+			zap.Any("data_source", ds),
+		)
+		return "", ErrFeatureNotSupportedYet
+	}
+}
+
+// unmarshalTlsContext unmarshals the TLS context from the transport socket,
+// false will be returned if the transport socket is not the TLS one.
+func (adaptor *adaptor) unmarshalTlsContext(ts *corev3.TransportSocket, typeUrl string, ctx proto.Message) (bool, error) {
+	if ts.GetName() != xdswellknown.TransportSocketTls || ts.GetTypedConfig().GetTypeUrl() != typeUrl {
+		return false, nil
+	}
+	if err := anypb.UnmarshalTo(ts.GetTypedConfig(), ctx, proto.UnmarshalOptions{}); err != nil {
+		adaptor.logger.Errorw("failed to unmarshal TLS context",
+			zap.Error(err),
+			zap.String("type_url", typeUrl),
+		)
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,232 @@
+package v3
+
+import (
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func newTlsTransportSocket(t *testing.T, ctx proto.Message) *corev3.TransportSocket {
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, ctx, proto.MarshalOptions{}))
+	return &corev3.TransportSocket{
+		Name: xdswellknown.TransportSocketTls,
+		ConfigType: &corev3.TransportSocket_TypedConfig{
+			TypedConfig: &opaque,
+		},
+	}
+}
+
+func newSdsCommonTlsContext(names ...string) *tlsv3.CommonTlsContext {
+	ctx := &tlsv3.CommonTlsContext{}
+	for _, name := range names {
+		ctx.TlsCertificateSdsSecretConfigs = append(ctx.TlsCertificateSdsSecretConfigs, &tlsv3.SdsSecretConfig{
+			Name: name,
+		})
+	}
+	return ctx
+}
+
+func TestCollectListenerSecrets(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+	l := &listenerv3.Listener{
+		Name: "0.0.0.0_443",
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					ServerNames: []string{"httpbin.org", "*.httpbin.org"},
+				},
+				TransportSocket: newTlsTransportSocket(t, &tlsv3.DownstreamTlsContext{
+					CommonTlsContext: newSdsCommonTlsContext("httpbin"),
+				}),
+			},
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					ServerNames: []string{"httpbin.com"},
+				},
+				TransportSocket: newTlsTransportSocket(t, &tlsv3.DownstreamTlsContext{
+					CommonTlsContext: newSdsCommonTlsContext("httpbin"),
+				}),
+			},
+			{
+				TransportSocket: newTlsTransportSocket(t, &tlsv3.DownstreamTlsContext{
+					CommonTlsContext: newSdsCommonTlsContext("default"),
+				}),
+			},
+			{
+				// Plain text filter chain.
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					TransportProtocol: "raw_buffer",
+				},
+			},
+		},
+	}
+	secrets, err := a.CollectListenerSecrets(l)
+	assert.Nil(t, err)
+	assert.Equal(t, secrets, map[string][]string{
+		"httpbin": {"*.httpbin.org", "httpbin.com", "httpbin.org"},
+		"default": {},
+	})
+
+	// The upstream TLS context is not used by listeners.
+	l.FilterChains[0].TransportSocket = newTlsTransportSocket(t, &tlsv3.UpstreamTlsContext{
+		CommonTlsContext: newSdsCommonTlsContext("httpbin"),
+	})
+	l.FilterChains[1].TransportSocket = nil
+	secrets, err = a.CollectListenerSecrets(l)
+	assert.Nil(t, err)
+	assert.Equal(t, secrets, map[string][]string{
+		"default": {},
+	})
+}
+
+func TestCollectClusterSecrets(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+	c := &clusterv3.Cluster{
+		Name: "httpbin.default.svc.cluster.local",
+	}
+	names, err := a.CollectClusterSecrets(c)
+	assert.Nil(t, err)
+	assert.Nil(t, names)
+
+	c.TransportSocket = newTlsTransportSocket(t, &tlsv3.UpstreamTlsContext{
+		CommonTlsContext: newSdsCommonTlsContext("default"),
+	})
+	names, err = a.CollectClusterSecrets(c)
+	assert.Nil(t, err)
+	assert.Equal(t, names, []string{"default"})
+
+	c.TransportSocket.ConfigType = &corev3.TransportSocket_TypedConfig{
+		TypedConfig: &anypb.Any{
+			TypeUrl: _upstreamTlsContextv3,
+			Value:   []byte{0xff},
+		},
+	}
+	_, err = a.CollectClusterSecrets(c)
+	assert.NotNil(t, err)
+}
+
+func TestTranslateClusterTransportSocket(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+	c := &clusterv3.Cluster{
+		Name: "httpbin.default.svc.cluster.local",
+	}
+	var ups apisix.Upstream
+	assert.Nil(t, a.translateClusterTransportSocket(c, &ups))
+	assert.Equal(t, ups.Scheme, "")
+	assert.Nil(t, ups.Tls)
+
+	c.TransportSocket = newTlsTransportSocket(t, &tlsv3.UpstreamTlsContext{
+		Sni: "httpbin.org",
+	})
+	assert.Nil(t, a.translateClusterTransportSocket(c, &ups))
+	assert.Equal(t, ups.Scheme, "https")
+	assert.Nil(t, ups.Tls)
+
+	c.TransportSocket = newTlsTransportSocket(t, &tlsv3.UpstreamTlsContext{
+		CommonTlsContext: newSdsCommonTlsContext("default"),
+	})
+	assert.Nil(t, a.translateClusterTransportSocket(c, &ups))
+	assert.Equal(t, ups.Scheme, "https")
+	// The client certificate is filled once the secret is received.
+	assert.Nil(t, ups.Tls)
+	// The validation context is not enforced.
+	ctx := &tlsv3.UpstreamTlsContext{
+		CommonTlsContext: newSdsCommonTlsContext("default"),
+	}
+	ctx.CommonTlsContext.ValidationContextType = &tlsv3.CommonTlsContext_ValidationContextSdsSecretConfig{
+		ValidationContextSdsSecretConfig: &tlsv3.SdsSecretConfig{
+			Name: "ROOTCA",
+		},
+	}
+	c.TransportSocket = newTlsTransportSocket(t, ctx)
+	assert.Nil(t, a.translateClusterTransportSocket(c, &ups))
+	assert.Equal(t, ups.Scheme, "https")
+	assert.Nil(t, ups.Tls)
+}
+
+func TestTranslateSecret(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+	secret := &tlsv3.Secret{
+		Name: "default",
+		Type: &tlsv3.Secret_TlsCertificate{
+			TlsCertificate: &tlsv3.TlsCertificate{
+				CertificateChain: &corev3.DataSource{
+					Specifier: &corev3.DataSource_InlineBytes{
+						InlineBytes: []byte("cert"),
+					},
+				},
+				PrivateKey: &corev3.DataSource{
+					Specifier: &corev3.DataSource_InlineString{
+						InlineString: "key",
+					},
+				},
+			},
+		},
+	}
+
+	// Not used by listeners.
+	ssl, err := a.TranslateSecret(secret, &TranslateOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, ssl)
+
+	// Not restricted by server names, APISIX rejects SSLs without snis.
+	ssl, err = a.TranslateSecret(secret, &TranslateOptions{
+		SecretServerNames: map[string][]string{
+			"default": {},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, ssl)
+
+	ssl, err = a.TranslateSecret(secret, &TranslateOptions{
+		SecretServerNames: map[string][]string{
+			"default": {"httpbin.org"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ssl, &apisix.SSL{
+		Id:   id.GenID("default"),
+		Cert: "cert",
+		Key:  "key",
+		Snis: []string{"httpbin.org"},
+	})
+
+	tls, err := a.TranslateClientCertificate(secret)
+	assert.Nil(t, err)
+	assert.Equal(t, tls, &apisix.Upstream_TLS{
+		ClientCert: "cert",
+		ClientKey:  "key",
+	})
+
+	secret.GetTlsCertificate().PrivateKey = nil
+	_, err = a.TranslateSecret(secret, &TranslateOptions{})
+	assert.Equal(t, err, _errIncompleteTlsCertificate)
+	_, err = a.TranslateClientCertificate(secret)
+	assert.Equal(t, err, _errIncompleteTlsCertificate)
+
+	secret.GetTlsCertificate().PrivateKey = &corev3.DataSource{
+		Specifier: &corev3.DataSource_Filename{
+			Filename: "/etc/certs/key.pem",
+		},
+	}
+	_, err = a.TranslateSecret(secret, &TranslateOptions{})
+	assert.Equal(t, err, ErrFeatureNotSupportedYet)
+
+	secret.Type = &tlsv3.Secret_ValidationContext{
+		ValidationContext: &tlsv3.CertificateValidationContext{},
+	}
+	_, err = a.TranslateSecret(secret, &TranslateOptions{})
+	assert.Equal(t, err, ErrFeatureNotSupportedYet)
+}
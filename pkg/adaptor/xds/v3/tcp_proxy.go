@@ -0,0 +1,226 @@
+package v3
+
+import (
+	"fmt"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tcpproxyv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+var (
+	_tcpProxyv3 = "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy"
+)
+
+func (adaptor *adaptor) TranslateStreamRoutes(listeners []*listenerv3.Listener, opts *TranslateOptions) ([]*apisix.StreamRoute, error) {
+	var srs []*apisix.StreamRoute
+	for _, l := range listeners {
+		if l.GetTrafficDirection() == corev3.TrafficDirection_INBOUND {
+			// The stream proxy matches the original destination port only,
+			// the inbound and outbound connections cannot be distinguished.
+			continue
+		}
+		partial, err := adaptor.translateListenerStreamRoutes(l, opts)
+		if err != nil {
+			return nil, err
+		}
+		srs = append(srs, partial...)
+	}
+	return adaptor.rejectConflictingStreamRoutes(srs), nil
+}
+
+func (adaptor *adaptor) translateListenerStreamRoutes(l *listenerv3.Listener, opts *TranslateOptions) ([]*apisix.StreamRoute, error) {
+	var srs []*apisix.StreamRoute
+	for i, fc := range l.GetFilterChains() {
+		for _, f := range fc.GetFilters() {
+			if f.GetName() != xdswellknown.TCPProxy || f.GetTypedConfig().GetTypeUrl() != _tcpProxyv3 {
+				continue
+			}
+			var tcpProxy tcpproxyv3.TcpProxy
+			if err := anypb.UnmarshalTo(f.GetTypedConfig(), &tcpProxy, proto.UnmarshalOptions{}); err != nil {
+				adaptor.logger.Errorw("failed to unmarshal TcpProxy config",
+					zap.Error(err),
+					zap.Any("listener", l),
+				)
+				return nil, err
+			}
+			name := fmt.Sprintf("%s#%d", l.GetName(), i)
+			srs = append(srs, adaptor.translateTcpProxy(name, l, fc, &tcpProxy, opts)...)
+		}
+	}
+	adaptor.logger.Debugw("got stream routes from listener",
+		zap.Any("stream_routes", srs),
+		zap.String("listener", l.GetName()),
+	)
+	return srs, nil
+}
+
+// rejectConflictingStreamRoutes removes the stream routes which have the same
+// match conditions but different upstreams. Since the original destination
+// address cannot be matched, listeners of different addresses (e.g. the per
+// VIP listeners of Istio) which have the same port collide, connections would
+// be proxied to an arbitrary one of them, so all of them are rejected.
+func (adaptor *adaptor) rejectConflictingStreamRoutes(srs []*apisix.StreamRoute) []*apisix.StreamRoute {
+	var (
+		keys      []string
+		conflicts = make(map[string][]string)
+		first     = make(map[string]*apisix.StreamRoute)
+	)
+	for _, sr := range srs {
+		key := fmt.Sprintf("%d#%s", sr.GetServerPort(), sr.GetRemoteAddr())
+		prev, ok := first[key]
+		if !ok {
+			first[key] = sr
+			keys = append(keys, key)
+			continue
+		}
+		if prev.GetUpstreamId() == sr.GetUpstreamId() && proto.Equal(prev.GetUpstream(), sr.GetUpstream()) {
+			// Same destination, the duplicated one is harmless.
+			continue
+		}
+		if _, ok := conflicts[key]; !ok {
+			conflicts[key] = []string{prev.GetDesc()}
+		}
+		conflicts[key] = append(conflicts[key], sr.GetDesc())
+	}
+	result := make([]*apisix.StreamRoute, 0, len(keys))
+	for _, key := range keys {
+		sr := first[key]
+		if descs, ok := conflicts[key]; ok {
+			adaptor.logger.Errorw("reject stream routes which cannot be distinguished by server port and remote address",
+				zap.Int32("server_port", sr.GetServerPort()),
+				zap.String("remote_addr", sr.GetRemoteAddr()),
+				zap.Strings("stream_routes", descs),
+			)
+			continue
+		}
+		result = append(result, sr)
+	}
+	return result
+}
+
+// translateTcpProxy translates the TcpProxy filter in a filter chain to stream routes.
+// Since connections are intercepted by the iptables REDIRECT target, the local server
+// address is not the original destination, so only the port is used to match the
+// connection, the conflicting ones are rejected by rejectConflictingStreamRoutes.
+// One stream route will be generated for each source prefix range, the TLS
+// passthrough filter chains (which match the server names) are ignored.
+func (adaptor *adaptor) translateTcpProxy(name string, l *listenerv3.Listener, fc *listenerv3.FilterChain,
+	tcpProxy *tcpproxyv3.TcpProxy, opts *TranslateOptions) []*apisix.StreamRoute {
+
+	name = strings.Replace(name, ".svc.cluster.local", "", -1) // avoid name too long
+	sr := &apisix.StreamRoute{
+		Id:         id.GenID(name),
+		Desc:       name,
+		ServerPort: int32(l.GetAddress().GetSocketAddress().GetPortValue()),
+	}
+
+	fcm := fc.GetFilterChainMatch()
+	if len(fcm.GetServerNames()) > 0 {
+		// Stream routes of Apache APISIX only match the SNI of the TLS
+		// connections it terminates, routing passthrough connections by
+		// the server name is not supported.
+		adaptor.logger.Warnw("ignore tcp_proxy on filter chain which matches server names",
+			zap.Strings("server_names", fcm.GetServerNames()),
+			zap.String("listener", l.GetName()),
+		)
+		return nil
+	}
+	if port := fcm.GetDestinationPort(); port != nil {
+		sr.ServerPort = int32(port.GetValue())
+	}
+	if sr.ServerPort == 0 {
+		adaptor.logger.Warnw("ignore tcp_proxy on listener without port",
+			zap.String("listener", l.GetName()),
+		)
+		return nil
+	}
+
+	if cluster := tcpProxy.GetCluster(); cluster != "" {
+		sr.UpstreamId = id.GenID(cluster)
+	} else if wc := tcpProxy.GetWeightedClusters(); wc != nil {
+		ups := adaptor.translateTcpProxyWeightedClusters(name, wc, opts)
+		if ups == nil {
+			return nil
+		}
+		sr.Upstream = ups
+	} else {
+		adaptor.logger.Warnw("ignore tcp_proxy without cluster",
+			zap.Any("tcp_proxy", tcpProxy),
+			zap.String("listener", l.GetName()),
+		)
+		return nil
+	}
+
+	srs := []*apisix.StreamRoute{sr}
+	// Stream route only accepts one remote address.
+	if cidrs := fcm.GetSourcePrefixRanges(); len(cidrs) > 0 {
+		srs = make([]*apisix.StreamRoute, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			cidrSr := proto.Clone(sr).(*apisix.StreamRoute)
+			prefixLen := cidr.GetPrefixLen().GetValue()
+			if prefixLen > 0 {
+				cidrSr.RemoteAddr = fmt.Sprintf("%s/%d", cidr.GetAddressPrefix(), prefixLen)
+			}
+			if len(cidrs) > 1 {
+				cidrName := fmt.Sprintf("%s#%s/%d", name, cidr.GetAddressPrefix(), prefixLen)
+				cidrSr.Id = id.GenID(cidrName)
+				cidrSr.Desc = cidrName
+			}
+			srs = append(srs, cidrSr)
+		}
+	}
+	return srs
+}
+
+// translateTcpProxyWeightedClusters merges the nodes of the weighted clusters to
+// an inline upstream, the weight of each node is scaled by the weight of the cluster.
+func (adaptor *adaptor) translateTcpProxyWeightedClusters(name string, wc *tcpproxyv3.TcpProxy_WeightedCluster,
+	opts *TranslateOptions) *apisix.Upstream {
+
+	ups := &apisix.Upstream{
+		Name:  name,
+		Id:    id.GenID(name),
+		Type:  "roundrobin",
+		Nodes: []*apisix.Node{},
+	}
+	for _, cw := range wc.GetClusters() {
+		var (
+			cluster *apisix.Upstream
+			ok      bool
+		)
+		if opts != nil {
+			cluster, ok = opts.Upstreams[cw.GetName()]
+		}
+		if !ok {
+			adaptor.logger.Warnw("ignore unknown cluster in tcp_proxy weighted clusters",
+				zap.String("cluster", cw.GetName()),
+				zap.String("stream_route", name),
+			)
+			continue
+		}
+		for _, node := range cluster.GetNodes() {
+			ups.Nodes = append(ups.Nodes, &apisix.Node{
+				Host:   node.GetHost(),
+				Port:   node.GetPort(),
+				Weight: node.GetWeight() * int32(cw.GetWeight()),
+			})
+		}
+	}
+	if len(ups.Nodes) == 0 {
+		adaptor.logger.Warnw("ignore tcp_proxy since no weighted clusters are available",
+			zap.Any("weighted_clusters", wc),
+			zap.String("stream_route", name),
+		)
+		return nil
+	}
+	return ups
+}
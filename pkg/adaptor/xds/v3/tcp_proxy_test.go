@@ -0,0 +1,282 @@
+package v3
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tcpproxyv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func newTcpProxyFilter(t *testing.T, tcpProxy *tcpproxyv3.TcpProxy) *listenerv3.Filter {
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, tcpProxy, proto.MarshalOptions{}))
+	return &listenerv3.Filter{
+		Name: xdswellknown.TCPProxy,
+		ConfigType: &listenerv3.Filter_TypedConfig{
+			TypedConfig: &opaque,
+		},
+	}
+}
+
+func TestTranslateStreamRoutes(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	listener := &listenerv3.Listener{
+		Name: "10.96.0.11_3306",
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Address: "10.96.0.11",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: 3306,
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					SourcePrefixRanges: []*corev3.CidrRange{
+						{
+							AddressPrefix: "10.0.0.0",
+							PrefixLen:     wrapperspb.UInt32(8),
+						},
+					},
+				},
+				Filters: []*listenerv3.Filter{
+					newTcpProxyFilter(t, &tcpproxyv3.TcpProxy{
+						ClusterSpecifier: &tcpproxyv3.TcpProxy_Cluster{
+							Cluster: "outbound|3306||mysql.default.svc.cluster.local",
+						},
+					}),
+				},
+			},
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					DestinationPort: wrapperspb.UInt32(3307),
+				},
+				Filters: []*listenerv3.Filter{
+					newTcpProxyFilter(t, &tcpproxyv3.TcpProxy{
+						ClusterSpecifier: &tcpproxyv3.TcpProxy_WeightedClusters{
+							WeightedClusters: &tcpproxyv3.TcpProxy_WeightedCluster{
+								Clusters: []*tcpproxyv3.TcpProxy_WeightedCluster_ClusterWeight{
+									{
+										Name:   "outbound|3306|v1|mysql.default.svc.cluster.local",
+										Weight: 80,
+									},
+									{
+										Name:   "outbound|3306|v2|mysql.default.svc.cluster.local",
+										Weight: 20,
+									},
+									{
+										Name:   "outbound|3306|v3|mysql.default.svc.cluster.local",
+										Weight: 20,
+									},
+								},
+							},
+						},
+					}),
+				},
+			},
+			{
+				// Weighted clusters are all unknown.
+				Filters: []*listenerv3.Filter{
+					newTcpProxyFilter(t, &tcpproxyv3.TcpProxy{
+						ClusterSpecifier: &tcpproxyv3.TcpProxy_WeightedClusters{
+							WeightedClusters: &tcpproxyv3.TcpProxy_WeightedCluster{
+								Clusters: []*tcpproxyv3.TcpProxy_WeightedCluster_ClusterWeight{
+									{
+										Name:   "unknown",
+										Weight: 100,
+									},
+								},
+							},
+						},
+					}),
+				},
+			},
+		},
+	}
+	opts := &TranslateOptions{
+		Upstreams: map[string]*apisix.Upstream{
+			"outbound|3306|v1|mysql.default.svc.cluster.local": {
+				Nodes: []*apisix.Node{
+					{
+						Host:   "10.0.5.3",
+						Port:   3306,
+						Weight: 100,
+					},
+				},
+			},
+			"outbound|3306|v2|mysql.default.svc.cluster.local": {
+				Nodes: []*apisix.Node{
+					{
+						Host:   "10.0.5.4",
+						Port:   3306,
+						Weight: 100,
+					},
+				},
+			},
+		},
+	}
+
+	srs, err := a.TranslateStreamRoutes([]*listenerv3.Listener{listener}, opts)
+	assert.Nil(t, err)
+	assert.Len(t, srs, 2)
+
+	assert.Equal(t, srs[0].Id, id.GenID("10.96.0.11_3306#0"))
+	assert.Equal(t, srs[0].Desc, "10.96.0.11_3306#0")
+	assert.Equal(t, srs[0].ServerPort, int32(3306))
+	assert.Equal(t, srs[0].RemoteAddr, "10.0.0.0/8")
+	assert.Equal(t, srs[0].UpstreamId, id.GenID("outbound|3306||mysql.default.svc.cluster.local"))
+	assert.Nil(t, srs[0].Upstream)
+	assert.Nil(t, srs[0].Validate())
+
+	assert.Equal(t, srs[1].ServerPort, int32(3307))
+	assert.Equal(t, srs[1].UpstreamId, "")
+	assert.Equal(t, srs[1].Upstream.Nodes, []*apisix.Node{
+		{
+			Host:   "10.0.5.3",
+			Port:   3306,
+			Weight: 8000,
+		},
+		{
+			Host:   "10.0.5.4",
+			Port:   3306,
+			Weight: 2000,
+		},
+	})
+}
+
+func TestTranslateStreamRoutesIgnoreTLSPassthrough(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	listener := &listenerv3.Listener{
+		Name: "0.0.0.0_443",
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Address: "0.0.0.0",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: 443,
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					TransportProtocol: "tls",
+					ServerNames:       []string{"api.apache.org", "*.apisix.apache.org"},
+				},
+				Filters: []*listenerv3.Filter{
+					newTcpProxyFilter(t, &tcpproxyv3.TcpProxy{
+						ClusterSpecifier: &tcpproxyv3.TcpProxy_Cluster{
+							Cluster: "outbound|443||apache.org",
+						},
+					}),
+				},
+			},
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					TransportProtocol: "tls",
+					ServerNames:       []string{"httpbin.org"},
+				},
+				Filters: []*listenerv3.Filter{
+					newTcpProxyFilter(t, &tcpproxyv3.TcpProxy{
+						ClusterSpecifier: &tcpproxyv3.TcpProxy_Cluster{
+							Cluster: "outbound|443||httpbin.org",
+						},
+					}),
+				},
+			},
+		},
+	}
+
+	// Routing by the server name is not supported.
+	srs, err := a.TranslateStreamRoutes([]*listenerv3.Listener{listener}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, srs, 0)
+}
+
+func TestTranslateStreamRoutesConflicts(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	newListener := func(addr string, cluster string, cidrs ...*corev3.CidrRange) *listenerv3.Listener {
+		return &listenerv3.Listener{
+			Name: addr + "_3306",
+			Address: &corev3.Address{
+				Address: &corev3.Address_SocketAddress{
+					SocketAddress: &corev3.SocketAddress{
+						Address: addr,
+						PortSpecifier: &corev3.SocketAddress_PortValue{
+							PortValue: 3306,
+						},
+					},
+				},
+			},
+			FilterChains: []*listenerv3.FilterChain{
+				{
+					FilterChainMatch: &listenerv3.FilterChainMatch{
+						SourcePrefixRanges: cidrs,
+					},
+					Filters: []*listenerv3.Filter{
+						newTcpProxyFilter(t, &tcpproxyv3.TcpProxy{
+							ClusterSpecifier: &tcpproxyv3.TcpProxy_Cluster{
+								Cluster: cluster,
+							},
+						}),
+					},
+				},
+			},
+		}
+	}
+	cidr1 := &corev3.CidrRange{AddressPrefix: "10.0.0.0", PrefixLen: wrapperspb.UInt32(8)}
+	cidr2 := &corev3.CidrRange{AddressPrefix: "172.16.0.0", PrefixLen: wrapperspb.UInt32(12)}
+
+	// One stream route for each source prefix range.
+	srs, err := a.TranslateStreamRoutes([]*listenerv3.Listener{
+		newListener("10.96.0.11", "mysql", cidr1, cidr2),
+	}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, srs, 2)
+	assert.Equal(t, srs[0].RemoteAddr, "10.0.0.0/8")
+	assert.Equal(t, srs[0].Desc, "10.96.0.11_3306#0#10.0.0.0/8")
+	assert.Equal(t, srs[1].RemoteAddr, "172.16.0.0/12")
+	assert.NotEqual(t, srs[0].Id, srs[1].Id)
+
+	// Listeners of different addresses on the same port cannot be
+	// distinguished, only the source prefix range 172.16.0.0/12 is
+	// not in conflict.
+	inbound := newListener("0.0.0.0", "inbound|3306||")
+	inbound.TrafficDirection = corev3.TrafficDirection_INBOUND
+	srs, err = a.TranslateStreamRoutes([]*listenerv3.Listener{
+		newListener("10.96.0.11", "mysql", cidr1, cidr2),
+		newListener("10.96.0.12", "mariadb", cidr1),
+		newListener("10.96.0.13", "mariadb", cidr1),
+		inbound,
+	}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, srs, 1)
+	assert.Equal(t, srs[0].RemoteAddr, "172.16.0.0/12")
+	assert.Equal(t, srs[0].UpstreamId, id.GenID("mysql"))
+
+	// Duplicated stream routes to the same upstream are harmless.
+	srs, err = a.TranslateStreamRoutes([]*listenerv3.Listener{
+		newListener("10.96.0.12", "mariadb", cidr1),
+		newListener("10.96.0.13", "mariadb", cidr1),
+	}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, srs, 1)
+	assert.Equal(t, srs[0].Desc, "10.96.0.12_3306#0")
+}
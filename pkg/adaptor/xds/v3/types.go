@@ -7,9 +7,12 @@ import (
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 
 	"github.com/api7/apisix-mesh-agent/pkg/config"
 	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
 	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
 )
 
@@ -40,6 +43,77 @@ type Adaptor interface {
 	// CollectRouteNamesAndConfigs collects Rds route names and static route configurations
 	// from listener.
 	CollectRouteNamesAndConfigs(*listenerv3.Listener) ([]string, []*routev3.RouteConfiguration, error)
+	// CollectRouteHTTPFilters collects the HTTP filters from listener, the returned map
+	// is keyed by the name of RouteConfiguration which the filters are applied to.
+	CollectRouteHTTPFilters(*listenerv3.Listener) (map[string][]*hcmv3.HttpFilter, error)
+	// CollectRouteUpgradeConfigs collects the upgrade configs of HttpConnectionManagers from
+	// listener, the returned map is keyed by the name of RouteConfiguration which the upgrade
+	// configs are applied to.
+	CollectRouteUpgradeConfigs(*listenerv3.Listener) (map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig, error)
+	// CollectRouteFilterChains collects the filter chains from listener, the returned
+	// map is keyed by the name of RouteConfiguration which is used in the filter chains,
+	// filter chains with the same match criteria are collected only once.
+	CollectRouteFilterChains(*listenerv3.Listener) (map[string][]*FilterChain, error)
+	// TranslateStreamRoutes translates the filter chains which use the tcp_proxy filter
+	// in the outbound Listeners to a series APISIX StreamRoutes, the stream routes which
+	// cannot be distinguished from each other are rejected.
+	TranslateStreamRoutes([]*listenerv3.Listener, *TranslateOptions) ([]*apisix.StreamRoute, error)
+	// CollectListenerSecrets collects the SDS secret names which are used to terminate
+	// the downstream TLS connections from listener, the returned map is keyed by the
+	// secret name and the value is the server names of the filter chains.
+	CollectListenerSecrets(*listenerv3.Listener) (map[string][]string, error)
+	// CollectClusterSecrets collects the SDS secret names which are used as the client
+	// certificates from cluster.
+	CollectClusterSecrets(*clusterv3.Cluster) ([]string, error)
+	// TranslateSecret translates a TLS certificate Secret to APISIX SSL, nil will be
+	// returned if listeners don't use it to terminate the downstream TLS connections
+	// with server names.
+	TranslateSecret(*tlsv3.Secret, *TranslateOptions) (*apisix.SSL, error)
+	// TranslateClientCertificate translates a TLS certificate Secret to the TLS settings
+	// of APISIX Upstream, so that it's used as the client certificate.
+	TranslateClientCertificate(*tlsv3.Secret) (*apisix.Upstream_TLS, error)
+	// CollectListenerStates collects the route names and the states that route
+	// translation depends on from listeners. Listeners which don't listen on a
+	// socket address are skipped, so are inbound listeners if the inbound port
+	// is zero.
+	CollectListenerStates([]*listenerv3.Listener, int) (*ListenerStates, error)
+}
+
+// ListenerStates contains the states collected from a group of listeners.
+type ListenerStates struct {
+	// Listeners are the listeners which were not skipped.
+	Listeners []*listenerv3.Listener
+	// RdsNames are the names of route configurations which should be fetched
+	// by RDS, in the order they appear.
+	RdsNames []string
+	// StaticRouteConfigurations are the route configurations embedded in the
+	// HttpConnectionManagers.
+	StaticRouteConfigurations []*routev3.RouteConfiguration
+	// RouteOwnership maps the route configuration name to the address of the
+	// outbound listener which uses it, see TranslateOptions.RouteOriginalDestination.
+	RouteOwnership map[string]string
+	// RouteHTTPFilters, see TranslateOptions.RouteHTTPFilters.
+	RouteHTTPFilters map[string][]*hcmv3.HttpFilter
+	// RouteUpgradeConfigs, see TranslateOptions.RouteUpgradeConfigs.
+	RouteUpgradeConfigs map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig
+	// RouteFilterChains, see TranslateOptions.RouteFilterChains.
+	RouteFilterChains map[string][]*FilterChain
+	// InboundRoutes, see TranslateOptions.InboundRoutes.
+	InboundRoutes set.StringSet
+	// SecretServerNames, see TranslateOptions.SecretServerNames.
+	SecretServerNames map[string][]string
+}
+
+// FilterChain is a filter chain which uses a route configuration.
+type FilterChain struct {
+	// Name identifies the filter chain, it's the filter chain name, or
+	// "<listener name>#<index>" if the filter chain has no name. It's
+	// suffixed to the names of routes if the route configuration is used
+	// by several filter chains.
+	Name string
+	// Match is the match criteria of the filter chain, nil means the
+	// filter chain matches all connections.
+	Match *listenerv3.FilterChainMatch
 }
 
 // TranslateOptions contains some options to customize the translate process.
@@ -53,6 +127,44 @@ type TranslateOptions struct {
 	// to avoid the cross-listener-use of routes.
 	// An extra `vars` expression will be added only if the listener address can be found here.
 	RouteOriginalDestination map[string]string
+	// RouteHTTPFilters is a map which key is the name of RouteConfiguration and
+	// value is the HTTP filters configured in the HttpConnectionManager that uses
+	// this route. Filters like ext_authz will be translated to APISIX plugins.
+	RouteHTTPFilters map[string][]*hcmv3.HttpFilter
+	// RouteVirtualHosts is a map which key is the name of RouteConfiguration and
+	// value is the virtual hosts discovered by VHDS for this route configuration,
+	// they're translated along with the virtual hosts in the route configuration
+	// if it enables VHDS.
+	RouteVirtualHosts map[string][]*routev3.VirtualHost
+	// RouteUpgradeConfigs is a map which key is the name of RouteConfiguration and
+	// value is the upgrade configs of the HttpConnectionManager that uses this route.
+	// Routes will enable the WebSocket proxy if the websocket upgrade is enabled.
+	RouteUpgradeConfigs map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig
+	// RouteFilterChains is a map which key is the name of RouteConfiguration and
+	// value is the filter chains that use this route configuration. The match
+	// criteria of filter chains will be translated to extra match conditions
+	// (remote_addrs and vars) of routes, so that routes from different filter
+	// chains don't collide. If several filter chains with different criteria use
+	// the same route configuration, each of them has its own routes, see
+	// FilterChain.Name.
+	RouteFilterChains map[string][]*FilterChain
+	// Upstreams is a map which key is the cluster name and value is the translated
+	// upstream, it's used to resolve clusters which are referred by HTTP filters
+	// (e.g. the authorization service of ext_authz) and the weighted clusters of
+	// tcp_proxy to concrete addresses.
+	Upstreams map[string]*apisix.Upstream
+	// SecretServerNames is a map which key is the name of the secret that listeners
+	// use to terminate the downstream TLS connections and value is the server names
+	// of the filter chains, they're translated to the snis of the server SSL.
+	SecretServerNames map[string][]string
+	// InboundPort is the port that APISIX listens on for the intercepted inbound
+	// traffic, if it's not zero, routes translated from route configurations in
+	// InboundRoutes will be bound to this port, and other routes will be excluded
+	// from it.
+	InboundPort int
+	// InboundRoutes contains the names of route configurations which are used by
+	// inbound listeners (e.g. the "virtualInbound" listener of Istio).
+	InboundRoutes set.StringSet
 }
 
 type adaptor struct {
@@ -0,0 +1,40 @@
+package v3
+
+import (
+	"strings"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+)
+
+const (
+	_websocketUpgradeType = "websocket"
+)
+
+// isWebSocketEnabled checks whether the websocket upgrade is enabled for the route.
+// The upgrade config on route can only enable or disable the websocket upgrade which
+// is configured on the HttpConnectionManager, just like what Envoy does.
+func (adaptor *adaptor) isWebSocketEnabled(upgrades []*hcmv3.HttpConnectionManager_UpgradeConfig, route *routev3.Route) bool {
+	var (
+		configured bool
+		enabled    bool
+	)
+	for _, upgrade := range upgrades {
+		if !strings.EqualFold(upgrade.GetUpgradeType(), _websocketUpgradeType) {
+			continue
+		}
+		configured = true
+		// Upgrade is enabled by default.
+		enabled = upgrade.GetEnabled() == nil || upgrade.GetEnabled().GetValue()
+	}
+	if !configured {
+		return false
+	}
+	for _, upgrade := range route.GetRoute().GetUpgradeConfigs() {
+		if !strings.EqualFold(upgrade.GetUpgradeType(), _websocketUpgradeType) {
+			continue
+		}
+		enabled = upgrade.GetEnabled() == nil || upgrade.GetEnabled().GetValue()
+	}
+	return enabled
+}
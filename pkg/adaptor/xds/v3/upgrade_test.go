@@ -0,0 +1,102 @@
+package v3
+
+import (
+	"testing"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+)
+
+func TestIsWebSocketEnabled(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	newRoute := func(ucs ...*routev3.RouteAction_UpgradeConfig) *routev3.Route {
+		return &routev3.Route{
+			Action: &routev3.Route_Route{
+				Route: &routev3.RouteAction{
+					UpgradeConfigs: ucs,
+				},
+			},
+		}
+	}
+
+	assert.False(t, a.isWebSocketEnabled(nil, newRoute()))
+	// Route level config cannot enable the upgrade which is not
+	// configured on the HttpConnectionManager.
+	assert.False(t, a.isWebSocketEnabled(nil, newRoute(&routev3.RouteAction_UpgradeConfig{
+		UpgradeType: "websocket",
+	})))
+
+	upgrades := []*hcmv3.HttpConnectionManager_UpgradeConfig{
+		{
+			UpgradeType: "CONNECT",
+		},
+		{
+			UpgradeType: "WebSocket",
+		},
+	}
+	assert.True(t, a.isWebSocketEnabled(upgrades, newRoute()))
+	assert.False(t, a.isWebSocketEnabled(upgrades, newRoute(&routev3.RouteAction_UpgradeConfig{
+		UpgradeType: "websocket",
+		Enabled:     wrapperspb.Bool(false),
+	})))
+
+	upgrades[1].Enabled = wrapperspb.Bool(false)
+	assert.False(t, a.isWebSocketEnabled(upgrades, newRoute()))
+	assert.True(t, a.isWebSocketEnabled(upgrades, newRoute(&routev3.RouteAction_UpgradeConfig{
+		UpgradeType: "websocket",
+		Enabled:     wrapperspb.Bool(true),
+	})))
+}
+
+func TestTranslateRouteConfigurationWithWebSocket(t *testing.T) {
+	a := &adaptor{logger: log.DefaultLogger}
+
+	rc := &routev3.RouteConfiguration{
+		Name: "rc1",
+		VirtualHosts: []*routev3.VirtualHost{
+			{
+				Name:    "vhost1",
+				Domains: []string{"*"},
+				Routes: []*routev3.Route{
+					{
+						Name: "route1",
+						Match: &routev3.RouteMatch{
+							PathSpecifier: &routev3.RouteMatch_Prefix{
+								Prefix: "/ws",
+							},
+						},
+						Action: &routev3.Route_Route{
+							Route: &routev3.RouteAction{
+								ClusterSpecifier: &routev3.RouteAction_Cluster{
+									Cluster: "echo.default.svc.cluster.local",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	routes, err := a.TranslateRouteConfiguration(rc, nil)
+	assert.Nil(t, err)
+	assert.Len(t, routes, 1)
+	assert.False(t, routes[0].EnableWebsocket)
+
+	routes, err = a.TranslateRouteConfiguration(rc, &TranslateOptions{
+		RouteUpgradeConfigs: map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig{
+			"rc1": {
+				{
+					UpgradeType: "websocket",
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, routes, 1)
+	assert.True(t, routes[0].EnableWebsocket)
+}
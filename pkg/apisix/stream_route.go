@@ -0,0 +1,43 @@
+package apisix
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+// CompareStreamRoutes diffs two apisix.StreamRoute array and finds the new adds, updates
+// and deleted ones. Note it stands on the first apisix.StreamRoute array's point
+// of view.
+func CompareStreamRoutes(r1, r2 []*apisix.StreamRoute) (added, deleted, updated []*apisix.StreamRoute) {
+	if r1 == nil {
+		return r2, nil, nil
+	}
+	if r2 == nil {
+		return nil, r1, nil
+	}
+
+	r1Map := make(map[string]*apisix.StreamRoute)
+	r2Map := make(map[string]*apisix.StreamRoute)
+	for _, r := range r1 {
+		r1Map[r.Id] = r
+	}
+	for _, r := range r2 {
+		r2Map[r.Id] = r
+	}
+	for _, r := range r2 {
+		if _, ok := r1Map[r.Id]; !ok {
+			added = append(added, r)
+		}
+	}
+	for _, ro := range r1 {
+		if rn, ok := r2Map[ro.Id]; !ok {
+			deleted = append(deleted, ro)
+		} else {
+			if !proto.Equal(ro, rn) {
+				updated = append(updated, rn)
+			}
+		}
+	}
+	return
+}
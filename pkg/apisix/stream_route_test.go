@@ -0,0 +1,60 @@
+package apisix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func TestCompareStreamRoutes(t *testing.T) {
+	r1 := []*apisix.StreamRoute{
+		{
+			Id: "1",
+		},
+		{
+			Id: "2",
+		},
+		{
+			Id: "3",
+		},
+	}
+
+	added, deleted, updated := CompareStreamRoutes(r1, nil)
+	assert.Nil(t, added)
+	assert.Nil(t, updated)
+	assert.Equal(t, deleted, r1)
+
+	added, deleted, updated = CompareStreamRoutes(nil, r1)
+	assert.Equal(t, added, r1)
+	assert.Nil(t, updated)
+	assert.Nil(t, deleted)
+
+	r2 := []*apisix.StreamRoute{
+		{
+			Id: "1",
+		},
+		{
+			Id: "4",
+		},
+		{
+			Id:         "3",
+			ServerPort: 6379,
+		},
+	}
+
+	added, deleted, updated = CompareStreamRoutes(r1, r2)
+	assert.Equal(t, added, []*apisix.StreamRoute{
+		{
+			Id: "4",
+		},
+	})
+	assert.Equal(t, deleted, []*apisix.StreamRoute{
+		{
+			Id: "2",
+		},
+	})
+	assert.Equal(t, updated[0].Id, "3")
+	assert.Equal(t, updated[0].ServerPort, int32(6379))
+}
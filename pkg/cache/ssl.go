@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+type ssl struct {
+	mu sync.RWMutex
+	// TODO optimize the store if the performance of map
+	// is unbearable.
+	store map[string]*apisix.SSL
+}
+
+func newSSL() SSL {
+	return &ssl{
+		store: make(map[string]*apisix.SSL),
+	}
+}
+
+func (r *ssl) Get(id string) (*apisix.SSL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	obj, ok := r.store[id]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	// Never return the original one to avoid race conditions.
+	return proto.Clone(obj).(*apisix.SSL), nil
+}
+
+func (r *ssl) List() ([]*apisix.SSL, error) {
+	var objs []*apisix.SSL
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, obj := range r.store {
+		objs = append(objs, proto.Clone(obj).(*apisix.SSL))
+	}
+	return objs, nil
+}
+
+func (r *ssl) Insert(obj *apisix.SSL) error {
+	obj = proto.Clone(obj).(*apisix.SSL)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store[obj.Id] = obj
+	return nil
+}
+
+func (r *ssl) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.store[id]
+	if !ok {
+		return ErrObjectNotFound
+	}
+	delete(r.store, id)
+	return nil
+}
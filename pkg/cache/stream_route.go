@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+type streamRoute struct {
+	mu sync.RWMutex
+	// TODO optimize the store if the performance of map
+	// is unbearable.
+	store map[string]*apisix.StreamRoute
+}
+
+func newStreamRoute() StreamRoute {
+	return &streamRoute{
+		store: make(map[string]*apisix.StreamRoute),
+	}
+}
+
+func (r *streamRoute) Get(id string) (*apisix.StreamRoute, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	obj, ok := r.store[id]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	// Never return the original one to avoid race conditions.
+	return proto.Clone(obj).(*apisix.StreamRoute), nil
+}
+
+func (r *streamRoute) List() ([]*apisix.StreamRoute, error) {
+	var objs []*apisix.StreamRoute
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, obj := range r.store {
+		objs = append(objs, proto.Clone(obj).(*apisix.StreamRoute))
+	}
+	return objs, nil
+}
+
+func (r *streamRoute) Insert(obj *apisix.StreamRoute) error {
+	obj = proto.Clone(obj).(*apisix.StreamRoute)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store[obj.Id] = obj
+	return nil
+}
+
+func (r *streamRoute) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.store[id]
+	if !ok {
+		return ErrObjectNotFound
+	}
+	delete(r.store, id)
+	return nil
+}
@@ -18,6 +18,10 @@ type Cache interface {
 	Route() Route
 	// Upstream returns the upstream exclusive cache object.
 	Upstream() Upstream
+	// StreamRoute returns the stream route exclusive cache object.
+	StreamRoute() StreamRoute
+	// SSL returns the SSL exclusive cache object.
+	SSL() SSL
 }
 
 // Route defines the exclusive behaviors for apisix.Route.
@@ -48,16 +52,48 @@ type Upstream interface {
 	Delete(string) error
 }
 
+// StreamRoute defines the exclusive behaviors for apisix.StreamRoute.
+type StreamRoute interface {
+	// Get the apisix.StreamRoute by its id. In case of the object not found,
+	// ErrObjectNotFound is given.
+	Get(string) (*apisix.StreamRoute, error)
+	// List lists all apisix.StreamRoute.
+	List() ([]*apisix.StreamRoute, error)
+	// Insert creates or updates an apisix.StreamRoute object, indexed by its id.
+	Insert(*apisix.StreamRoute) error
+	// Delete deletes the apisix.StreamRoute object by the id. In case of object not
+	// exist, ErrObjectNotFound is given.
+	Delete(string) error
+}
+
+// SSL defines the exclusive behaviors for apisix.SSL.
+type SSL interface {
+	// Get the apisix.SSL by its id. In case of the object not found,
+	// ErrObjectNotFound is given.
+	Get(string) (*apisix.SSL, error)
+	// List lists all apisix.SSL.
+	List() ([]*apisix.SSL, error)
+	// Insert creates or updates an apisix.SSL object, indexed by its id.
+	Insert(*apisix.SSL) error
+	// Delete deletes the apisix.SSL object by the id. In case of object not
+	// exist, ErrObjectNotFound is given.
+	Delete(string) error
+}
+
 type cache struct {
-	route    Route
-	upstream Upstream
+	route       Route
+	upstream    Upstream
+	streamRoute StreamRoute
+	ssl         SSL
 }
 
 // NewInMemoryCache creates a Cache object which stores all data in memory.
 func NewInMemoryCache() Cache {
 	return &cache{
-		route:    newRoute(),
-		upstream: newUpstream(),
+		route:       newRoute(),
+		upstream:    newUpstream(),
+		streamRoute: newStreamRoute(),
+		ssl:         newSSL(),
 	}
 }
 
@@ -68,3 +104,11 @@ func (c *cache) Route() Route {
 func (c *cache) Upstream() Upstream {
 	return c.upstream
 }
+
+func (c *cache) StreamRoute() StreamRoute {
+	return c.streamRoute
+}
+
+func (c *cache) SSL() SSL {
+	return c.ssl
+}
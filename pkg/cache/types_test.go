@@ -18,9 +18,17 @@ func TestInMemoryCache(t *testing.T) {
 	r := &apisix.Route{
 		Id: "1",
 	}
+	sr := &apisix.StreamRoute{
+		Id: "1",
+	}
+	ssl := &apisix.SSL{
+		Id: "1",
+	}
 
 	assert.Nil(t, c.Route().Insert(r))
 	assert.Nil(t, c.Upstream().Insert(ups))
+	assert.Nil(t, c.StreamRoute().Insert(sr))
+	assert.Nil(t, c.SSL().Insert(ssl))
 
 	rr, err := c.Route().Get("1")
 	assert.Nil(t, err)
@@ -29,4 +37,12 @@ func TestInMemoryCache(t *testing.T) {
 	uu, err := c.Upstream().Get("1")
 	assert.Nil(t, err)
 	assert.Equal(t, uu.GetId(), "1")
+
+	ss, err := c.StreamRoute().Get("1")
+	assert.Nil(t, err)
+	assert.Equal(t, ss.GetId(), "1")
+
+	s, err := c.SSL().Get("1")
+	assert.Nil(t, err)
+	assert.Equal(t, s.GetId(), "1")
 }
@@ -6,6 +6,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -15,6 +17,17 @@ const (
 	XDSV3FileProvisioner = "xds-v3-file"
 	// XDSV3GRPCProvisioner means to use the xds v3 grpc provisioner.
 	XDSV3GRPCProvisioner = "xds-v3-grpc"
+	// XDSV3RESTProvisioner means to use the xds v3 rest provisioner.
+	XDSV3RESTProvisioner = "xds-v3-rest"
+	// APISIXFileProvisioner means to use the APISIX declarative config
+	// (apisix.yaml style) file provisioner.
+	APISIXFileProvisioner = "apisix-file"
+	// KubernetesProvisioner means to use the Kubernetes provisioner, which
+	// watches Services, EndpointSlices and HTTPRoutes directly.
+	KubernetesProvisioner = "kubernetes"
+	// ReplayProvisioner means to use the replay provisioner, which plays
+	// back the events recorded by the recorder.
+	ReplayProvisioner = "replay"
 
 	// StandaloneMode means run apisix-mesh-agent standalone.
 	StandaloneMode = "standalone"
@@ -24,15 +37,73 @@ const (
 	DefaultAPISIXHomePath = "/usr/local/apisix"
 	// DefaultAPISIXBinPath is the default binary path for Apache APISIX.
 	DefaultAPISIXBinPath = "/usr/local/bin/apisix"
+	// DefaultXDSNodeIdTemplate is the default template to generate the xDS
+	// node id, it's compatible with Istio.
+	DefaultXDSNodeIdTemplate = "sidecar~{{ .IPAddress }}~{{ .PodName }}.{{ .PodNamespace }}~{{ .DNSDomain }}"
+	// DefaultPodLabelsFile is the default file path of pod labels, it's
+	// mounted by the Kubernetes downward API.
+	DefaultPodLabelsFile = "/etc/istio/pod/labels"
+	// DefaultTrustDomain is the default SPIFFE trust domain of the workload
+	// certificate.
+	DefaultTrustDomain = "cluster.local"
+	// DefaultClusterId is the default cluster id reported to the CA, it's
+	// the default value of Istio.
+	DefaultClusterId = "Kubernetes"
+	// DefaultWorkloadCertTTL is the default requested TTL of the workload
+	// certificate.
+	DefaultWorkloadCertTTL = 24 * time.Hour
+	// DefaultXDSWatchDebounce is the default debounce window of the file
+	// changes, for the xds-v3-file provisioner.
+	DefaultXDSWatchDebounce = 100 * time.Millisecond
+	// DefaultXDSRefreshInterval is the default interval to poll the xds
+	// config source, for the xds-v3-rest provisioner.
+	DefaultXDSRefreshInterval = 10 * time.Second
+	// DefaultXDSRequestTimeout is the default timeout of each request to
+	// the xds config source, for the xds-v3-rest provisioner.
+	DefaultXDSRequestTimeout = 5 * time.Second
+	// DefaultReplaySpeed is the default speed to replay the recorded events,
+	// it means the original timing.
+	DefaultReplaySpeed = 1.0
+	// InboundPassthroughPort is the port that the passthrough server listens
+	// on, it forwards the intercepted inbound traffic to the original destination
+	// directly, so the inbound port cannot be same to it.
+	InboundPassthroughPort = 9081
 )
 
 var (
 	// ErrUnknownProvisioner means user specified an unknown provisioner.
 	ErrUnknownProvisioner = errors.New("unknown provisioner")
+	// ErrDuplicatedProvisioner means a provisioner is specified more than
+	// once in the composite provisioners.
+	ErrDuplicatedProvisioner = errors.New("duplicated provisioner")
 	// ErrBadGRPCListen means the grpc listen address is invalid.
 	ErrBadGRPCListen = errors.New("bad grpc listen address")
 	// ErrEmptyXDSConfigSource means the XDS config source is empty.
 	ErrEmptyXDSConfigSource = errors.New("empty xds config source, --xds-config-source option is required")
+	// ErrBadStreamPort means the stream proxy port is invalid.
+	ErrBadStreamPort = errors.New("bad stream port")
+	// ErrBadXDSClientCert means only one of the client certificate
+	// and private key is specified.
+	ErrBadXDSClientCert = errors.New("client certificate and private key should be specified together")
+	// ErrBadXDSNodeIdTemplate means the xDS node id template is invalid.
+	ErrBadXDSNodeIdTemplate = errors.New("bad xds node id template")
+	// ErrBadCAAddress means the CA address is invalid.
+	ErrBadCAAddress = errors.New("bad ca address, it should be started with \"grpc://\" or \"grpcs://\"")
+	// ErrBadInboundPort means the inbound port is invalid.
+	ErrBadInboundPort = errors.New("bad inbound port")
+	// ErrBadWorkloadCertTTL means the TTL of workload certificate is invalid.
+	ErrBadWorkloadCertTTL = errors.New("bad workload certificate ttl")
+	// ErrBadXDSWatchDebounce means the debounce window of file changes is invalid.
+	ErrBadXDSWatchDebounce = errors.New("bad xds watch debounce")
+	// ErrBadXDSRefreshInterval means the interval to poll the xds config source
+	// is invalid.
+	ErrBadXDSRefreshInterval = errors.New("bad xds refresh interval")
+	// ErrBadXDSRequestTimeout means the timeout of xds requests is invalid.
+	ErrBadXDSRequestTimeout = errors.New("bad xds request timeout")
+	// ErrEmptyReplayFile means the replay file is empty.
+	ErrEmptyReplayFile = errors.New("empty replay file, --replay-file option is required")
+	// ErrBadReplaySpeed means the replay speed is invalid.
+	ErrBadReplaySpeed = errors.New("bad replay speed")
 
 	// DefaultGRPCListen is the default gRPC server listen address.
 	DefaultGRPCListen = "127.0.0.1:2379"
@@ -43,10 +114,14 @@ var (
 
 // RunningContext contains data which can be decided only when running.
 type RunningContext struct {
+	// PodName is the name of the resident pod.
+	PodName string
 	// PodNamespace is the namesapce of the resident pod.
 	PodNamespace string
 	// The IP address of the resident pod.
 	IPAddress string
+	// The service account of the resident pod.
+	ServiceAccount string
 }
 
 // Config contains configurations required for running apisix-mesh-agent.
@@ -59,11 +134,93 @@ type Config struct {
 	// The destination of logs.
 	LogOutput string `json:"log_output" yaml:"log_output"`
 	// The Provisioner to use.
-	// Value can be "xds-v3-file", "xds-v3-grpc".
+	// Value can be "xds-v3-file", "xds-v3-grpc", "xds-v3-rest", "apisix-file",
+	// "kubernetes", "replay".
 	Provisioner string `json:"provisioner" yaml:"provisioner"`
+	// The Provisioners to run together, objects from them are merged and
+	// the former one takes precedence if ids are conflicted. Provisioner
+	// is ignored if it's specified.
+	Provisioners []string `json:"provisioners" yaml:"provisioners"`
 	// The watched xds files, only valid if the Provisioner is "xds-v3-file"
-	XDSWatchFiles   []string `json:"xds_watch_files" yaml:"xds_watch_files"`
-	XDSConfigSource string   `json:"xds_config_source" yaml:"xds_config_source"`
+	XDSWatchFiles []string `json:"xds_watch_files" yaml:"xds_watch_files"`
+	// The watched files will be scanned after no more changes happened
+	// in this window (but no later than 10 windows since the first change),
+	// zero means to scan them immediately.
+	XDSWatchDebounce time.Duration `json:"xds_watch_debounce" yaml:"xds_watch_debounce"`
+	XDSConfigSource  string        `json:"xds_config_source" yaml:"xds_config_source"`
+	// The interval to poll the xds config source and the timeout of each
+	// request, only valid if the Provisioner is "xds-v3-rest".
+	XDSRefreshInterval time.Duration `json:"xds_refresh_interval" yaml:"xds_refresh_interval"`
+	XDSRequestTimeout  time.Duration `json:"xds_request_timeout" yaml:"xds_request_timeout"`
+	// The watched APISIX declarative config files, only valid if the
+	// Provisioner is "apisix-file".
+	APISIXWatchFiles []string `json:"apisix_watch_files" yaml:"apisix_watch_files"`
+	// The watched patch files, patches in them are applied to the routes
+	// and upstreams generated by the Provisioner.
+	PatchFiles []string `json:"patch_files" yaml:"patch_files"`
+	// The file to record the events emitted by the Provisioner, events are
+	// not recorded if it's empty.
+	RecordFile string `json:"record_file" yaml:"record_file"`
+	// Whether to remove the private keys of SSLs from the recording file,
+	// so that it can be shared safely, SSLs are skipped when replaying
+	// such a recording file.
+	RecordRedactKeys bool `json:"record_redact_keys" yaml:"record_redact_keys"`
+	// The recording file to play back and the speed, e.g. 2 means twice as
+	// fast as the original timing and 0 means no waiting at all. Only valid
+	// if the Provisioner is "replay".
+	ReplayFile  string  `json:"replay_file" yaml:"replay_file"`
+	ReplaySpeed float64 `json:"replay_speed" yaml:"replay_speed"`
+	// The kubeconfig file to access the Kubernetes API server, the in-cluster
+	// config will be used if it's empty. Only valid if the Provisioner is
+	// "kubernetes".
+	Kubeconfig string `json:"kubeconfig" yaml:"kubeconfig"`
+	// The namespace to watch, all namespaces will be watched if it's empty.
+	KubernetesNamespace string `json:"kubernetes_namespace" yaml:"kubernetes_namespace"`
+	// Whether to use the incremental (Delta) xDS protocol, only valid
+	// if the Provisioner is "xds-v3-grpc".
+	XDSDelta bool `json:"xds_delta" yaml:"xds_delta"`
+	// The CA bundle to verify the xds config source, only valid if
+	// the config source is "grpcs://", system roots will be used if
+	// it's empty.
+	XDSCAFile string `json:"xds_ca_file" yaml:"xds_ca_file"`
+	// The client certificate and private key, they're used to connect
+	// the xds config source with mTLS.
+	XDSClientCertFile string `json:"xds_client_cert_file" yaml:"xds_client_cert_file"`
+	XDSClientKeyFile  string `json:"xds_client_key_file" yaml:"xds_client_key_file"`
+	// Override the server name to verify the xds config source.
+	XDSServerName string `json:"xds_server_name" yaml:"xds_server_name"`
+	// The bearer token file to authenticate to the xds config source,
+	// it will be re-read once it's rotated.
+	XDSTokenFile string `json:"xds_token_file" yaml:"xds_token_file"`
+	// If specified, the token should be issued for one of the audiences.
+	XDSTokenAudiences []string `json:"xds_token_audiences" yaml:"xds_token_audiences"`
+	// The text/template to generate the xDS node id, available fields
+	// are RunId, IPAddress, PodName, PodNamespace and DNSDomain.
+	XDSNodeIdTemplate string `json:"xds_node_id_template" yaml:"xds_node_id_template"`
+	// The DNS domain of the resident pod, "<namespace>.svc.cluster.local"
+	// will be used if it's empty.
+	DNSDomain string `json:"dns_domain" yaml:"dns_domain"`
+	// The file which contains labels of the resident pod, labels will be
+	// reported in the xDS node metadata.
+	PodLabelsFile string `json:"pod_labels_file" yaml:"pod_labels_file"`
+	// The address of the Istio CA (e.g. "grpcs://istiod.istio-system.svc:15012"),
+	// if specified, the workload certificate will be requested from it, and
+	// used as the "default" secret (the name used by Istio). Only valid if the
+	// Provisioner is "xds-v3-grpc".
+	CAAddress string `json:"ca_address" yaml:"ca_address"`
+	// The root certificate to verify the CA, only valid if the CA address is
+	// "grpcs://", system roots will be used if it's empty.
+	CARootCertFile string `json:"ca_root_cert_file" yaml:"ca_root_cert_file"`
+	// The bearer token file to authenticate to the CA, it's usually the
+	// projected service account token.
+	CATokenFile string `json:"ca_token_file" yaml:"ca_token_file"`
+	// The SPIFFE trust domain of the workload certificate.
+	TrustDomain string `json:"trust_domain" yaml:"trust_domain"`
+	// The cluster id reported to the CA.
+	ClusterId string `json:"cluster_id" yaml:"cluster_id"`
+	// The requested TTL of the workload certificate, the certificate will
+	// be rotated once half of its lifetime passed.
+	WorkloadCertTTL time.Duration `json:"workload_cert_ttl" yaml:"workload_cert_ttl"`
 	// The grpc listen address
 	GRPCListen string `json:"grpc_listen" yaml:"grpc_listen"`
 	// The key prefix in the mimicking etcd v3 server.
@@ -78,6 +235,15 @@ type Config struct {
 	APISIXHomePath string `json:"apisix_home_path" yaml:"apisix_home_path"`
 	// The executable binary path of Apache APISIX.
 	APISIXBinPath string `json:"apisix_bin_path" yaml:"apisix_bin_path"`
+	// The port that Apache APISIX listens on for the intercepted inbound traffic,
+	// it should be same to the inbound capture port of iptables. If it's not zero,
+	// inbound listeners will be translated to routes bound to this port. Inbound
+	// traffic intercepted to the InboundPassthroughPort is always passed through
+	// to the original destination.
+	InboundPort int `json:"inbound_port" yaml:"inbound_port"`
+	// The TCP ports that the stream proxy of Apache APISIX listens on, traffic
+	// to these ports should be intercepted with the destination port kept.
+	StreamPorts []int `json:"stream_ports" yaml:"stream_ports"`
 
 	// RunningContext is the running context, it's self-contained.
 	// TODO: Move it outside here since it doesn't belong to "configuration".
@@ -98,20 +264,72 @@ func NewDefaultConfig() *Config {
 		APISIXBinPath:  DefaultAPISIXBinPath,
 		RunMode:        StandaloneMode,
 
+		XDSNodeIdTemplate: DefaultXDSNodeIdTemplate,
+		PodLabelsFile:     DefaultPodLabelsFile,
+		TrustDomain:       DefaultTrustDomain,
+		ClusterId:         DefaultClusterId,
+		WorkloadCertTTL:   DefaultWorkloadCertTTL,
+		XDSWatchDebounce:  DefaultXDSWatchDebounce,
+
+		XDSRefreshInterval: DefaultXDSRefreshInterval,
+		XDSRequestTimeout:  DefaultXDSRequestTimeout,
+		ReplaySpeed:        DefaultReplaySpeed,
+
 		RunningContext: getRunningContext(),
 	}
 }
 
 // Validate validates the config object.
 func (cfg *Config) Validate() error {
-	if cfg.Provisioner == "" {
-		return errors.New("unspecified provisioner")
+	provisioners := cfg.Provisioners
+	if len(provisioners) == 0 {
+		if cfg.Provisioner == "" {
+			return errors.New("unspecified provisioner")
+		}
+		provisioners = []string{cfg.Provisioner}
+	}
+	seen := make(map[string]struct{}, len(provisioners))
+	for _, p := range provisioners {
+		if p != XDSV3FileProvisioner && p != XDSV3GRPCProvisioner && p != XDSV3RESTProvisioner &&
+			p != APISIXFileProvisioner && p != KubernetesProvisioner && p != ReplayProvisioner {
+			return ErrUnknownProvisioner
+		}
+		if _, ok := seen[p]; ok {
+			return ErrDuplicatedProvisioner
+		}
+		seen[p] = struct{}{}
+		if (p == XDSV3GRPCProvisioner || p == XDSV3RESTProvisioner) && cfg.XDSConfigSource == "" {
+			return ErrEmptyXDSConfigSource
+		}
+		if p == ReplayProvisioner && cfg.ReplayFile == "" {
+			return ErrEmptyReplayFile
+		}
+	}
+	if (cfg.XDSClientCertFile == "") != (cfg.XDSClientKeyFile == "") {
+		return ErrBadXDSClientCert
 	}
-	if cfg.Provisioner != XDSV3FileProvisioner && cfg.Provisioner != XDSV3GRPCProvisioner {
-		return ErrUnknownProvisioner
+	if cfg.XDSNodeIdTemplate != "" {
+		if _, err := template.New("node_id").Parse(cfg.XDSNodeIdTemplate); err != nil {
+			return ErrBadXDSNodeIdTemplate
+		}
+	}
+	if cfg.CAAddress != "" && !strings.HasPrefix(cfg.CAAddress, "grpc://") && !strings.HasPrefix(cfg.CAAddress, "grpcs://") {
+		return ErrBadCAAddress
+	}
+	if cfg.WorkloadCertTTL < 0 {
+		return ErrBadWorkloadCertTTL
+	}
+	if cfg.XDSWatchDebounce < 0 {
+		return ErrBadXDSWatchDebounce
+	}
+	if cfg.XDSRefreshInterval < 0 {
+		return ErrBadXDSRefreshInterval
 	}
-	if cfg.Provisioner == XDSV3GRPCProvisioner && cfg.XDSConfigSource == "" {
-		return ErrEmptyXDSConfigSource
+	if cfg.XDSRequestTimeout < 0 {
+		return ErrBadXDSRequestTimeout
+	}
+	if cfg.ReplaySpeed < 0 {
+		return ErrBadReplaySpeed
 	}
 	ip, port, err := net.SplitHostPort(cfg.GRPCListen)
 	if err != nil {
@@ -125,6 +343,14 @@ func (cfg *Config) Validate() error {
 	if err != nil || pnum < 1 || pnum > 65535 {
 		return ErrBadGRPCListen
 	}
+	if cfg.InboundPort < 0 || cfg.InboundPort > 65535 || cfg.InboundPort == InboundPassthroughPort {
+		return ErrBadInboundPort
+	}
+	for _, port := range cfg.StreamPorts {
+		if port < 1 || port > 65535 {
+			return ErrBadStreamPort
+		}
+	}
 
 	return nil
 }
@@ -157,7 +383,9 @@ func getRunningContext() *RunningContext {
 		ipAddr = "127.0.0.1"
 	}
 	return &RunningContext{
-		PodNamespace: namespace,
-		IPAddress:    ipAddr,
+		PodName:        os.Getenv("POD_NAME"),
+		PodNamespace:   namespace,
+		IPAddress:      ipAddr,
+		ServiceAccount: os.Getenv("SERVICE_ACCOUNT"),
 	}
 }
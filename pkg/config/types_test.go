@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,6 +20,15 @@ func TestNewDefaultConfig(t *testing.T) {
 	assert.Equal(t, cfg.APISIXHomePath, DefaultAPISIXHomePath)
 	assert.Equal(t, cfg.APISIXBinPath, DefaultAPISIXBinPath)
 	assert.Equal(t, cfg.RunMode, StandaloneMode)
+	assert.Equal(t, cfg.XDSNodeIdTemplate, DefaultXDSNodeIdTemplate)
+	assert.Equal(t, cfg.PodLabelsFile, DefaultPodLabelsFile)
+	assert.Equal(t, cfg.TrustDomain, DefaultTrustDomain)
+	assert.Equal(t, cfg.ClusterId, DefaultClusterId)
+	assert.Equal(t, cfg.WorkloadCertTTL, DefaultWorkloadCertTTL)
+	assert.Equal(t, cfg.XDSWatchDebounce, DefaultXDSWatchDebounce)
+	assert.Equal(t, cfg.XDSRefreshInterval, DefaultXDSRefreshInterval)
+	assert.Equal(t, cfg.XDSRequestTimeout, DefaultXDSRequestTimeout)
+	assert.Equal(t, cfg.ReplaySpeed, DefaultReplaySpeed)
 }
 
 func TestConfigValidate(t *testing.T) {
@@ -29,6 +39,29 @@ func TestConfigValidate(t *testing.T) {
 	cfg.Provisioner = ""
 	assert.Equal(t, cfg.Validate(), errors.New("unspecified provisioner"))
 
+	cfg.Provisioner = APISIXFileProvisioner
+	assert.Nil(t, cfg.Validate())
+	cfg.Provisioner = KubernetesProvisioner
+	assert.Nil(t, cfg.Validate())
+
+	cfg.Provisioner = ""
+	cfg.Provisioners = []string{APISIXFileProvisioner, XDSV3FileProvisioner}
+	assert.Nil(t, cfg.Validate())
+	cfg.Provisioners = []string{APISIXFileProvisioner, "redis"}
+	assert.Equal(t, cfg.Validate(), ErrUnknownProvisioner)
+	cfg.Provisioners = []string{APISIXFileProvisioner, APISIXFileProvisioner}
+	assert.Equal(t, cfg.Validate(), ErrDuplicatedProvisioner)
+	cfg.Provisioners = []string{APISIXFileProvisioner, XDSV3GRPCProvisioner}
+	assert.Equal(t, cfg.Validate(), ErrEmptyXDSConfigSource)
+	cfg.Provisioners = nil
+
+	cfg.Provisioner = ReplayProvisioner
+	assert.Equal(t, cfg.Validate(), ErrEmptyReplayFile)
+	cfg.ReplayFile = "events.jsonl"
+	assert.Nil(t, cfg.Validate())
+	cfg.ReplaySpeed = -1
+	assert.Equal(t, cfg.Validate(), ErrBadReplaySpeed)
+
 	cfg = NewDefaultConfig()
 	cfg.GRPCListen = "127:8080"
 	assert.Equal(t, cfg.Validate(), ErrBadGRPCListen)
@@ -39,6 +72,55 @@ func TestConfigValidate(t *testing.T) {
 
 	cfg.Provisioner = "xds-v3-grpc"
 	assert.Equal(t, cfg.Validate(), ErrEmptyXDSConfigSource)
+
+	cfg = NewDefaultConfig()
+	cfg.StreamPorts = []int{3306, 0}
+	assert.Equal(t, cfg.Validate(), ErrBadStreamPort)
+	cfg.StreamPorts = []int{3306}
+	assert.Nil(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.InboundPort = 65536
+	assert.Equal(t, cfg.Validate(), ErrBadInboundPort)
+	cfg.InboundPort = InboundPassthroughPort
+	assert.Equal(t, cfg.Validate(), ErrBadInboundPort)
+	cfg.InboundPort = 9082
+	assert.Nil(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.XDSClientCertFile = "/etc/certs/cert-chain.pem"
+	assert.Equal(t, cfg.Validate(), ErrBadXDSClientCert)
+	cfg.XDSClientKeyFile = "/etc/certs/key.pem"
+	assert.Nil(t, cfg.Validate())
+
+	cfg = NewDefaultConfig()
+	cfg.XDSNodeIdTemplate = "sidecar~{{ .IPAddress"
+	assert.Equal(t, cfg.Validate(), ErrBadXDSNodeIdTemplate)
+
+	cfg = NewDefaultConfig()
+	cfg.CAAddress = "istiod.istio-system.svc:15012"
+	assert.Equal(t, cfg.Validate(), ErrBadCAAddress)
+	cfg.CAAddress = "grpcs://istiod.istio-system.svc:15012"
+	assert.Nil(t, cfg.Validate())
+	cfg.WorkloadCertTTL = -time.Hour
+	assert.Equal(t, cfg.Validate(), ErrBadWorkloadCertTTL)
+
+	cfg = NewDefaultConfig()
+	cfg.XDSWatchDebounce = 0
+	assert.Nil(t, cfg.Validate())
+	cfg.XDSWatchDebounce = -time.Second
+	assert.Equal(t, cfg.Validate(), ErrBadXDSWatchDebounce)
+
+	cfg = NewDefaultConfig()
+	cfg.Provisioner = XDSV3RESTProvisioner
+	assert.Equal(t, cfg.Validate(), ErrEmptyXDSConfigSource)
+	cfg.XDSConfigSource = "http://127.0.0.1:15010"
+	assert.Nil(t, cfg.Validate())
+	cfg.XDSRefreshInterval = -time.Second
+	assert.Equal(t, cfg.Validate(), ErrBadXDSRefreshInterval)
+	cfg.XDSRefreshInterval = time.Second
+	cfg.XDSRequestTimeout = -time.Second
+	assert.Equal(t, cfg.Validate(), ErrBadXDSRequestTimeout)
 }
 
 func TestGetRunningContext(t *testing.T) {
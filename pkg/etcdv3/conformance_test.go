@@ -74,6 +74,14 @@ func TestCheckWatchRequestConformance(t *testing.T) {
 	}
 	assert.Equal(t, e.checkWatchRequestConformance(r), rpctypes.ErrKeyNotFound)
 
+	r.RequestUnion = &etcdserverpb.WatchRequest_CreateRequest{
+		CreateRequest: &etcdserverpb.WatchCreateRequest{
+			Key:      []byte("/apisix/ssl"),
+			RangeEnd: []byte("/apisix/ssm"),
+		},
+	}
+	assert.Nil(t, e.checkWatchRequestConformance(r))
+
 	// PrevKv
 	r.RequestUnion = &etcdserverpb.WatchRequest_CreateRequest{
 		CreateRequest: &etcdserverpb.WatchCreateRequest{
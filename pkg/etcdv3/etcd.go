@@ -203,6 +203,10 @@ func (e *etcdV3) pushEvent(ev *types.Event) {
 		name = e.keyPrefix + "/routes/" + o.Id
 	case *apisix.Upstream:
 		name = e.keyPrefix + "/upstreams/" + o.Id
+	case *apisix.StreamRoute:
+		name = e.keyPrefix + "/stream_routes/" + o.Id
+	case *apisix.SSL:
+		name = e.keyPrefix + "/ssl/" + o.Id
 	default:
 		// ignore other resources for now.
 		return
@@ -282,6 +286,44 @@ func (e *etcdV3) pushEvent(ev *types.Event) {
 					zap.Any("events", event),
 				)
 			}
+		case *apisix.StreamRoute:
+			for id := range ws.streamRoute {
+				resp := &etcdserverpb.WatchResponse{
+					Header: &etcdserverpb.ResponseHeader{
+						Revision: ev.Revision,
+					},
+					WatchId: id,
+					Events: []*mvccpb.Event{
+						event,
+					},
+				}
+				resps = append(resps, resp)
+				ws.etcd.logger.Debugw("push to client",
+					zap.Any("watch_id", resp.WatchId),
+					zap.Any("revision", resp.Header.Revision),
+					zap.Any("resource", "stream_route"),
+					zap.Any("events", event),
+				)
+			}
+		case *apisix.SSL:
+			for id := range ws.ssl {
+				resp := &etcdserverpb.WatchResponse{
+					Header: &etcdserverpb.ResponseHeader{
+						Revision: ev.Revision,
+					},
+					WatchId: id,
+					Events: []*mvccpb.Event{
+						event,
+					},
+				}
+				resps = append(resps, resp)
+				ws.etcd.logger.Debugw("push to client",
+					zap.Any("watch_id", resp.WatchId),
+					zap.Any("revision", resp.Header.Revision),
+					zap.Any("resource", "ssl"),
+					zap.Any("events", event),
+				)
+			}
 		}
 		ws.mu.RUnlock()
 		// Must be non-blocking to release e.watcherMu, because once ws.ctx is done,
@@ -99,6 +99,10 @@ func TestPushEvents(t *testing.T) {
 			Type:   types.EventAdd,
 			Object: &apisix.Upstream{Id: "125"},
 		},
+		{
+			Type:   types.EventAdd,
+			Object: &apisix.SSL{Id: "126"},
+		},
 	}
 	f := &fakeRevisioner{rev: 1}
 	cfg := &config.Config{
@@ -114,14 +118,16 @@ func TestPushEvents(t *testing.T) {
 		etcd:     etcd.(*etcdV3),
 		route:    make(map[int64]struct{}),
 		upstream: make(map[int64]struct{}),
+		ssl:      make(map[int64]struct{}),
 	}
 	etcd.(*etcdV3).watchers[1] = ws
 	ws.route[1] = struct{}{}
 	ws.upstream[1] = struct{}{}
+	ws.ssl[1] = struct{}{}
 
 	etcd.PushEvents(events)
 
-	for i := 0; i < 3; i++ {
+	for i := 0; i < 4; i++ {
 		select {
 		case <-time.After(2 * time.Second):
 			assert.FailNow(t, "didn't receive event in time")
@@ -166,6 +166,44 @@ func (e *etcdV3) findExactKey(key []byte) (*etcdserverpb.RangeResponse, error) {
 			)
 			return nil, _errInternalError
 		}
+	case "stream_routes":
+		e.logger.Debugw("request for stream route",
+			zap.String("stream_route_id", parts[2]),
+		)
+		sr, err := e.cache.StreamRoute().Get(parts[2])
+		if err != nil {
+			if err == cache.ErrObjectNotFound {
+				return nil, rpctypes.ErrKeyNotFound
+			}
+			return nil, _errInternalError
+		}
+		value, err = json.Marshal(sr)
+		if err != nil {
+			e.logger.Errorw("failed to marshal stream route",
+				zap.Any("stream_route", sr),
+				zap.Error(err),
+			)
+			return nil, _errInternalError
+		}
+	case "ssl":
+		e.logger.Debugw("request for ssl",
+			zap.String("ssl_id", parts[2]),
+		)
+		ssl, err := e.cache.SSL().Get(parts[2])
+		if err != nil {
+			if err == cache.ErrObjectNotFound {
+				return nil, rpctypes.ErrKeyNotFound
+			}
+			return nil, _errInternalError
+		}
+		value, err = json.Marshal(ssl)
+		if err != nil {
+			e.logger.Errorw("failed to marshal ssl",
+				zap.String("ssl_id", ssl.Id),
+				zap.Error(err),
+			)
+			return nil, _errInternalError
+		}
 	default:
 		e.logger.Warnw("request for unknown resources",
 			zap.String("key", string(key)),
@@ -233,6 +271,46 @@ func (e *etcdV3) findAllKeys(key []byte) (*etcdserverpb.RangeResponse, error) {
 			}
 			kvs = append(kvs, e.composeKeyValue([]byte(itemKey), value))
 		}
+	case "stream_routes":
+		streamRoutes, err := e.cache.StreamRoute().List()
+		if err != nil {
+			e.logger.Errorw("failed to list stream routes",
+				zap.Error(err),
+			)
+			return nil, _errInternalError
+		}
+		for _, sr := range streamRoutes {
+			itemKey := e.keyPrefix + "/stream_routes/" + sr.Id
+			value, err := json.Marshal(sr)
+			if err != nil {
+				e.logger.Errorw("failed to marshal stream route",
+					zap.Error(err),
+					zap.Any("stream_route", sr),
+				)
+				return nil, _errInternalError
+			}
+			kvs = append(kvs, e.composeKeyValue([]byte(itemKey), value))
+		}
+	case "ssl":
+		ssls, err := e.cache.SSL().List()
+		if err != nil {
+			e.logger.Errorw("failed to list ssl",
+				zap.Error(err),
+			)
+			return nil, _errInternalError
+		}
+		for _, ssl := range ssls {
+			itemKey := e.keyPrefix + "/ssl/" + ssl.Id
+			value, err := json.Marshal(ssl)
+			if err != nil {
+				e.logger.Errorw("failed to marshal ssl",
+					zap.Error(err),
+					zap.String("ssl_id", ssl.Id),
+				)
+				return nil, _errInternalError
+			}
+			kvs = append(kvs, e.composeKeyValue([]byte(itemKey), value))
+		}
 	default:
 		return nil, rpctypes.ErrKeyNotFound
 	}
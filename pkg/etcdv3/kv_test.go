@@ -2,6 +2,7 @@ package etcdv3
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -134,6 +135,58 @@ func TestFindExactKey(t *testing.T) {
 	assert.Equal(t, ups.Timeout.Connect, ups2.Timeout.Connect)
 	assert.Equal(t, ups.Timeout.Send, ups2.Timeout.Send)
 	assert.Equal(t, ups.Timeout.Read, ups2.Timeout.Read)
+
+	resp, err = e.findExactKey([]byte("/apisix/stream_routes/00004"))
+	assert.Nil(t, resp, nil)
+	assert.Equal(t, err, rpctypes.ErrKeyNotFound)
+
+	sr := &apisix.StreamRoute{
+		Id:         "00004",
+		ServerPort: 3306,
+		UpstreamId: "00003",
+	}
+	fr.rev++
+	assert.Nil(t, e.cache.StreamRoute().Insert(sr))
+
+	resp, err = e.findExactKey([]byte("/apisix/stream_routes/00004"))
+	assert.NotNil(t, resp)
+	assert.Nil(t, err)
+	assert.Len(t, resp.Kvs, 1)
+	assert.Equal(t, resp.Kvs[0].ModRevision, int64(92))
+	assert.Equal(t, resp.Kvs[0].Key, []byte("/apisix/stream_routes/00004"))
+
+	var sr2 apisix.StreamRoute
+	assert.Nil(t, protojson.Unmarshal(resp.Kvs[0].Value, &sr2))
+	assert.Equal(t, sr.Id, sr2.Id)
+	assert.Equal(t, sr.ServerPort, sr2.ServerPort)
+	assert.Equal(t, sr.UpstreamId, sr2.UpstreamId)
+
+	resp, err = e.findExactKey([]byte("/apisix/ssl/00005"))
+	assert.Nil(t, resp, nil)
+	assert.Equal(t, err, rpctypes.ErrKeyNotFound)
+
+	ssl := &apisix.SSL{
+		Id:   "00005",
+		Cert: "cert",
+		Key:  "key",
+		Snis: []string{"httpbin.org"},
+	}
+	fr.rev++
+	assert.Nil(t, e.cache.SSL().Insert(ssl))
+
+	resp, err = e.findExactKey([]byte("/apisix/ssl/00005"))
+	assert.NotNil(t, resp)
+	assert.Nil(t, err)
+	assert.Len(t, resp.Kvs, 1)
+	assert.Equal(t, resp.Kvs[0].ModRevision, int64(93))
+	assert.Equal(t, resp.Kvs[0].Key, []byte("/apisix/ssl/00005"))
+
+	var ssl2 apisix.SSL
+	assert.Nil(t, protojson.Unmarshal(resp.Kvs[0].Value, &ssl2))
+	assert.Equal(t, ssl.Id, ssl2.Id)
+	assert.Equal(t, ssl.Cert, ssl2.Cert)
+	assert.Equal(t, ssl.Key, ssl2.Key)
+	assert.Equal(t, ssl.Snis, ssl2.Snis)
 }
 
 func TestFindAllKeys(t *testing.T) {
@@ -191,6 +244,31 @@ func TestFindAllKeys(t *testing.T) {
 	assert.Len(t, resp.Kvs, 1)
 	assert.Equal(t, resp.Kvs[0].CreateRevision, int64(90))
 	assert.Equal(t, resp.Kvs[0].ModRevision, int64(90))
+
+	sr1 := &apisix.StreamRoute{
+		Id:         "1",
+		ServerPort: 9100,
+	}
+	fr.rev++
+	assert.Nil(t, e.cache.StreamRoute().Insert(sr1))
+	resp, err = e.findAllKeys([]byte("/apisix/stream_routes"))
+	assert.Nil(t, err)
+	assert.Len(t, resp.Kvs, 1)
+	assert.Equal(t, resp.Kvs[0].Key, []byte("/apisix/stream_routes/1"))
+	assert.Equal(t, resp.Kvs[0].ModRevision, int64(91))
+
+	ssl1 := &apisix.SSL{
+		Id:   "1",
+		Cert: "cert",
+		Key:  "key",
+	}
+	fr.rev++
+	assert.Nil(t, e.cache.SSL().Insert(ssl1))
+	resp, err = e.findAllKeys([]byte("/apisix/ssl"))
+	assert.Nil(t, err)
+	assert.Len(t, resp.Kvs, 1)
+	assert.Equal(t, resp.Kvs[0].Key, []byte("/apisix/ssl/1"))
+	assert.Equal(t, resp.Kvs[0].ModRevision, int64(92))
 }
 
 func TestRangeRequest(t *testing.T) {
@@ -278,3 +356,54 @@ func TestPut(t *testing.T) {
 	assert.Equal(t, resp.Header.Revision, f.rev)
 	assert.Nil(t, err)
 }
+
+func TestRouteStatus(t *testing.T) {
+	e := &etcdV3{
+		metaCache:  make(map[string]meta),
+		revisioner: &fakeRevisioner{rev: 3},
+		keyPrefix:  "/apisix",
+		cache:      cache.NewInMemoryCache(),
+		logger:     log.DefaultLogger,
+	}
+	enabled := &apisix.Route{
+		Id:     "1",
+		Uris:   []string{"/foo"},
+		Status: apisix.Route_Enable,
+	}
+	// The disabled status is the zero value, it should be served explicitly
+	// since Apache APISIX treats the missing status as enabled.
+	disabled := &apisix.Route{
+		Id:     "2",
+		Uris:   []string{"/bar"},
+		Status: apisix.Route_Disable,
+	}
+	assert.Nil(t, e.cache.Route().Insert(enabled))
+	assert.Nil(t, e.cache.Route().Insert(disabled))
+
+	status := func(value []byte) interface{} {
+		var m map[string]interface{}
+		assert.Nil(t, json.Unmarshal(value, &m))
+		return m["status"]
+	}
+
+	resp, err := e.findExactKey([]byte("/apisix/routes/1"))
+	assert.Nil(t, err)
+	assert.Equal(t, status(resp.Kvs[0].Value), float64(1))
+	resp, err = e.findExactKey([]byte("/apisix/routes/2"))
+	assert.Nil(t, err)
+	assert.Equal(t, status(resp.Kvs[0].Value), float64(0))
+	var route apisix.Route
+	assert.Nil(t, json.Unmarshal(resp.Kvs[0].Value, &route))
+	assert.Equal(t, route.Uris, []string{"/bar"})
+
+	resp, err = e.findAllKeys([]byte("/apisix/routes"))
+	assert.Nil(t, err)
+	assert.Len(t, resp.Kvs, 2)
+	for _, kv := range resp.Kvs {
+		if string(kv.Key) == "/apisix/routes/2" {
+			assert.Equal(t, status(kv.Value), float64(0))
+		} else {
+			assert.Equal(t, status(kv.Value), float64(1))
+		}
+	}
+}
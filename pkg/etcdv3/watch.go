@@ -34,14 +34,16 @@ func randInt64() int64 {
 }
 
 type watchStream struct {
-	id       int64
-	ctx      context.Context
-	etcd     *etcdV3
-	stream   etcdserverpb.Watch_WatchServer
-	mu       sync.RWMutex
-	route    map[int64]struct{}
-	upstream map[int64]struct{}
-	eventCh  chan *etcdserverpb.WatchResponse
+	id          int64
+	ctx         context.Context
+	etcd        *etcdV3
+	stream      etcdserverpb.Watch_WatchServer
+	mu          sync.RWMutex
+	route       map[int64]struct{}
+	upstream    map[int64]struct{}
+	streamRoute map[int64]struct{}
+	ssl         map[int64]struct{}
+	eventCh     chan *etcdserverpb.WatchResponse
 }
 
 func (ws *watchStream) cancelWatch(id int64) bool {
@@ -55,6 +57,14 @@ func (ws *watchStream) cancelWatch(id int64) bool {
 		delete(ws.upstream, id)
 		return true
 	}
+	if _, ok := ws.streamRoute[id]; ok {
+		delete(ws.streamRoute, id)
+		return true
+	}
+	if _, ok := ws.ssl[id]; ok {
+		delete(ws.ssl, id)
+		return true
+	}
 	return false
 }
 
@@ -71,6 +81,16 @@ func (ws *watchStream) createWatch(id int64, resource string) error {
 			return _errDuplicatedWatchId
 		}
 		ws.upstream[id] = struct{}{}
+	} else if resource == "stream_route" {
+		if _, ok := ws.streamRoute[id]; ok {
+			return _errDuplicatedWatchId
+		}
+		ws.streamRoute[id] = struct{}{}
+	} else if resource == "ssl" {
+		if _, ok := ws.ssl[id]; ok {
+			return _errDuplicatedWatchId
+		}
+		ws.ssl[id] = struct{}{}
 	}
 	return nil
 }
@@ -84,6 +104,10 @@ func (ws *watchStream) firstWatch(id int64, resource string, minRev int64) error
 		kvs, err = ws.findAllRoutes(minRev)
 	} else if resource == "upstream" {
 		kvs, err = ws.findAllUpstreams(minRev)
+	} else if resource == "stream_route" {
+		kvs, err = ws.findAllStreamRoutes(minRev)
+	} else if resource == "ssl" {
+		kvs, err = ws.findAllSSLs(minRev)
 	}
 	if err != nil {
 		return err
@@ -195,6 +219,86 @@ func (ws *watchStream) findAllUpstreams(minRev int64) ([]*mvccpb.KeyValue, error
 	return kvs, nil
 }
 
+func (ws *watchStream) findAllStreamRoutes(minRev int64) ([]*mvccpb.KeyValue, error) {
+	streamRoutes, err := ws.etcd.cache.StreamRoute().List()
+	if err != nil {
+		ws.etcd.logger.Errorw("failed to list stream routes",
+			zap.Error(err),
+		)
+		return nil, _errInternalError
+	}
+	var kvs []*mvccpb.KeyValue
+	for _, sr := range streamRoutes {
+		key := ws.etcd.keyPrefix + "/stream_routes/" + sr.Id
+		ws.etcd.metaMu.RLock()
+		m, ok := ws.etcd.metaCache[key]
+		ws.etcd.metaMu.RUnlock()
+		if !ok {
+			ws.etcd.logger.Warnw("found stream route without metadata",
+				zap.String("stream_route_name", key),
+			)
+			continue
+		}
+		if m.modRevision >= minRev {
+			value, err := json.Marshal(sr)
+			if err != nil {
+				ws.etcd.logger.Errorw("protojson marshal failure",
+					zap.Error(err),
+					zap.Any("stream_route", sr),
+				)
+				return nil, err
+			}
+			kvs = append(kvs, &mvccpb.KeyValue{
+				Key:            []byte(key),
+				CreateRevision: m.createRevision,
+				ModRevision:    m.modRevision,
+				Value:          value,
+			})
+		}
+	}
+	return kvs, nil
+}
+
+func (ws *watchStream) findAllSSLs(minRev int64) ([]*mvccpb.KeyValue, error) {
+	ssls, err := ws.etcd.cache.SSL().List()
+	if err != nil {
+		ws.etcd.logger.Errorw("failed to list ssl",
+			zap.Error(err),
+		)
+		return nil, _errInternalError
+	}
+	var kvs []*mvccpb.KeyValue
+	for _, ssl := range ssls {
+		key := ws.etcd.keyPrefix + "/ssl/" + ssl.Id
+		ws.etcd.metaMu.RLock()
+		m, ok := ws.etcd.metaCache[key]
+		ws.etcd.metaMu.RUnlock()
+		if !ok {
+			ws.etcd.logger.Warnw("found ssl without metadata",
+				zap.String("ssl_name", key),
+			)
+			continue
+		}
+		if m.modRevision >= minRev {
+			value, err := json.Marshal(ssl)
+			if err != nil {
+				ws.etcd.logger.Errorw("protojson marshal failure",
+					zap.Error(err),
+					zap.String("ssl_id", ssl.Id),
+				)
+				return nil, err
+			}
+			kvs = append(kvs, &mvccpb.KeyValue{
+				Key:            []byte(key),
+				CreateRevision: m.createRevision,
+				ModRevision:    m.modRevision,
+				Value:          value,
+			})
+		}
+	}
+	return kvs, nil
+}
+
 func (e *etcdV3) addWatchStream(ws *watchStream) {
 	e.watcherMu.Lock()
 	id := e.nextWatchId
@@ -207,12 +311,14 @@ func (e *etcdV3) addWatchStream(ws *watchStream) {
 func (e *etcdV3) Watch(stream etcdserverpb.Watch_WatchServer) error {
 	ctx, cancel := context.WithCancel(stream.Context())
 	ws := &watchStream{
-		stream:   stream,
-		route:    make(map[int64]struct{}),
-		upstream: make(map[int64]struct{}),
-		etcd:     e,
-		eventCh:  make(chan *etcdserverpb.WatchResponse),
-		ctx:      ctx,
+		stream:      stream,
+		route:       make(map[int64]struct{}),
+		upstream:    make(map[int64]struct{}),
+		streamRoute: make(map[int64]struct{}),
+		ssl:         make(map[int64]struct{}),
+		etcd:        e,
+		eventCh:     make(chan *etcdserverpb.WatchResponse),
+		ctx:         ctx,
 	}
 	e.addWatchStream(ws)
 	e.logger.Debugw("add new watcher",
@@ -256,6 +362,8 @@ func (e *etcdV3) Watch(stream etcdserverpb.Watch_WatchServer) error {
 				zap.Int64("watcher_id", ws.id),
 				zap.Any("watching_routes", ws.route),
 				zap.Any("watching_upstreams", ws.upstream),
+				zap.Any("watching_stream_routes", ws.streamRoute),
+				zap.Any("watching_ssl", ws.ssl),
 			)
 			return nil
 		case werr := <-errCh:
@@ -297,6 +405,10 @@ func (ws *watchStream) onWire() error {
 				resource = "route"
 			} else if string(uv.CreateRequest.Key) == ws.etcd.keyPrefix+"/upstreams" {
 				resource = "upstream"
+			} else if string(uv.CreateRequest.Key) == ws.etcd.keyPrefix+"/stream_routes" {
+				resource = "stream_route"
+			} else if string(uv.CreateRequest.Key) == ws.etcd.keyPrefix+"/ssl" {
+				resource = "ssl"
 			} // others are not concerned
 			if uv.CreateRequest.WatchId == 0 {
 				id = randInt64()
@@ -0,0 +1,352 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+var (
+	_errNotAnObject = errors.New("document is not an object")
+	_errEmptyId     = errors.New("empty id")
+)
+
+var (
+	// _sections are the supported sections in order, SSLs are in the
+	// "ssl" section of APISIX 2.x, "ssls" is also accepted.
+	_sections = []string{"upstreams", "routes", "ssl", "ssls", "stream_routes"}
+	_decoders = map[string]func(map[string]interface{}, *util.Manifest) error{
+		"upstreams":     decodeUpstream,
+		"routes":        decodeRoute,
+		"ssl":           decodeSSL,
+		"ssls":          decodeSSL,
+		"stream_routes": decodeStreamRoute,
+	}
+)
+
+// decodeFile decodes the APISIX declarative config (the apisix.yaml used
+// by the standalone mode of Apache APISIX, see
+// https://apisix.apache.org/docs/apisix/stand-alone for more details),
+// the file can be either JSON or YAML (decided by the extension name).
+// Routes, upstreams, SSLs and stream routes are supported, names of the
+// other sections are returned so that they can be reported.
+func decodeFile(filename string, data []byte) (*util.Manifest, []string, error) {
+	doc, err := toJSON(filename, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &sections); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		m       util.Manifest
+		ignored []string
+	)
+	for name := range sections {
+		if _, ok := _decoders[name]; !ok {
+			ignored = append(ignored, name)
+		}
+	}
+	sort.Strings(ignored)
+	for _, name := range _sections {
+		raw, ok := sections[name]
+		if !ok {
+			continue
+		}
+		var objs []map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		// Keep the numeric ids as they are.
+		dec.UseNumber()
+		if err := dec.Decode(&objs); err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", name, err)
+		}
+		for i, obj := range objs {
+			if err := _decoders[name](obj, &m); err != nil {
+				return nil, nil, fmt.Errorf("%s[%d]: %s", name, i, err)
+			}
+		}
+	}
+	return &m, ignored, nil
+}
+
+// toJSON converts the file content to JSON, an empty file is treated as
+// an empty object.
+func toJSON(filename string, data []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []byte("{}"), nil
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return []byte("{}"), nil
+		}
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return nil, _errNotAnObject
+		}
+		return json.Marshal(doc)
+	default:
+		if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			return nil, _errNotAnObject
+		}
+		return data, nil
+	}
+}
+
+// decodeRoute converts the route to the protobuf form, the APISIX shorthands
+// (e.g. uri, host) are expanded. The inline upstream is extracted as an
+// individual upstream with the id "route-<route id>".
+func decodeRoute(obj map[string]interface{}, m *util.Manifest) error {
+	if err := normalizeId(obj, "id", true); err != nil {
+		return err
+	}
+	for _, key := range []string{"upstream_id", "service_id"} {
+		if err := normalizeId(obj, key, false); err != nil {
+			return err
+		}
+	}
+	expandShorthand(obj, "uri", "uris")
+	expandShorthand(obj, "host", "hosts")
+	expandShorthand(obj, "remote_addr", "remote_addrs")
+	setDefault(obj, "name", obj["id"])
+	// Routes are enabled by default in APISIX.
+	setDefault(obj, "status", "Enable")
+	if vars, ok := obj["vars"]; ok {
+		nv, err := normalizeVars(vars)
+		if err != nil {
+			return err
+		}
+		obj["vars"] = nv
+	}
+	if ups, ok := obj["upstream"].(map[string]interface{}); ok {
+		if _, ok := obj["upstream_id"]; ok {
+			return errors.New("upstream and upstream_id are exclusive")
+		}
+		delete(obj, "upstream")
+		id := "route-" + obj["id"].(string)
+		ups["id"] = id
+		if err := decodeUpstream(ups, m); err != nil {
+			return fmt.Errorf("upstream: %s", err)
+		}
+		obj["upstream_id"] = id
+	}
+
+	var r apisix.Route
+	if err := unmarshal(obj, &r); err != nil {
+		return err
+	}
+	if err := validateRoute(&r); err != nil {
+		return err
+	}
+	m.Routes = append(m.Routes, &r)
+	return nil
+}
+
+func decodeUpstream(obj map[string]interface{}, m *util.Manifest) error {
+	if err := normalizeId(obj, "id", true); err != nil {
+		return err
+	}
+	setDefault(obj, "name", obj["id"])
+	if err := normalizeUpstream(obj); err != nil {
+		return err
+	}
+	var ups apisix.Upstream
+	if err := unmarshal(obj, &ups); err != nil {
+		return err
+	}
+	if err := validateUpstream(&ups); err != nil {
+		return err
+	}
+	m.Upstreams = append(m.Upstreams, &ups)
+	return nil
+}
+
+func decodeSSL(obj map[string]interface{}, m *util.Manifest) error {
+	if err := normalizeId(obj, "id", true); err != nil {
+		return err
+	}
+	expandShorthand(obj, "sni", "snis")
+	var ssl apisix.SSL
+	if err := unmarshal(obj, &ssl); err != nil {
+		return err
+	}
+	if err := ssl.Validate(); err != nil {
+		return err
+	}
+	m.SSLs = append(m.SSLs, &ssl)
+	return nil
+}
+
+func decodeStreamRoute(obj map[string]interface{}, m *util.Manifest) error {
+	if err := normalizeId(obj, "id", true); err != nil {
+		return err
+	}
+	if err := normalizeId(obj, "upstream_id", false); err != nil {
+		return err
+	}
+	if ups, ok := obj["upstream"].(map[string]interface{}); ok {
+		setDefault(ups, "name", obj["id"])
+		if err := normalizeUpstream(ups); err != nil {
+			return fmt.Errorf("upstream: %s", err)
+		}
+	}
+	var sr apisix.StreamRoute
+	if err := unmarshal(obj, &sr); err != nil {
+		return err
+	}
+	if err := validateStreamRoute(&sr); err != nil {
+		return err
+	}
+	m.StreamRoutes = append(m.StreamRoutes, &sr)
+	return nil
+}
+
+func unmarshal(obj map[string]interface{}, msg proto.Message) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	// Unknown fields are not ignored, so that typos or unsupported
+	// features won't be dropped silently.
+	return protojson.Unmarshal(data, msg)
+}
+
+// normalizeId converts the numeric id to string.
+func normalizeId(obj map[string]interface{}, key string, required bool) error {
+	var id string
+	switch v := obj[key].(type) {
+	case nil:
+	case string:
+		id = v
+	case json.Number:
+		id = v.String()
+	default:
+		return fmt.Errorf("bad %s: %v", key, v)
+	}
+	if id == "" {
+		if required {
+			return _errEmptyId
+		}
+		delete(obj, key)
+		return nil
+	}
+	obj[key] = id
+	return nil
+}
+
+// expandShorthand moves the single value field to the plural one.
+func expandShorthand(obj map[string]interface{}, single, plural string) {
+	v, ok := obj[single]
+	if !ok {
+		return
+	}
+	delete(obj, single)
+	if _, ok := obj[plural]; !ok {
+		obj[plural] = []interface{}{v}
+	}
+}
+
+// normalizeVars converts the vars ([["arg_name", "==", "json"]]) to the
+// protobuf form ([{"vars": ["arg_name", "==", "json"]}]).
+func normalizeVars(vars interface{}) ([]interface{}, error) {
+	list, ok := vars.([]interface{})
+	if !ok {
+		return nil, errors.New("bad vars: not an array")
+	}
+	nv := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		expr, ok := item.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("bad vars: %v is not an array", item)
+		}
+		var strs []string
+		for _, e := range expr {
+			switch v := e.(type) {
+			case string:
+				strs = append(strs, v)
+			case json.Number:
+				strs = append(strs, v.String())
+			case bool:
+				strs = append(strs, strconv.FormatBool(v))
+			default:
+				return nil, fmt.Errorf("bad vars: unsupported operand %v", e)
+			}
+		}
+		nv = append(nv, map[string]interface{}{"vars": strs})
+	}
+	return nv, nil
+}
+
+// setDefault sets the field if it's absent.
+func setDefault(obj map[string]interface{}, key string, value interface{}) {
+	if _, ok := obj[key]; !ok {
+		obj[key] = value
+	}
+}
+
+// normalizeUpstream fills the default values of APISIX, and converts
+// the nodes in the hash form ({"host:port": weight}) to the array form,
+// the port is decided by the scheme if it's absent.
+func normalizeUpstream(ups map[string]interface{}) error {
+	setDefault(ups, "type", "roundrobin")
+	setDefault(ups, "hash_on", "vars")
+	setDefault(ups, "scheme", "http")
+	setDefault(ups, "pass_host", "pass")
+	if check, ok := ups["check"].(map[string]interface{}); ok {
+		for _, kind := range []string{"active", "passive"} {
+			if hc, ok := check[kind].(map[string]interface{}); ok {
+				setDefault(hc, "type", "http")
+			}
+		}
+	}
+
+	hash, ok := ups["nodes"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	defaultPort := "80"
+	if scheme, _ := ups["scheme"].(string); scheme == "https" || scheme == "grpcs" {
+		defaultPort = "443"
+	}
+	addrs := make([]string, 0, len(hash))
+	for addr := range hash {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	nodes := make([]interface{}, 0, len(hash))
+	for _, addr := range addrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, defaultPort
+		}
+		portNum, err := strconv.Atoi(port)
+		if err != nil {
+			return fmt.Errorf("bad node %s: %s", addr, err)
+		}
+		nodes = append(nodes, map[string]interface{}{
+			"host":   host,
+			"port":   portNum,
+			"weight": hash[addr],
+		})
+	}
+	ups["nodes"] = nodes
+	return nil
+}
@@ -0,0 +1,183 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func TestDecodeFileYAML(t *testing.T) {
+	data := `
+routes:
+- id: 1
+  uri: /hello
+  host: httpbin.org
+  methods: [GET]
+  vars:
+  - ["arg_name", "==", "json"]
+  - ["http_x_version", ">", 2]
+  upstream_id: 1
+  plugins:
+    fault-injection:
+      abort:
+        http_status: 403
+    limit-count:
+      count: 2
+      time_window: 60
+- id: r2
+  name: inline
+  uris: [/status/*]
+  status: 0
+  upstream:
+    type: roundrobin
+    scheme: https
+    nodes:
+      "10.0.3.12": 1
+upstreams:
+- id: 1
+  type: roundrobin
+  nodes:
+    "10.0.3.11:8000": 10
+ssl:
+- id: 1
+  sni: httpbin.org
+  cert: cert
+  key: key
+stream_routes:
+- id: 1
+  server_port: 9100
+  upstream:
+    type: roundrobin
+    nodes:
+      "10.0.3.13:3306": 1
+consumers:
+- username: jack
+#END
+`
+	m, ignored, err := decodeFile("apisix.yaml", []byte(data))
+	assert.Nil(t, err)
+	assert.Equal(t, ignored, []string{"consumers"})
+
+	assert.Len(t, m.Routes, 2)
+	r := m.Routes[0]
+	assert.Equal(t, r.Id, "1")
+	assert.Equal(t, r.Name, "1")
+	assert.Equal(t, r.Uris, []string{"/hello"})
+	assert.Equal(t, r.Hosts, []string{"httpbin.org"})
+	assert.Equal(t, r.Status, apisix.Route_Enable)
+	assert.Equal(t, r.UpstreamId, "1")
+	assert.Equal(t, r.Vars[0].Vars, []string{"arg_name", "==", "json"})
+	assert.Equal(t, r.Vars[1].Vars, []string{"http_x_version", ">", "2"})
+	assert.Equal(t, r.Plugins[apisix.FaultInjectionPlugin].AsMap(), map[string]interface{}{
+		"abort": map[string]interface{}{"http_status": float64(403)},
+	})
+	assert.Equal(t, r.Plugins["limit-count"].AsMap(), map[string]interface{}{
+		"count":       float64(2),
+		"time_window": float64(60),
+	})
+
+	r = m.Routes[1]
+	assert.Equal(t, r.Name, "inline")
+	assert.Equal(t, r.Status, apisix.Route_Disable)
+	assert.Equal(t, r.UpstreamId, "route-r2")
+
+	assert.Len(t, m.Upstreams, 2)
+	assert.Equal(t, m.Upstreams[0].Id, "1")
+	assert.Equal(t, m.Upstreams[0].Name, "1")
+	assert.Equal(t, m.Upstreams[0].Nodes[0].Host, "10.0.3.11")
+	assert.Equal(t, m.Upstreams[0].Nodes[0].Port, int32(8000))
+	assert.Equal(t, m.Upstreams[0].Nodes[0].Weight, int32(10))
+	assert.Equal(t, m.Upstreams[0].Scheme, "http")
+	assert.Equal(t, m.Upstreams[0].PassHost, "pass")
+	assert.Equal(t, m.Upstreams[1].Id, "route-r2")
+	assert.Equal(t, m.Upstreams[1].Nodes[0].Port, int32(443))
+
+	assert.Len(t, m.SSLs, 1)
+	assert.Equal(t, m.SSLs[0].Snis, []string{"httpbin.org"})
+	assert.Len(t, m.StreamRoutes, 1)
+	assert.Equal(t, m.StreamRoutes[0].Upstream.Nodes[0].Port, int32(3306))
+
+	// The "ssls" section is also accepted.
+	m, ignored, err = decodeFile("apisix.yaml", []byte("ssls:\n- id: 2\n  snis: [httpbin.org]\n  cert: cert\n  key: key\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, ignored)
+	assert.Len(t, m.SSLs, 1)
+	assert.Equal(t, m.SSLs[0].Id, "2")
+}
+
+func TestDecodeFileJSON(t *testing.T) {
+	data := `{
+  "upstreams": [
+    {
+      "id": "httpbin",
+      "name": "httpbin",
+      "type": "chash",
+      "hash_on": "header",
+      "key": "x-user",
+      "nodes": [{"host": "10.0.3.11", "port": 8000, "weight": 1}],
+      "check": {
+        "active": {"type": "http", "host": "httpbin.org", "port": 8000, "http_path": "/status/200"},
+        "passive": {"unhealthy": {"http_statuses": [500, 503]}}
+      }
+    }
+  ]
+}`
+	m, ignored, err := decodeFile("apisix.json", []byte(data))
+	assert.Nil(t, err)
+	assert.Nil(t, ignored)
+	assert.Len(t, m.Upstreams, 1)
+	assert.Equal(t, m.Upstreams[0].HashOn, "header")
+	// Empty optional fields are not filled.
+	assert.Nil(t, m.Upstreams[0].Check.Active.Healthy)
+	assert.Equal(t, m.Upstreams[0].Check.Passive.Unhealthy.HttpStatuses, []int32{500, 503})
+	assert.Equal(t, m.Upstreams[0].Check.Passive.Unhealthy.Timeouts, int32(0))
+
+	m, _, err = decodeFile("apisix.json", []byte("\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, m.Size(), 0)
+}
+
+func TestDecodeFileInvalid(t *testing.T) {
+	cases := []struct {
+		data string
+		err  string
+	}{
+		{
+			data: "- a\n",
+			err:  _errNotAnObject.Error(),
+		},
+		{
+			data: "routes:\n- uri: /hello\n",
+			err:  "routes[0]: empty id",
+		},
+		{
+			data: "routes:\n- id: 1\n  uri: /hello\n  methods: [FETCH]\n",
+			err:  "routes[0]: invalid Route.Methods[0]",
+		},
+		{
+			data: "routes:\n- id: 1\n  uri: /hello\n  upstream_id: 1\n  upstream: {type: roundrobin}\n",
+			err:  "routes[0]: upstream and upstream_id are exclusive",
+		},
+		{
+			data: "routes:\n- id: 1\n  uri: /hello\n  vars: [arg_name]\n",
+			err:  "routes[0]: bad vars: arg_name is not an array",
+		},
+		{
+			data: "upstreams:\n- id: 1\n  type: roundrobin\n  nodes: {\"10.0.3.11:abc\": 1}\n",
+			err:  "upstreams[0]: bad node 10.0.3.11:abc",
+		},
+		{
+			// Unknown fields are not dropped silently.
+			data: "upstreams:\n- id: 1\n  type: roundrobin\n  nodez: {}\n",
+			err:  "upstreams[0]: ",
+		},
+	}
+	for _, c := range cases {
+		_, _, err := decodeFile("apisix.yaml", []byte(c.data))
+		if assert.NotNil(t, err, c.data) {
+			assert.Contains(t, err.Error(), c.err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/watcher"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+)
+
+type apisixFileProvisioner struct {
+	logger   *log.Logger
+	evChan   chan []types.Event
+	files    []string
+	debounce time.Duration
+	// manifest is the last loaded state of all files.
+	manifest *util.Manifest
+}
+
+// NewAPISIXProvisioner creates a Provisioner which watches the APISIX
+// declarative config files (the apisix.yaml style), the paths can be
+// files or directories (only the .yaml, .yml and .json files in it are
+// loaded). All files are reloaded together once any of them changed,
+// resources are validated and the invalid config is rejected as a whole,
+// with the last valid state kept.
+func NewAPISIXProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
+	if len(cfg.APISIXWatchFiles) == 0 {
+		return nil, errors.New("apisix-file provisioner: no watch files")
+	}
+	logger, err := log.NewLogger(
+		log.WithContext("apisix-file-provisioner"),
+		log.WithLogLevel(cfg.LogLevel),
+		log.WithOutputFile(cfg.LogOutput),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &apisixFileProvisioner{
+		logger:   logger,
+		evChan:   make(chan []types.Event),
+		files:    cfg.APISIXWatchFiles,
+		debounce: watcher.DefaultDebounce,
+	}, nil
+}
+
+func (p *apisixFileProvisioner) Channel() <-chan []types.Event {
+	return p.evChan
+}
+
+func (p *apisixFileProvisioner) Run(stop chan struct{}) error {
+	p.logger.Infow("apisix file provisioner started")
+	defer p.logger.Infow("apisix file provisioner exited")
+	defer close(p.evChan)
+
+	w, err := watcher.NewWatcher(p.files, p.debounce, p.logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := w.Close(); err != nil {
+			p.logger.Errorw("failed to close watcher",
+				zap.Error(err),
+			)
+		}
+	}()
+	pending := p.reload()
+
+	for {
+		var sendCh chan<- []types.Event
+		if len(pending) > 0 {
+			sendCh = p.evChan
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-w.Changed():
+			// Events are coalesced if the last batch was not received.
+			pending = append(pending, p.reload()...)
+		case sendCh <- pending:
+			pending = nil
+		}
+	}
+}
+
+// reload loads all files and generates events by comparing with the last
+// state, nothing is generated if the config is invalid.
+func (p *apisixFileProvisioner) reload() []types.Event {
+	m, err := p.load()
+	if err != nil {
+		p.logger.Errorw("reject invalid apisix config",
+			zap.Error(err),
+		)
+		return nil
+	}
+	added, deleted, updated := util.DiffManifests(p.manifest, m)
+	p.manifest = m
+	p.logger.Debugw("found changes in apisix config",
+		zap.Any("added", added),
+		zap.Any("updated", updated),
+		zap.Any("deleted", deleted),
+	)
+	return util.ManifestEvents(added, deleted, updated)
+}
+
+// load decodes and merges all files, ids should be unique among files.
+func (p *apisixFileProvisioner) load() (*util.Manifest, error) {
+	files, err := watcher.ListFiles(p.files)
+	if err != nil {
+		return nil, err
+	}
+	var merged util.Manifest
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		m, ignored, err := decodeFile(file, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+		if len(ignored) > 0 {
+			p.logger.Warnw("ignore unsupported sections",
+				zap.String("filename", file),
+				zap.Strings("sections", ignored),
+			)
+		}
+		merged.Routes = append(merged.Routes, m.Routes...)
+		merged.Upstreams = append(merged.Upstreams, m.Upstreams...)
+		merged.SSLs = append(merged.SSLs, m.SSLs...)
+		merged.StreamRoutes = append(merged.StreamRoutes, m.StreamRoutes...)
+	}
+	if err := checkDuplicatedIds(&merged); err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
+
+func checkDuplicatedIds(m *util.Manifest) error {
+	ids := make(map[string]struct{})
+	check := func(kind, id string) error {
+		key := kind + "/" + id
+		if _, ok := ids[key]; ok {
+			return fmt.Errorf("duplicated %s id %s", kind, id)
+		}
+		ids[key] = struct{}{}
+		return nil
+	}
+	for _, r := range m.Routes {
+		if err := check("route", r.Id); err != nil {
+			return err
+		}
+	}
+	for _, u := range m.Upstreams {
+		if err := check("upstream", u.Id); err != nil {
+			return err
+		}
+	}
+	for _, ssl := range m.SSLs {
+		if err := check("ssl", ssl.Id); err != nil {
+			return err
+		}
+	}
+	for _, sr := range m.StreamRoutes {
+		if err := check("stream route", sr.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+const _testConfig = `
+routes:
+- id: 1
+  uri: /hello
+  upstream_id: 1
+upstreams:
+- id: 1
+  nodes:
+    "10.0.3.11:8000": 1
+`
+
+func receiveEvents(t *testing.T, ch <-chan []types.Event) []types.Event {
+	select {
+	case events := <-ch:
+		return events
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "no event arrived in time")
+	}
+	return nil
+}
+
+func assertNoEvents(t *testing.T, ch <-chan []types.Event) {
+	select {
+	case events := <-ch:
+		assert.FailNow(t, "unexpected events", events)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestNewAPISIXProvisioner(t *testing.T) {
+	p, err := NewAPISIXProvisioner(&config.Config{
+		LogLevel:  "info",
+		LogOutput: "stderr",
+	})
+	assert.Nil(t, p)
+	assert.Equal(t, err.Error(), "apisix-file provisioner: no watch files")
+}
+
+func TestAPISIXFileProvisionerRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apisix-file")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "apisix.yaml")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(_testConfig), 0644))
+
+	p, err := NewAPISIXProvisioner(&config.Config{
+		LogLevel:         "debug",
+		LogOutput:        "stderr",
+		APISIXWatchFiles: []string{dir},
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		assert.Nil(t, p.Run(stopCh))
+	}()
+
+	events := receiveEvents(t, p.Channel())
+	assert.Len(t, events, 2)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Equal(t, events[0].Object.(*apisix.Route).Uris, []string{"/hello"})
+	assert.Equal(t, events[1].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.11")
+
+	// Invalid config is rejected and the last state is kept.
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("routes:\n- id: 1\n"), 0644))
+	assertNoEvents(t, p.Channel())
+
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(`
+routes:
+- id: 1
+  uri: /hello
+  upstream_id: 1
+upstreams:
+- id: 1
+  nodes:
+    "10.0.3.12:8000": 1
+`), 0644))
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.12")
+
+	assert.Nil(t, os.Remove(filename))
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 2)
+	for _, ev := range events {
+		assert.Equal(t, ev.Type, types.EventDelete)
+	}
+}
+
+func TestAPISIXFileProvisionerLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apisix-file")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte(_testConfig), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"upstreams": [{"id": "2", "nodes": {"10.0.3.12": 1}}]}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# apisix"), 0644))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "..2021_09_01"), 0755))
+
+	p := &apisixFileProvisioner{
+		logger: log.DefaultLogger,
+		files:  []string{dir, filepath.Join(dir, "a.yaml")},
+	}
+	// The README.md and the atomic writer entries are skipped.
+	m, err := p.load()
+	assert.Nil(t, err)
+	assert.Len(t, m.Routes, 1)
+	assert.Len(t, m.Upstreams, 2)
+
+	// Ids are unique among files.
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"upstreams": [{"id": "1", "nodes": {"10.0.3.12": 1}}]}`), 0644))
+	_, err = p.load()
+	assert.Equal(t, err.Error(), "duplicated upstream id 1")
+}
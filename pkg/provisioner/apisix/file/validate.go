@@ -0,0 +1,88 @@
+package file
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+// The generated Validate() methods (by protoc-gen-validate v0.4.1) don't
+// support the ignore_empty rule, so the optional fields are rejected if
+// they're empty. They're filled with valid values on a copy before the
+// validation.
+
+func validateRoute(r *apisix.Route) error {
+	if len(r.Hosts) == 0 || len(r.RemoteAddrs) == 0 {
+		r = proto.Clone(r).(*apisix.Route)
+		if len(r.Hosts) == 0 {
+			r.Hosts = []string{"localhost"}
+		}
+		if len(r.RemoteAddrs) == 0 {
+			r.RemoteAddrs = []string{"127.0.0.1"}
+		}
+	}
+	return r.Validate()
+}
+
+func validateUpstream(ups *apisix.Upstream) error {
+	ups = proto.Clone(ups).(*apisix.Upstream)
+	if ups.UpstreamHost == "" {
+		// It's only used when pass_host is "rewrite".
+		ups.UpstreamHost = "localhost"
+	}
+	if active := ups.GetCheck().GetActive(); active != nil {
+		if len(active.ReqHeaders) == 0 {
+			active.ReqHeaders = []string{"User-Agent: apisix"}
+		}
+		if h := active.Healthy; h != nil {
+			fillInt32(&h.Interval)
+			fillInt32(&h.Successes)
+			fillStatuses(&h.HttpStatuses)
+		}
+		if u := active.Unhealthy; u != nil {
+			fillInt32(&u.Interval)
+			fillInt32(&u.HttpFailures)
+			fillInt32(&u.TcpFailures)
+			fillInt32(&u.Timeouts)
+			fillStatuses(&u.HttpStatuses)
+		}
+	}
+	if passive := ups.GetCheck().GetPassive(); passive != nil {
+		if h := passive.Healthy; h != nil {
+			fillInt32(&h.Successes)
+			fillStatuses(&h.HttpStatuses)
+		}
+		if u := passive.Unhealthy; u != nil {
+			fillInt32(&u.HttpFailures)
+			fillInt32(&u.TcpFailures)
+			fillInt32(&u.Timeouts)
+			fillStatuses(&u.HttpStatuses)
+		}
+	}
+	return ups.Validate()
+}
+
+func fillInt32(v *int32) {
+	if *v == 0 {
+		*v = 1
+	}
+}
+
+func fillStatuses(v *[]int32) {
+	if len(*v) == 0 {
+		*v = []int32{200}
+	}
+}
+
+func validateStreamRoute(sr *apisix.StreamRoute) error {
+	if sr.Upstream == nil {
+		return sr.Validate()
+	}
+	if err := validateUpstream(sr.Upstream); err != nil {
+		return err
+	}
+	// The inline upstream is validated above.
+	sr = proto.Clone(sr).(*apisix.StreamRoute)
+	sr.Upstream = nil
+	return sr.Validate()
+}
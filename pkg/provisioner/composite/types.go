@@ -0,0 +1,279 @@
+package composite
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+var (
+	_errNoSources = errors.New("composite provisioner: no sources")
+)
+
+// Source is a provisioner which is merged by the composite provisioner.
+type Source struct {
+	// Name is used to track where the objects come from.
+	Name        string
+	Provisioner provisioner.Provisioner
+}
+
+// sourceEvents are events from the source of the index.
+type sourceEvents struct {
+	index  int
+	events []types.Event
+}
+
+// sourceExit is the result of the source of the index.
+type sourceExit struct {
+	index int
+	err   error
+}
+
+type compositeProvisioner struct {
+	logger  *log.Logger
+	evChan  chan []types.Event
+	sources []Source
+	// objects are the objects of each source, the key is the kind and id
+	// of the object.
+	objects []map[string]interface{}
+	// owners maps the object key to the index of the source which the
+	// merged object comes from.
+	owners map[string]int
+}
+
+// NewCompositeProvisioner creates a provisioner which runs the sources
+// together and merges their objects. Sources are in the order of precedence,
+// if objects from different sources have the same id, the one from the
+// former source is used, and the shadowed one will be restored once the
+// former is deleted.
+func NewCompositeProvisioner(cfg *config.Config, sources []Source) (provisioner.Provisioner, error) {
+	if len(sources) == 0 {
+		return nil, _errNoSources
+	}
+	logger, err := log.NewLogger(
+		log.WithContext("composite-provisioner"),
+		log.WithLogLevel(cfg.LogLevel),
+		log.WithOutputFile(cfg.LogOutput),
+	)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]map[string]interface{}, len(sources))
+	for i := range objects {
+		objects[i] = make(map[string]interface{})
+	}
+	return &compositeProvisioner{
+		logger:  logger,
+		evChan:  make(chan []types.Event),
+		sources: sources,
+		objects: objects,
+		owners:  make(map[string]int),
+	}, nil
+}
+
+func (p *compositeProvisioner) Channel() <-chan []types.Event {
+	return p.evChan
+}
+
+// Run runs all sources and merges their events, it exits once any source
+// failed or exited, after all sources exited.
+func (p *compositeProvisioner) Run(stop chan struct{}) error {
+	p.logger.Infow("composite provisioner started")
+	defer p.logger.Infow("composite provisioner exited")
+	defer close(p.evChan)
+
+	// Sources are stopped once the composite provisioner exited, and
+	// they're waited so that nothing runs after Run returned.
+	var wg sync.WaitGroup
+	sourceStop := make(chan struct{})
+	defer func() {
+		close(sourceStop)
+		wg.Wait()
+	}()
+
+	inCh := make(chan sourceEvents)
+	// exitCh receives the results of sources once they exited.
+	exitCh := make(chan sourceExit, len(p.sources))
+	for i, src := range p.sources {
+		wg.Add(2)
+		go func(index int, src Source) {
+			defer wg.Done()
+			exitCh <- sourceExit{index: index, err: src.Provisioner.Run(sourceStop)}
+		}(i, src)
+		go func(index int, ch <-chan []types.Event) {
+			defer wg.Done()
+			for {
+				select {
+				case <-sourceStop:
+					return
+				case events, ok := <-ch:
+					if !ok {
+						return
+					}
+					select {
+					case <-sourceStop:
+						return
+					case inCh <- sourceEvents{index: index, events: events}:
+					}
+				}
+			}
+		}(i, src.Provisioner.Channel())
+	}
+
+	var pending []types.Event
+	for {
+		var sendCh chan<- []types.Event
+		if len(pending) > 0 {
+			sendCh = p.evChan
+		}
+		select {
+		case <-stop:
+			return nil
+		case exit := <-exitCh:
+			name := p.sources[exit.index].Name
+			if exit.err != nil {
+				p.logger.Errorw("source provisioner run failed",
+					zap.String("source", name),
+					zap.Error(exit.err),
+				)
+				return fmt.Errorf("%s: %s", name, exit.err)
+			}
+			p.logger.Warnw("source provisioner exited",
+				zap.String("source", name),
+			)
+			return nil
+		case in := <-inCh:
+			// Events are coalesced if the last batch was not received.
+			pending = append(pending, p.merge(in.index, in.events)...)
+		case sendCh <- pending:
+			pending = nil
+		}
+	}
+}
+
+// merge applies the events of the source to its objects, and generates
+// events of the merged view.
+func (p *compositeProvisioner) merge(index int, events []types.Event) []types.Event {
+	var merged []types.Event
+	for _, ev := range events {
+		obj := ev.Object
+		if ev.Type == types.EventDelete {
+			obj = ev.Tombstone
+		}
+		key, ok := objectKey(obj)
+		if !ok {
+			p.logger.Warnw("ignore event with unknown object",
+				zap.String("source", p.sources[index].Name),
+				zap.Any("event", ev),
+			)
+			continue
+		}
+		owner, owned := p.owners[key]
+
+		if ev.Type == types.EventDelete {
+			delete(p.objects[index], key)
+			if !owned || owner != index {
+				// Shadowed by the former source or not existing.
+				continue
+			}
+			// Restore the object from the next source which has it.
+			if next, restored := p.lookup(key); restored {
+				p.owners[key] = next
+				p.logger.Debugw("object restored from shadowed source",
+					zap.String("object", key),
+					zap.String("source", p.sources[next].Name),
+					zap.String("deleted_from", p.sources[index].Name),
+				)
+				merged = p.emit(merged, key, next, types.Event{
+					Type:   types.EventUpdate,
+					Object: p.objects[next][key],
+				})
+				continue
+			}
+			delete(p.owners, key)
+			merged = p.emit(merged, key, index, types.Event{
+				Type:      types.EventDelete,
+				Tombstone: ev.Tombstone,
+			})
+			continue
+		}
+
+		p.objects[index][key] = ev.Object
+		switch {
+		case !owned:
+			p.owners[key] = index
+			merged = p.emit(merged, key, index, types.Event{
+				Type:   types.EventAdd,
+				Object: ev.Object,
+			})
+		case owner < index:
+			p.logger.Debugw("object is shadowed by the former source",
+				zap.String("object", key),
+				zap.String("source", p.sources[index].Name),
+				zap.String("shadowed_by", p.sources[owner].Name),
+			)
+		default:
+			if owner != index {
+				p.logger.Debugw("object overrides the latter source",
+					zap.String("object", key),
+					zap.String("source", p.sources[index].Name),
+					zap.String("overridden", p.sources[owner].Name),
+				)
+			}
+			p.owners[key] = index
+			merged = p.emit(merged, key, index, types.Event{
+				Type:   types.EventUpdate,
+				Object: ev.Object,
+			})
+		}
+	}
+	return merged
+}
+
+// emit appends the event of the merged view, the source which the object
+// comes from (or is deleted by) is set to the event, so that every merged
+// object can be traced back (e.g. in the recording file).
+func (p *compositeProvisioner) emit(merged []types.Event, key string, index int, ev types.Event) []types.Event {
+	ev.Source = p.sources[index].Name
+	p.logger.Debugw("emit merged event",
+		zap.String("type", string(ev.Type)),
+		zap.String("object", key),
+		zap.String("source", p.sources[index].Name),
+	)
+	return append(merged, ev)
+}
+
+// lookup finds the source with the highest precedence that has the object.
+func (p *compositeProvisioner) lookup(key string) (int, bool) {
+	for i, objects := range p.objects {
+		if _, ok := objects[key]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// objectKey returns the kind and id of the object, objects of different
+// kinds can have the same id.
+func objectKey(obj interface{}) (string, bool) {
+	switch o := obj.(type) {
+	case *apisix.Route:
+		return "route/" + o.Id, true
+	case *apisix.Upstream:
+		return "upstream/" + o.Id, true
+	case *apisix.StreamRoute:
+		return "stream_route/" + o.Id, true
+	case *apisix.SSL:
+		return "ssl/" + o.Id, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,199 @@
+package composite
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+type fakeProvisioner struct {
+	evChan chan []types.Event
+	err    error
+	exited chan struct{}
+}
+
+func newFakeProvisioner() *fakeProvisioner {
+	return &fakeProvisioner{
+		evChan: make(chan []types.Event),
+		exited: make(chan struct{}),
+	}
+}
+
+func (f *fakeProvisioner) Channel() <-chan []types.Event {
+	return f.evChan
+}
+
+func (f *fakeProvisioner) Run(stop chan struct{}) error {
+	defer close(f.exited)
+	defer close(f.evChan)
+	if f.err != nil {
+		return f.err
+	}
+	<-stop
+	// Exits slowly.
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
+
+// assertExited asserts that the provisioner has exited.
+func assertExited(t *testing.T, f *fakeProvisioner) {
+	select {
+	case <-f.exited:
+	default:
+		assert.FailNow(t, "source provisioner is still running")
+	}
+}
+
+func newTestProvisioner(names ...string) *compositeProvisioner {
+	p := &compositeProvisioner{
+		logger: log.DefaultLogger,
+		owners: make(map[string]int),
+	}
+	for _, name := range names {
+		p.sources = append(p.sources, Source{Name: name})
+		p.objects = append(p.objects, make(map[string]interface{}))
+	}
+	return p
+}
+
+// sourceOf returns the name of the source which the merged object comes
+// from.
+func sourceOf(p *compositeProvisioner, obj interface{}) string {
+	key, _ := objectKey(obj)
+	owner, ok := p.owners[key]
+	if !ok {
+		return ""
+	}
+	return p.sources[owner].Name
+}
+
+func TestMergePrecedence(t *testing.T) {
+	p := newTestProvisioner("override", "xds")
+	base := &apisix.Route{Id: "1", Name: "base"}
+	override := &apisix.Route{Id: "1", Name: "override"}
+	ups := &apisix.Upstream{Id: "1", Name: "ups"}
+
+	events := p.merge(1, []types.Event{
+		{Type: types.EventAdd, Object: base},
+		{Type: types.EventAdd, Object: ups},
+	})
+	assert.Len(t, events, 2)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Equal(t, events[1].Type, types.EventAdd)
+	assert.Equal(t, events[0].Source, "xds")
+	assert.Equal(t, sourceOf(p, base), "xds")
+	assert.Equal(t, sourceOf(p, ups), "xds")
+
+	// The former source overrides the object.
+	events = p.merge(0, []types.Event{{Type: types.EventAdd, Object: override}})
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object, override)
+	assert.Equal(t, events[0].Source, "override")
+	assert.Equal(t, sourceOf(p, base), "override")
+
+	// Changes of the shadowed object are not visible.
+	updated := &apisix.Route{Id: "1", Name: "base-v2"}
+	events = p.merge(1, []types.Event{{Type: types.EventUpdate, Object: updated}})
+	assert.Len(t, events, 0)
+
+	// The shadowed object is restored once the override is deleted.
+	events = p.merge(0, []types.Event{{Type: types.EventDelete, Tombstone: override}})
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object, updated)
+	assert.Equal(t, events[0].Source, "xds")
+	assert.Equal(t, sourceOf(p, base), "xds")
+
+	events = p.merge(1, []types.Event{{Type: types.EventDelete, Tombstone: updated}})
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventDelete)
+	assert.Equal(t, events[0].Tombstone, updated)
+	assert.Equal(t, sourceOf(p, base), "")
+
+	// Deleting the shadowed object doesn't affect the merged one.
+	p.merge(0, []types.Event{{Type: types.EventAdd, Object: override}})
+	p.merge(1, []types.Event{{Type: types.EventAdd, Object: base}})
+	events = p.merge(1, []types.Event{{Type: types.EventDelete, Tombstone: base}})
+	assert.Len(t, events, 0)
+	events = p.merge(0, []types.Event{{Type: types.EventDelete, Tombstone: override}})
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventDelete)
+
+	// Objects of different kinds are not conflicted.
+	assert.Equal(t, sourceOf(p, ups), "xds")
+	events = p.merge(0, []types.Event{{Type: types.EventAdd, Object: "unknown"}})
+	assert.Len(t, events, 0)
+}
+
+func TestCompositeProvisionerRun(t *testing.T) {
+	override := newFakeProvisioner()
+	xds := newFakeProvisioner()
+	p, err := NewCompositeProvisioner(&config.Config{
+		LogLevel:  "debug",
+		LogOutput: "stderr",
+	}, []Source{
+		{Name: "override", Provisioner: override},
+		{Name: "xds", Provisioner: xds},
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	errCh := make(chan error)
+	go func() {
+		errCh <- p.Run(stopCh)
+	}()
+
+	xds.evChan <- []types.Event{{Type: types.EventAdd, Object: &apisix.Route{Id: "1", Name: "base"}}}
+	events := <-p.Channel()
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+
+	override.evChan <- []types.Event{{Type: types.EventAdd, Object: &apisix.Route{Id: "1", Name: "override"}}}
+	events = <-p.Channel()
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Route).Name, "override")
+	assert.Equal(t, events[0].Source, "override")
+
+	close(stopCh)
+	select {
+	case err := <-errCh:
+		assert.Nil(t, err)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "composite provisioner didn't exit in time")
+	}
+	// Sources are waited.
+	assertExited(t, override)
+	assertExited(t, xds)
+	_, ok := <-p.Channel()
+	assert.False(t, ok)
+}
+
+func TestCompositeProvisionerSourceFailure(t *testing.T) {
+	failed := newFakeProvisioner()
+	failed.err = errors.New("bad config")
+	xds := newFakeProvisioner()
+	p, err := NewCompositeProvisioner(&config.Config{
+		LogLevel:  "debug",
+		LogOutput: "stderr",
+	}, []Source{
+		{Name: "xds", Provisioner: xds},
+		{Name: "override", Provisioner: failed},
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	assert.Equal(t, p.Run(stopCh).Error(), "override: bad config")
+	// Other sources are stopped and waited.
+	assertExited(t, xds)
+
+	_, err = NewCompositeProvisioner(&config.Config{}, nil)
+	assert.Equal(t, err, _errNoSources)
+}
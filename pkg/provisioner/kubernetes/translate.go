@@ -0,0 +1,524 @@
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+const (
+	// _clusterDomain is the DNS domain of the Kubernetes cluster.
+	_clusterDomain = "cluster.local"
+	// _defaultNodeWeight is the weight of each endpoint.
+	_defaultNodeWeight = 100
+	// _serviceRoutePriority is the priority of the routes generated from
+	// Services, they're the fallback of the HTTPRoutes.
+	_serviceRoutePriority = 0
+	// _httpRoutePriority is the priority of the routes generated from
+	// HTTPRoutes.
+	_httpRoutePriority = 999
+)
+
+var (
+	// _nonHTTPProtocols are the protocols (in the Istio port naming
+	// convention) which cannot be proxied as HTTP, so routes are not
+	// generated for these Service ports.
+	_nonHTTPProtocols = map[string]struct{}{
+		"tcp":   {},
+		"tls":   {},
+		"https": {},
+		"mongo": {},
+		"mysql": {},
+		"redis": {},
+	}
+)
+
+// serviceUpstreams maps the Service port number to the upstream.
+type serviceUpstreams map[int32]*apisix.Upstream
+
+// routeScope is the hosts and the original destination ports of the
+// backend Services, which HTTPRoutes without hostnames are scoped to.
+type routeScope struct {
+	hosts set.StringSet
+	ports map[int32]struct{}
+}
+
+// translate translates the Services, EndpointSlices and HTTPRoutes to
+// APISIX routes and upstreams.
+//
+// An upstream is generated for each TCP port of Services, nodes of it are
+// the ready endpoints. A route, which matches the Service host names and
+// the original destination port, is generated for each HTTP port, so that
+// the mesh traffic can be routed without any HTTPRoute. Rules in HTTPRoutes
+// are translated to routes with a higher priority, rules of HTTPRoutes
+// without hostnames only match the hosts and ports of their backends.
+func (p *kubernetesProvisioner) translate(services []*corev1.Service, slices []*discoveryv1beta1.EndpointSlice,
+	httpRoutes []*gatewayv1alpha1.HTTPRoute) *util.Manifest {
+	slicesByService := make(map[string][]*discoveryv1beta1.EndpointSlice)
+	for _, slice := range slices {
+		name := slice.Labels[discoveryv1beta1.LabelServiceName]
+		if name == "" {
+			continue
+		}
+		key := slice.Namespace + "/" + name
+		slicesByService[key] = append(slicesByService[key], slice)
+	}
+
+	m := &util.Manifest{}
+	upstreams := make(map[string]serviceUpstreams)
+	servicesByKey := make(map[string]*corev1.Service)
+	for _, svc := range services {
+		key := svc.Namespace + "/" + svc.Name
+		ups := p.translateService(svc, slicesByService[key])
+		upstreams[key] = ups
+		servicesByKey[key] = svc
+		for _, port := range svc.Spec.Ports {
+			u, ok := ups[port.Port]
+			if !ok {
+				continue
+			}
+			m.Upstreams = append(m.Upstreams, u)
+			if isHTTPPort(&port) {
+				m.Routes = append(m.Routes, translateServiceRoute(svc, &port, u))
+			}
+		}
+	}
+	for _, hr := range httpRoutes {
+		routes, ups := p.translateHTTPRoute(hr, servicesByKey, upstreams)
+		m.Routes = append(m.Routes, routes...)
+		m.Upstreams = append(m.Upstreams, ups...)
+	}
+
+	// avoid unstable array for diff
+	sort.Slice(m.Routes, func(i, j int) bool {
+		return m.Routes[i].Name < m.Routes[j].Name
+	})
+	sort.Slice(m.Upstreams, func(i, j int) bool {
+		return m.Upstreams[i].Name < m.Upstreams[j].Name
+	})
+	return m
+}
+
+// translateService translates the TCP ports of the Service to upstreams.
+func (p *kubernetesProvisioner) translateService(svc *corev1.Service, slices []*discoveryv1beta1.EndpointSlice) serviceUpstreams {
+	ups := make(serviceUpstreams)
+	for _, port := range svc.Spec.Ports {
+		if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+			p.logger.Debugw("ignore non-tcp service port",
+				zap.String("service", svc.Namespace+"/"+svc.Name),
+				zap.Int32("port", port.Port),
+				zap.String("protocol", string(port.Protocol)),
+			)
+			continue
+		}
+		name := upstreamName(svc, port.Port)
+		u := &apisix.Upstream{
+			Name:  name,
+			Id:    id.GenID(name),
+			Type:  "roundrobin",
+			Nodes: []*apisix.Node{},
+		}
+		if svc.Spec.Type == corev1.ServiceTypeExternalName {
+			u.Nodes = append(u.Nodes, &apisix.Node{
+				Host:   svc.Spec.ExternalName,
+				Port:   port.Port,
+				Weight: _defaultNodeWeight,
+			})
+		} else {
+			u.Nodes = translateEndpointSlices(slices, &port)
+		}
+		ups[port.Port] = u
+	}
+	return ups
+}
+
+// translateEndpointSlices returns the ready endpoints of the Service port.
+func translateEndpointSlices(slices []*discoveryv1beta1.EndpointSlice, svcPort *corev1.ServicePort) []*apisix.Node {
+	nodes := []*apisix.Node{}
+	seen := set.StringSet{}
+	for _, slice := range slices {
+		var targetPort int32
+		for _, port := range slice.Ports {
+			var (
+				name     string
+				protocol = corev1.ProtocolTCP
+			)
+			if port.Name != nil {
+				name = *port.Name
+			}
+			if port.Protocol != nil {
+				protocol = *port.Protocol
+			}
+			if name == svcPort.Name && protocol == corev1.ProtocolTCP && port.Port != nil {
+				targetPort = *port.Port
+				break
+			}
+		}
+		if targetPort == 0 {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			for _, addr := range ep.Addresses {
+				key := addr + ":" + strconv.Itoa(int(targetPort))
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen.Add(key)
+				nodes = append(nodes, &apisix.Node{
+					Host:   addr,
+					Port:   targetPort,
+					Weight: _defaultNodeWeight,
+				})
+			}
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Host != nodes[j].Host {
+			return nodes[i].Host < nodes[j].Host
+		}
+		return nodes[i].Port < nodes[j].Port
+	})
+	return nodes
+}
+
+// translateServiceRoute generates the route which matches all requests
+// to the Service port.
+func translateServiceRoute(svc *corev1.Service, port *corev1.ServicePort, ups *apisix.Upstream) *apisix.Route {
+	hostSet := set.StringSet{}
+	addServiceHosts(hostSet, svc)
+	name := fmt.Sprintf("%s.%s#%d", svc.Name, svc.Namespace, port.Port)
+	return &apisix.Route{
+		Name:     name,
+		Id:       id.GenID(name),
+		Priority: _serviceRoutePriority,
+		Status:   1,
+		Hosts:    hostSet.OrderedStrings(),
+		Uris:     []string{"/*"},
+		Vars: []*apisix.Var{
+			originalDstPortVar([]int32{port.Port}),
+		},
+		UpstreamId: ups.Id,
+	}
+}
+
+// originalDstPortVar returns the var which matches the original destination
+// ports, the connection_original_dst is in the format of <ip>:<port>.
+func originalDstPortVar(ports []int32) *apisix.Var {
+	strs := make([]string, 0, len(ports))
+	for _, port := range ports {
+		strs = append(strs, strconv.Itoa(int(port)))
+	}
+	value := ":" + strs[0] + "$"
+	if len(strs) > 1 {
+		value = ":(" + strings.Join(strs, "|") + ")$"
+	}
+	return &apisix.Var{
+		Vars: []string{"connection_original_dst", "~~", value},
+	}
+}
+
+// translateHTTPRoute translates each match of the HTTPRoute rules to a
+// route. Upstreams are generated for the rules which forward requests
+// to multiple Services, with the endpoints merged and weighted.
+func (p *kubernetesProvisioner) translateHTTPRoute(hr *gatewayv1alpha1.HTTPRoute, services map[string]*corev1.Service,
+	upstreams map[string]serviceUpstreams) ([]*apisix.Route, []*apisix.Upstream) {
+	hostSet := set.StringSet{}
+	for _, hostname := range hr.Spec.Hostnames {
+		if hostname == "*" {
+			hostSet = set.StringSet{}
+			break
+		}
+		hostSet.Add(string(hostname))
+	}
+	hosts := hostSet.OrderedStrings()
+
+	var (
+		routes    []*apisix.Route
+		extraUps  []*apisix.Upstream
+		routeName = hr.Namespace + "/" + hr.Name
+	)
+	for i, rule := range hr.Spec.Rules {
+		if len(rule.Filters) > 0 {
+			p.logger.Warnw("ignore unsupported filters in http route rule",
+				zap.String("http_route", routeName),
+				zap.Int("rule", i),
+			)
+		}
+		ups, scope := p.translateForwardTo(hr, i, rule.ForwardTo, services, upstreams)
+		if ups == nil {
+			p.logger.Warnw("ignore http route rule without available backends",
+				zap.String("http_route", routeName),
+				zap.Int("rule", i),
+			)
+			continue
+		}
+		if ups.Name == weightedUpstreamName(hr, i) {
+			extraUps = append(extraUps, ups)
+		}
+		ruleHosts := hosts
+		var scopeVars []*apisix.Var
+		if len(hosts) == 0 {
+			// Without hostnames, the rule would take over the traffic
+			// to all Services, so it's scoped to the backends.
+			ruleHosts = scope.hosts.OrderedStrings()
+			ports := make([]int32, 0, len(scope.ports))
+			for port := range scope.ports {
+				ports = append(ports, port)
+			}
+			sort.Slice(ports, func(i, j int) bool {
+				return ports[i] < ports[j]
+			})
+			scopeVars = append(scopeVars, originalDstPortVar(ports))
+		}
+
+		matches := rule.Matches
+		if len(matches) == 0 {
+			matches = []gatewayv1alpha1.HTTPRouteMatch{{}}
+		}
+		for j, match := range matches {
+			uri, ok := translatePathMatch(&match.Path)
+			if !ok {
+				p.logger.Warnw("ignore http route match with unsupported path match type",
+					zap.String("http_route", routeName),
+					zap.Int("rule", i),
+					zap.String("type", string(match.Path.Type)),
+				)
+				continue
+			}
+			vars, ok := translateHeaderMatch(match.Headers)
+			if !ok {
+				p.logger.Warnw("ignore http route match with unsupported header match type",
+					zap.String("http_route", routeName),
+					zap.Int("rule", i),
+					zap.String("type", string(match.Headers.Type)),
+				)
+				continue
+			}
+			name := fmt.Sprintf("%s#%d#%d", routeName, i, j)
+			routes = append(routes, &apisix.Route{
+				Name:       name,
+				Id:         id.GenID(name),
+				Priority:   _httpRoutePriority,
+				Status:     1,
+				Hosts:      ruleHosts,
+				Uris:       []string{uri},
+				Vars:       append(vars, scopeVars...),
+				UpstreamId: ups.Id,
+			})
+		}
+	}
+	return routes, extraUps
+}
+
+// translateForwardTo returns the upstream of the rule, the Service upstream
+// is used if there is only one backend, otherwise a weighted upstream is
+// generated. Only Services in the same namespace can be referred. The hosts
+// and ports of the backends are also returned.
+func (p *kubernetesProvisioner) translateForwardTo(hr *gatewayv1alpha1.HTTPRoute, rule int,
+	forwardTo []gatewayv1alpha1.HTTPRouteForwardTo, services map[string]*corev1.Service,
+	upstreams map[string]serviceUpstreams) (*apisix.Upstream, *routeScope) {
+	type backend struct {
+		ups    *apisix.Upstream
+		weight int32
+	}
+	var backends []backend
+	scope := &routeScope{
+		hosts: set.StringSet{},
+		ports: make(map[int32]struct{}),
+	}
+	for _, ft := range forwardTo {
+		if ft.ServiceName == nil {
+			p.logger.Warnw("ignore unsupported backend reference",
+				zap.String("http_route", hr.Namespace+"/"+hr.Name),
+				zap.Int("rule", rule),
+			)
+			continue
+		}
+		if len(ft.Filters) > 0 {
+			p.logger.Warnw("ignore unsupported filters in http route backend",
+				zap.String("http_route", hr.Namespace+"/"+hr.Name),
+				zap.Int("rule", rule),
+				zap.String("service", *ft.ServiceName),
+			)
+		}
+		svcUps, ok := upstreams[hr.Namespace+"/"+*ft.ServiceName]
+		if !ok {
+			p.logger.Warnw("http route backend service not found",
+				zap.String("http_route", hr.Namespace+"/"+hr.Name),
+				zap.Int("rule", rule),
+				zap.String("service", *ft.ServiceName),
+			)
+			continue
+		}
+		var (
+			ups  *apisix.Upstream
+			port int32
+		)
+		if ft.Port != nil {
+			port = int32(*ft.Port)
+			ups = svcUps[port]
+		} else if len(svcUps) == 1 {
+			// The port can be omitted if the Service has only one port.
+			for number, u := range svcUps {
+				port, ups = number, u
+			}
+		}
+		if ups == nil {
+			p.logger.Warnw("http route backend service port not found",
+				zap.String("http_route", hr.Namespace+"/"+hr.Name),
+				zap.Int("rule", rule),
+				zap.String("service", *ft.ServiceName),
+			)
+			continue
+		}
+		backends = append(backends, backend{ups: ups, weight: ft.Weight})
+		addServiceHosts(scope.hosts, services[hr.Namespace+"/"+*ft.ServiceName])
+		scope.ports[port] = struct{}{}
+	}
+
+	switch len(backends) {
+	case 0:
+		return nil, nil
+	case 1:
+		return backends[0].ups, scope
+	}
+	name := weightedUpstreamName(hr, rule)
+	merged := &apisix.Upstream{
+		Name:  name,
+		Id:    id.GenID(name),
+		Type:  "roundrobin",
+		Nodes: []*apisix.Node{},
+	}
+	for _, b := range backends {
+		if b.weight <= 0 || len(b.ups.Nodes) == 0 {
+			continue
+		}
+		// Spread the backend weight to its endpoints, so that the traffic
+		// is split by the backend weights regardless of the endpoint counts.
+		weight := b.weight * _defaultNodeWeight / int32(len(b.ups.Nodes))
+		if weight == 0 {
+			weight = 1
+		}
+		for _, node := range b.ups.Nodes {
+			merged.Nodes = append(merged.Nodes, &apisix.Node{
+				Host:   node.Host,
+				Port:   node.Port,
+				Weight: weight,
+			})
+		}
+	}
+	return merged, scope
+}
+
+// translatePathMatch translates the path match to the APISIX uri, regular
+// expressions are not supported by APISIX uri.
+func translatePathMatch(match *gatewayv1alpha1.HTTPPathMatch) (string, bool) {
+	value := match.Value
+	if value == "" {
+		value = "/"
+	}
+	switch match.Type {
+	case "", gatewayv1alpha1.PathMatchPrefix:
+		return value + "*", true
+	case gatewayv1alpha1.PathMatchExact:
+		return value, true
+	default:
+		return "", false
+	}
+}
+
+// translateHeaderMatch translates the header match to vars.
+// See https://github.com/api7/lua-resty-expr for the translation details.
+func translateHeaderMatch(match *gatewayv1alpha1.HTTPHeaderMatch) ([]*apisix.Var, bool) {
+	if match == nil {
+		return nil, true
+	}
+	exact := true
+	switch match.Type {
+	case "", gatewayv1alpha1.HeaderMatchExact:
+	case gatewayv1alpha1.HeaderMatchRegularExpression:
+		exact = false
+	default:
+		return nil, false
+	}
+	names := make([]string, 0, len(match.Values))
+	for name := range match.Values {
+		names = append(names, name)
+	}
+	// avoid unstable array for diff
+	sort.Strings(names)
+
+	var vars []*apisix.Var
+	for _, name := range names {
+		value := match.Values[name]
+		if exact {
+			value = "^" + regexp.QuoteMeta(value) + "$"
+		}
+		vars = append(vars, &apisix.Var{
+			Vars: []string{"http_" + strings.ReplaceAll(strings.ToLower(name), "-", "_"), "~~", value},
+		})
+	}
+	return vars, true
+}
+
+// isHTTPPort checks whether the Service port can be proxied as HTTP, the
+// protocol is decided by the app protocol or the port name prefix (e.g.
+// "http-web"), ports without protocol hints are treated as HTTP.
+func isHTTPPort(port *corev1.ServicePort) bool {
+	protocol := port.Name
+	if port.AppProtocol != nil && *port.AppProtocol != "" {
+		protocol = *port.AppProtocol
+	}
+	if pos := strings.Index(protocol, "-"); pos != -1 {
+		protocol = protocol[:pos]
+	}
+	_, ok := _nonHTTPProtocols[strings.ToLower(protocol)]
+	return !ok
+}
+
+// addServiceHosts adds the host names and the cluster IP of the Service.
+func addServiceHosts(hostSet set.StringSet, svc *corev1.Service) {
+	for _, host := range serviceHosts(svc) {
+		hostSet.Add(host)
+	}
+	if ip := svc.Spec.ClusterIP; ip != "" && ip != corev1.ClusterIPNone {
+		hostSet.Add(ip)
+	}
+}
+
+// serviceHosts returns the host names to access the Service.
+func serviceHosts(svc *corev1.Service) []string {
+	return []string{
+		svc.Name,
+		svc.Name + "." + svc.Namespace,
+		svc.Name + "." + svc.Namespace + ".svc",
+		svc.Name + "." + svc.Namespace + ".svc." + _clusterDomain,
+	}
+}
+
+// upstreamName returns the name of the Service port upstream, it's same
+// to the Istio outbound cluster name.
+func upstreamName(svc *corev1.Service, port int32) string {
+	return fmt.Sprintf("outbound|%d||%s.%s.svc.%s", port, svc.Name, svc.Namespace, _clusterDomain)
+}
+
+// weightedUpstreamName returns the name of the upstream which merges the
+// backends of the HTTPRoute rule.
+func weightedUpstreamName(hr *gatewayv1alpha1.HTTPRoute, rule int) string {
+	return fmt.Sprintf("%s/%s#%d", hr.Namespace, hr.Name, rule)
+}
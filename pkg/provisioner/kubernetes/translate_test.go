@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func newService(name string, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Ports:     ports,
+		},
+	}
+}
+
+func newEndpointSlice(service, portName string, port int32, addrs ...string) *discoveryv1beta1.EndpointSlice {
+	ready := true
+	slice := &discoveryv1beta1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      service + "-" + portName,
+			Namespace: "default",
+			Labels: map[string]string{
+				discoveryv1beta1.LabelServiceName: service,
+			},
+		},
+		AddressType: discoveryv1beta1.AddressTypeIPv4,
+		Ports: []discoveryv1beta1.EndpointPort{
+			{
+				Name: &portName,
+				Port: &port,
+			},
+		},
+	}
+	for _, addr := range addrs {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1beta1.Endpoint{
+			Addresses:  []string{addr},
+			Conditions: discoveryv1beta1.EndpointConditions{Ready: &ready},
+		})
+	}
+	return slice
+}
+
+func TestTranslateService(t *testing.T) {
+	p := &kubernetesProvisioner{logger: log.DefaultLogger}
+	svc := newService("httpbin",
+		corev1.ServicePort{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP},
+		corev1.ServicePort{Name: "tcp-echo", Port: 9000, Protocol: corev1.ProtocolTCP},
+		corev1.ServicePort{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+	)
+	unready := false
+	slice := newEndpointSlice("httpbin", "http", 8080, "10.0.3.12", "10.0.3.11")
+	slice.Endpoints = append(slice.Endpoints, discoveryv1beta1.Endpoint{
+		Addresses:  []string{"10.0.3.13"},
+		Conditions: discoveryv1beta1.EndpointConditions{Ready: &unready},
+	})
+
+	m := p.translate([]*corev1.Service{svc}, []*discoveryv1beta1.EndpointSlice{
+		slice,
+		newEndpointSlice("httpbin", "tcp-echo", 9000, "10.0.3.11"),
+		newEndpointSlice("other", "http", 8080, "10.0.3.20"),
+	}, nil)
+
+	assert.Len(t, m.Upstreams, 2)
+	assert.Equal(t, m.Upstreams[0].Name, "outbound|80||httpbin.default.svc.cluster.local")
+	assert.Equal(t, m.Upstreams[0].Id, id.GenID("outbound|80||httpbin.default.svc.cluster.local"))
+	assert.Equal(t, m.Upstreams[0].Nodes, []*apisix.Node{
+		{Host: "10.0.3.11", Port: 8080, Weight: 100},
+		{Host: "10.0.3.12", Port: 8080, Weight: 100},
+	})
+	assert.Equal(t, m.Upstreams[1].Name, "outbound|9000||httpbin.default.svc.cluster.local")
+	assert.Len(t, m.Upstreams[1].Nodes, 1)
+
+	// No route for the TCP port.
+	assert.Len(t, m.Routes, 1)
+	route := m.Routes[0]
+	assert.Equal(t, route.Name, "httpbin.default#80")
+	assert.Equal(t, route.Priority, int32(_serviceRoutePriority))
+	assert.Equal(t, route.Uris, []string{"/*"})
+	assert.Equal(t, route.Hosts, []string{
+		"10.96.0.10",
+		"httpbin",
+		"httpbin.default",
+		"httpbin.default.svc",
+		"httpbin.default.svc.cluster.local",
+	})
+	assert.Equal(t, route.Vars[0].Vars, []string{"connection_original_dst", "~~", ":80$"})
+	assert.Equal(t, route.UpstreamId, m.Upstreams[0].Id)
+}
+
+func TestTranslateHTTPRoute(t *testing.T) {
+	p := &kubernetesProvisioner{logger: log.DefaultLogger}
+	services := []*corev1.Service{
+		newService("reviews-v1", corev1.ServicePort{Name: "http", Port: 9080}),
+		newService("reviews-v2", corev1.ServicePort{Name: "http", Port: 9080}),
+	}
+	slices := []*discoveryv1beta1.EndpointSlice{
+		newEndpointSlice("reviews-v1", "http", 9080, "10.0.3.11"),
+		newEndpointSlice("reviews-v2", "http", 9080, "10.0.3.21", "10.0.3.22"),
+	}
+	v1 := "reviews-v1"
+	v2 := "reviews-v2"
+	missing := "missing"
+	port := gatewayv1alpha1.PortNumber(9080)
+	hr := &gatewayv1alpha1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "reviews",
+			Namespace: "default",
+		},
+		Spec: gatewayv1alpha1.HTTPRouteSpec{
+			Hostnames: []gatewayv1alpha1.Hostname{"reviews.example.com"},
+			Rules: []gatewayv1alpha1.HTTPRouteRule{
+				{
+					Matches: []gatewayv1alpha1.HTTPRouteMatch{
+						{
+							Path: gatewayv1alpha1.HTTPPathMatch{
+								Type:  gatewayv1alpha1.PathMatchExact,
+								Value: "/reviews",
+							},
+							Headers: &gatewayv1alpha1.HTTPHeaderMatch{
+								Values: map[string]string{"End-User": "jason.x"},
+							},
+						},
+						{
+							Path: gatewayv1alpha1.HTTPPathMatch{
+								Type:  gatewayv1alpha1.PathMatchRegularExpression,
+								Value: "/reviews/.*",
+							},
+						},
+					},
+					ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{
+						{ServiceName: &v2, Port: &port},
+					},
+				},
+				{
+					ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{
+						{ServiceName: &v1, Weight: 1},
+						{ServiceName: &v2, Port: &port, Weight: 3},
+						{ServiceName: &missing, Weight: 1},
+					},
+				},
+				{
+					ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{
+						{ServiceName: &missing},
+					},
+				},
+			},
+		},
+	}
+	m := p.translate(services, slices, []*gatewayv1alpha1.HTTPRoute{hr})
+
+	assert.Len(t, m.Upstreams, 3)
+	weighted := m.Upstreams[0]
+	assert.Equal(t, weighted.Name, "default/reviews#1")
+	assert.Equal(t, weighted.Nodes, []*apisix.Node{
+		{Host: "10.0.3.11", Port: 9080, Weight: 100},
+		{Host: "10.0.3.21", Port: 9080, Weight: 150},
+		{Host: "10.0.3.22", Port: 9080, Weight: 150},
+	})
+
+	assert.Len(t, m.Routes, 4)
+	exact := m.Routes[0]
+	assert.Equal(t, exact.Name, "default/reviews#0#0")
+	assert.Equal(t, exact.Priority, int32(_httpRoutePriority))
+	assert.Equal(t, exact.Hosts, []string{"reviews.example.com"})
+	assert.Equal(t, exact.Uris, []string{"/reviews"})
+	assert.Equal(t, exact.Vars[0].Vars, []string{"http_end_user", "~~", `^jason\.x$`})
+	assert.Equal(t, exact.UpstreamId, id.GenID("outbound|9080||reviews-v2.default.svc.cluster.local"))
+
+	prefix := m.Routes[1]
+	assert.Equal(t, prefix.Name, "default/reviews#1#0")
+	assert.Equal(t, prefix.Uris, []string{"/*"})
+	assert.Nil(t, prefix.Vars)
+	assert.Equal(t, prefix.UpstreamId, weighted.Id)
+
+	assert.Equal(t, m.Routes[2].Name, "reviews-v1.default#9080")
+	assert.Equal(t, m.Routes[3].Name, "reviews-v2.default#9080")
+
+	// Without hostnames, rules are scoped to the backend Services.
+	hr.Spec.Hostnames = nil
+	m = p.translate(services, slices, []*gatewayv1alpha1.HTTPRoute{hr})
+	assert.Len(t, m.Routes, 4)
+	exact = m.Routes[0]
+	assert.Equal(t, exact.Hosts, []string{
+		"10.96.0.10",
+		"reviews-v2",
+		"reviews-v2.default",
+		"reviews-v2.default.svc",
+		"reviews-v2.default.svc.cluster.local",
+	})
+	assert.Len(t, exact.Vars, 2)
+	assert.Equal(t, exact.Vars[1].Vars, []string{"connection_original_dst", "~~", ":9080$"})
+	prefix = m.Routes[1]
+	assert.Len(t, prefix.Hosts, 9)
+	assert.Equal(t, prefix.Vars[0].Vars, []string{"connection_original_dst", "~~", ":9080$"})
+}
+
+func TestIsHTTPPort(t *testing.T) {
+	grpc := "grpc"
+	mysql := "mysql"
+	assert.True(t, isHTTPPort(&corev1.ServicePort{}))
+	assert.True(t, isHTTPPort(&corev1.ServicePort{Name: "http-web"}))
+	assert.True(t, isHTTPPort(&corev1.ServicePort{Name: "tcp", AppProtocol: &grpc}))
+	assert.False(t, isHTTPPort(&corev1.ServicePort{Name: "TCP-echo"}))
+	assert.False(t, isHTTPPort(&corev1.ServicePort{Name: "db", AppProtocol: &mysql}))
+}
+
+func TestOriginalDstPortVar(t *testing.T) {
+	assert.Equal(t, originalDstPortVar([]int32{80}).Vars, []string{"connection_original_dst", "~~", ":80$"})
+	assert.Equal(t, originalDstPortVar([]int32{80, 8080}).Vars, []string{"connection_original_dst", "~~", ":(80|8080)$"})
+}
@@ -0,0 +1,196 @@
+package kubernetes
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1beta1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	gatewayversioned "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+	gatewayinformers "sigs.k8s.io/gateway-api/pkg/client/informers/externalversions"
+	gatewaylisters "sigs.k8s.io/gateway-api/pkg/client/listers/apis/v1alpha1"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+)
+
+var (
+	_errCacheSyncFailed = errors.New("failed to sync the informer caches")
+)
+
+type kubernetesProvisioner struct {
+	logger         *log.Logger
+	evChan         chan []types.Event
+	kubeFactory    informers.SharedInformerFactory
+	gatewayFactory gatewayinformers.SharedInformerFactory
+
+	serviceLister       corelisters.ServiceLister
+	endpointSliceLister discoverylisters.EndpointSliceLister
+	httpRouteLister     gatewaylisters.HTTPRouteLister
+	// resyncCh is signalled once any watched object changed, all objects
+	// will be translated again.
+	resyncCh chan struct{}
+	// manifest is the last translated state.
+	manifest *util.Manifest
+}
+
+// NewKubernetesProvisioner creates a provisioner which watches Services,
+// EndpointSlices and Gateway API HTTPRoutes from the Kubernetes API server,
+// and translates them to APISIX routes and upstreams directly, so that
+// istiod is not required.
+func NewKubernetesProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	gatewayClient, err := gatewayversioned.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newKubernetesProvisioner(cfg, kubeClient, gatewayClient)
+}
+
+func newKubernetesProvisioner(cfg *config.Config, kubeClient kubernetes.Interface,
+	gatewayClient gatewayversioned.Interface) (*kubernetesProvisioner, error) {
+	logger, err := log.NewLogger(
+		log.WithContext("kubernetes-provisioner"),
+		log.WithLogLevel(cfg.LogLevel),
+		log.WithOutputFile(cfg.LogOutput),
+	)
+	if err != nil {
+		return nil, err
+	}
+	kubeFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0,
+		informers.WithNamespace(cfg.KubernetesNamespace),
+	)
+	gatewayFactory := gatewayinformers.NewSharedInformerFactoryWithOptions(gatewayClient, 0,
+		gatewayinformers.WithNamespace(cfg.KubernetesNamespace),
+	)
+	p := &kubernetesProvisioner{
+		logger:         logger,
+		evChan:         make(chan []types.Event),
+		kubeFactory:    kubeFactory,
+		gatewayFactory: gatewayFactory,
+		resyncCh:       make(chan struct{}, 1),
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { p.notify() },
+		UpdateFunc: func(interface{}, interface{}) { p.notify() },
+		DeleteFunc: func(interface{}) { p.notify() },
+	}
+	services := kubeFactory.Core().V1().Services()
+	services.Informer().AddEventHandler(handler)
+	endpointSlices := kubeFactory.Discovery().V1beta1().EndpointSlices()
+	endpointSlices.Informer().AddEventHandler(handler)
+	httpRoutes := gatewayFactory.Networking().V1alpha1().HTTPRoutes()
+	httpRoutes.Informer().AddEventHandler(handler)
+
+	p.serviceLister = services.Lister()
+	p.endpointSliceLister = endpointSlices.Lister()
+	p.httpRouteLister = httpRoutes.Lister()
+	return p, nil
+}
+
+func (p *kubernetesProvisioner) Channel() <-chan []types.Event {
+	return p.evChan
+}
+
+// notify signals the run loop to translate objects again, signals are
+// merged if the last one is not handled yet.
+func (p *kubernetesProvisioner) notify() {
+	select {
+	case p.resyncCh <- struct{}{}:
+	default:
+	}
+}
+
+func (p *kubernetesProvisioner) Run(stop chan struct{}) error {
+	p.logger.Infow("kubernetes provisioner started")
+	defer p.logger.Infow("kubernetes provisioner exited")
+	defer close(p.evChan)
+
+	p.kubeFactory.Start(stop)
+	p.gatewayFactory.Start(stop)
+	for typ, ok := range p.kubeFactory.WaitForCacheSync(stop) {
+		if !ok {
+			p.logger.Errorw("failed to sync informer cache",
+				zap.String("type", typ.String()),
+			)
+			return _errCacheSyncFailed
+		}
+	}
+	for typ, ok := range p.gatewayFactory.WaitForCacheSync(stop) {
+		if !ok {
+			p.logger.Errorw("failed to sync informer cache",
+				zap.String("type", typ.String()),
+			)
+			return _errCacheSyncFailed
+		}
+	}
+
+	var pending []types.Event
+	for {
+		var sendCh chan<- []types.Event
+		if len(pending) > 0 {
+			sendCh = p.evChan
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-p.resyncCh:
+			// Events are coalesced if the last batch was not received.
+			pending = append(pending, p.resync()...)
+		case sendCh <- pending:
+			pending = nil
+		}
+	}
+}
+
+// resync translates all objects in the caches and generates events by
+// comparing with the last state.
+func (p *kubernetesProvisioner) resync() []types.Event {
+	services, err := p.serviceLister.List(labels.Everything())
+	if err != nil {
+		p.logger.Errorw("failed to list services",
+			zap.Error(err),
+		)
+		return nil
+	}
+	slices, err := p.endpointSliceLister.List(labels.Everything())
+	if err != nil {
+		p.logger.Errorw("failed to list endpoint slices",
+			zap.Error(err),
+		)
+		return nil
+	}
+	httpRoutes, err := p.httpRouteLister.List(labels.Everything())
+	if err != nil {
+		p.logger.Errorw("failed to list http routes",
+			zap.Error(err),
+		)
+		return nil
+	}
+	m := p.translate(services, slices, httpRoutes)
+
+	added, deleted, updated := util.DiffManifests(p.manifest, m)
+	p.manifest = m
+	p.logger.Debugw("found changes in kubernetes objects",
+		zap.Any("added", added),
+		zap.Any("updated", updated),
+		zap.Any("deleted", deleted),
+	)
+	return util.ManifestEvents(added, deleted, updated)
+}
@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+	gatewayfake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func receiveEvents(t *testing.T, ch <-chan []types.Event) []types.Event {
+	select {
+	case events := <-ch:
+		return events
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "no event arrived in time")
+	}
+	return nil
+}
+
+func TestKubernetesProvisionerRun(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset(
+		newService("httpbin", corev1.ServicePort{Name: "http", Port: 80}),
+		newEndpointSlice("httpbin", "http", 8080, "10.0.3.11"),
+	)
+	gatewayClient := gatewayfake.NewSimpleClientset()
+
+	p, err := newKubernetesProvisioner(&config.Config{
+		LogLevel:  "debug",
+		LogOutput: "stderr",
+	}, kubeClient, gatewayClient)
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		assert.Nil(t, p.Run(stopCh))
+	}()
+
+	events := receiveEvents(t, p.Channel())
+	assert.Len(t, events, 2)
+	for _, ev := range events {
+		assert.Equal(t, ev.Type, types.EventAdd)
+		switch obj := ev.Object.(type) {
+		case *apisix.Route:
+			assert.Equal(t, obj.Name, "httpbin.default#80")
+		case *apisix.Upstream:
+			assert.Equal(t, obj.Nodes[0].Host, "10.0.3.11")
+		}
+	}
+
+	ctx := context.Background()
+	_, err = kubeClient.DiscoveryV1beta1().EndpointSlices("default").Update(ctx,
+		newEndpointSlice("httpbin", "http", 8080, "10.0.3.12"), metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.12")
+
+	svcName := "httpbin"
+	_, err = gatewayClient.NetworkingV1alpha1().HTTPRoutes("default").Create(ctx, &gatewayv1alpha1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "httpbin",
+			Namespace: "default",
+		},
+		Spec: gatewayv1alpha1.HTTPRouteSpec{
+			Rules: []gatewayv1alpha1.HTTPRouteRule{
+				{
+					Matches: []gatewayv1alpha1.HTTPRouteMatch{
+						{
+							Path: gatewayv1alpha1.HTTPPathMatch{
+								Type:  gatewayv1alpha1.PathMatchPrefix,
+								Value: "/status",
+							},
+						},
+					},
+					ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{
+						{ServiceName: &svcName},
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	route := events[0].Object.(*apisix.Route)
+	assert.Equal(t, route.Name, "default/httpbin#0#0")
+	assert.Equal(t, route.Uris, []string{"/status*"})
+
+	assert.Nil(t, kubeClient.CoreV1().Services("default").Delete(ctx, "httpbin", metav1.DeleteOptions{}))
+	events = receiveEvents(t, p.Channel())
+	// The HTTPRoute refers to the deleted Service, so it's also deleted.
+	assert.Len(t, events, 3)
+	for _, ev := range events {
+		assert.Equal(t, ev.Type, types.EventDelete)
+	}
+}
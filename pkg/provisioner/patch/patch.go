@@ -0,0 +1,221 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+const (
+	_kindRoute    = "route"
+	_kindUpstream = "upstream"
+)
+
+var (
+	_errBadKind        = errors.New("bad kind, it should be \"route\" or \"upstream\"")
+	_errBadSelector    = errors.New("one of id and name should be specified")
+	_errBadPatch       = errors.New("one of merge_patch and json_patch should be specified")
+	_errBadMergePatch  = errors.New("merge_patch should be an object")
+	_errIdChanged      = errors.New("id cannot be patched")
+	_errNotAnObject    = errors.New("document is not an object")
+	_errUnknownPatched = errors.New("unknown object to patch")
+)
+
+// patchFile is the layout of the patch file, for instance:
+//
+//	patches:
+//	- kind: route
+//	  name: "*#reviews.default:9080#*"
+//	  merge_patch:
+//	    plugins:
+//	      fault-injection:
+//	        abort: { http_status: 503, body: "unavailable" }
+//	- kind: upstream
+//	  id: "7ab6c3f2"
+//	  json_patch:
+//	  - { op: add, path: /timeout, value: { connect: 1, send: 5, read: 5 } }
+type patchFile struct {
+	Patches []*patchSpec `json:"patches"`
+}
+
+type patchSpec struct {
+	// Kind is the kind of objects to patch, "route" or "upstream".
+	Kind string `json:"kind"`
+	// Id selects the object by id.
+	Id string `json:"id"`
+	// Name selects objects by the name glob (in the path.Match syntax).
+	Name string `json:"name"`
+	// MergePatch is the JSON merge patch (RFC 7396).
+	MergePatch json.RawMessage `json:"merge_patch"`
+	// JSONPatch is the JSON patch (RFC 6902).
+	JSONPatch json.RawMessage `json:"json_patch"`
+}
+
+// patch is the decoded patch spec.
+type patch struct {
+	// source is the position where the patch is defined, for logging.
+	source     string
+	kind       string
+	id         string
+	name       string
+	mergePatch []byte
+	jsonPatch  jsonpatch.Patch
+}
+
+// decodeFile decodes the patch file, it can be in the YAML or JSON format.
+func decodeFile(filename string, data []byte) ([]*patch, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		// Empty file.
+		return nil, nil
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, _errNotAnObject
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var pf patchFile
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&pf); err != nil {
+		return nil, err
+	}
+
+	var patches []*patch
+	for i, spec := range pf.Patches {
+		pt, err := newPatch(spec)
+		if err != nil {
+			return nil, fmt.Errorf("patch #%d: %s", i, err)
+		}
+		pt.source = fmt.Sprintf("%s#%d", filename, i)
+		patches = append(patches, pt)
+	}
+	return patches, nil
+}
+
+func newPatch(spec *patchSpec) (*patch, error) {
+	if spec.Kind != _kindRoute && spec.Kind != _kindUpstream {
+		return nil, _errBadKind
+	}
+	if (spec.Id == "") == (spec.Name == "") {
+		return nil, _errBadSelector
+	}
+	if spec.Name != "" {
+		if _, err := path.Match(spec.Name, ""); err != nil {
+			return nil, fmt.Errorf("bad name glob: %s", err)
+		}
+	}
+	hasMerge := len(spec.MergePatch) > 0 && string(spec.MergePatch) != "null"
+	hasJSON := len(spec.JSONPatch) > 0 && string(spec.JSONPatch) != "null"
+	if hasMerge == hasJSON {
+		return nil, _errBadPatch
+	}
+	pt := &patch{
+		kind: spec.Kind,
+		id:   spec.Id,
+		name: spec.Name,
+	}
+	if hasMerge {
+		if !bytes.HasPrefix(bytes.TrimSpace(spec.MergePatch), []byte("{")) {
+			return nil, _errBadMergePatch
+		}
+		pt.mergePatch = spec.MergePatch
+		return pt, nil
+	}
+	jp, err := jsonpatch.DecodePatch(spec.JSONPatch)
+	if err != nil {
+		return nil, fmt.Errorf("bad json_patch: %s", err)
+	}
+	pt.jsonPatch = jp
+	return pt, nil
+}
+
+// matches checks whether the object should be patched.
+func (pt *patch) matches(kind, id, name string) bool {
+	if pt.kind != kind {
+		return false
+	}
+	if pt.id != "" {
+		return pt.id == id
+	}
+	ok, _ := path.Match(pt.name, name)
+	return ok
+}
+
+func (pt *patch) apply(doc []byte) ([]byte, error) {
+	if pt.mergePatch != nil {
+		return jsonpatch.MergePatch(doc, pt.mergePatch)
+	}
+	return pt.jsonPatch.Apply(doc)
+}
+
+// describe returns the kind, id and name of the object, ok is false if
+// the object cannot be patched.
+func describe(obj interface{}) (kind, id, name string, ok bool) {
+	switch o := obj.(type) {
+	case *apisix.Route:
+		return _kindRoute, o.Id, o.Name, true
+	case *apisix.Upstream:
+		return _kindUpstream, o.Id, o.Name, true
+	default:
+		return "", "", "", false
+	}
+}
+
+// applyPatches applies the matched patches in order to the object, the
+// object is encoded in the same JSON format that Apache APISIX uses. The
+// object itself is returned if no patch matches, otherwise a patched copy
+// is returned, with the sources of the applied patches.
+func applyPatches(patches []*patch, obj proto.Message) (proto.Message, []string, error) {
+	kind, id, name, ok := describe(obj)
+	if !ok {
+		return nil, nil, _errUnknownPatched
+	}
+	var matched []*patch
+	for _, pt := range patches {
+		if pt.matches(kind, id, name) {
+			matched = append(matched, pt)
+		}
+	}
+	if len(matched) == 0 {
+		return obj, nil, nil
+	}
+
+	doc, err := json.Marshal(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	var applied []string
+	for _, pt := range matched {
+		doc, err = pt.apply(doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", pt.source, err)
+		}
+		applied = append(applied, pt.source)
+	}
+
+	patched := obj.ProtoReflect().New().Interface()
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	// Typos in patches shouldn't be dropped silently.
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(patched); err != nil {
+		return nil, nil, err
+	}
+	if _, newId, _, _ := describe(patched); newId != id {
+		return nil, nil, _errIdChanged
+	}
+	return patched, applied, nil
+}
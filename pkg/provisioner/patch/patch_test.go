@@ -0,0 +1,166 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func TestDecodeFile(t *testing.T) {
+	data := `
+patches:
+- kind: route
+  name: "*#reviews*"
+  merge_patch:
+    status: 0
+- kind: upstream
+  id: "1"
+  json_patch:
+  - { op: add, path: /timeout, value: { connect: 1, send: 5, read: 5 } }
+`
+	patches, err := decodeFile("patch.yaml", []byte(data))
+	assert.Nil(t, err)
+	assert.Len(t, patches, 2)
+	assert.Equal(t, patches[0].source, "patch.yaml#0")
+	assert.True(t, patches[0].matches("route", "2", "<anon>#reviews.default:9080#9080"))
+	assert.False(t, patches[0].matches("upstream", "2", "<anon>#reviews.default:9080#9080"))
+	assert.False(t, patches[0].matches("route", "2", "<anon>#ratings.default:9080#9080"))
+	assert.True(t, patches[1].matches("upstream", "1", "reviews"))
+	assert.False(t, patches[1].matches("upstream", "2", "reviews"))
+
+	patches, err = decodeFile("patch.json", []byte(`{"patches": [{"kind": "route", "id": "1", "merge_patch": {"priority": 1}}]}`))
+	assert.Nil(t, err)
+	assert.Len(t, patches, 1)
+
+	patches, err = decodeFile("patch.yaml", nil)
+	assert.Nil(t, err)
+	assert.Nil(t, patches)
+
+	cases := []struct {
+		data string
+		err  string
+	}{
+		{"- a", _errNotAnObject.Error()},
+		{"patchs: []", `json: unknown field "patchs"`},
+		{"patches: [{kind: ssl, id: '1', merge_patch: {}}]", "patch #0: " + _errBadKind.Error()},
+		{"patches: [{kind: route, id: '1', name: a, merge_patch: {}}]", "patch #0: " + _errBadSelector.Error()},
+		{"patches: [{kind: route, name: '[', merge_patch: {}}]", "patch #0: bad name glob: syntax error in pattern"},
+		{"patches: [{kind: route, id: '1'}]", "patch #0: " + _errBadPatch.Error()},
+		{"patches: [{kind: route, id: '1', merge_patch: [1]}]", "patch #0: " + _errBadMergePatch.Error()},
+		{"patches: [{kind: route, id: '1', json_patch: {}}]", "patch #0: bad json_patch: json: cannot unmarshal object into Go value of type jsonpatch.Patch"},
+	}
+	for _, c := range cases {
+		_, err := decodeFile("patch.yaml", []byte(c.data))
+		assert.NotNil(t, err, c.data)
+		if err != nil {
+			assert.Equal(t, err.Error(), c.err, c.data)
+		}
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	patches, err := decodeFile("patch.yaml", []byte(`
+patches:
+- kind: route
+  id: "1"
+  merge_patch:
+    status: 0
+    plugins:
+      fault-injection:
+        abort: { http_status: 503, body: unavailable }
+- kind: route
+  name: "reviews*"
+  json_patch:
+  - { op: add, path: /vars/-, value: [http_x_canary, "~~", "^true$"] }
+- kind: upstream
+  name: "*"
+  merge_patch:
+    timeout: { connect: 1, send: 5, read: 5 }
+    retries: 3
+`))
+	assert.Nil(t, err)
+
+	route := &apisix.Route{
+		Id:         "1",
+		Name:       "reviews",
+		Status:     apisix.Route_Enable,
+		Uris:       []string{"/*"},
+		UpstreamId: "2",
+		Vars: []*apisix.Var{
+			{Vars: []string{"http_end_user", "~~", "^jason$"}},
+		},
+	}
+	out, applied, err := applyPatches(patches, route)
+	assert.Nil(t, err)
+	assert.Equal(t, applied, []string{"patch.yaml#0", "patch.yaml#1"})
+	patched := out.(*apisix.Route)
+	assert.Equal(t, patched.Status, apisix.Route_Disable)
+	assert.Equal(t, patched.Plugins[apisix.FaultInjectionPlugin].AsMap(), map[string]interface{}{
+		"abort": map[string]interface{}{"http_status": float64(503), "body": "unavailable"},
+	})
+	assert.Len(t, patched.Vars, 2)
+	assert.Equal(t, patched.Vars[1].Vars, []string{"http_x_canary", "~~", "^true$"})
+	assert.Equal(t, patched.UpstreamId, "2")
+	// The original object is not changed.
+	assert.Equal(t, route.Status, apisix.Route_Enable)
+	assert.Nil(t, route.Plugins)
+
+	// The disabled status should be kept in JSON.
+	data, err := json.Marshal(patched)
+	assert.Nil(t, err)
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &m))
+	assert.Equal(t, m["status"], float64(0))
+
+	ups := &apisix.Upstream{
+		Id:    "2",
+		Name:  "reviews",
+		Nodes: []*apisix.Node{{Host: "10.0.3.11", Port: 9080, Weight: 100}},
+	}
+	out, applied, err = applyPatches(patches, ups)
+	assert.Nil(t, err)
+	assert.Equal(t, applied, []string{"patch.yaml#2"})
+	assert.Equal(t, out.(*apisix.Upstream).Timeout.Connect, float64(1))
+	assert.Equal(t, out.(*apisix.Upstream).Retries, int32(3))
+	assert.Equal(t, out.(*apisix.Upstream).Nodes, ups.Nodes)
+
+	// Plugins which are not modeled are patched as is.
+	patches, err = decodeFile("patch.yaml", []byte(`
+patches:
+- kind: route
+  id: "1"
+  merge_patch:
+    plugins:
+      limit-count: { count: 100, time_window: 60, key: remote_addr, rejected_code: 429 }
+`))
+	assert.Nil(t, err)
+	out, _, err = applyPatches(patches, route)
+	assert.Nil(t, err)
+	assert.Equal(t, out.(*apisix.Route).Plugins["limit-count"].AsMap(), map[string]interface{}{
+		"count":         float64(100),
+		"time_window":   float64(60),
+		"key":           "remote_addr",
+		"rejected_code": float64(429),
+	})
+
+	// Not matched.
+	other := &apisix.Route{Id: "3", Name: "ratings"}
+	out, applied, err = applyPatches(patches, other)
+	assert.Nil(t, err)
+	assert.Nil(t, applied)
+	assert.Equal(t, out, other)
+
+	for _, data := range []string{
+		"patches: [{kind: route, id: '1', merge_patch: {id: '2'}}]",
+		"patches: [{kind: route, id: '1', merge_patch: {unknown: 1}}]",
+		"patches: [{kind: route, id: '1', json_patch: [{op: remove, path: /hosts}]}]",
+	} {
+		patches, err := decodeFile("patch.yaml", []byte(data))
+		assert.Nil(t, err)
+		_, _, err = applyPatches(patches, route)
+		assert.NotNil(t, err, data)
+	}
+}
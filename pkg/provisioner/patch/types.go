@@ -0,0 +1,286 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/watcher"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+)
+
+type patchProvisioner struct {
+	logger   *log.Logger
+	evChan   chan []types.Event
+	base     provisioner.Provisioner
+	files    []string
+	debounce time.Duration
+	// patches are the last loaded patches of all files, in order.
+	patches []*patch
+	// objects are the latest routes and upstreams from the base provisioner,
+	// and patched are the ones after patching, the key is the kind and id.
+	objects map[string]proto.Message
+	patched map[string]proto.Message
+	// sources are the sources of objects, see types.Event.Source.
+	sources map[string]string
+}
+
+// NewPatchProvisioner creates a provisioner which applies the patches in
+// the watched files to the routes and upstreams generated by the base
+// provisioner. Patches are applied again once the base objects or the
+// patch files changed. All files are reloaded together, the invalid files
+// (including the ones that cannot be applied to the current objects) are
+// rejected as a whole, with the last valid patches kept.
+func NewPatchProvisioner(cfg *config.Config, base provisioner.Provisioner) (provisioner.Provisioner, error) {
+	if len(cfg.PatchFiles) == 0 {
+		return nil, errors.New("patch provisioner: no patch files")
+	}
+	logger, err := log.NewLogger(
+		log.WithContext("patch-provisioner"),
+		log.WithLogLevel(cfg.LogLevel),
+		log.WithOutputFile(cfg.LogOutput),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &patchProvisioner{
+		logger:   logger,
+		evChan:   make(chan []types.Event),
+		base:     base,
+		files:    cfg.PatchFiles,
+		debounce: watcher.DefaultDebounce,
+		objects:  make(map[string]proto.Message),
+		patched:  make(map[string]proto.Message),
+		sources:  make(map[string]string),
+	}, nil
+}
+
+func (p *patchProvisioner) Channel() <-chan []types.Event {
+	return p.evChan
+}
+
+// Run runs the base provisioner and patches its events, it exits once the
+// base provisioner exited.
+func (p *patchProvisioner) Run(stop chan struct{}) error {
+	p.logger.Infow("patch provisioner started")
+	defer p.logger.Infow("patch provisioner exited")
+	defer close(p.evChan)
+
+	w, err := watcher.NewWatcher(p.files, p.debounce, p.logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := w.Close(); err != nil {
+			p.logger.Errorw("failed to close watcher",
+				zap.Error(err),
+			)
+		}
+	}()
+	p.reload()
+
+	// The base provisioner is stopped once the patch provisioner exited.
+	baseStop := make(chan struct{})
+	defer close(baseStop)
+	exitCh := make(chan error, 1)
+	go func() {
+		exitCh <- p.base.Run(baseStop)
+	}()
+
+	var (
+		pending []types.Event
+		baseCh  = p.base.Channel()
+	)
+	for {
+		var sendCh chan<- []types.Event
+		if len(pending) > 0 {
+			sendCh = p.evChan
+		}
+		select {
+		case <-stop:
+			return nil
+		case err := <-exitCh:
+			if err != nil {
+				p.logger.Errorw("base provisioner run failed",
+					zap.Error(err),
+				)
+			}
+			return err
+		case events, ok := <-baseCh:
+			if !ok {
+				// Wait for the exit result.
+				baseCh = nil
+				continue
+			}
+			// Events are coalesced if the last batch was not received.
+			pending = append(pending, p.patchEvents(events)...)
+		case <-w.Changed():
+			if p.reload() {
+				pending = append(pending, p.repatch()...)
+			}
+		case sendCh <- pending:
+			pending = nil
+		}
+	}
+}
+
+// patchEvents records the objects from the base provisioner and replaces
+// them with the patched ones, events of other objects are passed through.
+func (p *patchProvisioner) patchEvents(events []types.Event) []types.Event {
+	var patched []types.Event
+	for _, ev := range events {
+		obj := ev.Object
+		if ev.Type == types.EventDelete {
+			obj = ev.Tombstone
+		}
+		msg, ok := obj.(proto.Message)
+		if !ok {
+			patched = append(patched, ev)
+			continue
+		}
+		kind, id, _, ok := describe(msg)
+		if !ok {
+			patched = append(patched, ev)
+			continue
+		}
+		key := kind + "/" + id
+		if ev.Type == types.EventDelete {
+			tombstone := msg
+			if last, ok := p.patched[key]; ok {
+				tombstone = last
+			}
+			delete(p.objects, key)
+			delete(p.patched, key)
+			delete(p.sources, key)
+			patched = append(patched, types.Event{
+				Type:      types.EventDelete,
+				Tombstone: tombstone,
+				Revision:  ev.Revision,
+				Source:    ev.Source,
+			})
+			continue
+		}
+		out := p.apply(msg)
+		p.objects[key] = msg
+		p.patched[key] = out
+		p.sources[key] = ev.Source
+		patched = append(patched, types.Event{
+			Type:     ev.Type,
+			Object:   out,
+			Revision: ev.Revision,
+			Source:   ev.Source,
+		})
+	}
+	return patched
+}
+
+// repatch applies the patches to all objects again, update events are
+// generated for the changed ones.
+func (p *patchProvisioner) repatch() []types.Event {
+	var events []types.Event
+	for _, key := range p.objectKeys() {
+		out := p.apply(p.objects[key])
+		if proto.Equal(out, p.patched[key]) {
+			continue
+		}
+		p.patched[key] = out
+		events = append(events, types.Event{
+			Type:   types.EventUpdate,
+			Object: out,
+			Source: p.sources[key],
+		})
+	}
+	return events
+}
+
+// apply patches the object, the object itself is used if it cannot be
+// patched.
+func (p *patchProvisioner) apply(obj proto.Message) proto.Message {
+	out, applied, err := applyPatches(p.patches, obj)
+	if err != nil {
+		p.logger.Errorw("failed to patch object, use it as is",
+			zap.Error(err),
+			zap.Any("object", obj),
+		)
+		return obj
+	}
+	if len(applied) > 0 {
+		p.logger.Debugw("object patched",
+			zap.Any("object", out),
+			zap.Strings("patches", applied),
+		)
+	}
+	return out
+}
+
+// reload loads all patch files, false is returned if they're invalid or
+// cannot be applied to the current objects.
+func (p *patchProvisioner) reload() bool {
+	patches, err := p.load()
+	if err == nil {
+		err = p.check(patches)
+	}
+	if err != nil {
+		p.logger.Errorw("reject invalid patches",
+			zap.Error(err),
+		)
+		return false
+	}
+	p.patches = patches
+	p.logger.Debugw("patches loaded",
+		zap.Int("count", len(patches)),
+	)
+	return true
+}
+
+// check applies the patches to the current objects, so that the patches
+// which fail (e.g. the JSON patch removes a missing field, or the merge
+// patch has typos) are rejected once loaded, rather than being skipped
+// silently.
+func (p *patchProvisioner) check(patches []*patch) error {
+	for _, key := range p.objectKeys() {
+		if _, _, err := applyPatches(patches, p.objects[key]); err != nil {
+			return fmt.Errorf("failed to patch %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+// objectKeys returns the keys of objects in order.
+func (p *patchProvisioner) objectKeys() []string {
+	keys := make([]string, 0, len(p.objects))
+	for key := range p.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// load decodes all files, patches are in the order of files.
+func (p *patchProvisioner) load() ([]*patch, error) {
+	files, err := watcher.ListFiles(p.files)
+	if err != nil {
+		return nil, err
+	}
+	var patches []*patch
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		pts, err := decodeFile(file, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+		patches = append(patches, pts...)
+	}
+	return patches, nil
+}
@@ -0,0 +1,169 @@
+package patch
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+type fakeProvisioner struct {
+	evChan chan []types.Event
+	err    error
+}
+
+func (f *fakeProvisioner) Channel() <-chan []types.Event {
+	return f.evChan
+}
+
+func (f *fakeProvisioner) Run(stop chan struct{}) error {
+	defer close(f.evChan)
+	if f.err != nil {
+		return f.err
+	}
+	<-stop
+	return nil
+}
+
+func receiveEvents(t *testing.T, ch <-chan []types.Event) []types.Event {
+	select {
+	case events := <-ch:
+		return events
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "no event arrived in time")
+	}
+	return nil
+}
+
+func TestPatchProvisionerRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "patches")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "patch.yaml")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`
+patches:
+- kind: upstream
+  name: "*reviews*"
+  merge_patch:
+    retries: 3
+`), 0644))
+
+	base := &fakeProvisioner{evChan: make(chan []types.Event)}
+	p, err := NewPatchProvisioner(&config.Config{
+		LogLevel:   "debug",
+		LogOutput:  "stderr",
+		PatchFiles: []string{dir},
+	}, base)
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		assert.Nil(t, p.Run(stopCh))
+	}()
+
+	ups := &apisix.Upstream{Id: "1", Name: "outbound|9080||reviews.default.svc.cluster.local"}
+	route := &apisix.Route{Id: "2", Name: "reviews", Status: apisix.Route_Enable, UpstreamId: "1"}
+	ssl := &apisix.SSL{Id: "3"}
+	base.evChan <- []types.Event{
+		{Type: types.EventAdd, Object: ups},
+		{Type: types.EventAdd, Object: route, Source: "xds"},
+		{Type: types.EventAdd, Object: ssl},
+	}
+	events := receiveEvents(t, p.Channel())
+	assert.Len(t, events, 3)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Retries, int32(3))
+	assert.Equal(t, events[1].Object, route)
+	assert.Equal(t, events[1].Source, "xds")
+	assert.Equal(t, events[2].Object, ssl)
+
+	// The patch is applied again once the base object changed.
+	ups = &apisix.Upstream{Id: "1", Name: "outbound|9080||reviews.default.svc.cluster.local", Type: "chash"}
+	base.evChan <- []types.Event{{Type: types.EventUpdate, Object: ups}}
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Type, "chash")
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Retries, int32(3))
+
+	// Objects are patched again once the patches changed.
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`
+patches:
+- kind: route
+  id: "2"
+  merge_patch:
+    status: 0
+`), 0644))
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 2)
+	for _, ev := range events {
+		assert.Equal(t, ev.Type, types.EventUpdate)
+		switch obj := ev.Object.(type) {
+		case *apisix.Route:
+			assert.Equal(t, obj.Status, apisix.Route_Disable)
+			// The source of the object is kept.
+			assert.Equal(t, ev.Source, "xds")
+		case *apisix.Upstream:
+			assert.Equal(t, obj.Retries, int32(0))
+		}
+	}
+
+	// Invalid patches are rejected.
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`patches: [{kind: route}]`), 0644))
+	select {
+	case events := <-p.Channel():
+		assert.FailNow(t, "unexpected events", events)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// Patches which cannot be applied to the current objects are rejected
+	// as well, the last valid patches are kept.
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`
+patches:
+- kind: upstream
+  name: "*reviews*"
+  merge_patch:
+    retires: 3
+`), 0644))
+	select {
+	case events := <-p.Channel():
+		assert.FailNow(t, "unexpected events", events)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	base.evChan <- []types.Event{{Type: types.EventDelete, Tombstone: route}}
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventDelete)
+	assert.Equal(t, events[0].Tombstone.(*apisix.Route).Status, apisix.Route_Disable)
+}
+
+func TestPatchProvisionerBaseFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "patches")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	base := &fakeProvisioner{
+		evChan: make(chan []types.Event),
+		err:    errors.New("bad config"),
+	}
+	p, err := NewPatchProvisioner(&config.Config{
+		LogLevel:   "debug",
+		LogOutput:  "stderr",
+		PatchFiles: []string{dir},
+	}, base)
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	assert.Equal(t, p.Run(stopCh), base.err)
+
+	_, err = NewPatchProvisioner(&config.Config{}, base)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,153 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner"
+	"github.com/api7/apisix-mesh-agent/pkg/recorder"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+type replayProvisioner struct {
+	logger *log.Logger
+	evChan chan []types.Event
+	file   string
+	speed  float64
+}
+
+// NewReplayProvisioner creates a provisioner which plays back the events
+// in the recording file (written by the recorder). The intervals between
+// batches are kept and scaled by the speed, batches are sent one by one
+// without waiting if the speed is zero. It keeps running after all batches
+// are sent, so that the replayed state can be inspected. SSLs in redacted
+// records are skipped, since they don't have the private keys.
+func NewReplayProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
+	if cfg.ReplayFile == "" {
+		return nil, config.ErrEmptyReplayFile
+	}
+	if cfg.ReplaySpeed < 0 {
+		return nil, config.ErrBadReplaySpeed
+	}
+	logger, err := log.NewLogger(
+		log.WithContext("replay-provisioner"),
+		log.WithLogLevel(cfg.LogLevel),
+		log.WithOutputFile(cfg.LogOutput),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &replayProvisioner{
+		logger: logger,
+		evChan: make(chan []types.Event),
+		file:   cfg.ReplayFile,
+		speed:  cfg.ReplaySpeed,
+	}, nil
+}
+
+func (p *replayProvisioner) Channel() <-chan []types.Event {
+	return p.evChan
+}
+
+func (p *replayProvisioner) Run(stop chan struct{}) error {
+	p.logger.Infow("replay provisioner started",
+		zap.String("file", p.file),
+		zap.Float64("speed", p.speed),
+	)
+	defer p.logger.Infow("replay provisioner exited")
+	defer close(p.evChan)
+
+	f, err := os.Open(p.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		last   time.Time
+		reader = bufio.NewReader(f)
+		lineno int
+		count  int
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) > 0 {
+			lineno++
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var rec recorder.Record
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return fmt.Errorf("%s:%d: %s", p.file, lineno, err)
+			}
+			if !last.IsZero() && p.speed > 0 {
+				delay := time.Duration(float64(rec.Timestamp.Sub(last)) / p.speed)
+				if delay > 0 {
+					select {
+					case <-stop:
+						return nil
+					case <-time.After(delay):
+					}
+				}
+			}
+			last = rec.Timestamp
+			if rec.Redacted {
+				rec.Events = p.skipRedactedSSLs(lineno, rec.Events)
+			}
+			if len(rec.Events) > 0 {
+				p.logger.Debugw("replay recorded events",
+					zap.Int("line", lineno),
+					zap.Int64("revision", rec.Revision),
+					zap.Int("count", len(rec.Events)),
+				)
+				select {
+				case <-stop:
+					return nil
+				case p.evChan <- rec.Events:
+				}
+				count++
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	p.logger.Infow("all recorded events are replayed",
+		zap.Int("batches", count),
+	)
+	<-stop
+	return nil
+}
+
+// skipRedactedSSLs removes the SSL events, as the SSLs without private keys
+// are rejected by APISIX.
+func (p *replayProvisioner) skipRedactedSSLs(lineno int, events []types.Event) []types.Event {
+	kept := make([]types.Event, 0, len(events))
+	for _, ev := range events {
+		obj := ev.Object
+		if ev.Type == types.EventDelete {
+			obj = ev.Tombstone
+		}
+		if ssl, ok := obj.(*apisix.SSL); ok {
+			p.logger.Warnw("skip ssl in the redacted record",
+				zap.Int("line", lineno),
+				zap.String("id", ssl.Id),
+			)
+			continue
+		}
+		kept = append(kept, ev)
+	}
+	return kept
+}
@@ -0,0 +1,120 @@
+package replay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+const _testRecording = `{"timestamp":"2021-03-01T08:00:00Z","revision":1,"events":[{"type":"add","kind":"upstream","revision":0,"object":{"name":"reviews","id":"1"}},{"type":"add","kind":"route","revision":1,"object":{"name":"reviews","id":"2","upstreamId":"1","priority":999}}]}
+
+{"timestamp":"2021-03-01T08:00:00.400Z","revision":2,"events":[{"type":"update","kind":"route","revision":2,"object":{"name":"reviews","id":"2","upstreamId":"1","priority":1}}]}
+{"timestamp":"2021-03-01T08:00:00.800Z","revision":3,"events":[{"type":"delete","kind":"route","revision":3,"object":{"name":"reviews","id":"2"}}]}
+{"timestamp":"2021-03-01T08:00:00.800Z","revision":5,"redacted":true,"events":[{"type":"add","kind":"ssl","revision":4,"object":{"id":"3","cert":"cert","snis":["*.example.com"]}},{"type":"update","kind":"upstream","revision":5,"object":{"name":"reviews","id":"1","retries":1}}]}
+`
+
+func receiveEvents(t *testing.T, ch <-chan []types.Event) []types.Event {
+	select {
+	case events := <-ch:
+		return events
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "no event arrived in time")
+	}
+	return nil
+}
+
+func writeRecording(t *testing.T, data string) (string, func()) {
+	dir, err := ioutil.TempDir("", "replay")
+	assert.Nil(t, err)
+	file := filepath.Join(dir, "events.jsonl")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(data), 0644))
+	return file, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestReplayProvisionerRun(t *testing.T) {
+	file, cleanup := writeRecording(t, _testRecording)
+	defer cleanup()
+
+	// Twice as fast as the original timing.
+	p, err := NewReplayProvisioner(&config.Config{
+		LogLevel:    "debug",
+		LogOutput:   "stderr",
+		ReplayFile:  file,
+		ReplaySpeed: 2,
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		assert.Nil(t, p.Run(stopCh))
+	}()
+
+	events := receiveEvents(t, p.Channel())
+	start := time.Now()
+	assert.Len(t, events, 2)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Id, "1")
+	assert.Equal(t, events[1].Object.(*apisix.Route).Priority, int32(999))
+
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Revision, int64(2))
+	assert.Equal(t, events[0].Object.(*apisix.Route).Priority, int32(1))
+
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventDelete)
+	assert.Equal(t, events[0].Tombstone.(*apisix.Route).Id, "2")
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(400*time.Millisecond))
+
+	// SSLs in the redacted record are skipped.
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Retries, int32(1))
+
+	// It keeps running after all events are replayed.
+	select {
+	case events, ok := <-p.Channel():
+		assert.FailNow(t, "unexpected events", events, ok)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestReplayProvisionerBadRecording(t *testing.T) {
+	file, cleanup := writeRecording(t, _testRecording+"{\"events\": [{\"kind\": \"unknown\"}]}\n")
+	defer cleanup()
+
+	p, err := NewReplayProvisioner(&config.Config{
+		LogLevel:   "debug",
+		LogOutput:  "stderr",
+		ReplayFile: file,
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	errCh := make(chan error)
+	go func() {
+		errCh <- p.Run(stopCh)
+	}()
+	// No waiting if the speed is zero.
+	for i := 0; i < 4; i++ {
+		receiveEvents(t, p.Channel())
+	}
+	err = <-errCh
+	assert.NotNil(t, err)
+	assert.Equal(t, err.Error(), file+`:6: event #0: unknown kind "unknown"`)
+
+	_, err = NewReplayProvisioner(&config.Config{})
+	assert.Equal(t, err, config.ErrEmptyReplayFile)
+}
@@ -6,10 +6,12 @@ import (
 	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
 )
 
-// Manifest collects a couples Routes, Upstreams.
+// Manifest collects a couples Routes, Upstreams, StreamRoutes and SSLs.
 type Manifest struct {
-	Routes    []*apisix.Route
-	Upstreams []*apisix.Upstream
+	Routes       []*apisix.Route
+	Upstreams    []*apisix.Upstream
+	StreamRoutes []*apisix.StreamRoute
+	SSLs         []*apisix.SSL
 }
 
 // DiffFrom checks the difference between m and m2 from m's point of view.
@@ -30,12 +32,22 @@ func (m *Manifest) DiffFrom(m2 *Manifest) (*Manifest, *Manifest, *Manifest) {
 	updated.Upstreams = append(updated.Upstreams, uu...)
 	deleted.Upstreams = append(deleted.Upstreams, du...)
 
+	asr, dsr, usr := apisixutil.CompareStreamRoutes(m.StreamRoutes, m2.StreamRoutes)
+	added.StreamRoutes = append(added.StreamRoutes, asr...)
+	updated.StreamRoutes = append(updated.StreamRoutes, usr...)
+	deleted.StreamRoutes = append(deleted.StreamRoutes, dsr...)
+
+	assl, dssl, ussl := apisixutil.CompareSSLs(m.SSLs, m2.SSLs)
+	added.SSLs = append(added.SSLs, assl...)
+	updated.SSLs = append(updated.SSLs, ussl...)
+	deleted.SSLs = append(deleted.SSLs, dssl...)
+
 	return &added, &deleted, &updated
 }
 
 // Size calculates the number of resources in the manifest.
 func (m *Manifest) Size() int {
-	return len(m.Upstreams) + len(m.Routes)
+	return len(m.Upstreams) + len(m.Routes) + len(m.StreamRoutes) + len(m.SSLs)
 }
 
 // Events generates events according to its collection.
@@ -67,5 +79,69 @@ func (m *Manifest) Events(evType types.EventType) []types.Event {
 			})
 		}
 	}
+	for _, sr := range m.StreamRoutes {
+		if evType == types.EventDelete {
+			events = append(events, types.Event{
+				Type:      types.EventDelete,
+				Tombstone: sr,
+			})
+		} else {
+			events = append(events, types.Event{
+				Type:   evType,
+				Object: sr,
+			})
+		}
+	}
+	for _, ssl := range m.SSLs {
+		if evType == types.EventDelete {
+			events = append(events, types.Event{
+				Type:      types.EventDelete,
+				Tombstone: ssl,
+			})
+		} else {
+			events = append(events, types.Event{
+				Type:   evType,
+				Object: ssl,
+			})
+		}
+	}
+	return events
+}
+
+// DiffManifests checks the difference between the old and new manifests,
+// the new one is treated as all added if the old one is nil, and the old
+// one is treated as all deleted if the new one is nil.
+func DiffManifests(old, m *Manifest) (added, deleted, updated *Manifest) {
+	if old == nil {
+		return m, nil, nil
+	}
+	if m == nil {
+		return nil, old, nil
+	}
+	return old.DiffFrom(m)
+}
+
+// ManifestEvents generates the add, delete and update events in order, nil
+// manifests are skipped.
+func ManifestEvents(added, deleted, updated *Manifest) []types.Event {
+	var count int
+	for _, m := range []*Manifest{added, deleted, updated} {
+		if m != nil {
+			count += m.Size()
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	events := make([]types.Event, 0, count)
+	if added != nil {
+		events = append(events, added.Events(types.EventAdd)...)
+	}
+	if deleted != nil {
+		events = append(events, deleted.Events(types.EventDelete)...)
+	}
+	if updated != nil {
+		events = append(events, updated.Events(types.EventUpdate)...)
+	}
 	return events
 }
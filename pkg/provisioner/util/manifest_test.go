@@ -17,8 +17,14 @@ func TestManifestSize(t *testing.T) {
 		Upstreams: []*apisix.Upstream{
 			{}, {},
 		},
+		StreamRoutes: []*apisix.StreamRoute{
+			{},
+		},
+		SSLs: []*apisix.SSL{
+			{},
+		},
 	}
-	assert.Equal(t, m.Size(), 4)
+	assert.Equal(t, m.Size(), 6)
 }
 
 func TestManifestEvents(t *testing.T) {
@@ -29,21 +35,27 @@ func TestManifestEvents(t *testing.T) {
 		Upstreams: []*apisix.Upstream{
 			{}, {},
 		},
+		StreamRoutes: []*apisix.StreamRoute{
+			{},
+		},
+		SSLs: []*apisix.SSL{
+			{},
+		},
 	}
 	evs := m.Events(types.EventAdd)
-	assert.Len(t, evs, 4)
+	assert.Len(t, evs, 6)
 	assert.NotNil(t, evs[0].Object)
 	assert.Nil(t, evs[0].Tombstone)
 	assert.Equal(t, evs[0].Type, types.EventAdd)
 
 	evs = m.Events(types.EventUpdate)
-	assert.Len(t, evs, 4)
+	assert.Len(t, evs, 6)
 	assert.NotNil(t, evs[0].Object)
 	assert.Nil(t, evs[0].Tombstone)
 	assert.Equal(t, evs[0].Type, types.EventUpdate)
 
 	evs = m.Events(types.EventDelete)
-	assert.Len(t, evs, 4)
+	assert.Len(t, evs, 6)
 	assert.Nil(t, evs[0].Object)
 	assert.NotNil(t, evs[0].Tombstone)
 	assert.Equal(t, evs[0].Type, types.EventDelete)
@@ -67,6 +79,16 @@ func TestManifestDiffFrom(t *testing.T) {
 				Id: "2",
 			},
 		},
+		StreamRoutes: []*apisix.StreamRoute{
+			{
+				Id: "1",
+			},
+		},
+		SSLs: []*apisix.SSL{
+			{
+				Id: "1",
+			},
+		},
 	}
 	m2 := &Manifest{
 		Routes: []*apisix.Route{
@@ -83,6 +105,18 @@ func TestManifestDiffFrom(t *testing.T) {
 				Id: "1",
 			},
 		},
+		StreamRoutes: []*apisix.StreamRoute{
+			{
+				Id:         "1",
+				ServerPort: 6379,
+			},
+		},
+		SSLs: []*apisix.SSL{
+			{
+				Id:   "1",
+				Cert: "cert",
+			},
+		},
 	}
 	a, d, u := m.DiffFrom(m2)
 	assert.Equal(t, a.Size(), 1)
@@ -92,7 +126,56 @@ func TestManifestDiffFrom(t *testing.T) {
 	assert.Equal(t, d.Routes[0].Id, "1")
 	assert.Equal(t, d.Upstreams[0].Id, "2")
 
-	assert.Equal(t, u.Size(), 1)
+	assert.Equal(t, u.Size(), 3)
 	assert.Equal(t, u.Routes[0].Id, "2")
 	assert.Equal(t, u.Routes[0].Uris, []string{"/foo"})
+	assert.Equal(t, u.StreamRoutes[0].Id, "1")
+	assert.Equal(t, u.StreamRoutes[0].ServerPort, int32(6379))
+	assert.Equal(t, u.SSLs[0].Id, "1")
+	assert.Equal(t, u.SSLs[0].Cert, "cert")
+}
+
+func TestDiffManifests(t *testing.T) {
+	m := &Manifest{
+		Routes: []*apisix.Route{
+			{
+				Id: "1",
+			},
+		},
+	}
+	a, d, u := DiffManifests(nil, m)
+	assert.Equal(t, a, m)
+	assert.Nil(t, d)
+	assert.Nil(t, u)
+	evs := ManifestEvents(a, d, u)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventAdd)
+
+	a, d, u = DiffManifests(m, nil)
+	assert.Nil(t, a)
+	assert.Equal(t, d, m)
+	assert.Nil(t, u)
+	evs = ManifestEvents(a, d, u)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventDelete)
+
+	m2 := &Manifest{
+		Routes: []*apisix.Route{
+			{
+				Id:   "1",
+				Uris: []string{"/foo"},
+			},
+			{
+				Id: "2",
+			},
+		},
+	}
+	evs = ManifestEvents(DiffManifests(m, m2))
+	assert.Len(t, evs, 2)
+	assert.Equal(t, evs[0].Type, types.EventAdd)
+	assert.Equal(t, evs[0].Object.(*apisix.Route).Id, "2")
+	assert.Equal(t, evs[1].Type, types.EventUpdate)
+	assert.Equal(t, evs[1].Object.(*apisix.Route).Id, "1")
+
+	assert.Nil(t, ManifestEvents(DiffManifests(m, m)))
 }
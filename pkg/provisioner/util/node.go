@@ -0,0 +1,143 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/version"
+)
+
+const (
+	// Environment variables with this prefix will be reported
+	// in the node metadata (prefix trimmed), it's compatible with
+	// Istio.
+	_istioMetaPrefix = "ISTIO_META_"
+)
+
+// NewNode creates the xDS node, the node id and metadata are compatible
+// with Istio, so that the configurations pushed by istiod can be scoped
+// correctly.
+func NewNode(cfg *config.Config, environ []string, logger *log.Logger) (*corev3.Node, error) {
+	rc := cfg.RunningContext
+	dnsDomain := cfg.DNSDomain
+	if dnsDomain == "" {
+		dnsDomain = rc.PodNamespace + ".svc.cluster.local"
+	}
+	podName := rc.PodName
+	if podName == "" {
+		// Not running inside a Kubernetes pod.
+		podName = cfg.RunId
+	}
+	tmpl := cfg.XDSNodeIdTemplate
+	if tmpl == "" {
+		tmpl = config.DefaultXDSNodeIdTemplate
+	}
+	id, err := GenNodeId(tmpl, &NodeIdContext{
+		RunId:        cfg.RunId,
+		IPAddress:    rc.IPAddress,
+		PodName:      podName,
+		PodNamespace: rc.PodNamespace,
+		DNSDomain:    dnsDomain,
+	})
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := newNodeMetadata(cfg, podName, environ, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &corev3.Node{
+		Id:            id,
+		Metadata:      metadata,
+		UserAgentName: fmt.Sprintf("apisix-mesh-agent/%s", version.Short()),
+	}, nil
+}
+
+func newNodeMetadata(cfg *config.Config, podName string, environ []string, logger *log.Logger) (*structpb.Struct, error) {
+	rc := cfg.RunningContext
+	meta := map[string]interface{}{
+		"NAME":              podName,
+		"NAMESPACE":         rc.PodNamespace,
+		"INSTANCE_IPS":      rc.IPAddress,
+		"CLUSTER_ID":        "Kubernetes",
+		"INTERCEPTION_MODE": "REDIRECT",
+	}
+	if rc.ServiceAccount != "" {
+		meta["SERVICE_ACCOUNT"] = rc.ServiceAccount
+	}
+	for _, env := range environ {
+		if !strings.HasPrefix(env, _istioMetaPrefix) {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(env, _istioMetaPrefix), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		meta[kv[0]] = kv[1]
+	}
+	if cfg.PodLabelsFile != "" {
+		labels, err := readPodLabels(cfg.PodLabelsFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		if len(labels) > 0 {
+			meta["LABELS"] = labels
+		}
+	}
+	return structpb.NewStruct(meta)
+}
+
+// readPodLabels reads labels from the file which is generated by the downward
+// API, each line is in the format of: key="value". Nothing will be returned
+// if the file doesn't exist.
+func readPodLabels(name string, logger *log.Logger) (map[string]interface{}, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Infow("pod labels file not found, labels won't be reported",
+				zap.String("file", name),
+			)
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	labels := make(map[string]interface{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			logger.Warnw("ignore malformed pod label",
+				zap.String("label", line),
+			)
+			continue
+		}
+		value, err := strconv.Unquote(kv[1])
+		if err != nil {
+			logger.Warnw("ignore malformed pod label",
+				zap.Error(err),
+				zap.String("label", line),
+			)
+			continue
+		}
+		labels[kv[0]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
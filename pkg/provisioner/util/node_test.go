@@ -0,0 +1,88 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/version"
+)
+
+func TestNewNode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "podinfo")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	labelsFile := filepath.Join(dir, "labels")
+	labels := "app=\"httpbin\"\nversion=\"v1\"\nbad-label\n\nsecurity.istio.io/tlsMode=\"istio\"\n"
+	assert.Nil(t, ioutil.WriteFile(labelsFile, []byte(labels), 0644))
+
+	cfg := &config.Config{
+		RunId:             "12345",
+		XDSNodeIdTemplate: config.DefaultXDSNodeIdTemplate,
+		DNSDomain:         "apps.svc.mesh.local",
+		PodLabelsFile:     labelsFile,
+		RunningContext: &config.RunningContext{
+			PodName:        "httpbin-7d4b6cf58c-5x2jn",
+			PodNamespace:   "apps",
+			IPAddress:      "10.0.5.3",
+			ServiceAccount: "httpbin",
+		},
+	}
+	environ := []string{
+		"HOME=/root",
+		"ISTIO_META_CLUSTER_ID=cluster-1",
+		"ISTIO_META_MESH_ID=mesh-1",
+		"ISTIO_META_WORKLOAD_NAME=httpbin",
+		"ISTIO_META_ISTIO_VERSION=1.9.0",
+		"ISTIO_META_=ignored",
+	}
+	node, err := NewNode(cfg, environ, log.DefaultLogger)
+	assert.Nil(t, err)
+	assert.Equal(t, node.Id, "sidecar~10.0.5.3~httpbin-7d4b6cf58c-5x2jn.apps~apps.svc.mesh.local")
+	assert.Equal(t, node.UserAgentName, "apisix-mesh-agent/"+version.Short())
+
+	meta := node.Metadata.AsMap()
+	assert.Equal(t, meta["NAME"], "httpbin-7d4b6cf58c-5x2jn")
+	assert.Equal(t, meta["NAMESPACE"], "apps")
+	assert.Equal(t, meta["INSTANCE_IPS"], "10.0.5.3")
+	assert.Equal(t, meta["SERVICE_ACCOUNT"], "httpbin")
+	assert.Equal(t, meta["INTERCEPTION_MODE"], "REDIRECT")
+	assert.Equal(t, meta["CLUSTER_ID"], "cluster-1")
+	assert.Equal(t, meta["MESH_ID"], "mesh-1")
+	assert.Equal(t, meta["WORKLOAD_NAME"], "httpbin")
+	assert.Equal(t, meta["ISTIO_VERSION"], "1.9.0")
+	assert.Nil(t, meta[""])
+	assert.Nil(t, meta["HOME"])
+	assert.Equal(t, meta["LABELS"], map[string]interface{}{
+		"app":                       "httpbin",
+		"version":                   "v1",
+		"security.istio.io/tlsMode": "istio",
+	})
+}
+
+func TestNewNodeWithDefaults(t *testing.T) {
+	cfg := &config.Config{
+		RunId:         "12345",
+		PodLabelsFile: "/path/not/exist",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "10.0.5.3",
+		},
+	}
+	node, err := NewNode(cfg, nil, log.DefaultLogger)
+	assert.Nil(t, err)
+	assert.Equal(t, node.Id, "sidecar~10.0.5.3~12345.default~default.svc.cluster.local")
+	meta := node.Metadata.AsMap()
+	assert.Equal(t, meta["CLUSTER_ID"], "Kubernetes")
+	assert.Nil(t, meta["SERVICE_ACCOUNT"])
+	assert.Nil(t, meta["LABELS"])
+
+	cfg.XDSNodeIdTemplate = "sidecar~{{ .Unknown }}"
+	_, err = NewNode(cfg, nil, log.DefaultLogger)
+	assert.NotNil(t, err)
+}
@@ -2,17 +2,29 @@ package util
 
 import (
 	"strings"
+	"text/template"
 )
 
-// GenNodeId generates an id used for xDS protocol. The format is like:
-// sidecar~172.10.0.2~12345asad034~default.svc.cluster.local
-func GenNodeId(runId, ipAddr, dnsDomain string) string {
+// NodeIdContext contains the fields which can be used in the node id template.
+type NodeIdContext struct {
+	RunId        string
+	IPAddress    string
+	PodName      string
+	PodNamespace string
+	DNSDomain    string
+}
+
+// GenNodeId generates an id used for xDS protocol by rendering the template,
+// with the default template, the format is like:
+// sidecar~172.10.0.2~httpbin-7d4b6cf58c-5x2jn.default~default.svc.cluster.local
+func GenNodeId(tmpl string, ctx *NodeIdContext) (string, error) {
+	t, err := template.New("node_id").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
 	var buf strings.Builder
-	buf.WriteString("sidecar~")
-	buf.WriteString(ipAddr)
-	buf.WriteString("~")
-	buf.WriteString(runId)
-	buf.WriteString("~")
-	buf.WriteString(dnsDomain)
-	return buf.String()
+	if err := t.Execute(&buf, ctx); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
@@ -7,6 +7,21 @@ import (
 )
 
 func TestGenNodeId(t *testing.T) {
-	id := GenNodeId("12345", "10.0.5.3", "default.svc.cluster.local")
+	ctx := &NodeIdContext{
+		RunId:        "12345",
+		IPAddress:    "10.0.5.3",
+		PodName:      "httpbin-7d4b6cf58c-5x2jn",
+		PodNamespace: "default",
+		DNSDomain:    "default.svc.cluster.local",
+	}
+	id, err := GenNodeId("sidecar~{{ .IPAddress }}~{{ .PodName }}.{{ .PodNamespace }}~{{ .DNSDomain }}", ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, id, "sidecar~10.0.5.3~httpbin-7d4b6cf58c-5x2jn.default~default.svc.cluster.local")
+
+	id, err = GenNodeId("sidecar~{{ .IPAddress }}~{{ .RunId }}~{{ .DNSDomain }}", ctx)
+	assert.Nil(t, err)
 	assert.Equal(t, id, "sidecar~10.0.5.3~12345~default.svc.cluster.local")
+
+	_, err = GenNodeId("sidecar~{{ .Unknown }}", ctx)
+	assert.NotNil(t, err)
 }
@@ -0,0 +1,188 @@
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+)
+
+const (
+	// DefaultDebounce is the window to wait for more file changes before
+	// reloading, so that partially written files won't be parsed.
+	DefaultDebounce = 100 * time.Millisecond
+	// MaxDebounceWindows caps the delay of the notification to this many
+	// debounce windows since the first pending change, so that a stream of
+	// changes (e.g. a file appended continuously) won't postpone it forever.
+	MaxDebounceWindows = 10
+	// AtomicWriterPrefix is the prefix of the internal entries (e.g. the
+	// "..data" symlink and the timestamped directories) that Kubernetes
+	// uses to update the ConfigMap and Secret volumes atomically. Files
+	// there are reached through the user visible symlinks, so they are
+	// skipped.
+	AtomicWriterPrefix = ".."
+)
+
+// Watcher watches the files and directories, it notifies once they were
+// changed and no more changes arrived in the debounce window, or the
+// changes have been pending for MaxDebounceWindows windows.
+type Watcher struct {
+	logger   *log.Logger
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+	changed  chan struct{}
+	exited   chan struct{}
+}
+
+// NewWatcher creates a Watcher on the paths. Files might be replaced by
+// renaming or symlink flips, so the parent directories of files are
+// watched instead of the files themselves.
+func NewWatcher(paths []string, debounce time.Duration, logger *log.Logger) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			_ = fw.Close()
+			return nil, err
+		}
+		dir := path
+		if !info.IsDir() {
+			dir = filepath.Dir(path)
+		}
+		if err := fw.Add(dir); err != nil {
+			_ = fw.Close()
+			return nil, err
+		}
+	}
+	w := &Watcher{
+		logger:   logger,
+		watcher:  fw,
+		debounce: debounce,
+		changed:  make(chan struct{}, 1),
+		exited:   make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Changed returns a channel which receives a value once the watched paths
+// were changed, changes before the value is received are coalesced into it.
+func (w *Watcher) Changed() <-chan struct{} {
+	return w.changed
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	err := w.watcher.Close()
+	<-w.exited
+	return err
+}
+
+func (w *Watcher) run() {
+	defer close(w.exited)
+
+	var (
+		debounceCh  <-chan time.Time
+		firstChange time.Time
+	)
+	for {
+		select {
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Errorw("detected watch errors",
+				zap.Error(err),
+			)
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			w.logger.Debugw("file change event arrived",
+				zap.String("filename", ev.Name),
+				zap.String("type", ev.Op.String()),
+			)
+			if debounceCh == nil {
+				firstChange = time.Now()
+			}
+			debounceCh = time.After(DebounceDelay(w.debounce, firstChange))
+		case <-debounceCh:
+			debounceCh = nil
+			select {
+			case w.changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// DebounceDelay returns the delay to wait for more changes, which is the
+// debounce window but no later than MaxDebounceWindows windows since the
+// first pending change.
+func DebounceDelay(debounce time.Duration, firstChange time.Time) time.Duration {
+	left := time.Until(firstChange.Add(MaxDebounceWindows * debounce))
+	if left < 0 {
+		return 0
+	}
+	if left < debounce {
+		return left
+	}
+	return debounce
+}
+
+// ListFiles returns the files to load in order, only the .yaml, .yml and
+// .json files in the directories are listed, and not recursively.
+func ListFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), AtomicWriterPrefix) {
+				continue
+			}
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".yaml", ".yml", ".json":
+			default:
+				continue
+			}
+			file := filepath.Join(path, entry.Name())
+			// Follow the symlinks.
+			if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	var uniq []string
+	for i, file := range files {
+		if i == 0 || file != files[i-1] {
+			uniq = append(uniq, file)
+		}
+	}
+	return uniq, nil
+}
@@ -0,0 +1,100 @@
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+)
+
+func TestListFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watcher")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte("a: 1"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "b.json"), []byte("{}"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# apisix"), 0644))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "..2021_09_01"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "..2021_09_01", "c.yaml"), []byte("c: 1"), 0644))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "sub.yaml"), 0755))
+
+	files, err := ListFiles([]string{dir, filepath.Join(dir, "a.yaml")})
+	assert.Nil(t, err)
+	assert.Equal(t, files, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.json")})
+
+	_, err = ListFiles([]string{filepath.Join(dir, "not-exist.yaml")})
+	assert.NotNil(t, err)
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watcher")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "a.yaml")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("a: 1"), 0644))
+
+	_, err = NewWatcher([]string{filepath.Join(dir, "not-exist.yaml")}, time.Millisecond, log.DefaultLogger)
+	assert.NotNil(t, err)
+
+	w, err := NewWatcher([]string{filename}, 50*time.Millisecond, log.DefaultLogger)
+	assert.Nil(t, err)
+
+	// A burst of writes is coalesced.
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, ioutil.WriteFile(filename, []byte("a: 2"), 0644))
+	}
+	select {
+	case <-w.Changed():
+	case <-time.After(time.Second):
+		assert.FailNow(t, "no change notified")
+	}
+	select {
+	case <-w.Changed():
+		assert.FailNow(t, "changes are not coalesced")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	assert.Nil(t, w.Close())
+}
+
+func TestDebounceDelay(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, DebounceDelay(time.Second, now.Add(time.Hour)), time.Second)
+	assert.Equal(t, DebounceDelay(time.Second, now.Add(-time.Hour)), time.Duration(0))
+	delay := DebounceDelay(time.Second, now.Add(-(MaxDebounceWindows-1)*time.Second-500*time.Millisecond))
+	assert.True(t, delay > 0 && delay <= 500*time.Millisecond, delay)
+}
+
+func TestWatcherMaxDelay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watcher")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "a.yaml")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("a: 1"), 0644))
+
+	w, err := NewWatcher([]string{filename}, 50*time.Millisecond, log.DefaultLogger)
+	assert.Nil(t, err)
+	defer func() {
+		assert.Nil(t, w.Close())
+	}()
+
+	// Changes keep arriving within the debounce window, the notification
+	// is not postponed beyond MaxDebounceWindows windows.
+	start := time.Now()
+	deadline := start.Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		assert.Nil(t, ioutil.WriteFile(filename, []byte("a: 2"), 0644))
+		select {
+		case <-w.Changed():
+			assert.True(t, time.Since(start) < 2*time.Second)
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	assert.FailNow(t, "notification is postponed by continuous changes")
+}
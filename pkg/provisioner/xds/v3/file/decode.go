@@ -0,0 +1,294 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	bootstrapv3 "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	// The router filter is configured in almost all HTTP listeners, it's
+	// imported so that the filter config can be resolved.
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+)
+
+const (
+	_discoveryResponseUrl = "type.googleapis.com/envoy.service.discovery.v3.DiscoveryResponse"
+	_bootstrapUrl         = "type.googleapis.com/envoy.config.bootstrap.v3.Bootstrap"
+)
+
+var (
+	_errNotAnObject = errors.New("document is not an object")
+
+	// _unlinkedMessageType is an empty message type, typed configs whose
+	// types are not linked in are decoded to it.
+	_unlinkedMessageType = newUnlinkedMessageType()
+)
+
+// unlinkedTypeResolver resolves types by the global registry, except the
+// ones in unlinked, which are resolved to the _unlinkedMessageType. So that
+// typed configs of extensions that the adaptor ignores (e.g. the CORS filter,
+// access loggers and the TypedStruct used by Istio) don't fail the decoding,
+// they are kept as Any with the original type url and an empty value.
+type unlinkedTypeResolver struct {
+	*protoregistry.Types
+	unlinked set.StringSet
+}
+
+func (r *unlinkedTypeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if _, ok := r.unlinked[url]; ok {
+		return _unlinkedMessageType, nil
+	}
+	return r.Types.FindMessageByURL(url)
+}
+
+func newUnlinkedMessageType() protoreflect.MessageType {
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("apisix-mesh-agent/unlinked.proto"),
+		Package: proto.String("apisix_mesh_agent"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Unlinked")},
+		},
+	}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return dynamicpb.NewMessageType(fd.Messages().Get(0))
+}
+
+// dropUnlinkedTypedConfigs removes the fields of the typed objects nested in
+// the document if their types are not linked in, the document itself (and
+// the resources of a DiscoveryResponse) must have a linked type, so that
+// unknown resource types are still rejected. The returned options should be
+// used to decode the result document.
+func dropUnlinkedTypedConfigs(doc json.RawMessage, resources bool) (json.RawMessage, protojson.UnmarshalOptions, error) {
+	resolver := &unlinkedTypeResolver{
+		Types:    protoregistry.GlobalTypes,
+		unlinked: set.StringSet{},
+	}
+	opts := protojson.UnmarshalOptions{Resolver: resolver}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	// Keep the integers (e.g. uint64) as is.
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, opts, err
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, opts, _errNotAnObject
+	}
+	if resources {
+		if items, ok := root["resources"].([]interface{}); ok {
+			for _, item := range items {
+				if obj, ok := item.(map[string]interface{}); ok {
+					resolver.dropFields(obj)
+				}
+			}
+		}
+	} else {
+		resolver.dropFields(root)
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, opts, err
+	}
+	return data, opts, nil
+}
+
+// dropFields walks through the fields of the object (the type of the
+// object itself is not checked).
+func (r *unlinkedTypeResolver) dropFields(obj map[string]interface{}) {
+	for _, field := range obj {
+		r.drop(field)
+	}
+}
+
+func (r *unlinkedTypeResolver) drop(v interface{}) {
+	switch value := v.(type) {
+	case []interface{}:
+		for _, item := range value {
+			r.drop(item)
+		}
+	case map[string]interface{}:
+		if url, ok := value["@type"].(string); ok {
+			if _, err := r.Types.FindMessageByURL(url); err == protoregistry.NotFound {
+				r.unlinked.Add(url)
+				for key := range value {
+					if key != "@type" {
+						delete(value, key)
+					}
+				}
+				return
+			}
+		}
+		r.dropFields(value)
+	}
+}
+
+// decodeFile decodes the file content to a DiscoveryResponse, the file
+// can be either JSON or YAML (decided by the extension name), and it
+// might contain several documents, each of them is a DiscoveryResponse,
+// an Envoy Bootstrap (only the static resources are used), or a bare
+// xDS resource which is typed by the "@type" field.
+// Resources in all documents are merged.
+func decodeFile(filename string, data []byte) (*discoveryv3.DiscoveryResponse, error) {
+	var (
+		docs []json.RawMessage
+		err  error
+	)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		docs, err = splitYAMLDocuments(data)
+	default:
+		docs, err = splitJSONDocuments(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var merged discoveryv3.DiscoveryResponse
+	for i, doc := range docs {
+		if err := decodeDocument(doc, &merged); err != nil {
+			return nil, fmt.Errorf("document #%d: %s", i, err)
+		}
+	}
+	return &merged, nil
+}
+
+// decodeDocument decodes a single document and merges it into the
+// given DiscoveryResponse.
+func decodeDocument(doc json.RawMessage, merged *discoveryv3.DiscoveryResponse) error {
+	var header struct {
+		Type string `json:"@type"`
+		// Bootstrap files are usually not typed.
+		StaticResources      json.RawMessage `json:"static_resources"`
+		StaticResourcesCamel json.RawMessage `json:"staticResources"`
+	}
+	if err := json.Unmarshal(doc, &header); err != nil {
+		return _errNotAnObject
+	}
+	if header.Type == _bootstrapUrl || (header.Type == "" &&
+		(header.StaticResources != nil || header.StaticResourcesCamel != nil)) {
+		return decodeBootstrap(doc, header.Type != "", merged)
+	}
+	if header.Type == "" || header.Type == _discoveryResponseUrl {
+		doc, opts, err := dropUnlinkedTypedConfigs(doc, true)
+		if err != nil {
+			return err
+		}
+		// DiscoveryResponse itself is not packed in Any, so the
+		// "@type" field is unknown.
+		opts.DiscardUnknown = header.Type != ""
+		var dr discoveryv3.DiscoveryResponse
+		if err := opts.Unmarshal(doc, &dr); err != nil {
+			return err
+		}
+		if merged.VersionInfo == "" {
+			merged.VersionInfo = dr.VersionInfo
+		}
+		merged.Resources = append(merged.Resources, dr.Resources...)
+		return nil
+	}
+
+	doc, opts, err := dropUnlinkedTypedConfigs(doc, false)
+	if err != nil {
+		return err
+	}
+	var res any.Any
+	if err := opts.Unmarshal(doc, &res); err != nil {
+		return err
+	}
+	merged.Resources = append(merged.Resources, &res)
+	return nil
+}
+
+func splitJSONDocuments(data []byte) ([]json.RawMessage, error) {
+	var docs []json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc json.RawMessage
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return docs, nil
+			}
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// splitYAMLDocuments splits the YAML documents and converts them to JSON,
+// so that they can be decoded by protojson, empty documents are skipped.
+func splitYAMLDocuments(data []byte) ([]json.RawMessage, error) {
+	var docs []json.RawMessage
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return docs, nil
+			}
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return nil, _errNotAnObject
+		}
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, raw)
+	}
+}
+
+// decodeBootstrap decodes the Envoy Bootstrap document, static listeners
+// and clusters are merged into the given DiscoveryResponse.
+func decodeBootstrap(doc json.RawMessage, typed bool, merged *discoveryv3.DiscoveryResponse) error {
+	doc, opts, err := dropUnlinkedTypedConfigs(doc, false)
+	if err != nil {
+		return err
+	}
+	// Bootstrap itself is not packed in Any.
+	opts.DiscardUnknown = typed
+	var bootstrap bootstrapv3.Bootstrap
+	if err := opts.Unmarshal(doc, &bootstrap); err != nil {
+		return err
+	}
+	sr := bootstrap.GetStaticResources()
+	var msgs []proto.Message
+	for _, listener := range sr.GetListeners() {
+		msgs = append(msgs, listener)
+	}
+	for _, cluster := range sr.GetClusters() {
+		msgs = append(msgs, cluster)
+	}
+	for _, msg := range msgs {
+		res, err := anypb.New(msg)
+		if err != nil {
+			return err
+		}
+		merged.Resources = append(merged.Resources, res)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package file
+
+import (
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/stretchr/testify/assert"
+
+	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func TestDecodeFileJSON(t *testing.T) {
+	data := `
+{
+  "versionInfo": "1",
+  "resources": [
+    {
+      "@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+      "name": "httpbin.default.svc.cluster.local",
+      "type": "EDS"
+    }
+  ]
+}
+{
+  "@type": "type.googleapis.com/envoy.config.route.v3.RouteConfiguration",
+  "name": "rc1"
+}
+`
+	dr, err := decodeFile("xds.json", []byte(data))
+	assert.Nil(t, err)
+	assert.Equal(t, dr.VersionInfo, "1")
+	assert.Len(t, dr.Resources, 2)
+	assert.Equal(t, dr.Resources[0].TypeUrl, types.ClusterUrl)
+	assert.Equal(t, dr.Resources[1].TypeUrl, types.RouteConfigurationUrl)
+
+	var rc routev3.RouteConfiguration
+	assert.Nil(t, dr.Resources[1].UnmarshalTo(&rc))
+	assert.Equal(t, rc.Name, "rc1")
+
+	_, err = decodeFile("xds.json", []byte(`{"versionInfo": 1`))
+	assert.NotNil(t, err)
+	_, err = decodeFile("xds.json", []byte(`[]`))
+	assert.Equal(t, err.Error(), "document #0: document is not an object")
+}
+
+func TestDecodeFileYAML(t *testing.T) {
+	data := `
+"@type": type.googleapis.com/envoy.service.discovery.v3.DiscoveryResponse
+versionInfo: "2"
+resources:
+- "@type": type.googleapis.com/envoy.config.cluster.v3.Cluster
+  name: httpbin.default.svc.cluster.local
+  type: EDS
+---
+# Empty document is skipped.
+---
+"@type": type.googleapis.com/envoy.config.cluster.v3.Cluster
+name: kubernetes.default.svc.cluster.local
+type: STATIC
+connect_timeout: 1s
+---
+"@type": type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment
+clusterName: httpbin.default.svc.cluster.local
+endpoints:
+- lbEndpoints:
+  - endpoint:
+      address:
+        socketAddress:
+          address: 10.0.3.11
+          portValue: 8000
+    loadBalancingWeight: 100
+`
+	dr, err := decodeFile("xds.yaml", []byte(data))
+	assert.Nil(t, err)
+	assert.Equal(t, dr.VersionInfo, "2")
+	assert.Len(t, dr.Resources, 3)
+
+	var c clusterv3.Cluster
+	assert.Nil(t, dr.Resources[1].UnmarshalTo(&c))
+	assert.Equal(t, c.Name, "kubernetes.default.svc.cluster.local")
+	assert.Equal(t, c.GetType(), clusterv3.Cluster_STATIC)
+	assert.Equal(t, c.GetConnectTimeout().GetSeconds(), int64(1))
+
+	adaptor, err := xdsv3.NewAdaptor(&config.Config{
+		LogLevel:  "debug",
+		LogOutput: "stderr",
+	})
+	assert.Nil(t, err)
+	p := &xdsFileProvisioner{
+		logger:              log.DefaultLogger,
+		v3Adaptor:           adaptor,
+		state:               make(map[string]*util.Manifest),
+		upstreamCache:       make(map[string]*apisix.Upstream),
+		routeConfigurations: make(map[string][]*routev3.RouteConfiguration),
+	}
+	events := p.generateEventsFromDiscoveryResponseV3("xds.yaml", dr)
+	assert.Len(t, events, 2)
+	for _, ev := range events {
+		ups := ev.Object.(*apisix.Upstream)
+		if ups.Name == "httpbin.default.svc.cluster.local" {
+			assert.Len(t, ups.Nodes, 1)
+			assert.Equal(t, ups.Nodes[0].Host, "10.0.3.11")
+		}
+	}
+
+	_, err = decodeFile("xds.yml", []byte("- a\n- b\n"))
+	assert.Equal(t, err, _errNotAnObject)
+	_, err = decodeFile("xds.yml", []byte(`"@type": type.googleapis.com/envoy.config.cluster.v3.Unknown`))
+	assert.NotNil(t, err)
+}
+
+func TestDecodeFileBootstrap(t *testing.T) {
+	data := `
+admin:
+  address:
+    socket_address: { address: 127.0.0.1, port_value: 9901 }
+static_resources:
+  listeners:
+  - name: listener_0
+    address:
+      socket_address: { address: 0.0.0.0, port_value: 10000 }
+    filter_chains:
+    - filters:
+      - name: envoy.filters.network.http_connection_manager
+        typed_config:
+          "@type": type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager
+          stat_prefix: ingress_http
+          route_config:
+            name: local_route
+            virtual_hosts:
+            - name: local_service
+              domains: ["*"]
+              routes:
+              - match: { prefix: "/" }
+                route: { cluster: httpbin }
+          http_filters:
+          - name: envoy.filters.http.router
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.filters.http.router.v3.Router
+  clusters:
+  - name: httpbin
+    connect_timeout: 0.25s
+    type: STATIC
+    load_assignment:
+      cluster_name: httpbin
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address: { address: 10.0.3.11, port_value: 8000 }
+`
+	dr, err := decodeFile("envoy.yaml", []byte(data))
+	assert.Nil(t, err)
+	assert.Len(t, dr.Resources, 2)
+	assert.Equal(t, dr.Resources[0].TypeUrl, types.ListenerUrl)
+	assert.Equal(t, dr.Resources[1].TypeUrl, types.ClusterUrl)
+
+	adaptor, err := xdsv3.NewAdaptor(&config.Config{
+		LogLevel:  "debug",
+		LogOutput: "stderr",
+	})
+	assert.Nil(t, err)
+	p := &xdsFileProvisioner{
+		logger:              log.DefaultLogger,
+		v3Adaptor:           adaptor,
+		state:               make(map[string]*util.Manifest),
+		upstreamCache:       make(map[string]*apisix.Upstream),
+		listeners:           make(map[string][]*listenerv3.Listener),
+		routeConfigurations: make(map[string][]*routev3.RouteConfiguration),
+	}
+	events := p.generateEventsFromDiscoveryResponseV3("envoy.yaml", dr)
+	assert.Len(t, events, 2)
+	route := events[0].Object.(*apisix.Route)
+	assert.Equal(t, route.Name, "<anon>#local_service#local_route")
+	assert.Equal(t, route.Vars[0].Vars, []string{"connection_original_dst", "~~", "10000$"})
+	assert.Equal(t, events[1].Object.(*apisix.Upstream).Name, "httpbin")
+	assert.Equal(t, p.listenerStates.RouteOwnership, map[string]string{"local_route": "0.0.0.0:10000"})
+
+	// Typed bootstrap.
+	dr, err = decodeFile("envoy.json", []byte(`{
+  "@type": "type.googleapis.com/envoy.config.bootstrap.v3.Bootstrap",
+  "staticResources": {"clusters": [{"name": "httpbin", "type": "EDS"}]}
+}`))
+	assert.Nil(t, err)
+	assert.Len(t, dr.Resources, 1)
+	assert.Equal(t, dr.Resources[0].TypeUrl, types.ClusterUrl)
+}
+
+func TestDecodeFileRealisticBootstrap(t *testing.T) {
+	// Typed configs of extensions which are not linked in (e.g. CORS, Lua,
+	// the access loggers and the TypedStruct used by Istio) are kept as is.
+	data := `
+node:
+  id: sidecar~10.0.3.12~httpbin.default~default.svc.cluster.local
+  cluster: httpbin.default
+admin:
+  access_log_path: /dev/null
+  address:
+    socket_address: { address: 127.0.0.1, port_value: 15000 }
+static_resources:
+  listeners:
+  - name: 0.0.0.0_8080
+    address:
+      socket_address: { address: 0.0.0.0, port_value: 8080 }
+    traffic_direction: OUTBOUND
+    filter_chains:
+    - filters:
+      - name: envoy.filters.network.http_connection_manager
+        typed_config:
+          "@type": type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager
+          stat_prefix: outbound_0.0.0.0_8080
+          upgrade_configs:
+          - upgrade_type: websocket
+          access_log:
+          - name: envoy.access_loggers.file
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.access_loggers.file.v3.FileAccessLog
+              path: /dev/stdout
+              log_format:
+                text_format_source:
+                  inline_string: "[%START_TIME%] %REQ(:METHOD)% %RESPONSE_CODE%\n"
+          route_config:
+            name: "8080"
+            virtual_hosts:
+            - name: httpbin.default.svc.cluster.local:8080
+              domains: ["httpbin.default.svc.cluster.local"]
+              routes:
+              - name: default
+                match: { prefix: "/" }
+                route: { cluster: "outbound|8080||httpbin.default.svc.cluster.local" }
+          http_filters:
+          - name: istio.metadata_exchange
+            typed_config:
+              "@type": type.googleapis.com/udpa.type.v1.TypedStruct
+              type_url: type.googleapis.com/envoy.extensions.filters.http.wasm.v3.Wasm
+              value:
+                config:
+                  vm_config:
+                    runtime: envoy.wasm.runtime.null
+                    code: { local: { inline_string: envoy.wasm.metadata_exchange } }
+          - name: envoy.filters.http.health_check
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.filters.http.health_check.v3.HealthCheck
+              pass_through_mode: false
+              headers:
+              - name: ":path"
+                exact_match: /healthz
+          - name: envoy.filters.http.cors
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.filters.http.cors.v3.Cors
+          - name: envoy.filters.http.jwt_authn
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.filters.http.jwt_authn.v3.JwtAuthentication
+              providers:
+                example:
+                  issuer: https://example.com
+                  local_jwks: { inline_string: "{}" }
+          - name: envoy.filters.http.lua
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua
+              inline_code: |
+                function envoy_on_request(handle) end
+          - name: envoy.filters.http.ext_authz
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthz
+              http_service:
+                server_uri:
+                  uri: http://opa.default.svc.cluster.local:8181
+                  cluster: "outbound|8181||opa.default.svc.cluster.local"
+                  timeout: 1s
+                path_prefix: /authz
+          - name: envoy.filters.http.router
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.filters.http.router.v3.Router
+  - name: 0.0.0.0_6379
+    address:
+      socket_address: { address: 0.0.0.0, port_value: 6379 }
+    traffic_direction: OUTBOUND
+    filter_chains:
+    - filters:
+      - name: envoy.filters.network.tcp_proxy
+        typed_config:
+          "@type": type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy
+          stat_prefix: "outbound|6379||redis.default.svc.cluster.local"
+          cluster: "outbound|6379||redis.default.svc.cluster.local"
+          access_log:
+          - name: envoy.access_loggers.file
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.access_loggers.file.v3.FileAccessLog
+              path: /dev/stdout
+  clusters:
+  - name: "outbound|8080||httpbin.default.svc.cluster.local"
+    connect_timeout: 1s
+    type: STATIC
+    load_assignment:
+      cluster_name: "outbound|8080||httpbin.default.svc.cluster.local"
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address: { address: 10.0.3.11, port_value: 8080 }
+  - name: "outbound|8181||opa.default.svc.cluster.local"
+    connect_timeout: 1s
+    type: STATIC
+    load_assignment:
+      cluster_name: "outbound|8181||opa.default.svc.cluster.local"
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address: { address: 10.0.3.21, port_value: 8181 }
+  - name: "outbound|6379||redis.default.svc.cluster.local"
+    connect_timeout: 1s
+    type: STATIC
+    load_assignment:
+      cluster_name: "outbound|6379||redis.default.svc.cluster.local"
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address: { address: 10.0.3.31, port_value: 6379 }
+`
+	dr, err := decodeFile("envoy.yaml", []byte(data))
+	assert.Nil(t, err)
+	assert.Len(t, dr.Resources, 5)
+
+	var listener listenerv3.Listener
+	assert.Nil(t, dr.Resources[0].UnmarshalTo(&listener))
+	assert.Equal(t, listener.Name, "0.0.0.0_8080")
+
+	adaptor, err := xdsv3.NewAdaptor(&config.Config{
+		LogLevel:  "debug",
+		LogOutput: "stderr",
+	})
+	assert.Nil(t, err)
+	p := &xdsFileProvisioner{
+		logger:              log.DefaultLogger,
+		v3Adaptor:           adaptor,
+		state:               make(map[string]*util.Manifest),
+		upstreamCache:       make(map[string]*apisix.Upstream),
+		listeners:           make(map[string][]*listenerv3.Listener),
+		routeConfigurations: make(map[string][]*routev3.RouteConfiguration),
+	}
+	events := p.generateEventsFromDiscoveryResponseV3("envoy.yaml", dr)
+	assert.Len(t, events, 5)
+
+	// Routes are translated with the states of the listener, just like
+	// the ones from the gRPC provisioner.
+	route := events[0].Object.(*apisix.Route)
+	assert.Equal(t, route.Hosts, []string{"httpbin.default.svc.cluster.local"})
+	assert.True(t, route.EnableWebsocket)
+	assert.Equal(t, route.Plugins[apisix.ForwardAuthPlugin].AsMap()["uri"], "http://10.0.3.21:8181/authz")
+	for _, ev := range events[1:4] {
+		assert.NotNil(t, ev.Object.(*apisix.Upstream))
+	}
+	sr := events[4].Object.(*apisix.StreamRoute)
+	assert.Equal(t, sr.ServerPort, int32(6379))
+	assert.Equal(t, p.streamRoutes, []*apisix.StreamRoute{sr})
+}
@@ -1,8 +1,12 @@
 package file
 
 import (
+	"reflect"
+	"sort"
+
 	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	"github.com/golang/protobuf/ptypes/any"
 	"go.uber.org/zap"
@@ -10,10 +14,12 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 
 	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
 	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
 )
 
-func (p *xdsFileProvisioner) processRouteConfigurationV3(res *any.Any) []*apisix.Route {
+func (p *xdsFileProvisioner) processRouteConfigurationV3(res *any.Any) *routev3.RouteConfiguration {
 	var route routev3.RouteConfiguration
 	err := anypb.UnmarshalTo(res, &route, proto.UnmarshalOptions{
 		DiscardUnknown: true,
@@ -25,15 +31,133 @@ func (p *xdsFileProvisioner) processRouteConfigurationV3(res *any.Any) []*apisix
 		)
 		return nil
 	}
+	return &route
+}
+
+// translateRouteConfigurations translates the route configurations with the
+// states collected from listeners in all files.
+func (p *xdsFileProvisioner) translateRouteConfigurations(rcs []*routev3.RouteConfiguration) []*apisix.Route {
+	var routes []*apisix.Route
+	opts := p.translateOptions()
+	for _, rc := range rcs {
+		partial, err := p.v3Adaptor.TranslateRouteConfiguration(rc, opts)
+		if err != nil {
+			p.logger.Errorw("failed to translate RouteConfiguration to APISIX routes",
+				zap.Error(err),
+				zap.Any("route", rc),
+			)
+			continue
+		}
+		routes = append(routes, partial...)
+	}
+	return routes
+}
 
-	routes, err := p.v3Adaptor.TranslateRouteConfiguration(&route, nil)
+// translateOptions returns the same translate options as the gRPC provisioner
+// does, except the virtual hosts, which are only available through VHDS.
+func (p *xdsFileProvisioner) translateOptions() *xdsv3.TranslateOptions {
+	opts := &xdsv3.TranslateOptions{
+		Upstreams:   p.upstreamCache,
+		InboundPort: p.inboundPort,
+	}
+	if states := p.listenerStates; states != nil {
+		opts.RouteOriginalDestination = states.RouteOwnership
+		opts.RouteHTTPFilters = states.RouteHTTPFilters
+		opts.RouteUpgradeConfigs = states.RouteUpgradeConfigs
+		opts.RouteFilterChains = states.RouteFilterChains
+		opts.SecretServerNames = states.SecretServerNames
+		opts.InboundRoutes = states.InboundRoutes
+	}
+	return opts
+}
+
+func (p *xdsFileProvisioner) processListenerV3(res *any.Any) *listenerv3.Listener {
+	var listener listenerv3.Listener
+	err := anypb.UnmarshalTo(res, &listener, proto.UnmarshalOptions{
+		DiscardUnknown: true,
+	})
 	if err != nil {
-		p.logger.Errorw("failed to translate RouteConfiguration to APISIX routes",
+		p.logger.Errorw("found invalid Listener resource",
 			zap.Error(err),
-			zap.Any("route", &route),
+			zap.Any("resource", res),
 		)
+		return nil
 	}
-	return routes
+	sockAddr := listener.GetAddress().GetSocketAddress()
+	if sockAddr == nil || sockAddr.GetPortValue() == 0 {
+		// Only use listener which listens on socket.
+		p.logger.Warnw("ignore listener which doesn't listen on socket",
+			zap.Any("listener", &listener),
+		)
+		return nil
+	}
+	return &listener
+}
+
+// collectStaticRouteConfigurations collects the route configurations
+// embedded in the HTTP connection managers of listeners.
+func (p *xdsFileProvisioner) collectStaticRouteConfigurations(listeners []*listenerv3.Listener) []*routev3.RouteConfiguration {
+	states, err := p.v3Adaptor.CollectListenerStates(listeners, p.inboundPort)
+	if err != nil {
+		p.logger.Errorw("failed to collect route configurations from listeners",
+			zap.Error(err),
+		)
+		return nil
+	}
+	return states.StaticRouteConfigurations
+}
+
+// updateListenerStates rebuilds the listener states from listeners in all
+// files, listeners and route configurations might be in different files.
+func (p *xdsFileProvisioner) updateListenerStates() {
+	var all []*listenerv3.Listener
+	for _, filename := range sortedKeys(p.listeners) {
+		all = append(all, p.listeners[filename]...)
+	}
+	states, err := p.v3Adaptor.CollectListenerStates(all, p.inboundPort)
+	if err != nil {
+		p.logger.Errorw("failed to collect states from listeners",
+			zap.Error(err),
+		)
+		return
+	}
+	p.listenerStates = states
+}
+
+// retranslate translates routes in other files (except the given one) and
+// stream routes again, since the listener states and upstreams that they
+// depend on might be changed by the given file.
+func (p *xdsFileProvisioner) retranslate(except string) []types.Event {
+	var events []types.Event
+	for _, filename := range sortedKeys(p.routeConfigurations) {
+		rmo := p.state[filename]
+		if filename == except || rmo == nil {
+			continue
+		}
+		rm := *rmo
+		rm.Routes = p.translateRouteConfigurations(p.routeConfigurations[filename])
+		events = append(events, p.generateEvents(filename, rmo, &rm)...)
+	}
+
+	// Stream routes are translated from listeners in all files, so that
+	// the conflicting ones can be rejected.
+	var listeners []*listenerv3.Listener
+	if p.listenerStates != nil {
+		listeners = p.listenerStates.Listeners
+	}
+	srs, err := p.v3Adaptor.TranslateStreamRoutes(listeners, p.translateOptions())
+	if err != nil {
+		p.logger.Errorw("failed to translate stream routes, keep the old ones",
+			zap.Error(err),
+		)
+		return events
+	}
+	added, deleted, updated := util.DiffManifests(
+		&util.Manifest{StreamRoutes: p.streamRoutes},
+		&util.Manifest{StreamRoutes: srs},
+	)
+	p.streamRoutes = srs
+	return append(events, util.ManifestEvents(added, deleted, updated)...)
 }
 
 func (p *xdsFileProvisioner) processClusterV3(res *any.Any) []*apisix.Upstream {
@@ -109,3 +233,12 @@ func (p *xdsFileProvisioner) processClusterLoadAssignmentV3(res *any.Any) []*api
 	p.upstreamCache[cla.ClusterName] = newUps
 	return []*apisix.Upstream{newUps}
 }
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -67,7 +67,9 @@ func TestProcessRouteConfigurationV3(t *testing.T) {
 	var opaque any.Any
 	opaque.TypeUrl = "type.googleapis.com/" + string(rc.ProtoReflect().Descriptor().FullName())
 	assert.Nil(t, anypb.MarshalFrom(&opaque, rc, proto2.MarshalOptions{}))
-	routes := p.processRouteConfigurationV3(&opaque)
+	route := p.processRouteConfigurationV3(&opaque)
+	assert.NotNil(t, route)
+	routes := p.translateRouteConfigurations([]*routev3.RouteConfiguration{route})
 	assert.Len(t, routes, 1)
 }
 
@@ -0,0 +1,164 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/watcher"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+)
+
+// scanResult is the snapshot of the watched paths.
+type scanResult struct {
+	// files are the user visible paths of regular files (or symlinks to
+	// them).
+	files []string
+	// dirs maps the directories that should be watched to their resolved
+	// paths.
+	dirs map[string]string
+}
+
+// scan walks the watched paths, symlinks are followed, the paths that do
+// not exist are skipped.
+func (p *xdsFileProvisioner) scan() *scanResult {
+	res := &scanResult{
+		dirs: make(map[string]string),
+	}
+	visited := make(map[string]struct{})
+	for _, path := range p.files {
+		info, err := os.Stat(path)
+		if err != nil {
+			p.logger.Debugw("skip watched path which cannot be accessed",
+				zap.Error(err),
+				zap.String("path", path),
+			)
+			continue
+		}
+		if info.IsDir() {
+			p.scanDir(path, res, visited)
+			continue
+		}
+		res.files = append(res.files, path)
+		// The file might be a symlink which will be flipped, so the
+		// parent directory is watched instead of the file itself.
+		dir := filepath.Dir(path)
+		res.dirs[dir] = resolvePath(dir)
+	}
+	// A file might be watched both directly and through its directory.
+	sort.Strings(res.files)
+	var files []string
+	for i, file := range res.files {
+		if i == 0 || file != res.files[i-1] {
+			files = append(files, file)
+		}
+	}
+	res.files = files
+	return res
+}
+
+func (p *xdsFileProvisioner) scanDir(dir string, res *scanResult, visited map[string]struct{}) {
+	resolved := resolvePath(dir)
+	if _, ok := visited[resolved]; ok {
+		return
+	}
+	visited[resolved] = struct{}{}
+	res.dirs[dir] = resolved
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		p.logger.Warnw("failed to read directory",
+			zap.Error(err),
+			zap.String("directory", dir),
+		)
+		return
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), watcher.AtomicWriterPrefix) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info := entry
+		if entry.Mode()&os.ModeSymlink != 0 {
+			info, err = os.Stat(path)
+			if err != nil {
+				p.logger.Debugw("skip dangling symlink",
+					zap.Error(err),
+					zap.String("path", path),
+				)
+				continue
+			}
+		}
+		if info.IsDir() {
+			p.scanDir(path, res, visited)
+		} else if info.Mode().IsRegular() {
+			res.files = append(res.files, path)
+		}
+	}
+}
+
+// rescan reconciles the state with the watched paths, new directories
+// are watched, changed files are handled again and vanished files are
+// removed. Unchanged files are filtered out by the content hash. Events
+// of all files are returned in order.
+func (p *xdsFileProvisioner) rescan() []types.Event {
+	res := p.scan()
+	for dir, resolved := range res.dirs {
+		if old, ok := p.watchedDirs[dir]; ok && old == resolved {
+			continue
+		}
+		// Watch it again if the symlink was flipped.
+		if err := p.watcher.Add(dir); err != nil {
+			p.logger.Errorw("failed to watch directory",
+				zap.Error(err),
+				zap.String("directory", dir),
+			)
+			continue
+		}
+		p.watchedDirs[dir] = resolved
+	}
+	for dir := range p.watchedDirs {
+		if _, ok := res.dirs[dir]; !ok {
+			// The watch is removed automatically if the directory
+			// was deleted.
+			delete(p.watchedDirs, dir)
+		}
+	}
+
+	var events []types.Event
+	current := make(map[string]struct{}, len(res.files))
+	for _, file := range res.files {
+		current[file] = struct{}{}
+		events = append(events, p.handleFileEvent(fsnotify.Event{
+			Name: file,
+			Op:   fsnotify.Write,
+		})...)
+	}
+	var vanished []string
+	for file := range p.hashes {
+		if _, ok := current[file]; !ok {
+			vanished = append(vanished, file)
+		}
+	}
+	sort.Strings(vanished)
+	for _, file := range vanished {
+		events = append(events, p.handleFileEvent(fsnotify.Event{
+			Name: file,
+			Op:   fsnotify.Remove,
+		})...)
+	}
+	return events
+}
+
+func resolvePath(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+	return resolved
+}
@@ -0,0 +1,168 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+const _testClusterTemplate = `
+"@type": type.googleapis.com/envoy.config.cluster.v3.Cluster
+name: httpbin.default.svc.cluster.local
+type: STATIC
+load_assignment:
+  cluster_name: httpbin.default.svc.cluster.local
+  endpoints:
+  - lb_endpoints:
+    - endpoint:
+        address:
+          socket_address: { address: %s, port_value: 8000 }
+`
+
+// writeConfigMapVolume mimics how kubelet updates the ConfigMap volume:
+// files are written into a new timestamped directory, then the "..data"
+// symlink is flipped to it atomically.
+func writeConfigMapVolume(t *testing.T, dir, version string, files map[string]string) {
+	tsDir := filepath.Join(dir, "..v"+version)
+	assert.Nil(t, os.Mkdir(tsDir, 0755))
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(tsDir, name), []byte(content), 0644))
+	}
+	old, _ := os.Readlink(filepath.Join(dir, "..data"))
+	tmp := filepath.Join(dir, "..data_tmp")
+	assert.Nil(t, os.Symlink(filepath.Base(tsDir), tmp))
+	assert.Nil(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+	for name := range files {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			assert.Nil(t, os.Symlink(filepath.Join("..data", name), link))
+		}
+	}
+	if old != "" {
+		assert.Nil(t, os.RemoveAll(filepath.Join(dir, old)))
+	}
+}
+
+func receiveEvents(t *testing.T, ch <-chan []types.Event) []types.Event {
+	select {
+	case events := <-ch:
+		return events
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "no event arrived in time")
+	}
+	return nil
+}
+
+func assertNoEvents(t *testing.T, ch <-chan []types.Event) {
+	select {
+	case events := <-ch:
+		assert.FailNow(t, "unexpected events", events)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestFileProvisionerConfigMapVolume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xds-configmap")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	writeConfigMapVolume(t, dir, "1", map[string]string{
+		"cds.yaml": fmt.Sprintf(_testClusterTemplate, "10.0.3.11"),
+	})
+
+	p, err := NewXDSProvisioner(&config.Config{
+		LogLevel:      "debug",
+		LogOutput:     "stderr",
+		XDSWatchFiles: []string{dir},
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		assert.Nil(t, p.Run(stopCh))
+	}()
+
+	// Files in the timestamped directory are not handled again.
+	events := receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.11")
+
+	writeConfigMapVolume(t, dir, "2", map[string]string{
+		"cds.yaml": fmt.Sprintf(_testClusterTemplate, "10.0.3.12"),
+	})
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.12")
+
+	// New directory and file after the startup.
+	sub := filepath.Join(dir, "routes")
+	assert.Nil(t, os.Mkdir(sub, 0755))
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(sub, "rds.yaml"), []byte(`
+"@type": type.googleapis.com/envoy.config.route.v3.RouteConfiguration
+name: rc1
+virtual_hosts:
+- name: httpbin
+  domains: ["*"]
+  routes:
+  - match: { prefix: "/" }
+    route: { cluster: httpbin.default.svc.cluster.local }
+`), 0644))
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Equal(t, events[0].Object.(*apisix.Route).Name, "<anon>#httpbin#rc1")
+
+	// No duplicated events for the same content.
+	writeConfigMapVolume(t, dir, "3", map[string]string{
+		"cds.yaml": fmt.Sprintf(_testClusterTemplate, "10.0.3.12"),
+	})
+	assertNoEvents(t, p.Channel())
+
+	assert.Nil(t, os.RemoveAll(sub))
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventDelete)
+	assert.Equal(t, events[0].Tombstone.(*apisix.Route).Name, "<anon>#httpbin#rc1")
+	assertNoEvents(t, p.Channel())
+}
+
+func TestFileProvisionerScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xds-scan")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	writeConfigMapVolume(t, dir, "1", map[string]string{
+		"cds.yaml": "",
+		"rds.yaml": "",
+	})
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "sub", "eds.yaml"), nil, 0644))
+	// Loop.
+	assert.Nil(t, os.Symlink("..", filepath.Join(dir, "sub", "loop")))
+	assert.Nil(t, os.Symlink("missing.yaml", filepath.Join(dir, "dangling.yaml")))
+
+	p := &xdsFileProvisioner{
+		logger: log.DefaultLogger,
+		files:  []string{dir, filepath.Join(dir, "cds.yaml"), filepath.Join(dir, "missing")},
+	}
+	res := p.scan()
+	assert.Equal(t, res.files, []string{
+		filepath.Join(dir, "cds.yaml"),
+		filepath.Join(dir, "rds.yaml"),
+		filepath.Join(dir, "sub", "eds.yaml"),
+	})
+	assert.Len(t, res.dirs, 2)
+	assert.Contains(t, res.dirs, dir)
+	assert.Contains(t, res.dirs, filepath.Join(dir, "sub"))
+}
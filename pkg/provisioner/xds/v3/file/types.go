@@ -1,15 +1,18 @@
 package file
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io/ioutil"
 	"os"
-	"path/filepath"
+	"time"
 
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/fsnotify/fsnotify"
 	"go.uber.org/zap"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 
 	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
@@ -17,6 +20,7 @@ import (
 	"github.com/api7/apisix-mesh-agent/pkg/log"
 	"github.com/api7/apisix-mesh-agent/pkg/provisioner"
 	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/watcher"
 	"github.com/api7/apisix-mesh-agent/pkg/types"
 	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
 )
@@ -30,6 +34,22 @@ type xdsFileProvisioner struct {
 	state                   map[string]*util.Manifest
 	upstreamCache           map[string]*apisix.Upstream
 	updatedUpstreamsFromEDS map[string][]*apisix.Upstream
+	// listeners and routeConfigurations are indexed by the filename,
+	// route configurations include the static ones in listeners.
+	listeners           map[string][]*listenerv3.Listener
+	routeConfigurations map[string][]*routev3.RouteConfiguration
+	// listenerStates are collected from listeners in all files, routes
+	// are translated with them even if the route configurations are in
+	// other files.
+	listenerStates *xdsv3.ListenerStates
+	// streamRoutes are translated from listeners in all files.
+	streamRoutes []*apisix.StreamRoute
+	inboundPort  int
+	// hashes records the content hash of the handled files, so that
+	// files won't be handled repeatedly during the rescan.
+	hashes      map[string]string
+	watchedDirs map[string]string
+	debounce    time.Duration
 }
 
 // NewXDSProvisioner creates a files backed Provisioner, it watches
@@ -37,13 +57,15 @@ type xdsFileProvisioner struct {
 // invalid items will be ignored but leave with a log.
 // Note files watched by this Provisioner should be in the format DiscoveryResponse
 // (see https://github.com/envoyproxy/data-plane-api/blob/main/envoy/service/discovery/v3/discovery.proto#L68
-// for more details).
-// Currently only JSON are suppported as the file type and only xDS V3 are supported.
+// for more details), Envoy Bootstrap with static resources, or bare xDS
+// resources typed by the "@type" field.
+// Both JSON and YAML (files with the .yaml or .yml extension) are supported,
+// a file might contain several documents. Only xDS V3 are supported.
 func NewXDSProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
 	if len(cfg.XDSWatchFiles) == 0 {
 		return nil, errors.New("xds-v3-file provisioner: no watch files")
 	}
-	watcher, err := fsnotify.NewWatcher()
+	fw, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +82,7 @@ func NewXDSProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
 		return nil, err
 	}
 	p := &xdsFileProvisioner{
-		watcher:                 watcher,
+		watcher:                 fw,
 		logger:                  logger,
 		v3Adaptor:               adaptor,
 		evChan:                  make(chan []types.Event),
@@ -68,6 +90,13 @@ func NewXDSProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
 		state:                   make(map[string]*util.Manifest),
 		upstreamCache:           make(map[string]*apisix.Upstream),
 		updatedUpstreamsFromEDS: make(map[string][]*apisix.Upstream),
+		listeners:               make(map[string][]*listenerv3.Listener),
+		routeConfigurations:     make(map[string][]*routev3.RouteConfiguration),
+		listenerStates:          &xdsv3.ListenerStates{},
+		inboundPort:             cfg.InboundPort,
+		hashes:                  make(map[string]string),
+		watchedDirs:             make(map[string]string),
+		debounce:                cfg.XDSWatchDebounce,
 	}
 	return p, nil
 }
@@ -77,17 +106,23 @@ func (p *xdsFileProvisioner) Run(stop chan struct{}) error {
 	defer p.logger.Infow("xds v3 file provisioner exited")
 	defer close(p.evChan)
 
-	if err := p.handleInitialFileEvents(); err != nil {
+	pending, err := p.handleInitialFileEvents()
+	if err != nil {
 		return err
 	}
 
-	for _, file := range p.files {
-		if err := p.watcher.Add(file); err != nil {
-			return err
-		}
-	}
-
+	var (
+		debounceCh  <-chan time.Time
+		firstChange time.Time
+	)
 	for {
+		// Events are sent in this goroutine so that the order is kept,
+		// and events generated before the last batch was received are
+		// coalesced into it.
+		var sendCh chan<- []types.Event
+		if len(pending) > 0 {
+			sendCh = p.evChan
+		}
 		select {
 		case <-stop:
 			if err := p.watcher.Close(); err != nil {
@@ -101,64 +136,55 @@ func (p *xdsFileProvisioner) Run(stop chan struct{}) error {
 				zap.Error(err),
 			)
 		case ev := <-p.watcher.Events:
-			switch ev.Op {
-			case fsnotify.Create, fsnotify.Write, fsnotify.Remove:
-				p.logger.Infow("file change event arrived",
-					zap.String("filename", ev.Name),
-					zap.String("type", ev.Op.String()),
-				)
-			default:
+			if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
 				p.logger.Debugw("ignore unnecessary file change event",
 					zap.String("filename", ev.Name),
 					zap.String("type", ev.Op.String()),
 				)
 				continue
 			}
-			p.handleFileEvent(ev)
+			p.logger.Infow("file change event arrived",
+				zap.String("filename", ev.Name),
+				zap.String("type", ev.Op.String()),
+			)
+			// Files might be added, renamed or replaced by symlink
+			// flips (e.g. ConfigMap volumes), which cannot be told
+			// from the event itself, so all watched paths are scanned.
+			// The scan is delayed until no more events arrived in the
+			// debounce window, so that partially written files won't
+			// be parsed during a burst of writes, but changes are not
+			// delayed longer than watcher.MaxDebounceWindows windows.
+			if p.debounce > 0 {
+				if debounceCh == nil {
+					firstChange = time.Now()
+				}
+				debounceCh = time.After(watcher.DebounceDelay(p.debounce, firstChange))
+			} else {
+				pending = append(pending, p.rescan()...)
+			}
+		case <-debounceCh:
+			debounceCh = nil
+			pending = append(pending, p.rescan()...)
+		case sendCh <- pending:
+			pending = nil
 		}
 	}
 }
 
-func (p *xdsFileProvisioner) handleInitialFileEvents() error {
-	var files []string
-
+func (p *xdsFileProvisioner) handleInitialFileEvents() ([]types.Event, error) {
 	for _, file := range p.files {
-		info, err := os.Stat(file)
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			files = append(files, file)
-		} else {
-			err = filepath.Walk(file, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					return err
-				}
-				if info.IsDir() {
-					return nil
-				}
-				files = append(files, path)
-				return nil
-			})
-			if err != nil {
-				return err
-			}
+		if _, err := os.Stat(file); err != nil {
+			return nil, err
 		}
 	}
-	for _, file := range files {
-		p.handleFileEvent(fsnotify.Event{
-			Name: file,
-			Op:   fsnotify.Write,
-		})
-	}
-	return nil
+	return p.rescan(), nil
 }
 
 func (p *xdsFileProvisioner) Channel() <-chan []types.Event {
 	return p.evChan
 }
 
-func (p *xdsFileProvisioner) handleFileEvent(ev fsnotify.Event) {
+func (p *xdsFileProvisioner) handleFileEvent(ev fsnotify.Event) []types.Event {
 	var (
 		events []types.Event
 	)
@@ -170,20 +196,30 @@ func (p *xdsFileProvisioner) handleFileEvent(ev fsnotify.Event) {
 				zap.String("filename", ev.Name),
 				zap.String("type", ev.Op.String()),
 			)
-			return
+			return nil
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if p.hashes[ev.Name] == hash {
+			p.logger.Debugw("ignore unchanged file",
+				zap.String("filename", ev.Name),
+			)
+			return nil
 		}
+		p.hashes[ev.Name] = hash
 
-		var dr discoveryv3.DiscoveryResponse
-		if err := protojson.Unmarshal(data, &dr); err != nil {
+		dr, err := decodeFile(ev.Name, data)
+		if err != nil {
 			p.logger.Errorw("failed to unmarshal file",
 				zap.Error(err),
 				zap.String("filename", ev.Name),
 				zap.String("type", ev.Op.String()),
 			)
-			return
+			return nil
 		}
-		events = p.generateEventsFromDiscoveryResponseV3(ev.Name, &dr)
+		events = p.generateEventsFromDiscoveryResponseV3(ev.Name, dr)
 	} else {
+		delete(p.hashes, ev.Name)
 		rmo, ok := p.state[ev.Name]
 		if ok {
 			events = p.generateEvents(ev.Name, rmo, nil)
@@ -200,14 +236,14 @@ func (p *xdsFileProvisioner) handleFileEvent(ev fsnotify.Event) {
 			}
 			delete(p.updatedUpstreamsFromEDS, ev.Name)
 		}
+		delete(p.routeConfigurations, ev.Name)
+		if _, ok := p.listeners[ev.Name]; ok {
+			delete(p.listeners, ev.Name)
+			p.updateListenerStates()
+		}
+		events = append(events, p.retranslate(ev.Name)...)
 	}
-
-	// Send events in another goroutine to avoid blocking the watch.
-	if len(events) > 0 {
-		go func() {
-			p.evChan <- events
-		}()
-	}
+	return events
 }
 
 func (p *xdsFileProvisioner) generateEventsFromDiscoveryResponseV3(filename string, dr *discoveryv3.DiscoveryResponse) []types.Event {
@@ -217,11 +253,19 @@ func (p *xdsFileProvisioner) generateEventsFromDiscoveryResponseV3(filename stri
 	var (
 		rm               util.Manifest
 		updatedUpstreams []*apisix.Upstream
+		rcs              []*routev3.RouteConfiguration
+		listeners        []*listenerv3.Listener
 	)
 	for _, res := range dr.GetResources() {
 		switch res.GetTypeUrl() {
 		case types.RouteConfigurationUrl:
-			rm.Routes = append(rm.Routes, p.processRouteConfigurationV3(res)...)
+			if rc := p.processRouteConfigurationV3(res); rc != nil {
+				rcs = append(rcs, rc)
+			}
+		case types.ListenerUrl:
+			if listener := p.processListenerV3(res); listener != nil {
+				listeners = append(listeners, listener)
+			}
 		case types.ClusterUrl:
 			rm.Upstreams = append(rm.Upstreams, p.processClusterV3(res)...)
 		case types.ClusterLoadAssignmentUrl:
@@ -256,7 +300,25 @@ func (p *xdsFileProvisioner) generateEventsFromDiscoveryResponseV3(filename stri
 			)
 		}
 	}
+	rcs = append(rcs, p.collectStaticRouteConfigurations(listeners)...)
+	if len(rcs) > 0 {
+		p.routeConfigurations[filename] = rcs
+	} else {
+		delete(p.routeConfigurations, filename)
+	}
+	_, hadListeners := p.listeners[filename]
+	if len(listeners) > 0 {
+		p.listeners[filename] = listeners
+	} else {
+		delete(p.listeners, filename)
+	}
+	if hadListeners || len(listeners) > 0 {
+		p.updateListenerStates()
+	}
+	rm.Routes = p.translateRouteConfigurations(rcs)
+
 	evs := p.generateEvents(filename, p.state[filename], &rm)
+	evs = append(evs, p.retranslate(filename)...)
 
 	if len(updatedUpstreams) > 0 {
 		updatedUpstreamsFromEDS := p.updatedUpstreamsFromEDS[filename]
@@ -283,18 +345,7 @@ func (p *xdsFileProvisioner) generateEventsFromDiscoveryResponseV3(filename stri
 }
 
 func (p *xdsFileProvisioner) generateEvents(filename string, rmo, rm *util.Manifest) []types.Event {
-	var (
-		added   *util.Manifest
-		deleted *util.Manifest
-		updated *util.Manifest
-	)
-	if rmo == nil {
-		added = rm
-	} else if rm == nil {
-		deleted = rmo
-	} else {
-		added, deleted, updated = rmo.DiffFrom(rm)
-	}
+	added, deleted, updated := util.DiffManifests(rmo, rm)
 	p.logger.Debugw("found changes (after converting to APISIX resources) in xds file",
 		zap.String("filename", filename),
 		zap.Any("added", added),
@@ -302,29 +353,5 @@ func (p *xdsFileProvisioner) generateEvents(filename string, rmo, rm *util.Manif
 		zap.Any("deleted", deleted),
 	)
 	p.state[filename] = rm
-
-	var count int
-	if added != nil {
-		count += added.Size()
-	}
-	if deleted != nil {
-		count += deleted.Size()
-	}
-	if updated != nil {
-		count += updated.Size()
-	}
-	if count == 0 {
-		return nil
-	}
-	events := make([]types.Event, 0, count)
-	if added != nil {
-		events = append(events, added.Events(types.EventAdd)...)
-	}
-	if deleted != nil {
-		events = append(events, deleted.Events(types.EventDelete)...)
-	}
-	if updated != nil {
-		events = append(events, updated.Events(types.EventUpdate)...)
-	}
-	return events
+	return util.ManifestEvents(added, deleted, updated)
 }
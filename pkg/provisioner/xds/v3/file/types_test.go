@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/fsnotify/fsnotify"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/stretchr/testify/assert"
@@ -182,10 +184,11 @@ func TestFileProvisionerGenerateEventsFromDiscoveryResponse(t *testing.T) {
 	adaptor, err := xdsv3.NewAdaptor(cfg)
 	assert.Nil(t, err)
 	p := &xdsFileProvisioner{
-		logger:        log.DefaultLogger,
-		v3Adaptor:     adaptor,
-		state:         make(map[string]*util.Manifest),
-		upstreamCache: make(map[string]*apisix.Upstream),
+		logger:              log.DefaultLogger,
+		v3Adaptor:           adaptor,
+		state:               make(map[string]*util.Manifest),
+		upstreamCache:       make(map[string]*apisix.Upstream),
+		routeConfigurations: make(map[string][]*routev3.RouteConfiguration),
 	}
 	events := p.generateEventsFromDiscoveryResponseV3("null", dr)
 	assert.Len(t, events, 2)
@@ -223,40 +226,22 @@ func TestFileProvisionerHandleFileEvent(t *testing.T) {
 	case <-time.After(2 * time.Second):
 		t.Fatal("no event arrived in time")
 	}
-	assert.Len(t, events, 1)
-	assert.Equal(t, events[0].Type, types.EventAdd)
-	assert.Nil(t, events[0].Tombstone)
-
-	switch obj := events[0].Object.(type) {
-	case *apisix.Route:
-		assert.Equal(t, obj.Uris[0], "/foo")
-		assert.Equal(t, obj.Name, "route1#vhost1#rc1")
-		assert.Equal(t, obj.UpstreamId, id.GenID("kubernetes.default.svc.cluster.local"))
-		assert.Equal(t, obj.Status, apisix.Route_Enable)
-	case *apisix.Upstream:
-		assert.Len(t, obj.Nodes, 0)
-		assert.Equal(t, obj.Name, "httpbin.default.svc.cluster.local")
-	}
-
-	select {
-	case events = <-evCh:
-		break
-	case <-time.After(2 * time.Second):
-		t.Fatal("no event arrived in time")
-	}
-	assert.Len(t, events, 1)
-	assert.Equal(t, events[0].Type, types.EventAdd)
-	assert.Nil(t, events[0].Tombstone)
+	// Events of all files are coalesced into one batch.
+	assert.Len(t, events, 2)
+	for _, ev := range events {
+		assert.Equal(t, ev.Type, types.EventAdd)
+		assert.Nil(t, ev.Tombstone)
 
-	switch obj := events[0].Object.(type) {
-	case *apisix.Route:
-		assert.Equal(t, obj.Uris[0], "/foo")
-		assert.Equal(t, obj.Name, "route1#vhost1#rc1")
-		assert.Equal(t, obj.UpstreamId, id.GenID("kubernetes.default.svc.cluster.local"))
-		assert.Equal(t, obj.Status, apisix.Route_Enable)
-	case *apisix.Upstream:
-		assert.Len(t, obj.Nodes, 0)
-		assert.Equal(t, obj.Name, "httpbin.default.svc.cluster.local")
+		switch obj := ev.Object.(type) {
+		case *apisix.Route:
+			assert.Equal(t, obj.Uris[0], "/foo")
+			assert.Equal(t, obj.Name, "route1#vhost1#rc1")
+			assert.Equal(t, obj.UpstreamId, id.GenID("kubernetes.default.svc.cluster.local"))
+			assert.Equal(t, obj.Status, apisix.Route_Enable)
+		case *apisix.Upstream:
+			assert.Len(t, obj.Nodes, 0)
+			assert.Equal(t, obj.Name, "httpbin.default.svc.cluster.local")
+		}
 	}
 
 	eds := `
@@ -321,3 +306,161 @@ func TestFileProvisionerHandleFileEvent(t *testing.T) {
 	_, ok := <-evCh
 	assert.Equal(t, ok, false)
 }
+
+func TestFileProvisionerRouteOwnership(t *testing.T) {
+	rds := `
+"@type": type.googleapis.com/envoy.config.route.v3.RouteConfiguration
+name: "8080"
+virtual_hosts:
+- name: httpbin
+  domains: ["*"]
+  routes:
+  - match: { prefix: "/" }
+    route: { cluster: httpbin }
+`
+	lds := `
+"@type": type.googleapis.com/envoy.config.listener.v3.Listener
+name: 0.0.0.0_8080
+address:
+  socket_address: { address: 0.0.0.0, port_value: 8080 }
+filter_chains:
+- filters:
+  - name: envoy.filters.network.http_connection_manager
+    typed_config:
+      "@type": type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager
+      rds:
+        route_config_name: "8080"
+`
+	dir, err := ioutil.TempDir("", "xds-files")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	rdsFile := filepath.Join(dir, "rds.yaml")
+	ldsFile := filepath.Join(dir, "lds.yaml")
+	assert.Nil(t, ioutil.WriteFile(rdsFile, []byte(rds), 0644))
+	assert.Nil(t, ioutil.WriteFile(ldsFile, []byte(lds), 0644))
+
+	p, err := NewXDSProvisioner(&config.Config{
+		LogLevel:      "debug",
+		LogOutput:     "stderr",
+		XDSWatchFiles: []string{dir},
+	})
+	assert.Nil(t, err)
+	fp := p.(*xdsFileProvisioner)
+
+	events := fp.handleFileEvent(fsnotify.Event{Name: rdsFile, Op: fsnotify.Write})
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Len(t, events[0].Object.(*apisix.Route).Vars, 0)
+
+	// Routes in another file are patched once the listener arrives.
+	events = fp.handleFileEvent(fsnotify.Event{Name: ldsFile, Op: fsnotify.Write})
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Route).Vars[0].Vars, []string{"connection_original_dst", "~~", "8080$"})
+	assert.Equal(t, fp.state[rdsFile].Routes, []*apisix.Route{events[0].Object.(*apisix.Route)})
+
+	// Unchanged.
+	assert.Nil(t, fp.handleFileEvent(fsnotify.Event{Name: ldsFile, Op: fsnotify.Write}))
+	assert.Len(t, fp.listeners[ldsFile], 1)
+
+	assert.Nil(t, os.Remove(ldsFile))
+	events = fp.handleFileEvent(fsnotify.Event{Name: ldsFile, Op: fsnotify.Remove})
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Len(t, events[0].Object.(*apisix.Route).Vars, 0)
+	assert.Len(t, fp.listeners, 0)
+	assert.Len(t, fp.listenerStates.RouteOwnership, 0)
+}
+
+func TestFileProvisionerDebounce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xds-debounce")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	p, err := NewXDSProvisioner(&config.Config{
+		LogLevel:         "debug",
+		LogOutput:        "stderr",
+		XDSWatchFiles:    []string{dir},
+		XDSWatchDebounce: 200 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		assert.Nil(t, p.Run(stopCh))
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// A burst of writes, the partially written file is not parsed.
+	filename := filepath.Join(dir, "cds.yaml")
+	content := fmt.Sprintf(_testClusterTemplate, "10.0.3.11")
+	f, err := os.Create(filename)
+	assert.Nil(t, err)
+	for i := 0; i < len(content); i += 64 {
+		end := i + 64
+		if end > len(content) {
+			end = len(content)
+		}
+		_, err = f.WriteString(content[i:end])
+		assert.Nil(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, f.Close())
+
+	events := receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.11")
+	assertNoEvents(t, p.Channel())
+
+	// Changes are coalesced into the pending batch in order.
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(fmt.Sprintf(_testClusterTemplate, "10.0.3.12")), 0644))
+	time.Sleep(400 * time.Millisecond)
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(fmt.Sprintf(_testClusterTemplate, "10.0.3.13")), 0644))
+	time.Sleep(400 * time.Millisecond)
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 2)
+	for i, host := range []string{"10.0.3.12", "10.0.3.13"} {
+		assert.Equal(t, events[i].Type, types.EventUpdate)
+		assert.Equal(t, events[i].Object.(*apisix.Upstream).Nodes[0].Host, host)
+	}
+	assertNoEvents(t, p.Channel())
+}
+
+func TestFileProvisionerDebounceMaxDelay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xds-debounce")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	p, err := NewXDSProvisioner(&config.Config{
+		LogLevel:         "debug",
+		LogOutput:        "stderr",
+		XDSWatchFiles:    []string{dir},
+		XDSWatchDebounce: 50 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		assert.Nil(t, p.Run(stopCh))
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// Files keep changing within the debounce window, they're scanned
+	// once the changes have been pending for the max delay.
+	filename := filepath.Join(dir, "cds.yaml")
+	start := time.Now()
+	deadline := start.Add(3 * time.Second)
+	for i := 0; time.Now().Before(deadline); i++ {
+		content := fmt.Sprintf(_testClusterTemplate, fmt.Sprintf("10.0.3.%d", i%200+1))
+		assert.Nil(t, ioutil.WriteFile(filename, []byte(content), 0644))
+		select {
+		case events := <-p.Channel():
+			assert.True(t, time.Since(start) < 2*time.Second)
+			assert.Equal(t, events[0].Type, types.EventAdd)
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	assert.FailNow(t, "scan is postponed by continuous changes")
+}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	_minReconnectDelay = 500 * time.Millisecond
+	_maxReconnectDelay = 30 * time.Second
+	_dialTimeout       = 10 * time.Second
+)
+
+// backoff generates exponentially increasing delays with jitter, so that
+// sidecars won't reconnect to the config source at the same time.
+type backoff struct {
+	min     time.Duration
+	max     time.Duration
+	attempt uint
+	rand    *rand.Rand
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{
+		min:  min,
+		max:  max,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Next returns the delay before the next attempt, the delay is picked
+// randomly in [d/2, d), where d is min * 2^attempt, and capped by max.
+func (b *backoff) Next() time.Duration {
+	d := b.max
+	// Avoid overflow.
+	if b.attempt < 32 {
+		if exp := b.min << b.attempt; exp > 0 && exp < b.max {
+			d = exp
+		}
+	}
+	b.attempt++
+	half := d / 2
+	return half + time.Duration(b.rand.Int63n(int64(d-half)))
+}
+
+// Reset resets the delay to the minimum one.
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
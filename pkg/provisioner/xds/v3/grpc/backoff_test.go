@@ -0,0 +1,228 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/nettest"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+)
+
+func TestBackoff(t *testing.T) {
+	bo := newBackoff(time.Second, 10*time.Second)
+	for _, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		d := bo.Next()
+		assert.True(t, d >= max/2 && d < max, "delay %s should be in [%s, %s)", d, max/2, max)
+	}
+	for i := 0; i < 100; i++ {
+		d := bo.Next()
+		assert.True(t, d >= 5*time.Second && d < 10*time.Second)
+	}
+	bo.Reset()
+	d := bo.Next()
+	assert.True(t, d >= 500*time.Millisecond && d < time.Second)
+}
+
+func TestInitialRequests(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+
+	drs := gp.initialRequests()
+	assert.Len(t, drs, 2)
+	assert.Equal(t, drs[0].TypeUrl, types.ListenerUrl)
+	assert.Equal(t, drs[0].VersionInfo, "")
+	assert.Equal(t, drs[1].TypeUrl, types.ClusterUrl)
+
+	gp.acceptedVersions[types.ListenerUrl] = "3"
+	gp.acceptedVersions[types.ClusterUrl] = "4"
+	gp.acceptedVersions[types.RouteConfigurationUrl] = "5"
+	gp.acceptedVersions[types.ClusterLoadAssignmentUrl] = "6"
+	gp.rdsNames = []string{"rc1", "rc2"}
+	gp.edsRequiredClusters.Add("c2")
+	gp.edsRequiredClusters.Add("c1")
+	drs = gp.initialRequests()
+	assert.Len(t, drs, 4)
+	assert.Equal(t, drs[0].VersionInfo, "3")
+	assert.Equal(t, drs[1].VersionInfo, "4")
+	assert.Equal(t, drs[2].TypeUrl, types.RouteConfigurationUrl)
+	assert.Equal(t, drs[2].VersionInfo, "5")
+	assert.Equal(t, drs[2].ResourceNames, []string{"rc1", "rc2"})
+	assert.Equal(t, drs[3].TypeUrl, types.ClusterLoadAssignmentUrl)
+	assert.Equal(t, drs[3].VersionInfo, "6")
+	assert.Equal(t, drs[3].ResourceNames, []string{"c1", "c2"})
+	for _, dr := range drs {
+		assert.Equal(t, dr.ResponseNonce, "")
+		assert.Equal(t, dr.Node, gp.node)
+	}
+}
+
+func TestRunWithUnreachableConfigSource(t *testing.T) {
+	ln, err := nettest.NewLocalListener("tcp")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	assert.Nil(t, ln.Close())
+
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://" + addr,
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+
+	stopCh := make(chan struct{})
+	errCh := make(chan error)
+	go func() {
+		errCh <- p.Run(stopCh)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	close(stopCh)
+	select {
+	case err := <-errCh:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "provisioner is not stopped in time")
+	}
+}
+
+// brokenXdsServer breaks the stream once a value is sent to the breakCh.
+type brokenXdsServer struct {
+	fakeXdsServer
+	breakCh chan struct{}
+}
+
+func (srv *brokenXdsServer) StreamAggregatedResources(stream discoveryv3.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error {
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			srv.recvCh <- req
+		}
+	}()
+	for {
+		select {
+		case resp := <-srv.sendCh:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		case <-srv.breakCh:
+			return errors.New("stream broken")
+		case <-srv.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func TestGRPCProvisionerReconnect(t *testing.T) {
+	ln, err := nettest.NewLocalListener("tcp")
+	assert.Nil(t, err)
+	grpcSrv := grpc.NewServer()
+	go func() {
+		err := grpcSrv.Serve(ln)
+		assert.Nil(t, err)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &brokenXdsServer{
+		fakeXdsServer: fakeXdsServer{
+			t:      t,
+			sendCh: make(chan *discoveryv3.DiscoveryResponse),
+			recvCh: make(chan *discoveryv3.DiscoveryRequest),
+			ctx:    ctx,
+		},
+		breakCh: make(chan struct{}),
+	}
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(grpcSrv, srv)
+
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://" + ln.Addr().String(),
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+
+	stopCh := make(chan struct{})
+	go func() {
+		err := p.Run(stopCh)
+		assert.Nil(t, err)
+	}()
+	<-srv.recvCh
+	<-srv.recvCh
+
+	val, err := proto.Marshal(&clusterv3.Cluster{
+		Name: "httpbin.default.svc.cluster.local",
+	})
+	assert.Nil(t, err)
+	srv.sendCh <- &discoveryv3.DiscoveryResponse{
+		VersionInfo: "7",
+		Nonce:       "1",
+		TypeUrl:     types.ClusterUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.ClusterUrl,
+				Value:   val,
+			},
+		},
+	}
+	<-gp.evChan
+	ack := <-srv.recvCh
+	assert.Equal(t, ack.VersionInfo, "7")
+
+	srv.breakCh <- struct{}{}
+	urls := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		select {
+		case dr := <-srv.recvCh:
+			assert.Equal(t, dr.ResponseNonce, "")
+			urls[dr.TypeUrl] = dr.VersionInfo
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "provisioner doesn't reconnect in time")
+		}
+	}
+	assert.Equal(t, urls, map[string]string{
+		types.ListenerUrl: "",
+		types.ClusterUrl:  "7",
+	})
+	// The last state is kept.
+	assert.Len(t, gp.upstreams, 1)
+	close(stopCh)
+}
@@ -0,0 +1,283 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"go.uber.org/zap"
+	grpcp "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types/istio"
+)
+
+const (
+	// _workloadSecretName is the secret name of the workload certificate,
+	// it's the name that Istio uses in the TLS contexts.
+	_workloadSecretName = "default"
+	// The certificate will be rotated once this ratio of its lifetime
+	// passed.
+	_certRotationRatio = 0.5
+	// The minimum delay before rotating the certificate, so that the CA
+	// won't be flooded if it issues short-lived certificates.
+	_minCertRotationDelay = time.Second
+	_caRequestTimeout     = 10 * time.Second
+)
+
+var (
+	_errEmptyCertChain = errors.New("empty certificate chain")
+	_errBadCertificate = errors.New("bad PEM encoded certificate")
+)
+
+// certManager requests the workload certificate from the Istio CA, and
+// rotates it before it expires. The certificate is delivered as the
+// secret named "default", just like what the pilot-agent does for Envoy.
+type certManager struct {
+	caAddress   string
+	dialOptions []grpcp.DialOption
+	// The SPIFFE identity of the workload.
+	identity  string
+	ttl       time.Duration
+	clusterId string
+	podName   string
+	ipAddress string
+	logger    *log.Logger
+
+	secretCh chan *tlsv3.Secret
+}
+
+func newCertManager(cfg *config.Config, logger *log.Logger) (*certManager, error) {
+	var (
+		addr        string
+		dialOptions []grpcp.DialOption
+	)
+	secure := strings.HasPrefix(cfg.CAAddress, "grpcs://")
+	if secure {
+		addr = strings.TrimPrefix(cfg.CAAddress, "grpcs://")
+		files := &tlsFiles{
+			caFile: cfg.CARootCertFile,
+			logger: logger,
+		}
+		tlsCfg, err := files.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		dialOptions = append(dialOptions, grpcp.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	} else {
+		addr = strings.TrimPrefix(cfg.CAAddress, "grpc://")
+		dialOptions = append(dialOptions, grpcp.WithInsecure())
+	}
+	if cfg.CATokenFile != "" {
+		dialOptions = append(dialOptions, grpcp.WithPerRPCCredentials(&tokenCredentials{
+			file:       cfg.CATokenFile,
+			requireTLS: secure,
+			logger:     logger,
+		}))
+	}
+
+	ttl := cfg.WorkloadCertTTL
+	if ttl == 0 {
+		ttl = config.DefaultWorkloadCertTTL
+	}
+	trustDomain := cfg.TrustDomain
+	if trustDomain == "" {
+		trustDomain = config.DefaultTrustDomain
+	}
+	var (
+		namespace      string
+		serviceAccount string
+		podName        string
+		ipAddress      string
+	)
+	if rc := cfg.RunningContext; rc != nil {
+		namespace = rc.PodNamespace
+		serviceAccount = rc.ServiceAccount
+		podName = rc.PodName
+		ipAddress = rc.IPAddress
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	return &certManager{
+		caAddress:   addr,
+		dialOptions: dialOptions,
+		identity:    fmt.Sprintf("spiffe://%s/ns/%s/sa/%s", trustDomain, namespace, serviceAccount),
+		ttl:         ttl,
+		clusterId:   cfg.ClusterId,
+		podName:     podName,
+		ipAddress:   ipAddress,
+		logger:      logger,
+		secretCh:    make(chan *tlsv3.Secret),
+	}, nil
+}
+
+// channel returns the channel which delivers the issued (and rotated)
+// workload certificates.
+func (cm *certManager) channel() <-chan *tlsv3.Secret {
+	return cm.secretCh
+}
+
+// run requests the workload certificate and rotates it periodically, it
+// retries with backoff if the request fails.
+func (cm *certManager) run(stop chan struct{}) {
+	bo := newBackoff(_minReconnectDelay, _maxReconnectDelay)
+	for {
+		var delay time.Duration
+		secret, rotateAt, err := cm.issue()
+		if err != nil {
+			delay = bo.Next()
+			cm.logger.Errorw("failed to request workload certificate, retrying",
+				zap.Error(err),
+				zap.String("ca_address", cm.caAddress),
+				zap.Duration("delay", delay),
+			)
+		} else {
+			bo.Reset()
+			delay = time.Until(rotateAt)
+			if delay < _minCertRotationDelay {
+				delay = _minCertRotationDelay
+			}
+			cm.logger.Infow("workload certificate issued",
+				zap.String("identity", cm.identity),
+				zap.Time("rotate_at", rotateAt),
+			)
+			select {
+			case <-stop:
+				return
+			case cm.secretCh <- secret:
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// issue generates a new private key and requests the CA to sign it, the
+// time to rotate the certificate is also returned.
+func (cm *certManager) issue() (*tlsv3.Secret, time.Time, error) {
+	csr, key, err := cm.generateCSR()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	metadata, err := structpb.NewStruct(map[string]interface{}{
+		"ClusterID":    cm.clusterId,
+		"WorkloadName": cm.podName,
+		"WorkloadIP":   cm.ipAddress,
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), _caRequestTimeout)
+	defer cancel()
+	opts := append([]grpcp.DialOption{grpcp.WithBlock()}, cm.dialOptions...)
+	conn, err := grpcp.DialContext(ctx, cm.caAddress, opts...)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			cm.logger.Warnw("failed to close gRPC connection to CA",
+				zap.Error(err),
+				zap.String("ca_address", cm.caAddress),
+			)
+		}
+	}()
+	resp, err := istio.NewIstioCertificateServiceClient(conn).CreateCertificate(ctx, &istio.IstioCertificateRequest{
+		Csr:              string(csr),
+		ValidityDuration: int64(cm.ttl / time.Second),
+		Metadata:         metadata,
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(resp.GetCertChain()) == 0 {
+		return nil, time.Time{}, _errEmptyCertChain
+	}
+	leaf, err := parseCertificate(resp.GetCertChain()[0])
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	rotateAt := leaf.NotBefore.Add(time.Duration(float64(lifetime) * _certRotationRatio))
+
+	var chain strings.Builder
+	for _, cert := range resp.GetCertChain() {
+		chain.WriteString(cert)
+		if !strings.HasSuffix(cert, "\n") {
+			chain.WriteString("\n")
+		}
+	}
+	secret := &tlsv3.Secret{
+		Name: _workloadSecretName,
+		Type: &tlsv3.Secret_TlsCertificate{
+			TlsCertificate: &tlsv3.TlsCertificate{
+				CertificateChain: &corev3.DataSource{
+					Specifier: &corev3.DataSource_InlineString{
+						InlineString: chain.String(),
+					},
+				},
+				PrivateKey: &corev3.DataSource{
+					Specifier: &corev3.DataSource_InlineBytes{
+						InlineBytes: key,
+					},
+				},
+			},
+		},
+	}
+	return secret, rotateAt, nil
+}
+
+// generateCSR generates the private key and the certificate signing request
+// with the SPIFFE identity, both of them are PEM encoded.
+func (cm *certManager) generateCSR() ([]byte, []byte, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	uri, err := url.Parse(cm.identity)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		URIs: []*url.URL{uri},
+	}, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+	return csrPEM, keyPEM, nil
+}
+
+func parseCertificate(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, _errBadCertificate
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
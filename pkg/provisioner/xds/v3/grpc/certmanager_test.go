@@ -0,0 +1,246 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/nettest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+	"github.com/api7/apisix-mesh-agent/pkg/types/istio"
+)
+
+// fakeCAServer signs the CSR with the test CA, the first N (failures)
+// requests will be rejected.
+type fakeCAServer struct {
+	istio.UnimplementedIstioCertificateServiceServer
+
+	t        *testing.T
+	ca       *testCA
+	failures int32
+	serial   int64
+	reqCh    chan *istio.IstioCertificateRequest
+	authCh   chan []string
+}
+
+func (srv *fakeCAServer) CreateCertificate(ctx context.Context, req *istio.IstioCertificateRequest) (*istio.IstioCertificateResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	srv.authCh <- md.Get("authorization")
+	srv.reqCh <- req
+	if atomic.AddInt32(&srv.failures, -1) >= 0 {
+		return nil, errors.New("CA is not ready")
+	}
+
+	block, _ := pem.Decode([]byte(req.Csr))
+	assert.NotNil(srv.t, block)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	assert.Nil(srv.t, err)
+	assert.Nil(srv.t, csr.CheckSignature())
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(atomic.AddInt64(&srv.serial, 1)),
+		URIs:         csr.URIs,
+		NotBefore:    now,
+		NotAfter:     now.Add(time.Duration(req.ValidityDuration) * time.Second),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, srv.ca.cert, csr.PublicKey, srv.ca.key)
+	assert.Nil(srv.t, err)
+	return &istio.IstioCertificateResponse{
+		CertChain: []string{
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			string(srv.ca.pem),
+		},
+	}, nil
+}
+
+func newFakeCAServer(t *testing.T, failures int32) (*fakeCAServer, string, func()) {
+	ln, err := nettest.NewLocalListener("tcp")
+	assert.Nil(t, err)
+	grpcSrv := grpc.NewServer()
+	srv := &fakeCAServer{
+		t:        t,
+		ca:       newTestCA(t),
+		failures: failures,
+		reqCh:    make(chan *istio.IstioCertificateRequest, 10),
+		authCh:   make(chan []string, 10),
+	}
+	istio.RegisterIstioCertificateServiceServer(grpcSrv, srv)
+	go func() {
+		err := grpcSrv.Serve(ln)
+		assert.Nil(t, err)
+	}()
+	return srv, ln.Addr().String(), grpcSrv.Stop
+}
+
+func receiveSecret(t *testing.T, cm *certManager, timeout time.Duration) *tlsv3.Secret {
+	select {
+	case secret := <-cm.channel():
+		return secret
+	case <-time.After(timeout):
+		assert.FailNow(t, "workload certificate was not issued in time")
+	}
+	return nil
+}
+
+func TestCertManagerIssue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ca-token")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "istio-token")
+	token := newTestToken(`{"aud":["istio-ca"]}`)
+	writeTestFile(t, tokenFile, []byte(token), time.Now())
+
+	srv, addr, stopServer := newFakeCAServer(t, 0)
+	defer stopServer()
+
+	cfg := config.NewDefaultConfig()
+	cfg.CAAddress = "grpc://" + addr
+	cfg.CATokenFile = tokenFile
+	cfg.TrustDomain = "apisix.apache.org"
+	cfg.RunningContext = &config.RunningContext{
+		PodName:        "httpbin-58d8b4f7d5-hk8fx",
+		PodNamespace:   "test",
+		IPAddress:      "10.0.5.3",
+		ServiceAccount: "httpbin",
+	}
+	cm, err := newCertManager(cfg, log.DefaultLogger)
+	assert.Nil(t, err)
+	assert.Equal(t, cm.identity, "spiffe://apisix.apache.org/ns/test/sa/httpbin")
+
+	secret, rotateAt, err := cm.issue()
+	assert.Nil(t, err)
+	assert.Equal(t, <-srv.authCh, []string{"Bearer " + token})
+	req := <-srv.reqCh
+	assert.Equal(t, req.ValidityDuration, int64(24*3600))
+	assert.Equal(t, req.Metadata.AsMap(), map[string]interface{}{
+		"ClusterID":    "Kubernetes",
+		"WorkloadName": "httpbin-58d8b4f7d5-hk8fx",
+		"WorkloadIP":   "10.0.5.3",
+	})
+
+	assert.Equal(t, secret.Name, _workloadSecretName)
+	tc := secret.GetTlsCertificate()
+	assert.NotNil(t, tc)
+	pair, err := tls.X509KeyPair([]byte(tc.GetCertificateChain().GetInlineString()), tc.GetPrivateKey().GetInlineBytes())
+	assert.Nil(t, err)
+	assert.Len(t, pair.Certificate, 2)
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	assert.Nil(t, err)
+	assert.Len(t, leaf.URIs, 1)
+	assert.Equal(t, leaf.URIs[0].String(), "spiffe://apisix.apache.org/ns/test/sa/httpbin")
+	assert.Equal(t, rotateAt, leaf.NotBefore.Add(12*time.Hour))
+}
+
+func TestCertManagerRotation(t *testing.T) {
+	srv, addr, stopServer := newFakeCAServer(t, 1)
+	defer stopServer()
+
+	cfg := config.NewDefaultConfig()
+	cfg.CAAddress = "grpc://" + addr
+	cfg.WorkloadCertTTL = 2 * time.Second
+	cm, err := newCertManager(cfg, log.DefaultLogger)
+	assert.Nil(t, err)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go cm.run(stopCh)
+
+	// The first request was failed and retried.
+	first := receiveSecret(t, cm, 5*time.Second)
+	assert.Len(t, srv.reqCh, 2)
+	// Rotated once half of the lifetime passed.
+	second := receiveSecret(t, cm, 5*time.Second)
+	assert.Len(t, srv.reqCh, 3)
+	assert.NotEqual(t, first.GetTlsCertificate().GetPrivateKey().GetInlineBytes(),
+		second.GetTlsCertificate().GetPrivateKey().GetInlineBytes())
+}
+
+func TestTranslateLocalSecrets(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		CAAddress:       "grpc://127.0.0.1:15012",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	assert.NotNil(t, gp.certManager)
+	gp.sendCh = make(chan *discoveryv3.DiscoveryRequest, 1)
+
+	// The workload certificate is used by both inbound listeners and
+	// outbound clusters.
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "1",
+		TypeUrl:     types.ListenerUrl,
+		Resources: []*any.Any{
+			newResource(t, types.ListenerUrl, newTestTlsListener(t, _workloadSecretName)),
+		},
+	})
+	assert.Nil(t, err)
+	takeEvents(gp)
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "1",
+		TypeUrl:     types.ClusterUrl,
+		Resources: []*any.Any{
+			newResource(t, types.ClusterUrl, newTestTlsCluster(t, "httpbin.default.svc.cluster.local", _workloadSecretName)),
+		},
+	})
+	assert.Nil(t, err)
+	events := takeEvents(gp)
+	assert.Nil(t, events[0].Object.(*apisix.Upstream).Tls)
+	// Secrets provided locally are not subscribed.
+	assert.Len(t, gp.sendCh, 0)
+	assert.Len(t, gp.sdsNames, 0)
+
+	// The inbound listener doesn't restrict the server names, so only the
+	// client certificate is used.
+	gp.updateLocalSecret(newTestSecret(_workloadSecretName, "cert", "key"))
+	events = takeEvents(gp)
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	tls := events[0].Object.(*apisix.Upstream).Tls
+	assert.Equal(t, tls.ClientCert, "cert")
+	assert.Equal(t, tls.ClientKey, "key")
+	assert.Len(t, gp.ssls, 0)
+
+	// Rotated.
+	gp.updateLocalSecret(newTestSecret(_workloadSecretName, "cert2", "key2"))
+	events = takeEvents(gp)
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Tls.ClientCert, "cert2")
+
+	// Invalid secret is ignored.
+	gp.updateLocalSecret(newTestSecret(_workloadSecretName, "", "key3"))
+	assert.Equal(t, gp.upstreams["httpbin.default.svc.cluster.local"].Tls.ClientKey, "key2")
+}
@@ -3,6 +3,7 @@ package grpc
 import (
 	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	"github.com/golang/protobuf/ptypes/any"
 	"go.uber.org/zap"
@@ -14,6 +15,22 @@ import (
 )
 
 func (p *grpcProvisioner) processRouteConfigurationV3(res *any.Any) ([]*apisix.Route, error) {
+	route, err := p.unmarshalRouteConfigurationV3(res)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := p.v3Adaptor.TranslateRouteConfiguration(route, p.translateOptions())
+	if err != nil {
+		p.logger.Errorw("failed to translate RouteConfiguration to APISIX routes",
+			zap.Error(err),
+			zap.Any("route", route),
+		)
+		return nil, err
+	}
+	return routes, nil
+}
+
+func (p *grpcProvisioner) unmarshalRouteConfigurationV3(res *any.Any) (*routev3.RouteConfiguration, error) {
 	var route routev3.RouteConfiguration
 	err := anypb.UnmarshalTo(res, &route, proto.UnmarshalOptions{
 		DiscardUnknown: true,
@@ -25,39 +42,120 @@ func (p *grpcProvisioner) processRouteConfigurationV3(res *any.Any) ([]*apisix.R
 		)
 		return nil, err
 	}
+	return &route, nil
+}
 
-	opts := &xdsv3.TranslateOptions{
-		RouteOriginalDestination: p.routeOwnership,
+// translateRouteConfigurations translates the given route configurations
+// (from RDS) along with the static route configurations.
+func (p *grpcProvisioner) translateRouteConfigurations(rcs []*routev3.RouteConfiguration) ([]*apisix.Route, error) {
+	var (
+		routes []*apisix.Route
+		errs   resourceErrors
+	)
+	routesByConfiguration := make(map[string][]*apisix.Route)
+	opts := p.translateOptions()
+	for _, list := range [][]*routev3.RouteConfiguration{rcs, p.staticRouteConfigurations} {
+		for _, rc := range list {
+			partial, err := p.translateRouteConfiguration(rc, opts)
+			if err != nil {
+				errs.add(rc.GetName(), err)
+				continue
+			}
+			routes = append(routes, partial...)
+			routesByConfiguration[rc.GetName()] = append(routesByConfiguration[rc.GetName()], partial...)
+		}
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
 	}
-	routes, err := p.v3Adaptor.TranslateRouteConfiguration(&route, opts)
+	p.routesByConfiguration = routesByConfiguration
+	return routes, nil
+}
+
+func (p *grpcProvisioner) translateRouteConfiguration(rc *routev3.RouteConfiguration, opts *xdsv3.TranslateOptions) ([]*apisix.Route, error) {
+	routes, err := p.v3Adaptor.TranslateRouteConfiguration(rc, opts)
 	if err != nil {
 		p.logger.Errorw("failed to translate RouteConfiguration to APISIX routes",
 			zap.Error(err),
-			zap.Any("route", &route),
+			zap.Any("route", rc),
 		)
 		return nil, err
 	}
 	return routes, nil
 }
 
-func (p *grpcProvisioner) processStaticRouteConfigurations(rcs []*routev3.RouteConfiguration) ([]*apisix.Route, error) {
-	var (
-		routes []*apisix.Route
-	)
-	opts := &xdsv3.TranslateOptions{
+func (p *grpcProvisioner) translateOptions() *xdsv3.TranslateOptions {
+	return &xdsv3.TranslateOptions{
 		RouteOriginalDestination: p.routeOwnership,
+		RouteHTTPFilters:         p.routeHTTPFilters,
+		RouteVirtualHosts:        p.virtualHosts,
+		RouteUpgradeConfigs:      p.routeUpgradeConfigs,
+		RouteFilterChains:        p.routeFilterChains,
+		Upstreams:                p.upstreams,
+		SecretServerNames:        p.listenerSecrets,
+		InboundPort:              p.inboundPort,
+		InboundRoutes:            p.inboundRoutes,
 	}
-	for _, rc := range rcs {
-		route, err := p.v3Adaptor.TranslateRouteConfiguration(rc, opts)
-		if err != nil {
-			p.logger.Errorw("failed to translate RouteConfiguration to APISIX routes",
-				zap.Error(err),
-				zap.Any("route", &route),
-			)
-			return nil, err
-		}
+}
+
+func (p *grpcProvisioner) unmarshalVirtualHostV3(res *any.Any) (*routev3.VirtualHost, error) {
+	var vhost routev3.VirtualHost
+	err := anypb.UnmarshalTo(res, &vhost, proto.UnmarshalOptions{
+		DiscardUnknown: true,
+	})
+	if err != nil {
+		p.logger.Errorw("found invalid VirtualHost resource",
+			zap.Error(err),
+			zap.Any("resource", res),
+		)
+		return nil, err
 	}
-	return routes, nil
+	return &vhost, nil
+}
+
+func (p *grpcProvisioner) unmarshalListenerV3(res *any.Any) (*listenerv3.Listener, error) {
+	var listener listenerv3.Listener
+	if err := anypb.UnmarshalTo(res, &listener, proto.UnmarshalOptions{}); err != nil {
+		p.logger.Errorw("failed to unmarshal listener v3",
+			zap.Error(err),
+			zap.Any("response", res),
+		)
+		return nil, err
+	}
+	return &listener, nil
+}
+
+// processListenersV3 collects the route names and the states that route translation
+// depends on from the listeners, the states are not applied until applyListenerStates
+// is called, so that they can be discarded if the listeners are rejected.
+func (p *grpcProvisioner) processListenersV3(all []*listenerv3.Listener) (*xdsv3.ListenerStates, error) {
+	return p.v3Adaptor.CollectListenerStates(all, p.inboundPort)
+}
+
+// applyListenerStates replaces the listener states with the given ones.
+func (p *grpcProvisioner) applyListenerStates(states *xdsv3.ListenerStates) {
+	p.staticRouteConfigurations = states.StaticRouteConfigurations
+	p.routeOwnership = states.RouteOwnership
+	p.inboundRoutes = states.InboundRoutes
+	p.routeHTTPFilters = states.RouteHTTPFilters
+	p.routeUpgradeConfigs = states.RouteUpgradeConfigs
+	p.routeFilterChains = states.RouteFilterChains
+	p.listenerSecrets = states.SecretServerNames
+	p.listeners = states.Listeners
+	p.rdsNames = states.RdsNames
+}
+
+// listenerTranslateOptions returns the translate options with the listener
+// states which are not applied yet.
+func (p *grpcProvisioner) listenerTranslateOptions(states *xdsv3.ListenerStates) *xdsv3.TranslateOptions {
+	opts := p.translateOptions()
+	opts.RouteOriginalDestination = states.RouteOwnership
+	opts.RouteHTTPFilters = states.RouteHTTPFilters
+	opts.RouteUpgradeConfigs = states.RouteUpgradeConfigs
+	opts.RouteFilterChains = states.RouteFilterChains
+	opts.SecretServerNames = states.SecretServerNames
+	opts.InboundRoutes = states.InboundRoutes
+	return opts
 }
 
 func (p *grpcProvisioner) processClusterV3(res *any.Any) (*apisix.Upstream, error) {
@@ -76,6 +174,14 @@ func (p *grpcProvisioner) processClusterV3(res *any.Any) (*apisix.Upstream, erro
 	if err != nil && err != xdsv3.ErrRequireFurtherEDS {
 		return nil, err
 	}
+	secrets, cerr := p.v3Adaptor.CollectClusterSecrets(&cluster)
+	if cerr != nil {
+		return nil, cerr
+	}
+	if len(secrets) > 0 {
+		p.clusterSecrets[cluster.Name] = secrets
+		ups.Tls = p.clientCertificate(cluster.Name)
+	}
 	if err == xdsv3.ErrRequireFurtherEDS {
 		p.logger.Warnw("cluster depends on another EDS config, an upstream without nodes setting was generated",
 			zap.Any("upstream", ups),
@@ -0,0 +1,528 @@
+package grpc
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"go.uber.org/zap"
+	grpcp "google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func (p *grpcProvisioner) runDelta(ctx context.Context, conn *grpcp.ClientConn) error {
+	client, err := discoveryv3.NewAggregatedDiscoveryServiceClient(conn).DeltaAggregatedResources(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Build the initial requests before the translateLoop runs, so that
+	// states are not accessed concurrently.
+	initial := p.initialDeltaRequests()
+	p.streamCtx = ctx
+	p.goLoop(func() { p.deltaSendLoop(ctx, client) })
+	p.goLoop(func() { p.deltaRecvLoop(ctx, client) })
+	p.goLoop(func() { p.deltaTranslateLoop(ctx) })
+	p.goLoop(func() {
+		for _, dr := range initial {
+			if !p.deltaSend(dr) {
+				return
+			}
+		}
+		p.logger.Debugw("sent initial delta discovery requests")
+	})
+	return nil
+}
+
+// deltaSend is the Delta version of send.
+func (p *grpcProvisioner) deltaSend(dr *discoveryv3.DeltaDiscoveryRequest) bool {
+	select {
+	case <-p.streamCtx.Done():
+		return false
+	case p.deltaSendCh <- dr:
+		return true
+	}
+}
+
+// initialDeltaRequests generates the delta discovery requests which should be
+// sent once the stream was (re)created. Listeners and clusters are wildcard,
+// and the subscriptions of other types are restored. The versions of resources
+// we already have are carried so that the config source can skip them.
+func (p *grpcProvisioner) initialDeltaRequests() []*discoveryv3.DeltaDiscoveryRequest {
+	var drs []*discoveryv3.DeltaDiscoveryRequest
+	for _, typeUrl := range []string{
+		types.ListenerUrl,
+		types.ClusterUrl,
+		types.RouteConfigurationUrl,
+		types.ClusterLoadAssignmentUrl,
+		types.VirtualHostUrl,
+		types.SecretUrl,
+	} {
+		dr := &discoveryv3.DeltaDiscoveryRequest{
+			Node:    p.node,
+			TypeUrl: typeUrl,
+		}
+		if typeUrl != types.ListenerUrl && typeUrl != types.ClusterUrl {
+			subscription := p.subscriptions[typeUrl]
+			if len(subscription) == 0 {
+				// Subscribing nothing means wildcard for the first request.
+				continue
+			}
+			dr.ResourceNamesSubscribe = subscription.OrderedStrings()
+		}
+		if versions := p.resourceVersions[typeUrl]; len(versions) > 0 {
+			dr.InitialResourceVersions = make(map[string]string, len(versions))
+			for name, ver := range versions {
+				dr.InitialResourceVersions[name] = ver
+			}
+		}
+		drs = append(drs, dr)
+	}
+	return drs
+}
+
+// deltaSendLoop is the Delta version of sendLoop, the order of subscribing,
+// unsubscribing and ACK requests matters for the config source.
+func (p *grpcProvisioner) deltaSendLoop(ctx context.Context, client discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesClient) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case dr := <-p.deltaSendCh:
+			p.logger.Debugw("sending delta discovery request",
+				zap.Any("body", dr),
+			)
+			if err := client.Send(dr); err != nil {
+				p.logger.Errorw("failed to send delta discovery request",
+					zap.Error(err),
+					zap.String("config_source", p.configSource),
+				)
+			}
+		}
+	}
+}
+
+// deltaRecvLoop is the Delta version of recvLoop.
+func (p *grpcProvisioner) deltaRecvLoop(ctx context.Context, client discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesClient) {
+	for {
+		dr, err := client.Recv()
+		if err != nil {
+			p.reset(ctx, err)
+			return
+		}
+		atomic.StoreInt32(&p.streamHealthy, 1)
+		p.logger.Debugw("got delta discovery response",
+			zap.String("type", dr.TypeUrl),
+			zap.Any("body", dr),
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case p.deltaRecvCh <- dr:
+		}
+	}
+}
+
+// deltaTranslateLoop is the Delta version of translateLoop, there is no
+// need to carry resource names in the ACK request, since the subscriptions
+// are kept by the config source.
+func (p *grpcProvisioner) deltaTranslateLoop(ctx context.Context) {
+	for {
+		var evChan chan<- []types.Event
+		if len(p.pendingEvents) > 0 {
+			evChan = p.evChan
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case evChan <- p.pendingEvents:
+			p.pendingEvents = nil
+		case secret := <-p.localSecretCh:
+			p.updateLocalSecret(secret)
+		case resp := <-p.deltaRecvCh:
+			ackReq := &discoveryv3.DeltaDiscoveryRequest{
+				TypeUrl:       resp.TypeUrl,
+				ResponseNonce: resp.Nonce,
+			}
+			if err := p.translateDelta(resp); err != nil {
+				p.logger.Errorw("reject delta discovery response",
+					zap.Error(err),
+					zap.String("type", resp.TypeUrl),
+					zap.String("nonce", resp.Nonce),
+				)
+				ackReq.ErrorDetail = errorDetail(err)
+			}
+			p.deltaSend(ackReq)
+		}
+	}
+}
+
+// translateDelta translates the changed and removed resources in the
+// DeltaDiscoveryResponse, only the affected APISIX resources will be
+// re-translated.
+func (p *grpcProvisioner) translateDelta(resp *discoveryv3.DeltaDiscoveryResponse) error {
+	var (
+		m   util.Manifest
+		o   util.Manifest
+		err error
+	)
+	original := p.stageState()
+	switch resp.GetTypeUrl() {
+	case types.ListenerUrl:
+		err = p.translateDeltaListeners(resp, &m, &o)
+	case types.RouteConfigurationUrl:
+		err = p.translateDeltaRouteConfigurations(resp, &m, &o)
+	case types.ClusterUrl:
+		err = p.translateDeltaClusters(resp, &m, &o)
+	case types.ClusterLoadAssignmentUrl:
+		err = p.translateDeltaClusterLoadAssignments(resp, &m, &o)
+	case types.VirtualHostUrl:
+		err = p.translateDeltaVirtualHosts(resp, &m, &o)
+	case types.SecretUrl:
+		err = p.translateDeltaSecrets(resp, &m, &o)
+	default:
+		err = _errUnknownResourceTypeUrl
+	}
+	if err != nil {
+		// The response is rejected as a whole, so the changes
+		// made by the translated resources are discarded.
+		p.restoreState(original)
+		return err
+	}
+	p.updateResourceVersions(resp)
+	if resp.GetTypeUrl() == types.VirtualHostUrl {
+		// Ignored virtual hosts are not kept, so the config source
+		// will send them again after re-connecting.
+		for name := range p.resourceVersions[types.VirtualHostUrl] {
+			if _, ok := p.deltaVirtualHosts[name]; !ok {
+				delete(p.resourceVersions[types.VirtualHostUrl], name)
+			}
+		}
+	}
+
+	p.pendingEvents = append(p.pendingEvents, p.generateEvents(&m, &o)...)
+	return nil
+}
+
+func (p *grpcProvisioner) updateResourceVersions(resp *discoveryv3.DeltaDiscoveryResponse) {
+	versions, ok := p.resourceVersions[resp.GetTypeUrl()]
+	if !ok {
+		versions = make(map[string]string)
+		p.resourceVersions[resp.GetTypeUrl()] = versions
+	}
+	for _, res := range resp.GetResources() {
+		versions[res.GetName()] = res.GetVersion()
+	}
+	for _, name := range resp.GetRemovedResources() {
+		delete(versions, name)
+	}
+}
+
+func (p *grpcProvisioner) translateDeltaListeners(resp *discoveryv3.DeltaDiscoveryResponse, m, o *util.Manifest) error {
+	for _, res := range resp.GetResources() {
+		listener, err := p.unmarshalListenerV3(res.GetResource())
+		if err != nil {
+			return err
+		}
+		p.deltaListeners[res.GetName()] = listener
+	}
+	for _, name := range resp.GetRemovedResources() {
+		delete(p.deltaListeners, name)
+	}
+
+	names := make([]string, 0, len(p.deltaListeners))
+	for name := range p.deltaListeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	listeners := make([]*listenerv3.Listener, 0, len(names))
+	for _, name := range names {
+		listeners = append(listeners, p.deltaListeners[name])
+	}
+	states, err := p.processListenersV3(listeners)
+	if err != nil {
+		return err
+	}
+	// The states are staged, they're discarded if the response is rejected.
+	p.applyListenerStates(states)
+	rdsNames := states.RdsNames
+
+	// Route configurations which are not referenced by listeners anymore
+	// will be unsubscribed, the config source won't notify the removal.
+	rdsSet := set.StringSet{}
+	for _, name := range rdsNames {
+		rdsSet.Add(name)
+	}
+	var unreferenced []string
+	for name := range p.deltaRouteConfigurations {
+		if _, ok := rdsSet[name]; !ok {
+			delete(p.deltaRouteConfigurations, name)
+			unreferenced = append(unreferenced, name)
+		}
+	}
+	p.routeConfigurations = p.sortedDeltaRouteConfigurations()
+
+	// The route translation depends on the listeners, so all routes have to
+	// be re-translated, as the unchanged route configurations won't be sent
+	// again in the Delta protocol.
+	routes, err := p.translateRouteConfigurations(p.routeConfigurations)
+	if err != nil {
+		return err
+	}
+	m.Routes = routes
+	o.Routes = p.routes
+	p.routes = routes
+	if err := p.retranslateStreamRoutes(m, o); err != nil {
+		return err
+	}
+	for _, name := range unreferenced {
+		delete(p.resourceVersions[types.RouteConfigurationUrl], name)
+	}
+	p.trySendVhds()
+	p.trySendSds()
+	p.retranslateSSLs(m, o)
+	p.trySendRds(rdsNames)
+	return nil
+}
+
+func (p *grpcProvisioner) translateDeltaRouteConfigurations(resp *discoveryv3.DeltaDiscoveryResponse, m, o *util.Manifest) error {
+	if p.routesByConfiguration == nil {
+		p.routesByConfiguration = make(map[string][]*apisix.Route)
+	}
+	opts := p.translateOptions()
+	for _, res := range resp.GetResources() {
+		rc, err := p.unmarshalRouteConfigurationV3(res.GetResource())
+		if err != nil {
+			return err
+		}
+		routes, err := p.translateRouteConfiguration(rc, opts)
+		if err != nil {
+			return err
+		}
+		p.deltaRouteConfigurations[rc.GetName()] = rc
+		p.routesByConfiguration[rc.GetName()] = routes
+	}
+	for _, name := range resp.GetRemovedResources() {
+		delete(p.deltaRouteConfigurations, name)
+		delete(p.routesByConfiguration, name)
+	}
+	p.routeConfigurations = p.sortedDeltaRouteConfigurations()
+
+	var routes []*apisix.Route
+	for _, list := range [][]*routev3.RouteConfiguration{p.routeConfigurations, p.staticRouteConfigurations} {
+		for _, rc := range list {
+			routes = append(routes, p.routesByConfiguration[rc.GetName()]...)
+		}
+	}
+	m.Routes = routes
+	o.Routes = p.routes
+	p.routes = routes
+	p.trySendVhds()
+	return nil
+}
+
+func (p *grpcProvisioner) translateDeltaClusters(resp *discoveryv3.DeltaDiscoveryResponse, m, o *util.Manifest) error {
+	oldEdsRequiredClusters := set.StringSet{}
+	for name := range p.edsRequiredClusters {
+		oldEdsRequiredClusters.Add(name)
+	}
+	for _, res := range resp.GetResources() {
+		delete(p.edsRequiredClusters, res.GetName())
+		delete(p.clusterSecrets, res.GetName())
+		ups, err := p.processClusterV3(res.GetResource())
+		if err != nil {
+			if err == xdsv3.ErrFeatureNotSupportedYet {
+				p.logger.Warnw("failed to translate Cluster to APISIX upstreams",
+					zap.Error(err),
+					zap.Any("cluster", res),
+				)
+				continue
+			}
+			p.logger.Errorw("failed to translate Cluster to APISIX upstreams",
+				zap.Error(err),
+				zap.Any("cluster", res),
+			)
+			return err
+		}
+		if old, ok := p.upstreams[ups.Name]; ok {
+			o.Upstreams = append(o.Upstreams, old)
+			// The unchanged ClusterLoadAssignment won't be sent again,
+			// so the nodes should be kept.
+			if _, ok := p.edsRequiredClusters[ups.Name]; ok {
+				ups.Nodes = old.Nodes
+			}
+		}
+		p.upstreams[ups.Name] = ups
+		m.Upstreams = append(m.Upstreams, ups)
+	}
+	for _, name := range resp.GetRemovedResources() {
+		if old, ok := p.upstreams[name]; ok {
+			o.Upstreams = append(o.Upstreams, old)
+			delete(p.upstreams, name)
+		}
+		delete(p.edsRequiredClusters, name)
+		delete(p.clusterSecrets, name)
+	}
+	if err := p.retranslateRoutesOnUpstreamsChange(m, o); err != nil {
+		return err
+	}
+	if err := p.retranslateStreamRoutes(m, o); err != nil {
+		return err
+	}
+	p.trySendSds()
+	p.retranslateSSLs(m, o)
+	if !p.edsRequiredClusters.Equal(oldEdsRequiredClusters) {
+		p.logger.Infow("update EDS subscription",
+			zap.Any("old_eds_required_clusters", oldEdsRequiredClusters),
+			zap.Any("eds_required_clusters", p.edsRequiredClusters),
+		)
+		p.sendEds()
+	}
+	return nil
+}
+
+func (p *grpcProvisioner) translateDeltaClusterLoadAssignments(resp *discoveryv3.DeltaDiscoveryResponse, m, o *util.Manifest) error {
+	for _, res := range resp.GetResources() {
+		old, ok := p.upstreams[res.GetName()]
+		ups, err := p.processClusterLoadAssignmentV3(res.GetResource())
+		if err != nil {
+			return err
+		}
+		if ok {
+			o.Upstreams = append(o.Upstreams, old)
+		}
+		m.Upstreams = append(m.Upstreams, ups)
+	}
+	for _, name := range resp.GetRemovedResources() {
+		old, ok := p.upstreams[name]
+		if !ok {
+			continue
+		}
+		ups := proto.Clone(old).(*apisix.Upstream)
+		ups.Nodes = nil
+		p.upstreams[name] = ups
+		o.Upstreams = append(o.Upstreams, old)
+		m.Upstreams = append(m.Upstreams, ups)
+	}
+	if err := p.retranslateRoutesOnUpstreamsChange(m, o); err != nil {
+		return err
+	}
+	return p.retranslateStreamRoutes(m, o)
+}
+
+func (p *grpcProvisioner) translateDeltaVirtualHosts(resp *discoveryv3.DeltaDiscoveryResponse, m, o *util.Manifest) error {
+	for _, res := range resp.GetResources() {
+		vhost, err := p.unmarshalVirtualHostV3(res.GetResource())
+		if err != nil {
+			return err
+		}
+		p.deltaVirtualHosts[res.GetName()] = vhost
+	}
+	for _, name := range resp.GetRemovedResources() {
+		delete(p.deltaVirtualHosts, name)
+	}
+
+	names := make([]string, 0, len(p.deltaVirtualHosts))
+	for name := range p.deltaVirtualHosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	virtualHosts := make(map[string][]*routev3.VirtualHost)
+	for _, name := range names {
+		rcName, ok := p.vhdsRouteConfigurationName(name)
+		if !ok {
+			delete(p.deltaVirtualHosts, name)
+			continue
+		}
+		virtualHosts[rcName] = append(virtualHosts[rcName], p.deltaVirtualHosts[name])
+	}
+	p.virtualHosts = virtualHosts
+	routes, err := p.translateRouteConfigurations(p.routeConfigurations)
+	if err != nil {
+		return err
+	}
+	m.Routes = routes
+	o.Routes = p.routes
+	p.routes = routes
+	return nil
+}
+
+func (p *grpcProvisioner) translateDeltaSecrets(resp *discoveryv3.DeltaDiscoveryResponse, m, o *util.Manifest) error {
+	secrets := make(map[string]*tlsv3.Secret)
+	opts := p.translateOptions()
+	for _, res := range resp.GetResources() {
+		secret, err := p.unmarshalSecretV3(res.GetResource())
+		if err != nil {
+			return err
+		}
+		if _, err := p.translateSecret(secret, opts); err != nil {
+			return err
+		}
+		secrets[res.GetName()] = secret
+	}
+	for name, secret := range secrets {
+		p.secrets[name] = secret
+	}
+	for _, name := range resp.GetRemovedResources() {
+		delete(p.secrets, name)
+	}
+	p.retranslateSSLs(m, o)
+	p.retranslateClientCertificates(m, o)
+	return nil
+}
+
+func (p *grpcProvisioner) sortedDeltaRouteConfigurations() []*routev3.RouteConfiguration {
+	names := make([]string, 0, len(p.deltaRouteConfigurations))
+	for name := range p.deltaRouteConfigurations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rcs := make([]*routev3.RouteConfiguration, 0, len(names))
+	for _, name := range names {
+		rcs = append(rcs, p.deltaRouteConfigurations[name])
+	}
+	return rcs
+}
+
+// deltaSubscribe updates the subscription of the given type, only the
+// difference will be sent to the config source.
+func (p *grpcProvisioner) deltaSubscribe(typeUrl string, names []string) {
+	current := set.StringSet{}
+	for _, name := range names {
+		current.Add(name)
+	}
+	last := p.subscriptions[typeUrl]
+	dr := &discoveryv3.DeltaDiscoveryRequest{
+		Node:    p.node,
+		TypeUrl: typeUrl,
+	}
+	for _, name := range current.OrderedStrings() {
+		if _, ok := last[name]; !ok {
+			dr.ResourceNamesSubscribe = append(dr.ResourceNamesSubscribe, name)
+		}
+	}
+	for _, name := range last.OrderedStrings() {
+		if _, ok := current[name]; !ok {
+			dr.ResourceNamesUnsubscribe = append(dr.ResourceNamesUnsubscribe, name)
+			delete(p.resourceVersions[typeUrl], name)
+		}
+	}
+	p.subscriptions[typeUrl] = current
+	if len(dr.ResourceNamesSubscribe) == 0 && len(dr.ResourceNamesUnsubscribe) == 0 {
+		return
+	}
+	p.logger.Debugw("sending delta discovery request to update subscription",
+		zap.Any("body", dr),
+	)
+	p.deltaSend(dr)
+}
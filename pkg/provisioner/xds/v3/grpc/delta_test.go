@@ -0,0 +1,517 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/nettest"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func newDeltaProvisioner(t *testing.T, configSource string) *grpcProvisioner {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: configSource,
+		XDSDelta:        true,
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	assert.True(t, gp.delta)
+	return gp
+}
+
+func newDeltaResource(t *testing.T, name, version string, m proto.Message) *discoveryv3.Resource {
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, m, proto.MarshalOptions{}))
+	return &discoveryv3.Resource{
+		Name:     name,
+		Version:  version,
+		Resource: &opaque,
+	}
+}
+
+func newTestRouteConfiguration(name, path, cluster string) *routev3.RouteConfiguration {
+	return &routev3.RouteConfiguration{
+		Name: name,
+		VirtualHosts: []*routev3.VirtualHost{
+			{
+				Name:    "vhost1",
+				Domains: []string{"*.apache.org"},
+				Routes: []*routev3.Route{
+					{
+						Name: "route1",
+						Match: &routev3.RouteMatch{
+							PathSpecifier: &routev3.RouteMatch_Path{
+								Path: path,
+							},
+						},
+						Action: &routev3.Route_Route{
+							Route: &routev3.RouteAction{
+								ClusterSpecifier: &routev3.RouteAction_Cluster{
+									Cluster: cluster,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newTestClusterLoadAssignment(cluster, ip string) *endpointv3.ClusterLoadAssignment {
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: cluster,
+		Endpoints: []*endpointv3.LocalityLbEndpoints{
+			{
+				LbEndpoints: []*endpointv3.LbEndpoint{
+					{
+						HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+							Endpoint: &endpointv3.Endpoint{
+								Address: &corev3.Address{
+									Address: &corev3.Address_SocketAddress{
+										SocketAddress: &corev3.SocketAddress{
+											Protocol: corev3.SocketAddress_TCP,
+											Address:  ip,
+											PortSpecifier: &corev3.SocketAddress_PortValue{
+												PortValue: 8000,
+											},
+										},
+									},
+								},
+							},
+						},
+						LoadBalancingWeight: &wrappers.UInt32Value{
+							Value: 100,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDeltaSubscribe(t *testing.T) {
+	gp := newDeltaProvisioner(t, "grpc://127.0.0.1:11111")
+	gp.deltaSendCh = make(chan *discoveryv3.DeltaDiscoveryRequest, 1)
+
+	gp.deltaSubscribe(types.RouteConfigurationUrl, []string{"rc2", "rc1"})
+	dr := <-gp.deltaSendCh
+	assert.Equal(t, dr.TypeUrl, types.RouteConfigurationUrl)
+	assert.Equal(t, dr.ResourceNamesSubscribe, []string{"rc1", "rc2"})
+	assert.Nil(t, dr.ResourceNamesUnsubscribe)
+
+	gp.resourceVersions[types.RouteConfigurationUrl] = map[string]string{"rc1": "1", "rc2": "1"}
+	gp.deltaSubscribe(types.RouteConfigurationUrl, []string{"rc2", "rc3"})
+	dr = <-gp.deltaSendCh
+	assert.Equal(t, dr.ResourceNamesSubscribe, []string{"rc3"})
+	assert.Equal(t, dr.ResourceNamesUnsubscribe, []string{"rc1"})
+	assert.Equal(t, gp.resourceVersions[types.RouteConfigurationUrl], map[string]string{"rc2": "1"})
+
+	// Nothing changed, no request should be sent.
+	gp.deltaSubscribe(types.RouteConfigurationUrl, []string{"rc3", "rc2"})
+	select {
+	case dr = <-gp.deltaSendCh:
+		assert.FailNow(t, "unexpected delta discovery request", dr)
+	default:
+	}
+}
+
+func TestInitialDeltaRequests(t *testing.T) {
+	gp := newDeltaProvisioner(t, "grpc://127.0.0.1:11111")
+
+	drs := gp.initialDeltaRequests()
+	assert.Len(t, drs, 2)
+	assert.Equal(t, drs[0].TypeUrl, types.ListenerUrl)
+	assert.Equal(t, drs[1].TypeUrl, types.ClusterUrl)
+	assert.Nil(t, drs[0].ResourceNamesSubscribe)
+	assert.Equal(t, drs[0].Node, gp.node)
+
+	gp.subscriptions[types.ClusterLoadAssignmentUrl] = map[string]struct{}{"c1": {}}
+	gp.resourceVersions[types.ClusterUrl] = map[string]string{"c1": "3"}
+	gp.resourceVersions[types.ClusterLoadAssignmentUrl] = map[string]string{"c1": "5"}
+	drs = gp.initialDeltaRequests()
+	assert.Len(t, drs, 3)
+	assert.Equal(t, drs[1].InitialResourceVersions, map[string]string{"c1": "3"})
+	assert.Equal(t, drs[2].TypeUrl, types.ClusterLoadAssignmentUrl)
+	assert.Equal(t, drs[2].ResourceNamesSubscribe, []string{"c1"})
+	assert.Equal(t, drs[2].InitialResourceVersions, map[string]string{"c1": "5"})
+}
+
+func TestTranslateDelta(t *testing.T) {
+	gp := newDeltaProvisioner(t, "grpc://127.0.0.1:11111")
+	gp.deltaSendCh = make(chan *discoveryv3.DeltaDiscoveryRequest, 1)
+
+	c1 := &clusterv3.Cluster{
+		Name: "httpbin.default.svc.cluster.local",
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{
+			Type: clusterv3.Cluster_EDS,
+		},
+		LbPolicy: clusterv3.Cluster_ROUND_ROBIN,
+	}
+	c2 := &clusterv3.Cluster{
+		Name: "kubernetes.default.svc.cluster.local",
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{
+			Type: clusterv3.Cluster_EDS,
+		},
+		LbPolicy: clusterv3.Cluster_ROUND_ROBIN,
+	}
+	err := gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.ClusterUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, c1.Name, "1", c1),
+			newDeltaResource(t, c2.Name, "1", c2),
+		},
+	})
+	assert.Nil(t, err)
+	evs := takeEvents(gp)
+	assert.Len(t, evs, 2)
+	assert.Equal(t, evs[0].Type, types.EventAdd)
+	dr := <-gp.deltaSendCh
+	assert.Equal(t, dr.TypeUrl, types.ClusterLoadAssignmentUrl)
+	assert.Equal(t, dr.ResourceNamesSubscribe, []string{c1.Name, c2.Name})
+	assert.Equal(t, gp.resourceVersions[types.ClusterUrl], map[string]string{c1.Name: "1", c2.Name: "1"})
+
+	// Only the changed ClusterLoadAssignment generates event.
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.ClusterLoadAssignmentUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, c1.Name, "1", newTestClusterLoadAssignment(c1.Name, "10.0.3.11")),
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventUpdate)
+	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Name, c1.Name)
+	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.11")
+
+	// The same endpoints, nothing changed.
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.ClusterLoadAssignmentUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, c1.Name, "2", newTestClusterLoadAssignment(c1.Name, "10.0.3.11")),
+		},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, takeEvents(gp), 0)
+	assert.Equal(t, gp.resourceVersions[types.ClusterLoadAssignmentUrl][c1.Name], "2")
+
+	// Cluster updated, nodes from EDS are kept.
+	c1.LbPolicy = clusterv3.Cluster_LEAST_REQUEST
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.ClusterUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, c1.Name, "2", c1),
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventUpdate)
+	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Type, "least_conn")
+	assert.Len(t, evs[0].Object.(*apisix.Upstream).Nodes, 1)
+
+	// Cluster removed, EDS should be unsubscribed.
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl:          types.ClusterUrl,
+		RemovedResources: []string{c2.Name},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventDelete)
+	assert.Equal(t, evs[0].Tombstone.(*apisix.Upstream).Name, c2.Name)
+	dr = <-gp.deltaSendCh
+	assert.Nil(t, dr.ResourceNamesSubscribe)
+	assert.Equal(t, dr.ResourceNamesUnsubscribe, []string{c2.Name})
+	assert.Equal(t, gp.resourceVersions[types.ClusterUrl], map[string]string{c1.Name: "2"})
+
+	// Route configurations.
+	rc1 := newTestRouteConfiguration("rc1", "/foo", c1.Name)
+	rc2 := newTestRouteConfiguration("rc2", "/bar", c1.Name)
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.RouteConfigurationUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "rc1", "1", rc1),
+			newDeltaResource(t, "rc2", "1", rc2),
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 2)
+	assert.Len(t, gp.routes, 2)
+
+	rc2 = newTestRouteConfiguration("rc2", "/baz", c1.Name)
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.RouteConfigurationUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "rc2", "2", rc2),
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventUpdate)
+	assert.Equal(t, evs[0].Object.(*apisix.Route).Uris, []string{"/baz"})
+	assert.Len(t, gp.routes, 2)
+
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl:          types.RouteConfigurationUrl,
+		RemovedResources: []string{"rc1"},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventDelete)
+	assert.Equal(t, evs[0].Tombstone.(*apisix.Route).Name, "route1#vhost1#rc1")
+	assert.Len(t, gp.routes, 1)
+	assert.Equal(t, gp.resourceVersions[types.RouteConfigurationUrl], map[string]string{"rc2": "2"})
+
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: "unknown",
+	})
+	assert.Equal(t, err, _errUnknownResourceTypeUrl)
+}
+
+func TestTranslateDeltaRejected(t *testing.T) {
+	gp := newDeltaProvisioner(t, "grpc://127.0.0.1:11111")
+	gp.deltaSendCh = make(chan *discoveryv3.DeltaDiscoveryRequest, 1)
+
+	newBadResource := func(typeUrl, name string) *discoveryv3.Resource {
+		return &discoveryv3.Resource{
+			Name:     name,
+			Version:  "1",
+			Resource: newMalformedResource(typeUrl, name),
+		}
+	}
+	c1 := &clusterv3.Cluster{
+		Name: "httpbin.default.svc.cluster.local",
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{
+			Type: clusterv3.Cluster_EDS,
+		},
+		LbPolicy: clusterv3.Cluster_ROUND_ROBIN,
+	}
+	err := gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.ClusterUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, c1.Name, "1", c1),
+		},
+	})
+	assert.Nil(t, err)
+	<-gp.deltaSendCh
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.RouteConfigurationUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "rc1", "1", newTestRouteConfiguration("rc1", "/foo", c1.Name)),
+		},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, takeEvents(gp), 2)
+
+	// One good and one bad cluster.
+	c2 := proto.Clone(c1).(*clusterv3.Cluster)
+	c2.Name = "kubernetes.default.svc.cluster.local"
+	c1 = proto.Clone(c1).(*clusterv3.Cluster)
+	c1.LbPolicy = clusterv3.Cluster_LEAST_REQUEST
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.ClusterUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, c1.Name, "2", c1),
+			newDeltaResource(t, c2.Name, "1", c2),
+			newBadResource(types.ClusterUrl, "bad-cluster"),
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Len(t, gp.upstreams, 1)
+	assert.Equal(t, gp.upstreams[c1.Name].Type, "roundrobin")
+	assert.Equal(t, gp.edsRequiredClusters, set.StringSet{c1.Name: {}})
+	assert.Equal(t, gp.resourceVersions[types.ClusterUrl], map[string]string{c1.Name: "1"})
+
+	// One good and one bad route configuration.
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.RouteConfigurationUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "rc1", "2", newTestRouteConfiguration("rc1", "/bar", c1.Name)),
+			newDeltaResource(t, "rc2", "1", newTestRouteConfiguration("rc2", "/baz", c1.Name)),
+			newBadResource(types.RouteConfigurationUrl, "bad-rc"),
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Len(t, gp.deltaRouteConfigurations, 1)
+	assert.Len(t, gp.routesByConfiguration, 1)
+	assert.Len(t, gp.routes, 1)
+	assert.Equal(t, gp.routes[0].Uris, []string{"/foo"})
+
+	// One good and one bad listener.
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.ListenerUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "listener1", "1", &listenerv3.Listener{Name: "listener1"}),
+			newBadResource(types.ListenerUrl, "bad-listener"),
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Len(t, gp.deltaListeners, 0)
+	assert.Len(t, gp.deltaRouteConfigurations, 1)
+	assert.Equal(t, gp.resourceVersions[types.RouteConfigurationUrl], map[string]string{"rc1": "1"})
+
+	// Nothing is generated or subscribed.
+	assert.Len(t, takeEvents(gp), 0)
+	select {
+	case dr := <-gp.deltaSendCh:
+		assert.FailNow(t, "unexpected delta discovery request", dr)
+	default:
+	}
+}
+
+func TestTranslateDeltaVirtualHosts(t *testing.T) {
+	gp := newDeltaProvisioner(t, "grpc://127.0.0.1:11111")
+	gp.deltaSendCh = make(chan *discoveryv3.DeltaDiscoveryRequest, 1)
+
+	rc := &routev3.RouteConfiguration{
+		Name: "rc1",
+		Vhds: &routev3.Vhds{},
+	}
+	err := gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.RouteConfigurationUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "rc1", "1", rc),
+		},
+	})
+	assert.Nil(t, err)
+	// Virtual hosts of the route configuration are subscribed by its name.
+	dr := <-gp.deltaSendCh
+	assert.Equal(t, dr.TypeUrl, types.VirtualHostUrl)
+	assert.Equal(t, dr.ResourceNamesSubscribe, []string{"rc1"})
+
+	// Virtual hosts are keyed by the resource name rather than their names.
+	vhost := newTestRouteConfiguration("rc1", "/get", "httpbin.default.svc.cluster.local").VirtualHosts[0]
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.VirtualHostUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "rc1/httpbin.apache.org", "1", vhost),
+			newDeltaResource(t, "rc2/httpbin.apache.org", "1", vhost),
+			newDeltaResource(t, "httpbin.apache.org", "1", vhost),
+		},
+	})
+	assert.Nil(t, err)
+	evs := takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventAdd)
+	assert.Equal(t, evs[0].Object.(*apisix.Route).Name, "route1#vhost1#rc1")
+	assert.Len(t, gp.virtualHosts["rc1"], 1)
+	assert.Equal(t, gp.resourceVersions[types.VirtualHostUrl], map[string]string{"rc1/httpbin.apache.org": "1"})
+}
+
+type fakeDeltaXdsServer struct {
+	fakeXdsServer
+	deltaRecvCh chan *discoveryv3.DeltaDiscoveryRequest
+	deltaSendCh chan *discoveryv3.DeltaDiscoveryResponse
+}
+
+func (srv *fakeDeltaXdsServer) DeltaAggregatedResources(stream discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			srv.deltaRecvCh <- req
+		}
+	}()
+
+	go func() {
+		for {
+			resp := <-srv.deltaSendCh
+			err := stream.Send(resp)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	<-srv.ctx.Done()
+	return nil
+}
+
+func TestGRPCProvisionerDelta(t *testing.T) {
+	ln, err := nettest.NewLocalListener("tcp")
+	assert.Nil(t, err)
+	grpcSrv := grpc.NewServer()
+	go func() {
+		err := grpcSrv.Serve(ln)
+		assert.Nil(t, err)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &fakeDeltaXdsServer{
+		fakeXdsServer: fakeXdsServer{
+			t:   t,
+			ctx: ctx,
+		},
+		deltaRecvCh: make(chan *discoveryv3.DeltaDiscoveryRequest),
+		deltaSendCh: make(chan *discoveryv3.DeltaDiscoveryResponse),
+	}
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(grpcSrv, srv)
+
+	gp := newDeltaProvisioner(t, "grpc://"+ln.Addr().String())
+	stopCh := make(chan struct{})
+	go func() {
+		err := gp.Run(stopCh)
+		assert.Nil(t, err)
+	}()
+
+	dr := <-srv.deltaRecvCh
+	assert.Equal(t, dr.TypeUrl, types.ListenerUrl)
+	dr = <-srv.deltaRecvCh
+	assert.Equal(t, dr.TypeUrl, types.ClusterUrl)
+
+	srv.deltaSendCh <- &discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.RouteConfigurationUrl,
+		Nonce:   "1",
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "rc1", "1", newTestRouteConfiguration("rc1", "/foo", "kubernetes.default.svc.cluster.local")),
+		},
+	}
+	ev := <-gp.evChan
+	assert.Len(t, ev, 1)
+	assert.Equal(t, ev[0].Object.(*apisix.Route).Name, "route1#vhost1#rc1")
+	ack := <-srv.deltaRecvCh
+	assert.Nil(t, ack.ErrorDetail)
+	assert.Equal(t, ack.TypeUrl, types.RouteConfigurationUrl)
+	assert.Equal(t, ack.ResponseNonce, "1")
+
+	srv.deltaSendCh <- &discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: "unknown",
+		Nonce:   "2",
+	}
+	nack := <-srv.deltaRecvCh
+	assert.Equal(t, nack.ResponseNonce, "2")
+	assert.NotNil(t, nack.ErrorDetail)
+	close(stopCh)
+}
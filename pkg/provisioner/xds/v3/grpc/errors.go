@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// resourceError records the xDS resource which cannot be accepted.
+type resourceError struct {
+	name string
+	err  error
+}
+
+// resourceErrors collects all rejected resources in a discovery response,
+// so that they can be reported in the NACK request together.
+type resourceErrors []*resourceError
+
+func (errs resourceErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", e.name, e.err))
+	}
+	return fmt.Sprintf("%d resource(s) rejected: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+func (errs *resourceErrors) add(name string, err error) {
+	*errs = append(*errs, &resourceError{
+		name: name,
+		err:  err,
+	})
+}
+
+// err returns nil if there is no rejected resource, note the nil
+// resourceErrors shouldn't be returned as an error directly.
+func (errs resourceErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// errorDetail generates the ErrorDetail of the NACK request, rejected resources
+// are listed as field violations in the details.
+func errorDetail(err error) *status.Status {
+	st := &status.Status{
+		Code:    int32(code.Code_INVALID_ARGUMENT),
+		Message: err.Error(),
+	}
+	var errs resourceErrors
+	if !errors.As(err, &errs) {
+		return st
+	}
+	br := &errdetails.BadRequest{}
+	for _, e := range errs {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       e.name,
+			Description: e.err.Error(),
+		})
+	}
+	if detail, err := anypb.New(br); err == nil {
+		st.Details = append(st.Details, detail)
+	}
+	return st
+}
+
+// resourceName extracts the name from the encoded xDS resource without
+// unmarshalling it, so that even the malformed resource can be reported.
+// The name is the first field of all supported resources (the cluster_name
+// of ClusterLoadAssignment).
+func resourceName(res *any.Any) string {
+	b := res.GetValue()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				break
+			}
+			return string(v)
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+	}
+	return "<unknown>"
+}
@@ -0,0 +1,229 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/nettest"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+)
+
+// newMalformedResource creates a resource which has a valid name
+// field but truncated content.
+func newMalformedResource(typeUrl, name string) *any.Any {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, name)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendVarint(b, 100)
+	return &any.Any{
+		TypeUrl: typeUrl,
+		Value:   b,
+	}
+}
+
+func newResource(t *testing.T, typeUrl string, m proto.Message) *any.Any {
+	val, err := proto.Marshal(m)
+	assert.Nil(t, err)
+	return &any.Any{
+		TypeUrl: typeUrl,
+		Value:   val,
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	assert.Equal(t, resourceName(newResource(t, types.ClusterUrl, &clusterv3.Cluster{
+		Name:           "httpbin.default.svc.cluster.local",
+		ConnectTimeout: nil,
+		LbPolicy:       clusterv3.Cluster_LEAST_REQUEST,
+	})), "httpbin.default.svc.cluster.local")
+	assert.Equal(t, resourceName(newResource(t, types.ClusterLoadAssignmentUrl, &endpointv3.ClusterLoadAssignment{
+		ClusterName: "httpbin.default.svc.cluster.local",
+	})), "httpbin.default.svc.cluster.local")
+	assert.Equal(t, resourceName(newMalformedResource(types.RouteConfigurationUrl, "rc1")), "rc1")
+	assert.Equal(t, resourceName(&any.Any{Value: []byte{0xff}}), "<unknown>")
+	assert.Equal(t, resourceName(&any.Any{}), "<unknown>")
+}
+
+func TestErrorDetail(t *testing.T) {
+	st := errorDetail(errors.New("unknown resource type url"))
+	assert.Equal(t, st.Code, int32(code.Code_INVALID_ARGUMENT))
+	assert.Equal(t, st.Message, "unknown resource type url")
+	assert.Len(t, st.Details, 0)
+
+	var errs resourceErrors
+	assert.Nil(t, errs.err())
+	errs.add("rc1", errors.New("bad route"))
+	errs.add("rc2", errors.New("bad virtual host"))
+	st = errorDetail(errs.err())
+	assert.Equal(t, st.Message, "2 resource(s) rejected: rc1: bad route; rc2: bad virtual host")
+	assert.Len(t, st.Details, 1)
+	var br errdetails.BadRequest
+	assert.Nil(t, st.Details[0].UnmarshalTo(&br))
+	assert.Len(t, br.FieldViolations, 2)
+	assert.Equal(t, br.FieldViolations[0].Field, "rc1")
+	assert.Equal(t, br.FieldViolations[0].Description, "bad route")
+	assert.Equal(t, br.FieldViolations[1].Field, "rc2")
+}
+
+func TestGRPCProvisionerACKAndNACK(t *testing.T) {
+	ln, err := nettest.NewLocalListener("tcp")
+	assert.Nil(t, err)
+	grpcSrv := grpc.NewServer()
+	go func() {
+		err := grpcSrv.Serve(ln)
+		assert.Nil(t, err)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &fakeXdsServer{
+		t:      t,
+		sendCh: make(chan *discoveryv3.DiscoveryResponse),
+		recvCh: make(chan *discoveryv3.DiscoveryRequest),
+		ctx:    ctx,
+	}
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(grpcSrv, srv)
+
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://" + ln.Addr().String(),
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	go func() {
+		for range gp.evChan {
+		}
+	}()
+
+	stopCh := make(chan struct{})
+	runCh := make(chan error)
+	go func() {
+		runCh <- p.Run(stopCh)
+	}()
+	<-srv.recvCh
+	<-srv.recvCh
+
+	cluster := newResource(t, types.ClusterUrl, &clusterv3.Cluster{
+		Name: "httpbin.default.svc.cluster.local",
+	})
+	rc := newResource(t, types.RouteConfigurationUrl, newTestRouteConfiguration("rc1", "/foo", "httpbin.default.svc.cluster.local"))
+
+	testCases := []struct {
+		resp         *discoveryv3.DiscoveryResponse
+		version      string
+		rejectedList []string
+	}{
+		{
+			resp: &discoveryv3.DiscoveryResponse{
+				VersionInfo: "c1",
+				Nonce:       "n1",
+				TypeUrl:     types.ClusterUrl,
+				Resources:   []*any.Any{cluster},
+			},
+			version: "c1",
+		},
+		{
+			resp: &discoveryv3.DiscoveryResponse{
+				VersionInfo: "r1",
+				Nonce:       "n2",
+				TypeUrl:     types.RouteConfigurationUrl,
+				Resources: []*any.Any{
+					newMalformedResource(types.RouteConfigurationUrl, "bad-rc1"),
+					rc,
+					newMalformedResource(types.RouteConfigurationUrl, "bad-rc2"),
+				},
+			},
+			// RDS was never accepted, CDS version shouldn't be used.
+			version:      "",
+			rejectedList: []string{"bad-rc1", "bad-rc2"},
+		},
+		{
+			resp: &discoveryv3.DiscoveryResponse{
+				VersionInfo: "r2",
+				Nonce:       "n3",
+				TypeUrl:     types.RouteConfigurationUrl,
+				Resources:   []*any.Any{rc},
+			},
+			version: "r2",
+		},
+		{
+			resp: &discoveryv3.DiscoveryResponse{
+				VersionInfo: "c2",
+				Nonce:       "n4",
+				TypeUrl:     types.ClusterUrl,
+				Resources: []*any.Any{
+					cluster,
+					newMalformedResource(types.ClusterUrl, "bad-cluster"),
+				},
+			},
+			version:      "c1",
+			rejectedList: []string{"bad-cluster"},
+		},
+		{
+			resp: &discoveryv3.DiscoveryResponse{
+				VersionInfo: "r3",
+				Nonce:       "n5",
+				TypeUrl:     types.RouteConfigurationUrl,
+				Resources: []*any.Any{
+					newMalformedResource(types.RouteConfigurationUrl, "bad-rc3"),
+				},
+			},
+			version:      "r2",
+			rejectedList: []string{"bad-rc3"},
+		},
+	}
+	for _, tc := range testCases {
+		srv.sendCh <- tc.resp
+		req := <-srv.recvCh
+		assert.Equal(t, req.TypeUrl, tc.resp.TypeUrl, tc.resp.Nonce)
+		assert.Equal(t, req.ResponseNonce, tc.resp.Nonce)
+		assert.Equal(t, req.VersionInfo, tc.version, tc.resp.Nonce)
+		if tc.rejectedList == nil {
+			assert.Nil(t, req.ErrorDetail, tc.resp.Nonce)
+			continue
+		}
+		assert.NotNil(t, req.ErrorDetail, tc.resp.Nonce)
+		assert.Len(t, req.ErrorDetail.Details, 1)
+		var br errdetails.BadRequest
+		assert.Nil(t, req.ErrorDetail.Details[0].UnmarshalTo(&br))
+		var rejected []string
+		for _, fv := range br.FieldViolations {
+			rejected = append(rejected, fv.Field)
+		}
+		assert.Equal(t, rejected, tc.rejectedList, tc.resp.Nonce)
+	}
+	// States are inspected after the provisioner exited, so that
+	// they're not accessed concurrently.
+	close(stopCh)
+	select {
+	case err := <-runCh:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		assert.FailNow(t, "provisioner doesn't exit in time")
+	}
+	// The rejected CDS response doesn't affect the state.
+	assert.Len(t, gp.upstreams, 1)
+}
@@ -0,0 +1,247 @@
+package grpc
+
+import (
+	"sort"
+
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func (p *grpcProvisioner) unmarshalSecretV3(res *any.Any) (*tlsv3.Secret, error) {
+	var secret tlsv3.Secret
+	err := anypb.UnmarshalTo(res, &secret, proto.UnmarshalOptions{
+		DiscardUnknown: true,
+	})
+	if err != nil {
+		// Don't log the resource since it contains the private key.
+		p.logger.Errorw("found invalid Secret resource",
+			zap.Error(err),
+			zap.String("type_url", res.GetTypeUrl()),
+		)
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// translateSecret translates the secret to SSL, secrets which cannot be
+// represented by SSL (like the validation context) are ignored.
+func (p *grpcProvisioner) translateSecret(secret *tlsv3.Secret, opts *xdsv3.TranslateOptions) (*apisix.SSL, error) {
+	ssl, err := p.v3Adaptor.TranslateSecret(secret, opts)
+	if err == xdsv3.ErrFeatureNotSupportedYet {
+		p.logger.Warnw("ignore secret which cannot be translated to APISIX SSL",
+			zap.String("secret", secret.GetName()),
+		)
+		return nil, nil
+	}
+	if err != nil {
+		p.logger.Errorw("failed to translate Secret to APISIX SSL",
+			zap.Error(err),
+			zap.String("secret", secret.GetName()),
+		)
+		return nil, err
+	}
+	return ssl, nil
+}
+
+// processSecretsV3 validates the secrets in SDS response, the last received
+// secrets will be replaced only if all of them are valid.
+func (p *grpcProvisioner) processSecretsV3(resources []*any.Any) (map[string]*tlsv3.Secret, error) {
+	var errs resourceErrors
+	secrets := make(map[string]*tlsv3.Secret)
+	opts := p.translateOptions()
+	for _, res := range resources {
+		secret, err := p.unmarshalSecretV3(res)
+		if err != nil {
+			errs.add(resourceName(res), err)
+			continue
+		}
+		if _, err := p.translateSecret(secret, opts); err != nil {
+			errs.add(secret.GetName(), err)
+			continue
+		}
+		secrets[secret.GetName()] = secret
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// retranslateSSLs re-translates the SSLs from the last received secrets, the
+// SSLs are filled into the manifests so that changes can be generated together.
+// SSLs depend on the listeners (server names), so they're re-translated once
+// the listeners or the secrets changed.
+func (p *grpcProvisioner) retranslateSSLs(m, o *util.Manifest) {
+	if len(p.secrets) == 0 && len(p.localSecrets) == 0 && len(p.ssls) == 0 {
+		return
+	}
+	var ssls []*apisix.SSL
+	opts := p.translateOptions()
+	for _, name := range p.referencedSecrets().OrderedStrings() {
+		secret, ok := p.secret(name)
+		if !ok {
+			continue
+		}
+		// Secrets were validated when they were received.
+		ssl, err := p.translateSecret(secret, opts)
+		if err != nil || ssl == nil {
+			continue
+		}
+		ssls = append(ssls, ssl)
+	}
+	m.SSLs = ssls
+	o.SSLs = p.ssls
+	p.ssls = ssls
+}
+
+// clientCertificate returns the TLS settings of the upstream translated
+// from the cluster, nil will be returned if the cluster doesn't use a
+// client certificate or the secret is not received yet.
+func (p *grpcProvisioner) clientCertificate(clusterName string) *apisix.Upstream_TLS {
+	names := p.clusterSecrets[clusterName]
+	if len(names) == 0 {
+		return nil
+	}
+	// Only the first client certificate is used, see the adaptor.
+	secret, ok := p.secret(names[0])
+	if !ok {
+		return nil
+	}
+	tls, err := p.v3Adaptor.TranslateClientCertificate(secret)
+	if err != nil {
+		// Secrets were validated when they were received, so it's
+		// a secret that cannot be used as the client certificate.
+		p.logger.Warnw("ignore client certificate which cannot be translated",
+			zap.Error(err),
+			zap.String("secret", names[0]),
+			zap.String("cluster", clusterName),
+		)
+		return nil
+	}
+	return tls
+}
+
+// retranslateClientCertificates updates the client certificates of upstreams
+// once the secrets changed, the changed upstreams are filled into the manifests
+// so that changes can be generated together.
+func (p *grpcProvisioner) retranslateClientCertificates(m, o *util.Manifest) {
+	names := make([]string, 0, len(p.clusterSecrets))
+	for name := range p.clusterSecrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ups, ok := p.upstreams[name]
+		if !ok {
+			continue
+		}
+		tls := p.clientCertificate(name)
+		if proto.Equal(ups.GetTls(), tls) {
+			continue
+		}
+		// Do not set on the original ups to avoid race conditions.
+		newUps := proto.Clone(ups).(*apisix.Upstream)
+		newUps.Tls = tls
+		p.upstreams[name] = newUps
+		o.Upstreams = append(o.Upstreams, ups)
+		m.Upstreams = append(m.Upstreams, newUps)
+	}
+}
+
+// secret returns the secret by the name, the local secrets take precedence
+// over the ones from SDS.
+func (p *grpcProvisioner) secret(name string) (*tlsv3.Secret, bool) {
+	if secret, ok := p.localSecrets[name]; ok {
+		return secret, true
+	}
+	secret, ok := p.secrets[name]
+	return secret, ok
+}
+
+// clientSecrets returns names of secrets which are used by clusters.
+func (p *grpcProvisioner) clientSecrets() set.StringSet {
+	names := set.StringSet{}
+	for _, list := range p.clusterSecrets {
+		for _, name := range list {
+			names.Add(name)
+		}
+	}
+	return names
+}
+
+// referencedSecrets returns names of secrets which are used by listeners
+// and clusters.
+func (p *grpcProvisioner) referencedSecrets() set.StringSet {
+	names := p.clientSecrets()
+	for name := range p.listenerSecrets {
+		names.Add(name)
+	}
+	return names
+}
+
+// trySendSds sends the SDS discovery request if the secrets which are
+// referenced by listeners and clusters changed, secrets provided locally
+// are excluded.
+func (p *grpcProvisioner) trySendSds() {
+	sdsNames := set.StringSet{}
+	for name := range p.referencedSecrets() {
+		if _, ok := p.localSecretNames[name]; !ok {
+			sdsNames.Add(name)
+		}
+	}
+	if sdsNames.Equal(p.sdsNames) {
+		return
+	}
+	p.sdsNames = sdsNames
+	for name := range p.secrets {
+		if _, ok := sdsNames[name]; !ok {
+			delete(p.secrets, name)
+		}
+	}
+	if p.delta {
+		p.deltaSubscribe(types.SecretUrl, sdsNames.OrderedStrings())
+		return
+	}
+	if len(sdsNames) == 0 {
+		return
+	}
+	dr := &discoveryv3.DiscoveryRequest{
+		Node:          p.node,
+		ResourceNames: sdsNames.OrderedStrings(),
+		TypeUrl:       types.SecretUrl,
+		VersionInfo:   p.acceptedVersions[types.SecretUrl],
+		ResponseNonce: p.nonces[types.SecretUrl],
+	}
+	p.logger.Debugw("sending SDS discovery request",
+		zap.Any("body", dr),
+	)
+	p.send(dr)
+}
+
+// updateLocalSecret replaces the local secret (like the rotated workload
+// certificate) and re-translates the SSLs, the last one will be kept if
+// the new one is invalid.
+func (p *grpcProvisioner) updateLocalSecret(secret *tlsv3.Secret) {
+	if _, err := p.translateSecret(secret, p.translateOptions()); err != nil {
+		return
+	}
+	p.localSecrets[secret.GetName()] = secret
+
+	var (
+		m util.Manifest
+		o util.Manifest
+	)
+	p.retranslateSSLs(&m, &o)
+	p.retranslateClientCertificates(&m, &o)
+	p.pendingEvents = append(p.pendingEvents, p.generateEvents(&m, &o)...)
+}
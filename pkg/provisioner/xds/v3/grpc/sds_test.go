@@ -0,0 +1,294 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func newTestTlsTransportSocket(t *testing.T, ctx proto.Message) *corev3.TransportSocket {
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, ctx, proto.MarshalOptions{}))
+	return &corev3.TransportSocket{
+		Name: xdswellknown.TransportSocketTls,
+		ConfigType: &corev3.TransportSocket_TypedConfig{
+			TypedConfig: &opaque,
+		},
+	}
+}
+
+func newTestSdsTlsContext(name string) *tlsv3.CommonTlsContext {
+	return &tlsv3.CommonTlsContext{
+		TlsCertificateSdsSecretConfigs: []*tlsv3.SdsSecretConfig{
+			{
+				Name: name,
+			},
+		},
+	}
+}
+
+func newTestTlsListener(t *testing.T, secret string, serverNames ...string) *listenerv3.Listener {
+	return &listenerv3.Listener{
+		Name: "0.0.0.0_443",
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Address: "0.0.0.0",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: 443,
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					ServerNames: serverNames,
+				},
+				TransportSocket: newTestTlsTransportSocket(t, &tlsv3.DownstreamTlsContext{
+					CommonTlsContext: newTestSdsTlsContext(secret),
+				}),
+			},
+		},
+	}
+}
+
+func newTestTlsCluster(t *testing.T, name, secret string) *clusterv3.Cluster {
+	return &clusterv3.Cluster{
+		Name:     name,
+		LbPolicy: clusterv3.Cluster_ROUND_ROBIN,
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{
+			Type: clusterv3.Cluster_STATIC,
+		},
+		TransportSocket: newTestTlsTransportSocket(t, &tlsv3.UpstreamTlsContext{
+			CommonTlsContext: newTestSdsTlsContext(secret),
+		}),
+	}
+}
+
+func newTestSecret(name, cert, key string) *tlsv3.Secret {
+	return &tlsv3.Secret{
+		Name: name,
+		Type: &tlsv3.Secret_TlsCertificate{
+			TlsCertificate: &tlsv3.TlsCertificate{
+				CertificateChain: &corev3.DataSource{
+					Specifier: &corev3.DataSource_InlineString{
+						InlineString: cert,
+					},
+				},
+				PrivateKey: &corev3.DataSource{
+					Specifier: &corev3.DataSource_InlineString{
+						InlineString: key,
+					},
+				},
+			},
+		},
+	}
+}
+
+func receiveDiscoveryRequest(t *testing.T, gp *grpcProvisioner) *discoveryv3.DiscoveryRequest {
+	select {
+	case dr := <-gp.sendCh:
+		return dr
+	case <-time.After(time.Second):
+		assert.FailNow(t, "DiscoveryRequest was not sent in time")
+	}
+	return nil
+}
+
+func TestTranslateSecrets(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	gp.sendCh = make(chan *discoveryv3.DiscoveryRequest, 1)
+
+	// The secret referenced by listener is subscribed.
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "1",
+		TypeUrl:     types.ListenerUrl,
+		Resources: []*any.Any{
+			newResource(t, types.ListenerUrl, newTestTlsListener(t, "httpbin", "httpbin.org")),
+		},
+	})
+	assert.Nil(t, err)
+	takeEvents(gp)
+	dr := receiveDiscoveryRequest(t, gp)
+	assert.Equal(t, dr.TypeUrl, types.SecretUrl)
+	assert.Equal(t, dr.ResourceNames, []string{"httpbin"})
+
+	// The secret referenced by cluster is subscribed.
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "1",
+		TypeUrl:     types.ClusterUrl,
+		Resources: []*any.Any{
+			newResource(t, types.ClusterUrl, newTestTlsCluster(t, "httpbin.default.svc.cluster.local", "default")),
+		},
+	})
+	assert.Nil(t, err)
+	events := takeEvents(gp)
+	assert.Len(t, events, 1)
+	ups := events[0].Object.(*apisix.Upstream)
+	assert.Equal(t, ups.Scheme, "https")
+	// The client certificate is filled once the secret is received.
+	assert.Nil(t, ups.Tls)
+	dr = receiveDiscoveryRequest(t, gp)
+	assert.Equal(t, dr.TypeUrl, types.SecretUrl)
+	assert.Equal(t, dr.ResourceNames, []string{"default", "httpbin"})
+
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "1",
+		TypeUrl:     types.SecretUrl,
+		Resources: []*any.Any{
+			newResource(t, types.SecretUrl, newTestSecret("httpbin", "httpbin-cert", "httpbin-key")),
+			newResource(t, types.SecretUrl, newTestSecret("default", "default-cert", "default-key")),
+		},
+	})
+	assert.Nil(t, err)
+	events = takeEvents(gp)
+	assert.Len(t, events, 2)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Equal(t, events[0].Object, &apisix.SSL{
+		Id:   id.GenID("httpbin"),
+		Cert: "httpbin-cert",
+		Key:  "httpbin-key",
+		Snis: []string{"httpbin.org"},
+	})
+	assert.Equal(t, events[1].Type, types.EventUpdate)
+	tls := events[1].Object.(*apisix.Upstream).Tls
+	assert.Equal(t, tls.ClientCert, "default-cert")
+	assert.Equal(t, tls.ClientKey, "default-key")
+
+	// Rotation.
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "2",
+		TypeUrl:     types.SecretUrl,
+		Resources: []*any.Any{
+			newResource(t, types.SecretUrl, newTestSecret("httpbin", "httpbin-cert", "httpbin-key")),
+			newResource(t, types.SecretUrl, newTestSecret("default", "default-cert2", "default-key2")),
+		},
+	})
+	assert.Nil(t, err)
+	events = takeEvents(gp)
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Tls.ClientCert, "default-cert2")
+	assert.Equal(t, gp.upstreams["httpbin.default.svc.cluster.local"].Tls.ClientKey, "default-key2")
+
+	// Incomplete certificate is rejected and the last state is kept.
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "3",
+		TypeUrl:     types.SecretUrl,
+		Resources: []*any.Any{
+			newResource(t, types.SecretUrl, newTestSecret("httpbin", "", "httpbin-key")),
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Len(t, gp.secrets, 2)
+	assert.Len(t, gp.ssls, 1)
+
+	// The server names changed.
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "2",
+		TypeUrl:     types.ListenerUrl,
+		Resources: []*any.Any{
+			newResource(t, types.ListenerUrl, newTestTlsListener(t, "httpbin", "httpbin.org", "httpbin.com")),
+		},
+	})
+	assert.Nil(t, err)
+	events = takeEvents(gp)
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.SSL).Snis, []string{"httpbin.com", "httpbin.org"})
+
+	// The secret is not referenced anymore.
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "3",
+		TypeUrl:     types.ListenerUrl,
+	})
+	assert.Nil(t, err)
+	events = takeEvents(gp)
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventDelete)
+	assert.Equal(t, events[0].Tombstone.(*apisix.SSL).Id, id.GenID("httpbin"))
+	dr = receiveDiscoveryRequest(t, gp)
+	assert.Equal(t, dr.TypeUrl, types.SecretUrl)
+	assert.Equal(t, dr.ResourceNames, []string{"default"})
+	assert.Len(t, gp.secrets, 1)
+
+	drs := gp.initialRequests()
+	assert.Equal(t, drs[len(drs)-1].TypeUrl, types.SecretUrl)
+	assert.Equal(t, drs[len(drs)-1].ResourceNames, []string{"default"})
+}
+
+func TestTranslateDeltaSecrets(t *testing.T) {
+	gp := newDeltaProvisioner(t, "grpc://127.0.0.1:11111")
+	gp.deltaSendCh = make(chan *discoveryv3.DeltaDiscoveryRequest, 1)
+
+	err := gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.ClusterUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "httpbin.default.svc.cluster.local", "1",
+				newTestTlsCluster(t, "httpbin.default.svc.cluster.local", "default")),
+		},
+	})
+	assert.Nil(t, err)
+	takeEvents(gp)
+	dr := <-gp.deltaSendCh
+	assert.Equal(t, dr.TypeUrl, types.SecretUrl)
+	assert.Equal(t, dr.ResourceNamesSubscribe, []string{"default"})
+
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl: types.SecretUrl,
+		Resources: []*discoveryv3.Resource{
+			newDeltaResource(t, "default", "1", newTestSecret("default", "cert", "key")),
+		},
+	})
+	assert.Nil(t, err)
+	events := takeEvents(gp)
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Tls.ClientCert, "cert")
+	assert.Equal(t, gp.resourceVersions[types.SecretUrl], map[string]string{"default": "1"})
+
+	// The cluster is removed, so is the secret.
+	err = gp.translateDelta(&discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl:          types.ClusterUrl,
+		RemovedResources: []string{"httpbin.default.svc.cluster.local"},
+	})
+	assert.Nil(t, err)
+	events = takeEvents(gp)
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventDelete)
+	assert.Equal(t, events[0].Tombstone.(*apisix.Upstream).Name, "httpbin.default.svc.cluster.local")
+	dr = <-gp.deltaSendCh
+	assert.Equal(t, dr.TypeUrl, types.SecretUrl)
+	assert.Equal(t, dr.ResourceNamesUnsubscribe, []string{"default"})
+	assert.Len(t, gp.secrets, 0)
+	assert.Len(t, gp.resourceVersions[types.SecretUrl], 0)
+}
@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+// translationState contains the states that the translation of a discovery
+// response might change.
+type translationState struct {
+	deltaListeners           map[string]*listenerv3.Listener
+	deltaRouteConfigurations map[string]*routev3.RouteConfiguration
+	deltaVirtualHosts        map[string]*routev3.VirtualHost
+	routesByConfiguration    map[string][]*apisix.Route
+	upstreams                map[string]*apisix.Upstream
+	edsRequiredClusters      set.StringSet
+	clusterSecrets           map[string][]string
+	secrets                  map[string]*tlsv3.Secret
+
+	// The following states are always replaced rather than changed in place.
+	routeConfigurations       []*routev3.RouteConfiguration
+	staticRouteConfigurations []*routev3.RouteConfiguration
+	virtualHosts              map[string][]*routev3.VirtualHost
+	listeners                 []*listenerv3.Listener
+	rdsNames                  []string
+	routeOwnership            map[string]string
+	inboundRoutes             set.StringSet
+	routeHTTPFilters          map[string][]*hcmv3.HttpFilter
+	routeUpgradeConfigs       map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig
+	routeFilterChains         map[string][]*xdsv3.FilterChain
+	listenerSecrets           map[string][]string
+	routes                    []*apisix.Route
+	streamRoutes              []*apisix.StreamRoute
+	ssls                      []*apisix.SSL
+}
+
+// stageState replaces the states that are changed in place with copies, so
+// that the translation of a discovery response is staged on them, the original
+// states are returned, they're restored if the response is rejected.
+func (p *grpcProvisioner) stageState() *translationState {
+	original := &translationState{
+		deltaListeners:            p.deltaListeners,
+		deltaRouteConfigurations:  p.deltaRouteConfigurations,
+		deltaVirtualHosts:         p.deltaVirtualHosts,
+		routesByConfiguration:     p.routesByConfiguration,
+		upstreams:                 p.upstreams,
+		edsRequiredClusters:       p.edsRequiredClusters,
+		clusterSecrets:            p.clusterSecrets,
+		secrets:                   p.secrets,
+		routeConfigurations:       p.routeConfigurations,
+		staticRouteConfigurations: p.staticRouteConfigurations,
+		virtualHosts:              p.virtualHosts,
+		listeners:                 p.listeners,
+		rdsNames:                  p.rdsNames,
+		routeOwnership:            p.routeOwnership,
+		inboundRoutes:             p.inboundRoutes,
+		routeHTTPFilters:          p.routeHTTPFilters,
+		routeUpgradeConfigs:       p.routeUpgradeConfigs,
+		routeFilterChains:         p.routeFilterChains,
+		listenerSecrets:           p.listenerSecrets,
+		routes:                    p.routes,
+		streamRoutes:              p.streamRoutes,
+		ssls:                      p.ssls,
+	}
+
+	p.deltaListeners = make(map[string]*listenerv3.Listener, len(original.deltaListeners))
+	for name, l := range original.deltaListeners {
+		p.deltaListeners[name] = l
+	}
+	p.deltaRouteConfigurations = make(map[string]*routev3.RouteConfiguration, len(original.deltaRouteConfigurations))
+	for name, rc := range original.deltaRouteConfigurations {
+		p.deltaRouteConfigurations[name] = rc
+	}
+	p.deltaVirtualHosts = make(map[string]*routev3.VirtualHost, len(original.deltaVirtualHosts))
+	for name, vhost := range original.deltaVirtualHosts {
+		p.deltaVirtualHosts[name] = vhost
+	}
+	p.routesByConfiguration = make(map[string][]*apisix.Route, len(original.routesByConfiguration))
+	for name, routes := range original.routesByConfiguration {
+		p.routesByConfiguration[name] = routes
+	}
+	p.upstreams = make(map[string]*apisix.Upstream, len(original.upstreams))
+	for name, ups := range original.upstreams {
+		p.upstreams[name] = ups
+	}
+	p.edsRequiredClusters = set.StringSet{}
+	for name := range original.edsRequiredClusters {
+		p.edsRequiredClusters.Add(name)
+	}
+	p.clusterSecrets = make(map[string][]string, len(original.clusterSecrets))
+	for name, secrets := range original.clusterSecrets {
+		p.clusterSecrets[name] = secrets
+	}
+	p.secrets = make(map[string]*tlsv3.Secret, len(original.secrets))
+	for name, secret := range original.secrets {
+		p.secrets[name] = secret
+	}
+	return original
+}
+
+// restoreState discards the staged states.
+func (p *grpcProvisioner) restoreState(original *translationState) {
+	p.deltaListeners = original.deltaListeners
+	p.deltaRouteConfigurations = original.deltaRouteConfigurations
+	p.deltaVirtualHosts = original.deltaVirtualHosts
+	p.routesByConfiguration = original.routesByConfiguration
+	p.upstreams = original.upstreams
+	p.edsRequiredClusters = original.edsRequiredClusters
+	p.clusterSecrets = original.clusterSecrets
+	p.secrets = original.secrets
+	p.routeConfigurations = original.routeConfigurations
+	p.staticRouteConfigurations = original.staticRouteConfigurations
+	p.virtualHosts = original.virtualHosts
+	p.listeners = original.listeners
+	p.rdsNames = original.rdsNames
+	p.routeOwnership = original.routeOwnership
+	p.inboundRoutes = original.inboundRoutes
+	p.routeHTTPFilters = original.routeHTTPFilters
+	p.routeUpgradeConfigs = original.routeUpgradeConfigs
+	p.routeFilterChains = original.routeFilterChains
+	p.listenerSecrets = original.listenerSecrets
+	p.routes = original.routes
+	p.streamRoutes = original.streamRoutes
+	p.ssls = original.ssls
+}
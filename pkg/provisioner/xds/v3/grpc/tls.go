@@ -0,0 +1,173 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+)
+
+var (
+	_errBadCABundle       = errors.New("no certificate found in CA bundle")
+	_errNoPeerCertificate = errors.New("no peer certificate")
+)
+
+// tlsFiles loads the CA bundle and the client certificate from files,
+// files will be reloaded in the next TLS handshake once they changed
+// on disk, so that the rotated certificates can be used when reconnecting.
+type tlsFiles struct {
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string
+	logger     *log.Logger
+
+	mu          sync.Mutex
+	caModTime   time.Time
+	roots       *x509.CertPool
+	certModTime time.Time
+	keyModTime  time.Time
+	cert        *tls.Certificate
+}
+
+// tlsConfig returns the TLS config, certificate verification is done in
+// the VerifyConnection callback so that the latest CA bundle is used.
+func (f *tlsFiles) tlsConfig() (*tls.Config, error) {
+	// Check files in advance, so that errors can be reported early.
+	if _, err := f.rootCAs(); err != nil {
+		return nil, err
+	}
+	if f.certFile != "" {
+		if _, err := f.clientCertificate(); err != nil {
+			return nil, err
+		}
+	}
+	cfg := &tls.Config{
+		ServerName: f.serverName,
+		// Verified in VerifyConnection.
+		InsecureSkipVerify: true,
+		VerifyConnection:   f.verifyConnection,
+	}
+	if f.certFile != "" {
+		cfg.GetClientCertificate = func(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return f.clientCertificate()
+		}
+	}
+	return cfg, nil
+}
+
+func (f *tlsFiles) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return _errNoPeerCertificate
+	}
+	roots, err := f.rootCAs()
+	if err != nil {
+		return err
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err = cs.PeerCertificates[0].Verify(opts)
+	if err != nil {
+		f.logger.Errorw("failed to verify the xds config source certificate",
+			zap.Error(err),
+			zap.String("server_name", cs.ServerName),
+		)
+	}
+	return err
+}
+
+// rootCAs returns the CA pool, nil will be returned if the CA bundle is not
+// specified, in such a case, the system roots will be used.
+func (f *tlsFiles) rootCAs() (*x509.CertPool, error) {
+	if f.caFile == "" {
+		return nil, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	modTime, err := fileModTime(f.caFile)
+	if err != nil {
+		return nil, err
+	}
+	if f.roots != nil && modTime.Equal(f.caModTime) {
+		return f.roots, nil
+	}
+	data, err := ioutil.ReadFile(f.caFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, _errBadCABundle
+	}
+	if f.roots != nil {
+		f.logger.Infow("CA bundle reloaded",
+			zap.String("ca_file", f.caFile),
+		)
+	}
+	f.roots = roots
+	f.caModTime = modTime
+	return roots, nil
+}
+
+func (f *tlsFiles) clientCertificate() (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	certModTime, err := fileModTime(f.certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyModTime, err := fileModTime(f.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if f.cert != nil && certModTime.Equal(f.certModTime) && keyModTime.Equal(f.keyModTime) {
+		return f.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		if f.cert != nil {
+			// The certificate and the private key might be written
+			// separately, keep using the old one until they're matched.
+			f.logger.Warnw("failed to reload client certificate, the old one is used",
+				zap.Error(err),
+				zap.String("cert_file", f.certFile),
+				zap.String("key_file", f.keyFile),
+			)
+			return f.cert, nil
+		}
+		return nil, err
+	}
+	if f.cert != nil {
+		f.logger.Infow("client certificate reloaded",
+			zap.String("cert_file", f.certFile),
+			zap.String("key_file", f.keyFile),
+		)
+	}
+	f.cert = &cert
+	f.certModTime = certModTime
+	f.keyModTime = keyModTime
+	return f.cert, nil
+}
+
+func fileModTime(name string) (time.Time, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
@@ -0,0 +1,238 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/nettest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue issues a certificate, returns the PEM encoded certificate and private key.
+func (ca *testCA) issue(t *testing.T, serial int64, dnsName string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func writeTestFile(t *testing.T, name string, data []byte, modTime time.Time) {
+	assert.Nil(t, ioutil.WriteFile(name, data, 0600))
+	assert.Nil(t, os.Chtimes(name, modTime, modTime))
+}
+
+func TestTLSFilesRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xds-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	f := &tlsFiles{
+		caFile:   filepath.Join(dir, "root-cert.pem"),
+		certFile: filepath.Join(dir, "cert-chain.pem"),
+		keyFile:  filepath.Join(dir, "key.pem"),
+		logger:   log.DefaultLogger,
+	}
+
+	_, err = f.tlsConfig()
+	assert.NotNil(t, err)
+
+	now := time.Now()
+	certPEM, keyPEM := ca.issue(t, 2, "sidecar")
+	writeTestFile(t, f.caFile, ca.pem, now)
+	writeTestFile(t, f.certFile, certPEM, now)
+	writeTestFile(t, f.keyFile, keyPEM, now)
+
+	cfg, err := f.tlsConfig()
+	assert.Nil(t, err)
+	cert1, err := cfg.GetClientCertificate(nil)
+	assert.Nil(t, err)
+	leaf, err := x509.ParseCertificate(cert1.Certificate[0])
+	assert.Nil(t, err)
+	assert.Equal(t, leaf.SerialNumber.Int64(), int64(2))
+
+	// Unchanged files are not reloaded.
+	cert, err := cfg.GetClientCertificate(nil)
+	assert.Nil(t, err)
+	assert.True(t, cert == cert1)
+
+	// Only the certificate is rotated, the old one is kept.
+	certPEM, keyPEM = ca.issue(t, 3, "sidecar")
+	writeTestFile(t, f.certFile, certPEM, now.Add(time.Second))
+	cert, err = cfg.GetClientCertificate(nil)
+	assert.Nil(t, err)
+	assert.True(t, cert == cert1)
+
+	writeTestFile(t, f.keyFile, keyPEM, now.Add(time.Second))
+	cert, err = cfg.GetClientCertificate(nil)
+	assert.Nil(t, err)
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	assert.Nil(t, err)
+	assert.Equal(t, leaf.SerialNumber.Int64(), int64(3))
+
+	// CA rotated.
+	roots1, err := f.rootCAs()
+	assert.Nil(t, err)
+	writeTestFile(t, f.caFile, newTestCA(t).pem, now.Add(time.Second))
+	roots2, err := f.rootCAs()
+	assert.Nil(t, err)
+	assert.False(t, roots1 == roots2)
+
+	writeTestFile(t, f.caFile, []byte("bad"), now.Add(2*time.Second))
+	_, err = f.rootCAs()
+	assert.Equal(t, err, _errBadCABundle)
+}
+
+func TestGRPCProvisionerWithMTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xds-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	serverCertPEM, serverKeyPEM := ca.issue(t, 2, "istiod.istio-system.svc")
+	clientCertPEM, clientKeyPEM := ca.issue(t, 3, "sidecar")
+	now := time.Now()
+	caFile := filepath.Join(dir, "root-cert.pem")
+	certFile := filepath.Join(dir, "cert-chain.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestFile(t, caFile, ca.pem, now)
+	writeTestFile(t, certFile, clientCertPEM, now)
+	writeTestFile(t, keyFile, clientKeyPEM, now)
+
+	serverCert, err := tls.X509KeyPair(serverCertPEM, serverKeyPEM)
+	assert.Nil(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	ln, err := nettest.NewLocalListener("tcp")
+	assert.Nil(t, err)
+	grpcSrv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	go func() {
+		err := grpcSrv.Serve(ln)
+		assert.Nil(t, err)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &fakeXdsServer{
+		t:      t,
+		sendCh: make(chan *discoveryv3.DiscoveryResponse),
+		recvCh: make(chan *discoveryv3.DiscoveryRequest),
+		ctx:    ctx,
+	}
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(grpcSrv, srv)
+
+	cfg := &config.Config{
+		RunId:             "12345",
+		LogLevel:          "info",
+		LogOutput:         "stderr",
+		Provisioner:       "xds-v3-grpc",
+		XDSConfigSource:   "grpcs://" + ln.Addr().String(),
+		XDSCAFile:         caFile,
+		XDSClientCertFile: certFile,
+		XDSClientKeyFile:  keyFile,
+		XDSServerName:     "istiod.istio-system.svc",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+
+	stopCh := make(chan struct{})
+	go func() {
+		err := p.Run(stopCh)
+		assert.Nil(t, err)
+	}()
+
+	select {
+	case dr := <-srv.recvCh:
+		assert.Contains(t, []string{types.ListenerUrl, types.ClusterUrl}, dr.TypeUrl)
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "DiscoveryRequest is not received in time")
+	}
+}
+
+func TestNewXDSProvisionerWithBadTLSFiles(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpcs://127.0.0.1:15012",
+		XDSCAFile:       "/path/not/exist",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, p)
+	assert.True(t, os.IsNotExist(err))
+}
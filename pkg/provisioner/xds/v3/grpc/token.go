@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+)
+
+var (
+	_errEmptyToken       = errors.New("empty token")
+	_errMalformedToken   = errors.New("malformed token")
+	_errAudienceMismatch = errors.New("token audience mismatch")
+)
+
+// tokenCredentials is a credentials.PerRPCCredentials which attaches the bearer
+// token (like the Kubernetes projected service account token) to each RPC,
+// the token file will be re-read once it changed on disk.
+type tokenCredentials struct {
+	file string
+	// If not empty, the token should be issued for at least one of
+	// these audiences.
+	audiences  []string
+	requireTLS bool
+	logger     *log.Logger
+
+	mu      sync.Mutex
+	modTime time.Time
+	token   string
+}
+
+var _ credentials.PerRPCCredentials = (*tokenCredentials)(nil)
+
+func (tc *tokenCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	token, err := tc.loadToken()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"authorization": "Bearer " + token,
+	}, nil
+}
+
+func (tc *tokenCredentials) RequireTransportSecurity() bool {
+	return tc.requireTLS
+}
+
+func (tc *tokenCredentials) loadToken() (string, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	modTime, err := fileModTime(tc.file)
+	if err != nil {
+		return "", err
+	}
+	if tc.token != "" && modTime.Equal(tc.modTime) {
+		return tc.token, nil
+	}
+	data, err := ioutil.ReadFile(tc.file)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", _errEmptyToken
+	}
+	if len(tc.audiences) > 0 {
+		if err := checkTokenAudiences(token, tc.audiences); err != nil {
+			tc.logger.Errorw("bad token",
+				zap.Error(err),
+				zap.String("token_file", tc.file),
+				zap.Strings("audiences", tc.audiences),
+			)
+			return "", err
+		}
+	}
+	if tc.token != "" {
+		tc.logger.Infow("token reloaded",
+			zap.String("token_file", tc.file),
+		)
+	}
+	tc.token = token
+	tc.modTime = modTime
+	return token, nil
+}
+
+// checkTokenAudiences checks whether the JWT is issued for one of the audiences,
+// note the signature is not verified as it's the duty of the config source.
+func checkTokenAudiences(token string, audiences []string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return _errMalformedToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return _errMalformedToken
+	}
+	var claims struct {
+		// The "aud" claim can be either a string or an array of strings.
+		Aud interface{} `json:"aud"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return _errMalformedToken
+	}
+	var tokenAudiences []string
+	switch aud := claims.Aud.(type) {
+	case string:
+		tokenAudiences = append(tokenAudiences, aud)
+	case []interface{}:
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				tokenAudiences = append(tokenAudiences, s)
+			}
+		}
+	}
+	for _, expected := range audiences {
+		for _, aud := range tokenAudiences {
+			if aud == expected {
+				return nil
+			}
+		}
+	}
+	return _errAudienceMismatch
+}
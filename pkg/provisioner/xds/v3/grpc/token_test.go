@@ -0,0 +1,140 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/nettest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+)
+
+func newTestToken(payload string) string {
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestCheckTokenAudiences(t *testing.T) {
+	audiences := []string{"istio-ca"}
+	assert.Nil(t, checkTokenAudiences(newTestToken(`{"aud":"istio-ca"}`), audiences))
+	assert.Nil(t, checkTokenAudiences(newTestToken(`{"aud":["kubernetes","istio-ca"]}`), audiences))
+	assert.Equal(t, checkTokenAudiences(newTestToken(`{"aud":["kubernetes"]}`), audiences), _errAudienceMismatch)
+	assert.Equal(t, checkTokenAudiences(newTestToken(`{"sub":"abc"}`), audiences), _errAudienceMismatch)
+	assert.Equal(t, checkTokenAudiences(newTestToken(`{"aud":`), audiences), _errMalformedToken)
+	assert.Equal(t, checkTokenAudiences("abc", audiences), _errMalformedToken)
+}
+
+func TestTokenCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xds-token")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	tc := &tokenCredentials{
+		file:      filepath.Join(dir, "istio-token"),
+		audiences: []string{"istio-ca"},
+		logger:    log.DefaultLogger,
+	}
+	_, err = tc.GetRequestMetadata(context.Background())
+	assert.True(t, os.IsNotExist(err))
+
+	now := time.Now()
+	token1 := newTestToken(`{"aud":["istio-ca"],"sub":"1"}`)
+	writeTestFile(t, tc.file, []byte(token1+"\n"), now)
+	md, err := tc.GetRequestMetadata(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, md["authorization"], "Bearer "+token1)
+
+	// Rotated.
+	token2 := newTestToken(`{"aud":["istio-ca"],"sub":"2"}`)
+	writeTestFile(t, tc.file, []byte(token2), now.Add(time.Second))
+	md, err = tc.GetRequestMetadata(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, md["authorization"], "Bearer "+token2)
+
+	writeTestFile(t, tc.file, []byte(newTestToken(`{"aud":["kubernetes"]}`)), now.Add(2*time.Second))
+	_, err = tc.GetRequestMetadata(context.Background())
+	assert.Equal(t, err, _errAudienceMismatch)
+
+	writeTestFile(t, tc.file, []byte(" \n"), now.Add(3*time.Second))
+	_, err = tc.GetRequestMetadata(context.Background())
+	assert.Equal(t, err, _errEmptyToken)
+}
+
+type authXdsServer struct {
+	fakeXdsServer
+	authCh chan []string
+}
+
+func (srv *authXdsServer) StreamAggregatedResources(stream discoveryv3.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	srv.authCh <- md.Get("authorization")
+	return srv.fakeXdsServer.StreamAggregatedResources(stream)
+}
+
+func TestGRPCProvisionerWithToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xds-token")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "istio-token")
+	token := newTestToken(`{"aud":["istio-ca"]}`)
+	writeTestFile(t, tokenFile, []byte(token), time.Now())
+
+	ln, err := nettest.NewLocalListener("tcp")
+	assert.Nil(t, err)
+	grpcSrv := grpc.NewServer()
+	go func() {
+		err := grpcSrv.Serve(ln)
+		assert.Nil(t, err)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &authXdsServer{
+		fakeXdsServer: fakeXdsServer{
+			t:      t,
+			sendCh: make(chan *discoveryv3.DiscoveryResponse),
+			recvCh: make(chan *discoveryv3.DiscoveryRequest, 2),
+			ctx:    ctx,
+		},
+		authCh: make(chan []string, 1),
+	}
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(grpcSrv, srv)
+
+	cfg := &config.Config{
+		RunId:             "12345",
+		LogLevel:          "info",
+		LogOutput:         "stderr",
+		Provisioner:       "xds-v3-grpc",
+		XDSConfigSource:   "grpc://" + ln.Addr().String(),
+		XDSTokenFile:      tokenFile,
+		XDSTokenAudiences: []string{"istio-ca"},
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+
+	stopCh := make(chan struct{})
+	go func() {
+		err := p.Run(stopCh)
+		assert.Nil(t, err)
+	}()
+
+	select {
+	case auth := <-srv.authCh:
+		assert.Equal(t, auth, []string{"Bearer " + token})
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "ADS stream is not created in time")
+	}
+}
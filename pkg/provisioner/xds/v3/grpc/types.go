@@ -3,19 +3,21 @@ package grpc
 import (
 	"context"
 	"errors"
-	"fmt"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"go.uber.org/zap"
-	"google.golang.org/genproto/googleapis/rpc/code"
-	"google.golang.org/genproto/googleapis/rpc/status"
 	grpcp "google.golang.org/grpc"
-	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/grpc/credentials"
 
 	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
 	"github.com/api7/apisix-mesh-agent/pkg/config"
@@ -25,7 +27,6 @@ import (
 	"github.com/api7/apisix-mesh-agent/pkg/set"
 	"github.com/api7/apisix-mesh-agent/pkg/types"
 	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
-	"github.com/api7/apisix-mesh-agent/pkg/version"
 )
 
 var (
@@ -34,24 +35,80 @@ var (
 )
 
 // Note this provisioner is based on the xDS State of The World
-// protocol, not the Delta one.
+// protocol by default, the incremental (Delta) one will be used
+// if the xds_delta option is enabled.
 type grpcProvisioner struct {
 	configSource string
-	node         *corev3.Node
-	logger       *log.Logger
-	evChan       chan []types.Event
-	v3Adaptor    xdsv3.Adaptor
+	// options (like credentials) to dial the config source.
+	dialOptions []grpcp.DialOption
+	node        *corev3.Node
+	logger      *log.Logger
+	evChan      chan []types.Event
+	v3Adaptor   xdsv3.Adaptor
+	delta       bool
 
 	// find the listener (address) owner, an extra match
 	// condition will be patched to the APISIX route.
 	// "connection_original_dst == <ip>:<port>"
 	routeOwnership map[string]string
+	// the port that APISIX listens on for the intercepted inbound
+	// traffic, inbound listeners are ignored if it's zero.
+	inboundPort int
+	// names of route configurations which are used by inbound
+	// listeners, routes will be bound to the inbound port.
+	inboundRoutes set.StringSet
+
+	// HTTP filters of the HttpConnectionManager that the route
+	// configuration belongs to.
+	routeHTTPFilters map[string][]*hcmv3.HttpFilter
+	// upgrade configs of the HttpConnectionManager that the
+	// route configuration belongs to.
+	routeUpgradeConfigs map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig
+	// filter chains that the route configuration belongs to.
+	routeFilterChains map[string][]*xdsv3.FilterChain
 
 	// static route configuration from listeners.
 	staticRouteConfigurations []*routev3.RouteConfiguration
+	// last received route configurations from RDS, they're kept
+	// so that routes can be re-translated once the dependencies
+	// (like the ext_authz cluster) changed.
+	routeConfigurations []*routev3.RouteConfiguration
+	// names of route configurations which enable VHDS.
+	vhdsRouteConfigurations set.StringSet
+	// last received virtual hosts from VHDS, the key is the name
+	// of route configuration that the virtual hosts belong to.
+	virtualHosts map[string][]*routev3.VirtualHost
+	// last received listeners, they're kept so that stream routes
+	// can be re-translated once the weighted clusters changed.
+	listeners []*listenerv3.Listener
+	// secrets that listeners use to terminate the downstream TLS
+	// connections, the value is the server names of filter chains.
+	listenerSecrets map[string][]string
+	// secrets that clusters use as the client certificates, the key
+	// is the cluster name.
+	clusterSecrets map[string][]string
+	// names of secrets that are subscribed by SDS.
+	sdsNames set.StringSet
+	// last received secrets, the key is the secret name.
+	secrets map[string]*tlsv3.Secret
+	// certManager requests the workload certificate from the CA, it's
+	// nil if the CA address is not specified.
+	certManager *certManager
+	// secrets which are issued locally (by the certManager), they're
+	// not subscribed by SDS, the key is the secret name.
+	localSecrets map[string]*tlsv3.Secret
+	// names of secrets that are provided locally.
+	localSecretNames set.StringSet
+	// the channel which delivers the local secrets, it's nil if
+	// there is no local secret provider.
+	localSecretCh <-chan *tlsv3.Secret
 
 	// last state of routes.
 	routes []*apisix.Route
+	// last state of stream routes.
+	streamRoutes []*apisix.StreamRoute
+	// last state of SSLs.
+	ssls []*apisix.SSL
 	// last state of upstreams.
 	// map is necessary since EDS requires the original cluster
 	// by the name.
@@ -60,17 +117,57 @@ type grpcProvisioner struct {
 	// this map enrolls all clusters that require further EDS requests.
 	edsRequiredClusters set.StringSet
 
-	sendCh  chan *discoveryv3.DiscoveryRequest
-	recvCh  chan *discoveryv3.DiscoveryResponse
-	resetCh chan error
+	// translated routes, the key is the name of route configuration
+	// that the routes belong to, so that only the changed route
+	// configurations need to be translated in the Delta protocol.
+	routesByConfiguration map[string][]*apisix.Route
+
+	// The following fields are only used in the Delta protocol.
+	// versions of received resources, the key is the type url, and
+	// the value maps the resource name to its version.
+	resourceVersions map[string]map[string]string
+	// subscribed resource names of the non-wildcard types (RDS, EDS
+	// and VHDS), the key is the type url.
+	subscriptions map[string]set.StringSet
+	// received resources, the key is the resource name, they're
+	// merged since the config source only sends the changed ones.
+	deltaListeners           map[string]*listenerv3.Listener
+	deltaRouteConfigurations map[string]*routev3.RouteConfiguration
+	deltaVirtualHosts        map[string]*routev3.VirtualHost
+
+	// names of route configurations that listeners reference.
+	rdsNames []string
+	// last accepted versions and the last received nonces of the
+	// current stream, the key is the type url.
+	acceptedVersions map[string]string
+	nonces           map[string]string
+	// whether a response was received from the current stream,
+	// 1 means true.
+	streamHealthy int32
+	// streamCtx is the context of the current stream, requests are
+	// dropped once it's done, since they'll be regenerated by the
+	// initial requests of the next stream.
+	streamCtx context.Context
+	// loops tracks the goroutines of the current stream, they should
+	// exit before the next stream is created, so that the states are
+	// not accessed concurrently.
+	loops sync.WaitGroup
+	// events which are not received yet, they're kept across streams
+	// and delivered by the translateLoop.
+	pendingEvents []types.Event
+
+	sendCh      chan *discoveryv3.DiscoveryRequest
+	recvCh      chan *discoveryv3.DiscoveryResponse
+	deltaSendCh chan *discoveryv3.DeltaDiscoveryRequest
+	deltaRecvCh chan *discoveryv3.DeltaDiscoveryResponse
+	resetCh     chan error
 }
 
 // NewXDSProvisioner creates a provisioner which fetches config over gRPC.
 func NewXDSProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
-	if !strings.HasPrefix(cfg.XDSConfigSource, "grpc://") {
+	if !strings.HasPrefix(cfg.XDSConfigSource, "grpc://") && !strings.HasPrefix(cfg.XDSConfigSource, "grpcs://") {
 		return nil, errors.New("bad xds config source")
 	}
-	cs := strings.TrimPrefix(cfg.XDSConfigSource, "grpc://")
 	logger, err := log.NewLogger(
 		log.WithOutputFile(cfg.LogOutput),
 		log.WithLogLevel(cfg.LogLevel),
@@ -79,28 +176,88 @@ func NewXDSProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
 	if err != nil {
 		return nil, err
 	}
+	var (
+		cs          string
+		dialOptions []grpcp.DialOption
+	)
+	if strings.HasPrefix(cfg.XDSConfigSource, "grpcs://") {
+		cs = strings.TrimPrefix(cfg.XDSConfigSource, "grpcs://")
+		files := &tlsFiles{
+			caFile:     cfg.XDSCAFile,
+			certFile:   cfg.XDSClientCertFile,
+			keyFile:    cfg.XDSClientKeyFile,
+			serverName: cfg.XDSServerName,
+			logger:     logger,
+		}
+		tlsCfg, err := files.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		dialOptions = append(dialOptions, grpcp.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	} else {
+		cs = strings.TrimPrefix(cfg.XDSConfigSource, "grpc://")
+		dialOptions = append(dialOptions, grpcp.WithInsecure())
+	}
+	if cfg.XDSTokenFile != "" {
+		dialOptions = append(dialOptions, grpcp.WithPerRPCCredentials(&tokenCredentials{
+			file:       cfg.XDSTokenFile,
+			audiences:  cfg.XDSTokenAudiences,
+			requireTLS: strings.HasPrefix(cfg.XDSConfigSource, "grpcs://"),
+			logger:     logger,
+		}))
+	}
 	adapter, err := xdsv3.NewAdaptor(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO Configurable domain suffix.
-	dnsDomain := cfg.RunningContext.PodNamespace + ".svc.cluster.local"
-	node := &corev3.Node{
-		Id:            util.GenNodeId(cfg.RunId, cfg.RunningContext.IPAddress, dnsDomain),
-		UserAgentName: fmt.Sprintf("apisix-mesh-agent/%s", version.Short()),
+	node, err := util.NewNode(cfg, os.Environ(), logger)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		cm               *certManager
+		localSecretCh    <-chan *tlsv3.Secret
+		localSecretNames = set.StringSet{}
+	)
+	if cfg.CAAddress != "" {
+		cm, err = newCertManager(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		localSecretCh = cm.channel()
+		localSecretNames.Add(_workloadSecretName)
 	}
 	return &grpcProvisioner{
-		node:                node,
-		configSource:        cs,
-		logger:              logger,
-		evChan:              make(chan []types.Event),
-		v3Adaptor:           adapter,
-		sendCh:              make(chan *discoveryv3.DiscoveryRequest),
-		recvCh:              make(chan *discoveryv3.DiscoveryResponse),
-		resetCh:             make(chan error),
-		upstreams:           make(map[string]*apisix.Upstream),
-		edsRequiredClusters: make(map[string]struct{}),
+		node:                     node,
+		configSource:             cs,
+		dialOptions:              dialOptions,
+		logger:                   logger,
+		evChan:                   make(chan []types.Event),
+		v3Adaptor:                adapter,
+		delta:                    cfg.XDSDelta,
+		inboundPort:              cfg.InboundPort,
+		sendCh:                   make(chan *discoveryv3.DiscoveryRequest),
+		recvCh:                   make(chan *discoveryv3.DiscoveryResponse),
+		deltaSendCh:              make(chan *discoveryv3.DeltaDiscoveryRequest),
+		deltaRecvCh:              make(chan *discoveryv3.DeltaDiscoveryResponse),
+		resetCh:                  make(chan error),
+		streamCtx:                context.Background(),
+		upstreams:                make(map[string]*apisix.Upstream),
+		edsRequiredClusters:      make(map[string]struct{}),
+		clusterSecrets:           make(map[string][]string),
+		secrets:                  make(map[string]*tlsv3.Secret),
+		certManager:              cm,
+		localSecrets:             make(map[string]*tlsv3.Secret),
+		localSecretNames:         localSecretNames,
+		localSecretCh:            localSecretCh,
+		acceptedVersions:         make(map[string]string),
+		nonces:                   make(map[string]string),
+		resourceVersions:         make(map[string]map[string]string),
+		subscriptions:            make(map[string]set.StringSet),
+		deltaListeners:           make(map[string]*listenerv3.Listener),
+		deltaRouteConfigurations: make(map[string]*routev3.RouteConfiguration),
+		deltaVirtualHosts:        make(map[string]*routev3.VirtualHost),
 	}, nil
 }
 
@@ -108,77 +265,192 @@ func (p *grpcProvisioner) Channel() <-chan []types.Event {
 	return p.evChan
 }
 
+// Run connects to the config source and keeps the connection, it reconnects
+// with backoff once the connection or the stream is broken, in such a case,
+// the last translated state is kept.
 func (p *grpcProvisioner) Run(stop chan struct{}) error {
 	defer close(p.evChan)
+	if p.certManager != nil {
+		go p.certManager.run(stop)
+	}
+	bo := newBackoff(_minReconnectDelay, _maxReconnectDelay)
 	for {
 		ctx, cancel := context.WithCancel(context.Background())
-		conn, err := grpcp.DialContext(ctx, p.configSource,
-			grpcp.WithInsecure(),
-			grpcp.WithBlock(),
-		)
-		if err != nil {
-			cancel()
-			return err
-		}
-		cleanup := func() {
-			cancel()
-			if err := conn.Close(); err != nil {
-				p.logger.Errorw("failed to close gRPC connection to XDS config source",
-					zap.Error(err),
-					zap.String("config_source", p.configSource),
-				)
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
 			}
+		}()
+		atomic.StoreInt32(&p.streamHealthy, 0)
+		err := p.connect(ctx, stop)
+		cancel()
+		if err == nil {
+			return nil
 		}
-		if err := p.run(ctx, conn); err != nil {
-			cleanup()
-			return err
+		if atomic.LoadInt32(&p.streamHealthy) == 1 {
+			bo.Reset()
+		}
+		delay := bo.Next()
+		p.logger.Errorw("connection to xds config source broken, reconnecting",
+			zap.Error(err),
+			zap.String("config_source", p.configSource),
+			zap.Duration("delay", delay),
+		)
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(delay):
 		}
+	}
+}
 
+// connect dials the config source and creates the ADS stream, it returns
+// an error once the connection is broken, or nil if it's stopped.
+func (p *grpcProvisioner) connect(ctx context.Context, stop chan struct{}) error {
+	dialCtx, cancel := context.WithTimeout(ctx, _dialTimeout)
+	opts := append([]grpcp.DialOption{grpcp.WithBlock()}, p.dialOptions...)
+	conn, err := grpcp.DialContext(dialCtx, p.configSource, opts...)
+	cancel()
+	if err != nil {
 		select {
 		case <-stop:
-			cleanup()
 			return nil
-		case err = <-p.resetCh:
-			cleanup()
-			log.Errorw("grpc client reset",
+		default:
+			return err
+		}
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			p.logger.Errorw("failed to close gRPC connection to XDS config source",
 				zap.Error(err),
+				zap.String("config_source", p.configSource),
 			)
-			continue
 		}
+	}()
+	ctx, cancel = context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		p.loops.Wait()
+	}()
+	if err := p.run(ctx, conn); err != nil {
+		return err
+	}
+	select {
+	case <-stop:
+		return nil
+	case err := <-p.resetCh:
+		return err
 	}
 }
 
 func (p *grpcProvisioner) run(ctx context.Context, conn *grpcp.ClientConn) error {
+	if p.delta {
+		return p.runDelta(ctx, conn)
+	}
 	client, err := discoveryv3.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
 	if err != nil {
 		return err
 	}
 
-	go p.sendLoop(ctx, client)
-	go p.recvLoop(ctx, client)
-	go p.translateLoop(ctx)
-	go p.firstSend()
+	// Build the initial requests before the translateLoop runs, so that
+	// states are not accessed concurrently.
+	initial := p.initialRequests()
+	// Nonces are only meaningful in a stream.
+	p.nonces = make(map[string]string)
+	p.streamCtx = ctx
+	p.goLoop(func() { p.sendLoop(ctx, client) })
+	p.goLoop(func() { p.recvLoop(ctx, client) })
+	p.goLoop(func() { p.translateLoop(ctx) })
+	p.goLoop(func() { p.firstSend(initial) })
 	return nil
 }
 
-func (p *grpcProvisioner) firstSend() {
-	dr1 := &discoveryv3.DiscoveryRequest{
-		Node:    p.node,
-		TypeUrl: types.ListenerUrl,
+// goLoop runs the loop of the current stream in a goroutine.
+func (p *grpcProvisioner) goLoop(loop func()) {
+	p.loops.Add(1)
+	go func() {
+		defer p.loops.Done()
+		loop()
+	}()
+}
+
+// initialRequests generates the discovery requests which should be sent once
+// the stream was (re)created. When reconnecting, the last accepted versions and
+// the resource names are carried, so that the config source can resume.
+func (p *grpcProvisioner) initialRequests() []*discoveryv3.DiscoveryRequest {
+	drs := []*discoveryv3.DiscoveryRequest{
+		{
+			Node:        p.node,
+			TypeUrl:     types.ListenerUrl,
+			VersionInfo: p.acceptedVersions[types.ListenerUrl],
+		},
+		{
+			Node:        p.node,
+			TypeUrl:     types.ClusterUrl,
+			VersionInfo: p.acceptedVersions[types.ClusterUrl],
+		},
+	}
+	if len(p.rdsNames) > 0 {
+		drs = append(drs, &discoveryv3.DiscoveryRequest{
+			Node:          p.node,
+			TypeUrl:       types.RouteConfigurationUrl,
+			VersionInfo:   p.acceptedVersions[types.RouteConfigurationUrl],
+			ResourceNames: p.rdsNames,
+		})
+	}
+	if len(p.edsRequiredClusters) > 0 {
+		drs = append(drs, &discoveryv3.DiscoveryRequest{
+			Node:          p.node,
+			TypeUrl:       types.ClusterLoadAssignmentUrl,
+			VersionInfo:   p.acceptedVersions[types.ClusterLoadAssignmentUrl],
+			ResourceNames: p.edsRequiredClusters.OrderedStrings(),
+		})
+	}
+	if len(p.vhdsRouteConfigurations) > 0 {
+		drs = append(drs, &discoveryv3.DiscoveryRequest{
+			Node:          p.node,
+			TypeUrl:       types.VirtualHostUrl,
+			VersionInfo:   p.acceptedVersions[types.VirtualHostUrl],
+			ResourceNames: p.vhdsResourceNames(),
+		})
+	}
+	if len(p.sdsNames) > 0 {
+		drs = append(drs, &discoveryv3.DiscoveryRequest{
+			Node:          p.node,
+			TypeUrl:       types.SecretUrl,
+			VersionInfo:   p.acceptedVersions[types.SecretUrl],
+			ResourceNames: p.sdsNames.OrderedStrings(),
+		})
 	}
-	dr2 := &discoveryv3.DiscoveryRequest{
-		Node:    p.node,
-		TypeUrl: types.ClusterUrl,
+	return drs
+}
+
+func (p *grpcProvisioner) firstSend(drs []*discoveryv3.DiscoveryRequest) {
+	for _, dr := range drs {
+		if !p.send(dr) {
+			return
+		}
 	}
+	p.logger.Debugw("sent initial discovery requests")
+}
 
-	p.sendCh <- dr1
-	p.sendCh <- dr2
-	p.logger.Debugw("sent initial discovery requests for listeners and clusters")
+// send queues the discovery request to the current stream, it returns false
+// if the stream is closed, in such a case, the request is dropped.
+func (p *grpcProvisioner) send(dr *discoveryv3.DiscoveryRequest) bool {
+	select {
+	case <-p.streamCtx.Done():
+		return false
+	case p.sendCh <- dr:
+		return true
+	}
 }
 
 // sendLoop receives pending DiscoveryRequest objects and sends them to client.
-// Send operation will be retried continuously until successful or the context is
-// cancelled.
+// Requests are sent one by one in order, since gRPC doesn't allow concurrent
+// sending on the same stream, and the ACK/NACK of each type should follow
+// the order of responses.
 func (p *grpcProvisioner) sendLoop(ctx context.Context, client discoveryv3.AggregatedDiscoveryService_StreamAggregatedResourcesClient) {
 	for {
 		select {
@@ -188,14 +460,14 @@ func (p *grpcProvisioner) sendLoop(ctx context.Context, client discoveryv3.Aggre
 			p.logger.Debugw("sending discovery request",
 				zap.Any("body", dr),
 			)
-			go func(dr *discoveryv3.DiscoveryRequest) {
-				if err := client.Send(dr); err != nil {
-					p.logger.Errorw("failed to send discovery request",
-						zap.Error(err),
-						zap.String("config_source", p.configSource),
-					)
-				}
-			}(dr)
+			if err := client.Send(dr); err != nil {
+				// The stream is broken, recvLoop will notice it and
+				// trigger the reconnection.
+				p.logger.Errorw("failed to send discovery request",
+					zap.Error(err),
+					zap.String("config_source", p.configSource),
+				)
+			}
 		}
 	}
 }
@@ -206,102 +478,140 @@ func (p *grpcProvisioner) recvLoop(ctx context.Context, client discoveryv3.Aggre
 	for {
 		dr, err := client.Recv()
 		if err != nil {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				p.logger.Errorw("failed to receive discovery response",
-					zap.Error(err),
-				)
-				if strings.Contains(err.Error(), "transport is closing") {
-					p.resetCh <- err
-					return
-				}
-				continue
-			}
+			p.reset(ctx, err)
+			return
 		}
+		atomic.StoreInt32(&p.streamHealthy, 1)
 		p.logger.Debugw("got discovery response",
 			zap.String("type", dr.TypeUrl),
 			zap.Any("body", dr),
 		)
-		go func(dr *discoveryv3.DiscoveryResponse) {
-			select {
-			case <-ctx.Done():
-			case p.recvCh <- dr:
-			}
-		}(dr)
+		select {
+		case <-ctx.Done():
+			return
+		case p.recvCh <- dr:
+		}
+	}
+}
+
+// reset notifies Run to reconnect, all stream errors are treated equally.
+func (p *grpcProvisioner) reset(ctx context.Context, err error) {
+	select {
+	case <-ctx.Done():
+	default:
+		p.logger.Errorw("failed to receive discovery response",
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+		case p.resetCh <- err:
+		}
 	}
 }
 
 // translateLoop mediates the input DiscoveryResponse objects, translating
-// them APISIX resources, and generating an ACK request ultimately.
+// them APISIX resources, and generating an ACK request ultimately. Events
+// are coalesced if the last batch was not received.
 func (p *grpcProvisioner) translateLoop(ctx context.Context) {
-	var verInfo string
 	for {
+		var evChan chan<- []types.Event
+		if len(p.pendingEvents) > 0 {
+			evChan = p.evChan
+		}
 		select {
 		case <-ctx.Done():
 			return
+		case evChan <- p.pendingEvents:
+			p.pendingEvents = nil
+		case secret := <-p.localSecretCh:
+			p.updateLocalSecret(secret)
 		case resp := <-p.recvCh:
 			ackReq := &discoveryv3.DiscoveryRequest{
 				Node:          p.node,
 				TypeUrl:       resp.TypeUrl,
 				ResponseNonce: resp.Nonce,
 			}
-			// RDS and EDS are not wildcard Url, so ResourceNames
-			// field has to be set explicitly.
+			// RDS, EDS, VHDS and SDS are not wildcard Url, so
+			// ResourceNames field has to be set explicitly.
 			if resp.TypeUrl == types.ClusterLoadAssignmentUrl {
 				ackReq.ResourceNames = p.edsRequiredClusters.Strings()
 			} else if resp.TypeUrl == types.RouteConfigurationUrl {
-				ackReq.ResourceNames = make([]string, 0, len(p.routeOwnership))
-				for r := range p.routeOwnership {
-					ackReq.ResourceNames = append(ackReq.ResourceNames, r)
-				}
+				ackReq.ResourceNames = p.rdsNames
+			} else if resp.TypeUrl == types.VirtualHostUrl {
+				ackReq.ResourceNames = p.vhdsResourceNames()
+			} else if resp.TypeUrl == types.SecretUrl {
+				ackReq.ResourceNames = p.sdsNames.OrderedStrings()
 			}
+			// The nonce should be recorded before translating, since
+			// requests (like EDS) might be sent during the translation.
+			p.nonces[resp.TypeUrl] = resp.Nonce
 			if err := p.translate(resp); err != nil {
-				ackReq.ErrorDetail = &status.Status{
-					Code:    int32(code.Code_INVALID_ARGUMENT),
-					Message: err.Error(),
-				}
+				p.logger.Errorw("reject discovery response",
+					zap.Error(err),
+					zap.String("type", resp.TypeUrl),
+					zap.String("version", resp.VersionInfo),
+					zap.String("nonce", resp.Nonce),
+				)
+				ackReq.ErrorDetail = errorDetail(err)
 			} else {
-				verInfo = resp.VersionInfo
+				p.acceptedVersions[resp.TypeUrl] = resp.VersionInfo
 			}
-			ackReq.VersionInfo = verInfo
-			p.sendCh <- ackReq
+			// For NACK, the version is the previously accepted one.
+			ackReq.VersionInfo = p.acceptedVersions[resp.TypeUrl]
+			p.send(ackReq)
 		}
 	}
 }
 
-func (p *grpcProvisioner) translate(resp *discoveryv3.DiscoveryResponse) error {
+func (p *grpcProvisioner) translate(resp *discoveryv3.DiscoveryResponse) (err error) {
 	var (
 		// Since the type url is fixed, only one field is filled in m and o.
 		m      util.Manifest
 		o      util.Manifest
 		events []types.Event
 	)
+	original := p.stageState()
+	defer func() {
+		if err != nil {
+			// The response is rejected as a whole, so the changes
+			// made by the translated resources are discarded.
+			p.restoreState(original)
+		}
+	}()
 	// As we use ADS, the TypeUrl field indicates the resource type already.
 	switch resp.GetTypeUrl() {
 	case types.RouteConfigurationUrl:
+		var (
+			rcs  []*routev3.RouteConfiguration
+			errs resourceErrors
+		)
 		for _, res := range resp.GetResources() {
-			partial, err := p.processRouteConfigurationV3(res)
+			rc, err := p.unmarshalRouteConfigurationV3(res)
 			if err != nil {
-				return err
+				errs.add(resourceName(res), err)
+				continue
 			}
-			m.Routes = append(m.Routes, partial...)
+			rcs = append(rcs, rc)
 		}
-		if p.staticRouteConfigurations != nil {
-			partial, err := p.processStaticRouteConfigurations(p.staticRouteConfigurations)
-			if err != nil {
-				return err
-			}
-			m.Routes = append(m.Routes, partial...)
+		if err := errs.err(); err != nil {
+			return err
 		}
+		routes, err := p.translateRouteConfigurations(rcs)
+		if err != nil {
+			return err
+		}
+		p.routeConfigurations = rcs
+		m.Routes = routes
 		o.Routes = p.routes
 		p.routes = m.Routes
+		p.trySendVhds()
 
 	case types.ClusterUrl:
+		var errs resourceErrors
 		newUps := make(map[string]*apisix.Upstream)
 		oldEdsRequiredClusters := p.edsRequiredClusters
 		p.edsRequiredClusters = set.StringSet{}
+		p.clusterSecrets = make(map[string][]string)
 		for _, res := range resp.GetResources() {
 			ups, err := p.processClusterV3(res)
 			if err != nil {
@@ -310,23 +620,41 @@ func (p *grpcProvisioner) translate(resp *discoveryv3.DiscoveryResponse) error {
 						zap.Error(err),
 						zap.Any("cluster", res),
 					)
-					continue
 				} else {
 					p.logger.Errorw("failed to translate Cluster to APISIX upstreams",
 						zap.Error(err),
 						zap.Any("cluster", res),
 					)
-					return err
+					errs.add(resourceName(res), err)
+				}
+				continue
+			}
+			// Keep the nodes from EDS, so that the last state is served
+			// until the ClusterLoadAssignment is received.
+			if old, ok := p.upstreams[ups.Name]; ok {
+				if _, eds := p.edsRequiredClusters[ups.Name]; eds {
+					ups.Nodes = old.Nodes
 				}
 			}
 			m.Upstreams = append(m.Upstreams, ups)
 			newUps[ups.Name] = ups
 		}
+		if err := errs.err(); err != nil {
+			return err
+		}
 		// TODO Refactor util.Manifest to just use map.
 		for _, ups := range p.upstreams {
 			o.Upstreams = append(o.Upstreams, ups)
 		}
 		p.upstreams = newUps
+		if err := p.retranslateRoutesOnUpstreamsChange(&m, &o); err != nil {
+			return err
+		}
+		if err := p.retranslateStreamRoutes(&m, &o); err != nil {
+			return err
+		}
+		p.trySendSds()
+		p.retranslateSSLs(&m, &o)
 		if !p.edsRequiredClusters.Equal(oldEdsRequiredClusters) {
 			p.logger.Infow("(re)launch EDS discovery request",
 				zap.Any("old_eds_required_clusters", oldEdsRequiredClusters),
@@ -335,52 +663,94 @@ func (p *grpcProvisioner) translate(resp *discoveryv3.DiscoveryResponse) error {
 			p.sendEds()
 		}
 	case types.ClusterLoadAssignmentUrl:
+		var errs resourceErrors
 		for _, res := range resp.GetResources() {
 			ups, err := p.processClusterLoadAssignmentV3(res)
 			if err != nil {
-				return err
+				errs.add(resourceName(res), err)
+				continue
 			}
 			p.upstreams[ups.Name] = ups
 			m.Upstreams = append(m.Upstreams, ups)
 		}
+		if err := errs.err(); err != nil {
+			return err
+		}
+		if err := p.retranslateRoutesOnUpstreamsChange(&m, &o); err != nil {
+			return err
+		}
+		if err := p.retranslateStreamRoutes(&m, &o); err != nil {
+			return err
+		}
 	case types.ListenerUrl:
 		var (
-			rdsNames      []string
-			staticConfigs []*routev3.RouteConfiguration
+			listeners []*listenerv3.Listener
+			errs      resourceErrors
 		)
-		routeOwnership := make(map[string]string)
 		for _, res := range resp.GetResources() {
-			var listener listenerv3.Listener
-			if err := anypb.UnmarshalTo(res, &listener, proto.UnmarshalOptions{}); err != nil {
-				p.logger.Errorw("failed to unmarshal listener v3",
-					zap.Error(err),
-					zap.Any("response", res),
-				)
-				return err
-			}
-			sockAddr := listener.Address.GetSocketAddress()
-			if sockAddr == nil || sockAddr.GetPortValue() == 0 {
-				// Only use listener which listens on socket.
-				// TODO Support named port.
+			listener, err := p.unmarshalListenerV3(res)
+			if err != nil {
+				errs.add(resourceName(res), err)
 				continue
 			}
-			addr := fmt.Sprintf("%s:%d", sockAddr.GetAddress(), sockAddr.GetPortValue())
-			names, cfgs, err := p.v3Adaptor.CollectRouteNamesAndConfigs(&listener)
+			listeners = append(listeners, listener)
+		}
+		if err := errs.err(); err != nil {
+			return err
+		}
+		states, err := p.processListenersV3(listeners)
+		if err != nil {
+			return err
+		}
+		// Stream routes are translated before the listener states are
+		// applied, so that nothing changes if they cannot be translated.
+		streamRoutes, err := p.translateStreamRoutes(states.Listeners, p.listenerTranslateOptions(states))
+		if err != nil {
+			return err
+		}
+		p.applyListenerStates(states)
+		m.StreamRoutes = streamRoutes
+		o.StreamRoutes = p.streamRoutes
+		p.streamRoutes = streamRoutes
+		p.trySendVhds()
+		p.trySendSds()
+		p.retranslateSSLs(&m, &o)
+		p.trySendRds(states.RdsNames)
+	case types.VirtualHostUrl:
+		var errs resourceErrors
+		virtualHosts := make(map[string][]*routev3.VirtualHost)
+		for _, res := range resp.GetResources() {
+			vhost, err := p.unmarshalVirtualHostV3(res)
 			if err != nil {
-				return err
-			}
-			rdsNames = append(rdsNames, names...)
-			staticConfigs = append(staticConfigs, cfgs...)
-			for _, name := range names {
-				routeOwnership[name] = addr
+				errs.add(resourceName(res), err)
+				continue
 			}
-			for _, cfg := range cfgs {
-				routeOwnership[cfg.GetName()] = addr
+			// The name of VirtualHost is the resource name.
+			rcName, ok := p.vhdsRouteConfigurationName(resourceName(res))
+			if !ok {
+				continue
 			}
+			virtualHosts[rcName] = append(virtualHosts[rcName], vhost)
 		}
-		p.staticRouteConfigurations = staticConfigs
-		p.routeOwnership = routeOwnership
-		p.trySendRds(rdsNames)
+		if err := errs.err(); err != nil {
+			return err
+		}
+		p.virtualHosts = virtualHosts
+		routes, err := p.translateRouteConfigurations(p.routeConfigurations)
+		if err != nil {
+			return err
+		}
+		m.Routes = routes
+		o.Routes = p.routes
+		p.routes = m.Routes
+	case types.SecretUrl:
+		secrets, err := p.processSecretsV3(resp.GetResources())
+		if err != nil {
+			return err
+		}
+		p.secrets = secrets
+		p.retranslateSSLs(&m, &o)
+		p.retranslateClientCertificates(&m, &o)
 	default:
 		return _errUnknownResourceTypeUrl
 	}
@@ -393,43 +763,89 @@ func (p *grpcProvisioner) translate(resp *discoveryv3.DiscoveryResponse) error {
 				Object: ups,
 			})
 		}
+		if m.Routes != nil || o.Routes != nil || m.StreamRoutes != nil || o.StreamRoutes != nil {
+			events = append(events, p.generateEvents(
+				&util.Manifest{Routes: m.Routes, StreamRoutes: m.StreamRoutes},
+				&util.Manifest{Routes: o.Routes, StreamRoutes: o.StreamRoutes},
+			)...)
+		}
 	} else {
 		events = p.generateEvents(&m, &o)
 	}
-	go func() {
-		p.evChan <- events
-	}()
+	p.pendingEvents = append(p.pendingEvents, events...)
+	return nil
+}
+
+// retranslateRoutesOnUpstreamsChange re-translates the routes if the upstreams
+// that routes depend on (e.g. the ext_authz cluster) changed, the routes are
+// filled into the manifests so that route changes can be generated together.
+func (p *grpcProvisioner) retranslateRoutesOnUpstreamsChange(m, o *util.Manifest) error {
+	clusters := xdsv3.ExtAuthzClusters(p.routeHTTPFilters)
+	if len(clusters) == 0 {
+		return nil
+	}
+	added, deleted, updated := o.DiffFrom(m)
+	affected := false
+	for _, manifest := range []*util.Manifest{added, deleted, updated} {
+		for _, ups := range manifest.Upstreams {
+			if _, ok := clusters[ups.Name]; ok {
+				affected = true
+			}
+		}
+	}
+	if !affected {
+		return nil
+	}
+	routes, err := p.translateRouteConfigurations(p.routeConfigurations)
+	if err != nil {
+		return err
+	}
+	m.Routes = routes
+	o.Routes = p.routes
+	p.routes = routes
+	return nil
+}
+
+// retranslateStreamRoutes re-translates the stream routes from the last received
+// listeners, the stream routes are filled into the manifests so that changes can
+// be generated together. Stream routes are always re-translated since the inline
+// upstreams (from the weighted clusters) depend on the upstreams.
+func (p *grpcProvisioner) retranslateStreamRoutes(m, o *util.Manifest) error {
+	if len(p.listeners) == 0 && len(p.streamRoutes) == 0 {
+		return nil
+	}
+	streamRoutes, err := p.translateStreamRoutes(p.listeners, p.translateOptions())
+	if err != nil {
+		return err
+	}
+	m.StreamRoutes = streamRoutes
+	o.StreamRoutes = p.streamRoutes
+	p.streamRoutes = streamRoutes
 	return nil
 }
 
+func (p *grpcProvisioner) translateStreamRoutes(listeners []*listenerv3.Listener, opts *xdsv3.TranslateOptions) ([]*apisix.StreamRoute, error) {
+	if len(listeners) == 0 {
+		return nil, nil
+	}
+	streamRoutes, err := p.v3Adaptor.TranslateStreamRoutes(listeners, opts)
+	if err != nil {
+		p.logger.Errorw("failed to translate Listeners to APISIX stream routes",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return streamRoutes, nil
+}
+
 func (p *grpcProvisioner) generateEvents(m, o *util.Manifest) []types.Event {
 	p.logger.Debugw("comparing old and new manifests",
 		zap.Any("old", o),
 		zap.Any("new", m),
 	)
-	var (
-		added   *util.Manifest
-		deleted *util.Manifest
-		updated *util.Manifest
-		count   int
-	)
-	if o == nil {
-		added = m
-	} else if m == nil {
-		deleted = o
-	} else {
-		added, deleted, updated = o.DiffFrom(m)
-	}
-	if added != nil {
-		count += added.Size()
-	}
-	if deleted != nil {
-		count += deleted.Size()
-	}
-	if updated != nil {
-		count += updated.Size()
-	}
-	if count == 0 {
+	added, deleted, updated := util.DiffManifests(o, m)
+	events := util.ManifestEvents(added, deleted, updated)
+	if len(events) == 0 {
 		p.logger.Debugw("old and new manifests are exactly same")
 		return nil
 	}
@@ -439,29 +855,24 @@ func (p *grpcProvisioner) generateEvents(m, o *util.Manifest) []types.Event {
 		zap.Any("updated", updated),
 		zap.Any("deleted", deleted),
 	)
-
-	events := make([]types.Event, 0, count)
-	if added != nil {
-		events = append(events, added.Events(types.EventAdd)...)
-	}
-	if deleted != nil {
-		events = append(events, deleted.Events(types.EventDelete)...)
-	}
-	if updated != nil {
-		events = append(events, updated.Events(types.EventUpdate)...)
-	}
 	return events
 }
 
 func (p *grpcProvisioner) sendEds() {
+	if p.delta {
+		p.deltaSubscribe(types.ClusterLoadAssignmentUrl, p.edsRequiredClusters.Strings())
+		return
+	}
 	if len(p.edsRequiredClusters) == 0 {
 		// Sent EDS request with empty ResourceNames field will
 		// cause Istio unsubscribes EDS.
 		return
 	}
 	dr := &discoveryv3.DiscoveryRequest{
-		Node:    p.node,
-		TypeUrl: types.ClusterLoadAssignmentUrl,
+		Node:          p.node,
+		TypeUrl:       types.ClusterLoadAssignmentUrl,
+		VersionInfo:   p.acceptedVersions[types.ClusterLoadAssignmentUrl],
+		ResponseNonce: p.nonces[types.ClusterLoadAssignmentUrl],
 	}
 	for name := range p.edsRequiredClusters {
 		dr.ResourceNames = append(dr.ResourceNames, name)
@@ -469,10 +880,92 @@ func (p *grpcProvisioner) sendEds() {
 	p.logger.Debugw("sending EDS discovery request",
 		zap.Any("body", dr),
 	)
-	p.sendCh <- dr
+	p.send(dr)
+}
+
+// trySendVhds sends the VHDS discovery request if the route configurations
+// which enable VHDS changed.
+func (p *grpcProvisioner) trySendVhds() {
+	vhdsRouteConfigurations := set.StringSet{}
+	for _, list := range [][]*routev3.RouteConfiguration{p.routeConfigurations, p.staticRouteConfigurations} {
+		for _, rc := range list {
+			if rc.GetVhds() != nil {
+				vhdsRouteConfigurations.Add(rc.GetName())
+			}
+		}
+	}
+	if vhdsRouteConfigurations.Equal(p.vhdsRouteConfigurations) {
+		return
+	}
+	if len(vhdsRouteConfigurations) > 0 && len(p.vhdsRouteConfigurations) == 0 {
+		p.logger.Warnw("VHDS is enabled, all virtual hosts of the route configurations are subscribed, "+
+			"on-demand virtual host discovery is not supported",
+			zap.Strings("route_configurations", vhdsRouteConfigurations.OrderedStrings()),
+		)
+	}
+	p.vhdsRouteConfigurations = vhdsRouteConfigurations
+	for name := range p.virtualHosts {
+		if _, ok := vhdsRouteConfigurations[name]; !ok {
+			delete(p.virtualHosts, name)
+		}
+	}
+	if p.delta {
+		p.deltaSubscribe(types.VirtualHostUrl, p.vhdsResourceNames())
+		return
+	}
+	if len(vhdsRouteConfigurations) == 0 {
+		return
+	}
+	dr := &discoveryv3.DiscoveryRequest{
+		Node:          p.node,
+		ResourceNames: p.vhdsResourceNames(),
+		TypeUrl:       types.VirtualHostUrl,
+		VersionInfo:   p.acceptedVersions[types.VirtualHostUrl],
+		ResponseNonce: p.nonces[types.VirtualHostUrl],
+	}
+	p.logger.Debugw("sending VHDS discovery request",
+		zap.Any("body", dr),
+	)
+	p.send(dr)
+}
+
+// vhdsResourceNames returns the resource names for the VHDS discovery request.
+// Like Envoy, the route configuration names are subscribed, the config source
+// matches them to the namespace of the VirtualHost resource names, which are in
+// the format of "<route configuration name>/<domain>", and sends all virtual
+// hosts of the route configurations. Envoy also requests "<route configuration
+// name>/<domain>" on demand once a request to the unknown domain arrives, but
+// APISIX doesn't report such misses, so it's not supported.
+func (p *grpcProvisioner) vhdsResourceNames() []string {
+	return p.vhdsRouteConfigurations.OrderedStrings()
+}
+
+// vhdsRouteConfigurationName returns the route configuration name of the
+// VirtualHost resource name, it's the part before the last "/". Virtual hosts
+// which don't belong to any route configuration that enables VHDS are ignored.
+func (p *grpcProvisioner) vhdsRouteConfigurationName(name string) (string, bool) {
+	pos := strings.LastIndex(name, "/")
+	if pos == -1 {
+		p.logger.Warnw("ignore virtual host whose resource name is not in the format of <route configuration name>/<domain>",
+			zap.String("resource_name", name),
+		)
+		return "", false
+	}
+	rcName := name[:pos]
+	if _, ok := p.vhdsRouteConfigurations[rcName]; !ok {
+		p.logger.Warnw("ignore virtual host which doesn't belong to any route configuration",
+			zap.String("resource_name", name),
+		)
+		return "", false
+	}
+	return rcName, true
 }
 
 func (p *grpcProvisioner) trySendRds(rdsNames []string) {
+	if p.delta {
+		p.deltaSubscribe(types.RouteConfigurationUrl, rdsNames)
+		return
+	}
 	if len(rdsNames) == 0 {
 		return
 	}
@@ -480,9 +973,11 @@ func (p *grpcProvisioner) trySendRds(rdsNames []string) {
 		Node:          p.node,
 		ResourceNames: rdsNames,
 		TypeUrl:       types.RouteConfigurationUrl,
+		VersionInfo:   p.acceptedVersions[types.RouteConfigurationUrl],
+		ResponseNonce: p.nonces[types.RouteConfigurationUrl],
 	}
 	p.logger.Debugw("sending RDS discovery request",
 		zap.Any("body", dr),
 	)
-	p.sendCh <- dr
+	p.send(dr)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,7 +13,9 @@ import (
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	extauthzv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
 	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxyv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes/any"
@@ -25,7 +28,8 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/api7/apisix-mesh-agent/pkg/config"
-	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
 	"github.com/api7/apisix-mesh-agent/pkg/types"
 	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
 	"github.com/api7/apisix-mesh-agent/pkg/version"
@@ -53,7 +57,7 @@ func TestNewXDSProvisioner(t *testing.T) {
 	assert.NotNil(t, p.Channel())
 
 	gp := p.(*grpcProvisioner)
-	assert.Equal(t, gp.node.Id, util.GenNodeId(cfg.RunId, "1.1.1.1", "default.svc.cluster.local"))
+	assert.Equal(t, gp.node.Id, "sidecar~1.1.1.1~12345.default~default.svc.cluster.local")
 	assert.Equal(t, gp.node.UserAgentName, "apisix-mesh-agent/"+version.Short())
 }
 
@@ -74,7 +78,7 @@ func TestFirstSend(t *testing.T) {
 	gp := p.(*grpcProvisioner)
 
 	go func() {
-		gp.firstSend()
+		gp.firstSend(gp.initialRequests())
 	}()
 
 	select {
@@ -169,6 +173,20 @@ func TestSendLoop(t *testing.T) {
 
 	assert.Equal(t, rr.VersionInfo, "111")
 	assert.Equal(t, rr.TypeUrl, types.RouteConfigurationUrl)
+
+	// Requests are sent in order.
+	go func() {
+		for _, ver := range []string{"1", "2", "3"} {
+			gp.sendCh <- &discoveryv3.DiscoveryRequest{
+				VersionInfo: ver,
+				TypeUrl:     types.ClusterUrl,
+			}
+		}
+	}()
+	for _, ver := range []string{"1", "2", "3"} {
+		rr = <-client.sendCh
+		assert.Equal(t, rr.VersionInfo, ver)
+	}
 }
 
 func TestRecvLoop(t *testing.T) {
@@ -262,6 +280,28 @@ func TestTranslateLoop(t *testing.T) {
 	assert.Equal(t, ack.VersionInfo, "111")
 	assert.Equal(t, ack.TypeUrl, types.ClusterUrl)
 	assert.NotNil(t, ack.Node)
+
+	// Events are delivered by the translateLoop.
+	select {
+	case <-time.After(time.Second):
+		assert.FailNow(t, "events are not sent in time")
+	case evs := <-gp.evChan:
+		assert.Len(t, evs, 1)
+		assert.Equal(t, evs[0].Type, types.EventAdd)
+	}
+
+	// Requests are dropped once the stream is closed.
+	gp.streamCtx = ctx
+	cancel()
+	assert.False(t, gp.send(&discoveryv3.DiscoveryRequest{TypeUrl: types.ClusterUrl}))
+}
+
+// takeEvents returns the events generated by the translations, which should
+// be delivered by the translateLoop.
+func takeEvents(gp *grpcProvisioner) []types.Event {
+	events := gp.pendingEvents
+	gp.pendingEvents = nil
+	return events
 }
 
 func TestTranslate(t *testing.T) {
@@ -392,7 +432,7 @@ func TestTranslate(t *testing.T) {
 
 	err = gp.translate(dr1)
 	assert.Nil(t, err)
-	evs := <-gp.evChan
+	evs := takeEvents(gp)
 	assert.Len(t, evs, 1)
 	assert.Equal(t, evs[0].Type, types.EventAdd)
 	assert.Equal(t, evs[0].Object.(*apisix.Route).Name, "route1#vhost1#rc1")
@@ -400,7 +440,7 @@ func TestTranslate(t *testing.T) {
 
 	err = gp.translate(dr2)
 	assert.Nil(t, err)
-	evs = <-gp.evChan
+	evs = takeEvents(gp)
 	assert.Len(t, evs, 1)
 	assert.Equal(t, evs[0].Type, types.EventAdd)
 	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Name, "httpbin.default.svc.cluster.local")
@@ -409,7 +449,7 @@ func TestTranslate(t *testing.T) {
 
 	err = gp.translate(dr3)
 	assert.Nil(t, err)
-	evs = <-gp.evChan
+	evs = takeEvents(gp)
 	assert.Len(t, evs, 1)
 	assert.Equal(t, evs[0].Type, types.EventUpdate)
 	assert.Len(t, evs[0].Object.(*apisix.Upstream).Nodes, 1)
@@ -417,6 +457,209 @@ func TestTranslate(t *testing.T) {
 	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Nodes[0].Port, int32(8000))
 }
 
+func TestTranslateWithExtAuthz(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	gp.sendCh = make(chan *discoveryv3.DiscoveryRequest, 1)
+
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, &extauthzv3.ExtAuthz{
+		Services: &extauthzv3.ExtAuthz_HttpService{
+			HttpService: &extauthzv3.HttpService{
+				ServerUri: &corev3.HttpUri{
+					Uri: "http://opa.default.svc.cluster.local",
+					HttpUpstreamType: &corev3.HttpUri_Cluster{
+						Cluster: "opa.default.svc.cluster.local",
+					},
+				},
+			},
+		},
+	}, proto.MarshalOptions{}))
+	gp.routeHTTPFilters = map[string][]*hcmv3.HttpFilter{
+		"rc1": {
+			{
+				Name: xdswellknown.HTTPExternalAuthorization,
+				ConfigType: &hcmv3.HttpFilter_TypedConfig{
+					TypedConfig: &opaque,
+				},
+			},
+		},
+	}
+
+	rc := &routev3.RouteConfiguration{
+		Name: "rc1",
+		VirtualHosts: []*routev3.VirtualHost{
+			{
+				Name:    "vhost1",
+				Domains: []string{"*"},
+				Routes: []*routev3.Route{
+					{
+						Name: "route1",
+						Match: &routev3.RouteMatch{
+							PathSpecifier: &routev3.RouteMatch_Prefix{
+								Prefix: "/",
+							},
+						},
+						Action: &routev3.Route_Route{
+							Route: &routev3.RouteAction{
+								ClusterSpecifier: &routev3.RouteAction_Cluster{
+									Cluster: "httpbin.default.svc.cluster.local",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	c := &clusterv3.Cluster{
+		Name: "opa.default.svc.cluster.local",
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{
+			Type: clusterv3.Cluster_EDS,
+		},
+		LbPolicy: clusterv3.Cluster_ROUND_ROBIN,
+	}
+	ep := &endpointv3.ClusterLoadAssignment{
+		ClusterName: "opa.default.svc.cluster.local",
+		Endpoints: []*endpointv3.LocalityLbEndpoints{
+			{
+				LbEndpoints: []*endpointv3.LbEndpoint{
+					{
+						HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+							Endpoint: &endpointv3.Endpoint{
+								Address: &corev3.Address{
+									Address: &corev3.Address_SocketAddress{
+										SocketAddress: &corev3.SocketAddress{
+											Protocol: corev3.SocketAddress_TCP,
+											Address:  "10.0.3.12",
+											PortSpecifier: &corev3.SocketAddress_PortValue{
+												PortValue: 8181,
+											},
+										},
+									},
+								},
+							},
+						},
+						LoadBalancingWeight: &wrappers.UInt32Value{
+							Value: 100,
+						},
+					},
+				},
+			},
+		},
+	}
+	val1, err := proto.Marshal(rc)
+	assert.Nil(t, err)
+	val2, err := proto.Marshal(c)
+	assert.Nil(t, err)
+	val3, err := proto.Marshal(ep)
+	assert.Nil(t, err)
+
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "111",
+		TypeUrl:     types.RouteConfigurationUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.RouteConfigurationUrl,
+				Value:   val1,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs := takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventAdd)
+	// The authorization cluster is unknown, fail closed.
+	assert.Contains(t, evs[0].Object.(*apisix.Route).Plugins, apisix.FaultInjectionPlugin)
+
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "111",
+		TypeUrl:     types.ClusterUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.ClusterUrl,
+				Value:   val2,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	// Routes are not changed as the cluster still has no endpoints.
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventAdd)
+	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Name, "opa.default.svc.cluster.local")
+
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "111",
+		TypeUrl:     types.ClusterLoadAssignmentUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.ClusterLoadAssignmentUrl,
+				Value:   val3,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 2)
+	assert.Equal(t, evs[0].Type, types.EventUpdate)
+	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Name, "opa.default.svc.cluster.local")
+	assert.Equal(t, evs[1].Type, types.EventUpdate)
+	route := evs[1].Object.(*apisix.Route)
+	assert.NotContains(t, route.Plugins, apisix.FaultInjectionPlugin)
+	assert.Equal(t, route.Plugins[apisix.ForwardAuthPlugin].AsMap()["uri"], "http://10.0.3.12:8181")
+
+	// The same endpoints, routes are not changed.
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "112",
+		TypeUrl:     types.ClusterLoadAssignmentUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.ClusterLoadAssignmentUrl,
+				Value:   val3,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Name, "opa.default.svc.cluster.local")
+
+	// The authorization service is moved, routes follow the endpoint change.
+	ep.Endpoints[0].LbEndpoints[0].GetEndpoint().GetAddress().GetSocketAddress().Address = "10.0.3.13"
+	val3, err = proto.Marshal(ep)
+	assert.Nil(t, err)
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "113",
+		TypeUrl:     types.ClusterLoadAssignmentUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.ClusterLoadAssignmentUrl,
+				Value:   val3,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 2)
+	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.13")
+	assert.Equal(t, evs[1].Type, types.EventUpdate)
+	route = evs[1].Object.(*apisix.Route)
+	assert.Equal(t, route.Plugins[apisix.ForwardAuthPlugin].AsMap()["uri"], "http://10.0.3.13:8181")
+}
+
 type fakeXdsServer struct {
 	t      *testing.T
 	ctx    context.Context
@@ -675,6 +918,9 @@ func TestTranslateListener(t *testing.T) {
 		},
 		FilterChains: []*listenerv3.FilterChain{
 			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					TransportProtocol: "raw_buffer",
+				},
 				Filters: []*listenerv3.Filter{
 					{
 						Name: xdswellknown.HTTPConnectionManager,
@@ -711,4 +957,539 @@ func TestTranslateListener(t *testing.T) {
 	assert.Equal(t, dr.TypeUrl, types.RouteConfigurationUrl)
 	assert.Len(t, dr.ResourceNames, 1)
 	assert.Equal(t, dr.ResourceNames[0], "route1")
+	assert.Len(t, gp.routeFilterChains, 1)
+	assert.Len(t, gp.routeFilterChains["route1"], 1)
+	assert.Equal(t, gp.routeFilterChains["route1"][0].Match.TransportProtocol, "raw_buffer")
+}
+
+func TestTranslateListenerRejectedByStreamRoutes(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	gp.sendCh = make(chan *discoveryv3.DiscoveryRequest, 1)
+
+	newListener := func(name string, port uint32, filter *listenerv3.Filter) *listenerv3.Listener {
+		return &listenerv3.Listener{
+			Name: name,
+			Address: &corev3.Address{
+				Address: &corev3.Address_SocketAddress{
+					SocketAddress: &corev3.SocketAddress{
+						Address: "0.0.0.0",
+						PortSpecifier: &corev3.SocketAddress_PortValue{
+							PortValue: port,
+						},
+					},
+				},
+			},
+			FilterChains: []*listenerv3.FilterChain{
+				{
+					Filters: []*listenerv3.Filter{filter},
+				},
+			},
+		}
+	}
+	l1 := newListener("listener1", 8080, newTestStaticHCMFilter(t, "rc1", "httpbin.default.svc.cluster.local"))
+	assert.Nil(t, gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "1",
+		TypeUrl:     types.ListenerUrl,
+		Resources:   []*any.Any{newResource(t, types.ListenerUrl, l1)},
+	}))
+	assert.Len(t, gp.listeners, 1)
+	assert.Len(t, gp.staticRouteConfigurations, 1)
+
+	// The tcp_proxy config is broken, so stream routes cannot be translated.
+	l2 := newListener("listener2", 3306, &listenerv3.Filter{
+		Name: xdswellknown.TCPProxy,
+		ConfigType: &listenerv3.Filter_TypedConfig{
+			TypedConfig: &anypb.Any{
+				TypeUrl: "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+				Value:   []byte("bad"),
+			},
+		},
+	})
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "2",
+		TypeUrl:     types.ListenerUrl,
+		Resources: []*any.Any{
+			newResource(t, types.ListenerUrl, newListener("listener1", 8080, newTestStaticHCMFilter(t, "rc2", "httpbin.default.svc.cluster.local"))),
+			newResource(t, types.ListenerUrl, l2),
+		},
+	})
+	assert.NotNil(t, err)
+	// Listener states are not changed.
+	assert.Len(t, gp.listeners, 1)
+	assert.True(t, proto.Equal(gp.listeners[0], l1))
+	assert.Len(t, gp.staticRouteConfigurations, 1)
+	assert.Equal(t, gp.staticRouteConfigurations[0].Name, "rc1")
+	assert.Equal(t, gp.routeOwnership, map[string]string{"rc1": "0.0.0.0:8080"})
+	assert.Len(t, gp.streamRoutes, 0)
+}
+
+func newTestStaticHCMFilter(t *testing.T, rcName, cluster string) *listenerv3.Filter {
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, &hcmv3.HttpConnectionManager{
+		RouteSpecifier: &hcmv3.HttpConnectionManager_RouteConfig{
+			RouteConfig: newTestRouteConfiguration(rcName, "/", cluster),
+		},
+	}, proto.MarshalOptions{}))
+	return &listenerv3.Filter{
+		Name: xdswellknown.HTTPConnectionManager,
+		ConfigType: &listenerv3.Filter_TypedConfig{
+			TypedConfig: &opaque,
+		},
+	}
+}
+
+func TestTranslateInboundListener(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		InboundPort:     9082,
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	gp.sendCh = make(chan *discoveryv3.DiscoveryRequest, 1)
+
+	var tcpProxy anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&tcpProxy, &tcpproxyv3.TcpProxy{
+		ClusterSpecifier: &tcpproxyv3.TcpProxy_Cluster{
+			Cluster: "inbound|3306||",
+		},
+	}, proto.MarshalOptions{}))
+	newSocketAddress := func(port uint32) *corev3.Address {
+		return &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Address: "0.0.0.0",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: port,
+					},
+				},
+			},
+		}
+	}
+	inbound := &listenerv3.Listener{
+		Name:             "virtualInbound",
+		Address:          newSocketAddress(15006),
+		TrafficDirection: corev3.TrafficDirection_INBOUND,
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					DestinationPort:   &wrappers.UInt32Value{Value: 8080},
+					TransportProtocol: "tls",
+				},
+				Filters: []*listenerv3.Filter{newTestStaticHCMFilter(t, "inbound|8080||", "inbound|8080||")},
+			},
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					DestinationPort:   &wrappers.UInt32Value{Value: 8080},
+					TransportProtocol: "raw_buffer",
+				},
+				Filters: []*listenerv3.Filter{newTestStaticHCMFilter(t, "inbound|8080||", "inbound|8080||")},
+			},
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					DestinationPort: &wrappers.UInt32Value{Value: 3306},
+				},
+				Filters: []*listenerv3.Filter{
+					{
+						Name: xdswellknown.TCPProxy,
+						ConfigType: &listenerv3.Filter_TypedConfig{
+							TypedConfig: &tcpProxy,
+						},
+					},
+				},
+			},
+		},
+	}
+	outbound := &listenerv3.Listener{
+		Name:             "0.0.0.0_8080",
+		Address:          newSocketAddress(8080),
+		TrafficDirection: corev3.TrafficDirection_OUTBOUND,
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				Filters: []*listenerv3.Filter{newTestStaticHCMFilter(t, "8080", "outbound|8080||httpbin.default.svc.cluster.local")},
+			},
+		},
+	}
+	resp := &discoveryv3.DiscoveryResponse{
+		VersionInfo: "1",
+		TypeUrl:     types.ListenerUrl,
+		Resources: []*any.Any{
+			newResource(t, types.ListenerUrl, inbound),
+			newResource(t, types.ListenerUrl, outbound),
+		},
+	}
+	routeVars := func(r *apisix.Route) [][]string {
+		var vars [][]string
+		for _, v := range r.Vars {
+			vars = append(vars, v.Vars)
+		}
+		return vars
+	}
+	rdsResp := &discoveryv3.DiscoveryResponse{
+		VersionInfo: "1",
+		TypeUrl:     types.RouteConfigurationUrl,
+	}
+	assert.Nil(t, gp.translate(resp))
+	// Stream routes are not generated for the inbound listener.
+	assert.Len(t, gp.streamRoutes, 0)
+	// Static route configurations are translated along with RDS.
+	assert.Nil(t, gp.translate(rdsResp))
+	events := takeEvents(gp)
+	assert.Len(t, events, 3)
+	assert.Equal(t, gp.inboundRoutes, set.StringSet{"inbound|8080||": {}})
+	for _, ev := range events {
+		r := ev.Object.(*apisix.Route)
+		if r.UpstreamId == id.GenID("inbound|8080||") {
+			// The TLS and plain text filter chains have their own routes.
+			scheme := "http"
+			if strings.HasSuffix(r.Name, "#virtualInbound#0") {
+				scheme = "https"
+			}
+			assert.Equal(t, routeVars(r), [][]string{
+				{"connection_original_dst", "~~", ":8080$"},
+				{"scheme", "==", scheme},
+				{"server_port", "==", "9082"},
+			})
+		} else {
+			assert.Equal(t, routeVars(r), [][]string{
+				{"connection_original_dst", "~~", "8080$"},
+				{"server_port", "~=", "9082"},
+			})
+		}
+	}
+
+	// Inbound listeners are ignored if the inbound port is not specified.
+	gp.inboundPort = 0
+	resp.VersionInfo = "2"
+	assert.Nil(t, gp.translate(resp))
+	rdsResp.VersionInfo = "2"
+	assert.Nil(t, gp.translate(rdsResp))
+	events = takeEvents(gp)
+	assert.Len(t, events, 3)
+	for _, ev := range events {
+		switch ev.Type {
+		case types.EventDelete:
+			assert.Equal(t, ev.Tombstone.(*apisix.Route).UpstreamId, id.GenID("inbound|8080||"))
+		case types.EventUpdate:
+			assert.Equal(t, routeVars(ev.Object.(*apisix.Route)), [][]string{
+				{"connection_original_dst", "~~", "8080$"},
+			})
+		default:
+			assert.FailNow(t, "unexpected event", ev.Type)
+		}
+	}
+	assert.Len(t, gp.inboundRoutes, 0)
+}
+
+func TestTranslateTcpProxyListener(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	gp.sendCh = make(chan *discoveryv3.DiscoveryRequest, 1)
+	gp.upstreams = map[string]*apisix.Upstream{
+		"mysql-v1": {
+			Name: "mysql-v1",
+			Nodes: []*apisix.Node{
+				{
+					Host:   "10.0.3.12",
+					Port:   3306,
+					Weight: 100,
+				},
+			},
+		},
+		"mysql-v2": {
+			Name:  "mysql-v2",
+			Nodes: []*apisix.Node{},
+		},
+	}
+
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, &tcpproxyv3.TcpProxy{
+		ClusterSpecifier: &tcpproxyv3.TcpProxy_WeightedClusters{
+			WeightedClusters: &tcpproxyv3.TcpProxy_WeightedCluster{
+				Clusters: []*tcpproxyv3.TcpProxy_WeightedCluster_ClusterWeight{
+					{
+						Name:   "mysql-v1",
+						Weight: 50,
+					},
+					{
+						Name:   "mysql-v2",
+						Weight: 50,
+					},
+				},
+			},
+		},
+	}, proto.MarshalOptions{}))
+	li := &listenerv3.Listener{
+		Name: "0.0.0.0_3306",
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Address: "0.0.0.0",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: 3306,
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				Filters: []*listenerv3.Filter{
+					{
+						Name: xdswellknown.TCPProxy,
+						ConfigType: &listenerv3.Filter_TypedConfig{
+							TypedConfig: &opaque,
+						},
+					},
+				},
+			},
+		},
+	}
+	ep := &endpointv3.ClusterLoadAssignment{
+		ClusterName: "mysql-v2",
+		Endpoints: []*endpointv3.LocalityLbEndpoints{
+			{
+				LbEndpoints: []*endpointv3.LbEndpoint{
+					{
+						HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+							Endpoint: &endpointv3.Endpoint{
+								Address: &corev3.Address{
+									Address: &corev3.Address_SocketAddress{
+										SocketAddress: &corev3.SocketAddress{
+											Protocol: corev3.SocketAddress_TCP,
+											Address:  "10.0.3.13",
+											PortSpecifier: &corev3.SocketAddress_PortValue{
+												PortValue: 3306,
+											},
+										},
+									},
+								},
+							},
+						},
+						LoadBalancingWeight: &wrappers.UInt32Value{
+							Value: 100,
+						},
+					},
+				},
+			},
+		},
+	}
+	val1, err := proto.Marshal(li)
+	assert.Nil(t, err)
+	val2, err := proto.Marshal(ep)
+	assert.Nil(t, err)
+
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "111",
+		TypeUrl:     types.ListenerUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.ListenerUrl,
+				Value:   val1,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs := takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventAdd)
+	sr := evs[0].Object.(*apisix.StreamRoute)
+	assert.Equal(t, sr.ServerPort, int32(3306))
+	assert.Len(t, sr.Upstream.Nodes, 1)
+
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "111",
+		TypeUrl:     types.ClusterLoadAssignmentUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.ClusterLoadAssignmentUrl,
+				Value:   val2,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 2)
+	assert.Equal(t, evs[0].Type, types.EventUpdate)
+	assert.Equal(t, evs[0].Object.(*apisix.Upstream).Name, "mysql-v2")
+	assert.Equal(t, evs[1].Type, types.EventUpdate)
+	sr = evs[1].Object.(*apisix.StreamRoute)
+	assert.Equal(t, sr.Upstream.Nodes, []*apisix.Node{
+		{
+			Host:   "10.0.3.12",
+			Port:   3306,
+			Weight: 5000,
+		},
+		{
+			Host:   "10.0.3.13",
+			Port:   3306,
+			Weight: 5000,
+		},
+	})
+}
+
+func TestTranslateVirtualHosts(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     "xds-v3-grpc",
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	gp := p.(*grpcProvisioner)
+	gp.sendCh = make(chan *discoveryv3.DiscoveryRequest, 1)
+
+	newVirtualHost := func(name, prefix string) *routev3.VirtualHost {
+		return &routev3.VirtualHost{
+			Name:    name,
+			Domains: []string{"*"},
+			Routes: []*routev3.Route{
+				{
+					Name: "route1",
+					Match: &routev3.RouteMatch{
+						PathSpecifier: &routev3.RouteMatch_Prefix{
+							Prefix: prefix,
+						},
+					},
+					Action: &routev3.Route_Route{
+						Route: &routev3.RouteAction{
+							ClusterSpecifier: &routev3.RouteAction_Cluster{
+								Cluster: "httpbin.default.svc.cluster.local",
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	rc := &routev3.RouteConfiguration{
+		Name: "rc1",
+		Vhds: &routev3.Vhds{},
+	}
+	val1, err := proto.Marshal(rc)
+	assert.Nil(t, err)
+
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "111",
+		TypeUrl:     types.RouteConfigurationUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.RouteConfigurationUrl,
+				Value:   val1,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	select {
+	case <-time.After(time.Second):
+		assert.FailNow(t, "DiscoveryRequest is not sent in time")
+	case dr := <-gp.sendCh:
+		assert.Equal(t, dr.TypeUrl, types.VirtualHostUrl)
+		assert.Equal(t, dr.ResourceNames, []string{"rc1"})
+	}
+	// No virtual hosts yet.
+	evs := takeEvents(gp)
+	assert.Len(t, evs, 0)
+
+	val2, err := proto.Marshal(newVirtualHost("rc1/httpbin.org", "/get"))
+	assert.Nil(t, err)
+	val3, err := proto.Marshal(newVirtualHost("rc2/httpbin.org", "/get"))
+	assert.Nil(t, err)
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "111",
+		TypeUrl:     types.VirtualHostUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.VirtualHostUrl,
+				Value:   val2,
+			},
+			{
+				// Unknown route configuration.
+				TypeUrl: types.VirtualHostUrl,
+				Value:   val3,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventAdd)
+	assert.Equal(t, evs[0].Object.(*apisix.Route).Uris, []string{"/get*"})
+
+	val2, err = proto.Marshal(newVirtualHost("rc1/httpbin.org", "/headers"))
+	assert.Nil(t, err)
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "112",
+		TypeUrl:     types.VirtualHostUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.VirtualHostUrl,
+				Value:   val2,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventUpdate)
+	assert.Equal(t, evs[0].Object.(*apisix.Route).Uris, []string{"/headers*"})
+
+	// Route configuration disables VHDS, virtual hosts are dropped.
+	rc.Vhds = nil
+	val1, err = proto.Marshal(rc)
+	assert.Nil(t, err)
+	err = gp.translate(&discoveryv3.DiscoveryResponse{
+		VersionInfo: "112",
+		TypeUrl:     types.RouteConfigurationUrl,
+		Resources: []*any.Any{
+			{
+				TypeUrl: types.RouteConfigurationUrl,
+				Value:   val1,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	evs = takeEvents(gp)
+	assert.Len(t, evs, 1)
+	assert.Equal(t, evs[0].Type, types.EventDelete)
+	assert.Len(t, gp.virtualHosts, 0)
 }
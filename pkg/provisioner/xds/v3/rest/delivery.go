@@ -0,0 +1,372 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+// _discoveryPaths are the REST endpoints of each resource type, see
+// https://www.envoyproxy.io/docs/envoy/latest/api-docs/xds_protocol#rest-json-polling-subscriptions
+// for more details.
+var _discoveryPaths = map[string]string{
+	types.ClusterUrl:               "/v3/discovery:clusters",
+	types.ClusterLoadAssignmentUrl: "/v3/discovery:endpoints",
+	types.ListenerUrl:              "/v3/discovery:listeners",
+	types.RouteConfigurationUrl:    "/v3/discovery:routes",
+}
+
+// _pollOrder is the order of resource types in one round, EDS and RDS
+// are fetched after CDS and LDS so that their resource names are known.
+var _pollOrder = []string{
+	types.ClusterUrl,
+	types.ClusterLoadAssignmentUrl,
+	types.ListenerUrl,
+	types.RouteConfigurationUrl,
+}
+
+// poll fetches all resource types once, events are generated if any of
+// them was changed.
+func (p *restProvisioner) poll(ctx context.Context) []types.Event {
+	var changed bool
+	for _, typeUrl := range _pollOrder {
+		var names []string
+		switch typeUrl {
+		case types.ClusterLoadAssignmentUrl:
+			names = p.edsRequiredClusters.OrderedStrings()
+		case types.RouteConfigurationUrl:
+			names = p.rdsNames
+		}
+		if len(names) == 0 && (typeUrl == types.ClusterLoadAssignmentUrl || typeUrl == types.RouteConfigurationUrl) {
+			// Nothing to subscribe, drop the stale state.
+			if p.dropSubscription(typeUrl) {
+				changed = true
+			}
+			continue
+		}
+		resp, err := p.fetch(ctx, typeUrl, names)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			p.logger.Errorw("failed to fetch resources from config source",
+				zap.Error(err),
+				zap.String("type_url", typeUrl),
+			)
+			continue
+		}
+		if resp == nil {
+			// Not modified.
+			continue
+		}
+		p.nonces[typeUrl] = resp.GetNonce()
+		if err := p.translate(typeUrl, resp); err != nil {
+			p.logger.Errorw("reject discovery response",
+				zap.Error(err),
+				zap.String("type_url", typeUrl),
+				zap.String("version", resp.GetVersionInfo()),
+			)
+			p.errorDetails[typeUrl] = &status.Status{
+				Code:    int32(code.Code_INVALID_ARGUMENT),
+				Message: err.Error(),
+			}
+			continue
+		}
+		delete(p.errorDetails, typeUrl)
+		// The subscription is recorded along with the version only if the
+		// response was accepted, or the version of the old subscription
+		// would be used to ask for the new one.
+		p.versions[typeUrl] = resp.GetVersionInfo()
+		p.resourceNames[typeUrl] = names
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return p.generateEvents(p.buildManifest())
+}
+
+// dropSubscription clears the state of the resource type which is no
+// longer subscribed, it returns true if there was something.
+func (p *restProvisioner) dropSubscription(typeUrl string) bool {
+	delete(p.versions, typeUrl)
+	delete(p.nonces, typeUrl)
+	delete(p.resourceNames, typeUrl)
+	delete(p.errorDetails, typeUrl)
+	switch typeUrl {
+	case types.ClusterLoadAssignmentUrl:
+		if len(p.assignments) == 0 {
+			return false
+		}
+		p.assignments = make(map[string][]*apisix.Node)
+	case types.RouteConfigurationUrl:
+		if len(p.routeConfigurations) == 0 {
+			return false
+		}
+		p.routeConfigurations = nil
+	}
+	return true
+}
+
+// fetch sends the discovery request of the resource type, a nil response
+// is returned if the config source responds 304 (Not Modified).
+func (p *restProvisioner) fetch(ctx context.Context, typeUrl string, names []string) (*discoveryv3.DiscoveryResponse, error) {
+	dr := &discoveryv3.DiscoveryRequest{
+		VersionInfo:   p.versions[typeUrl],
+		ResponseNonce: p.nonces[typeUrl],
+		Node:          p.node,
+		TypeUrl:       typeUrl,
+		ResourceNames: names,
+		ErrorDetail:   p.errorDetails[typeUrl],
+	}
+	if !equalNames(names, p.resourceNames[typeUrl]) {
+		// The subscription was changed, asks for the full state again.
+		dr.VersionInfo = ""
+	}
+	body, err := protojson.Marshal(dr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.configSource+_discoveryPaths[typeUrl], bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	var dresp discoveryv3.DiscoveryResponse
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &dresp); err != nil {
+		return nil, err
+	}
+	p.logger.Debugw("got discovery response",
+		zap.String("type_url", typeUrl),
+		zap.String("version", dresp.GetVersionInfo()),
+		zap.Int("resources", len(dresp.GetResources())),
+	)
+	return &dresp, nil
+}
+
+// translate processes the resources in the response, the state is only
+// updated if all resources are valid.
+func (p *restProvisioner) translate(typeUrl string, resp *discoveryv3.DiscoveryResponse) error {
+	if resp.GetTypeUrl() != "" && resp.GetTypeUrl() != typeUrl {
+		return fmt.Errorf("unexpected type url %s", resp.GetTypeUrl())
+	}
+	for _, res := range resp.GetResources() {
+		if res.GetTypeUrl() != typeUrl {
+			return fmt.Errorf("unexpected resource type %s", res.GetTypeUrl())
+		}
+	}
+	switch typeUrl {
+	case types.ClusterUrl:
+		return p.processClusters(resp.GetResources())
+	case types.ClusterLoadAssignmentUrl:
+		return p.processClusterLoadAssignments(resp.GetResources())
+	case types.ListenerUrl:
+		return p.processListeners(resp.GetResources())
+	default:
+		return p.processRouteConfigurations(resp.GetResources())
+	}
+}
+
+func (p *restProvisioner) processClusters(resources []*anypb.Any) error {
+	upstreams := make(map[string]*apisix.Upstream, len(resources))
+	edsRequiredClusters := set.StringSet{}
+	for _, res := range resources {
+		var cluster clusterv3.Cluster
+		if err := anypb.UnmarshalTo(res, &cluster, proto.UnmarshalOptions{DiscardUnknown: true}); err != nil {
+			return err
+		}
+		ups, err := p.v3Adaptor.TranslateCluster(&cluster)
+		if err == xdsv3.ErrFeatureNotSupportedYet {
+			p.logger.Warnw("skip cluster which uses unsupported features",
+				zap.String("cluster", cluster.GetName()),
+			)
+			continue
+		}
+		if err != nil && err != xdsv3.ErrRequireFurtherEDS {
+			return fmt.Errorf("cluster %s: %s", cluster.GetName(), err)
+		}
+		if err == xdsv3.ErrRequireFurtherEDS {
+			edsRequiredClusters.Add(ups.Name)
+		}
+		upstreams[ups.Name] = ups
+	}
+	p.upstreams = upstreams
+	p.edsRequiredClusters = edsRequiredClusters
+	return nil
+}
+
+func (p *restProvisioner) processClusterLoadAssignments(resources []*anypb.Any) error {
+	assignments := make(map[string][]*apisix.Node, len(resources))
+	for _, res := range resources {
+		var cla endpointv3.ClusterLoadAssignment
+		if err := anypb.UnmarshalTo(res, &cla, proto.UnmarshalOptions{DiscardUnknown: true}); err != nil {
+			return err
+		}
+		nodes, err := p.v3Adaptor.TranslateClusterLoadAssignment(&cla)
+		if err != nil {
+			return fmt.Errorf("cluster load assignment %s: %s", cla.GetClusterName(), err)
+		}
+		assignments[cla.GetClusterName()] = nodes
+	}
+	p.assignments = assignments
+	return nil
+}
+
+// processListeners collects the route names and the states that route
+// translation depends on from the listeners.
+func (p *restProvisioner) processListeners(resources []*anypb.Any) error {
+	all := make([]*listenerv3.Listener, 0, len(resources))
+	for _, res := range resources {
+		var listener listenerv3.Listener
+		if err := anypb.UnmarshalTo(res, &listener, proto.UnmarshalOptions{DiscardUnknown: true}); err != nil {
+			return err
+		}
+		all = append(all, &listener)
+	}
+	states, err := p.v3Adaptor.CollectListenerStates(all, p.inboundPort)
+	if err != nil {
+		return err
+	}
+	p.listeners = states.Listeners
+	p.rdsNames = states.RdsNames
+	p.staticRouteConfigurations = states.StaticRouteConfigurations
+	p.routeOwnership = states.RouteOwnership
+	p.routeHTTPFilters = states.RouteHTTPFilters
+	p.routeUpgradeConfigs = states.RouteUpgradeConfigs
+	p.routeFilterChains = states.RouteFilterChains
+	p.inboundRoutes = states.InboundRoutes
+	return nil
+}
+
+func (p *restProvisioner) processRouteConfigurations(resources []*anypb.Any) error {
+	rcs := make([]*routev3.RouteConfiguration, 0, len(resources))
+	for _, res := range resources {
+		var rc routev3.RouteConfiguration
+		if err := anypb.UnmarshalTo(res, &rc, proto.UnmarshalOptions{DiscardUnknown: true}); err != nil {
+			return err
+		}
+		rcs = append(rcs, &rc)
+	}
+	p.routeConfigurations = rcs
+	return nil
+}
+
+// buildManifest translates the current state to APISIX resources, it's
+// always done from scratch since every response of REST polling carries
+// the full state of its resource type.
+func (p *restProvisioner) buildManifest() *util.Manifest {
+	var m util.Manifest
+	names := make([]string, 0, len(p.upstreams))
+	for name := range p.upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	upstreams := make(map[string]*apisix.Upstream, len(p.upstreams))
+	for _, name := range names {
+		ups := p.upstreams[name]
+		if nodes, ok := p.assignments[name]; ok {
+			// Do not modify the translated cluster since the nodes
+			// might be changed before the next CDS response.
+			ups = proto.Clone(ups).(*apisix.Upstream)
+			ups.Nodes = nodes
+		}
+		upstreams[name] = ups
+		m.Upstreams = append(m.Upstreams, ups)
+	}
+
+	opts := &xdsv3.TranslateOptions{
+		RouteOriginalDestination: p.routeOwnership,
+		RouteHTTPFilters:         p.routeHTTPFilters,
+		RouteUpgradeConfigs:      p.routeUpgradeConfigs,
+		RouteFilterChains:        p.routeFilterChains,
+		Upstreams:                upstreams,
+		InboundPort:              p.inboundPort,
+		InboundRoutes:            p.inboundRoutes,
+	}
+	for _, rcs := range [][]*routev3.RouteConfiguration{p.routeConfigurations, p.staticRouteConfigurations} {
+		for _, rc := range rcs {
+			routes, err := p.v3Adaptor.TranslateRouteConfiguration(rc, opts)
+			if err != nil {
+				p.logger.Errorw("failed to translate RouteConfiguration to APISIX routes",
+					zap.Error(err),
+					zap.String("route_configuration", rc.GetName()),
+				)
+				continue
+			}
+			m.Routes = append(m.Routes, routes...)
+		}
+	}
+	srs, err := p.v3Adaptor.TranslateStreamRoutes(p.listeners, opts)
+	if err != nil {
+		p.logger.Errorw("failed to translate Listeners to APISIX stream routes",
+			zap.Error(err),
+		)
+	}
+	m.StreamRoutes = srs
+	return &m
+}
+
+// generateEvents compares the manifest with the last one and generates
+// events for the differences.
+func (p *restProvisioner) generateEvents(m *util.Manifest) []types.Event {
+	added, deleted, updated := util.DiffManifests(p.manifest, m)
+	p.logger.Debugw("found changes (after converting to APISIX resources)",
+		zap.Any("added", added),
+		zap.Any("updated", updated),
+		zap.Any("deleted", deleted),
+	)
+	p.manifest = m
+	return util.ManifestEvents(added, deleted, updated)
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,394 @@
+package rest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+// fakeConfigSource serves the xDS REST API, it responds 304 if the
+// requested version is the current one.
+type fakeConfigSource struct {
+	t         *testing.T
+	mu        sync.Mutex
+	responses map[string]*discoveryv3.DiscoveryResponse
+	requests  map[string][]*discoveryv3.DiscoveryRequest
+	status    int
+	delay     time.Duration
+}
+
+func newFakeConfigSource(t *testing.T) *fakeConfigSource {
+	return &fakeConfigSource{
+		t:         t,
+		responses: make(map[string]*discoveryv3.DiscoveryResponse),
+		requests:  make(map[string][]*discoveryv3.DiscoveryRequest),
+	}
+}
+
+func (cs *fakeConfigSource) set(typeUrl, version string, resources ...proto.Message) {
+	dr := &discoveryv3.DiscoveryResponse{
+		VersionInfo: version,
+		TypeUrl:     typeUrl,
+		Nonce:       "nonce-" + version,
+	}
+	for _, res := range resources {
+		any, err := anypb.New(res)
+		assert.Nil(cs.t, err)
+		dr.Resources = append(dr.Resources, any)
+	}
+	cs.mu.Lock()
+	cs.responses[typeUrl] = dr
+	cs.mu.Unlock()
+}
+
+// fail makes the config source respond with the status code after the
+// delay, zero status code means normal responses.
+func (cs *fakeConfigSource) fail(status int, delay time.Duration) {
+	cs.mu.Lock()
+	cs.status = status
+	cs.delay = delay
+	cs.mu.Unlock()
+}
+
+func (cs *fakeConfigSource) lastRequest(typeUrl string) *discoveryv3.DiscoveryRequest {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	reqs := cs.requests[typeUrl]
+	if len(reqs) == 0 {
+		return nil
+	}
+	return reqs[len(reqs)-1]
+}
+
+func (cs *fakeConfigSource) requestCount(typeUrl string) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return len(cs.requests[typeUrl])
+}
+
+func (cs *fakeConfigSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var typeUrl string
+	for tu, path := range _discoveryPaths {
+		if path == r.URL.Path {
+			typeUrl = tu
+		}
+	}
+	if r.Method != http.MethodPost || typeUrl == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	assert.Nil(cs.t, err)
+	var dr discoveryv3.DiscoveryRequest
+	assert.Nil(cs.t, protojson.Unmarshal(data, &dr))
+	assert.Equal(cs.t, dr.TypeUrl, typeUrl)
+
+	cs.mu.Lock()
+	cs.requests[typeUrl] = append(cs.requests[typeUrl], &dr)
+	resp := cs.responses[typeUrl]
+	status, delay := cs.status, cs.delay
+	cs.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	if resp == nil {
+		resp = &discoveryv3.DiscoveryResponse{TypeUrl: typeUrl}
+	}
+	if resp.VersionInfo == dr.VersionInfo {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	data, err = protojson.Marshal(resp)
+	assert.Nil(cs.t, err)
+	_, _ = w.Write(data)
+}
+
+func newStaticCluster(name, host string) *clusterv3.Cluster {
+	return &clusterv3.Cluster{
+		Name:                 name,
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_STATIC},
+		LoadAssignment:       newClusterLoadAssignment(name, host),
+	}
+}
+
+func newClusterLoadAssignment(name, host string) *endpointv3.ClusterLoadAssignment {
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: name,
+		Endpoints: []*endpointv3.LocalityLbEndpoints{
+			{
+				LbEndpoints: []*endpointv3.LbEndpoint{
+					{
+						HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+							Endpoint: &endpointv3.Endpoint{
+								Address: &corev3.Address{
+									Address: &corev3.Address_SocketAddress{
+										SocketAddress: &corev3.SocketAddress{
+											Address: host,
+											PortSpecifier: &corev3.SocketAddress_PortValue{
+												PortValue: 8000,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newRDSListener(t *testing.T, port uint32, rdsName string) *listenerv3.Listener {
+	hcm, err := anypb.New(&hcmv3.HttpConnectionManager{
+		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+			Rds: &hcmv3.Rds{
+				RouteConfigName: rdsName,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	return &listenerv3.Listener{
+		Name: "listener",
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Address: "0.0.0.0",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: port,
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				Filters: []*listenerv3.Filter{
+					{
+						Name: xdswellknown.HTTPConnectionManager,
+						ConfigType: &listenerv3.Filter_TypedConfig{
+							TypedConfig: hcm,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newRouteConfiguration(name, cluster string) *routev3.RouteConfiguration {
+	return &routev3.RouteConfiguration{
+		Name: name,
+		VirtualHosts: []*routev3.VirtualHost{
+			{
+				Name:    "httpbin",
+				Domains: []string{"*"},
+				Routes: []*routev3.Route{
+					{
+						Match: &routev3.RouteMatch{
+							PathSpecifier: &routev3.RouteMatch_Prefix{
+								Prefix: "/",
+							},
+						},
+						Action: &routev3.Route_Route{
+							Route: &routev3.RouteAction{
+								ClusterSpecifier: &routev3.RouteAction_Cluster{
+									Cluster: cluster,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newTestProvisioner(t *testing.T, url string) *restProvisioner {
+	p, err := NewXDSProvisioner(&config.Config{
+		RunId:           "12345",
+		LogLevel:        "debug",
+		LogOutput:       "stderr",
+		Provisioner:     config.XDSV3RESTProvisioner,
+		XDSConfigSource: url,
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	})
+	assert.Nil(t, err)
+	return p.(*restProvisioner)
+}
+
+func TestPoll(t *testing.T) {
+	cs := newFakeConfigSource(t)
+	cs.set(types.ClusterUrl, "1",
+		newStaticCluster("kubernetes", "10.0.0.1"),
+		&clusterv3.Cluster{
+			Name:                 "httpbin",
+			ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_EDS},
+		},
+	)
+	cs.set(types.ClusterLoadAssignmentUrl, "1", newClusterLoadAssignment("httpbin", "10.0.3.11"))
+	cs.set(types.ListenerUrl, "1", newRDSListener(t, 8080, "rc1"))
+	cs.set(types.RouteConfigurationUrl, "1", newRouteConfiguration("rc1", "httpbin"))
+	srv := httptest.NewServer(cs)
+	defer srv.Close()
+
+	p := newTestProvisioner(t, srv.URL)
+	events := p.poll(context.Background())
+	assert.Len(t, events, 3)
+	var route *apisix.Route
+	upstreams := make(map[string]*apisix.Upstream)
+	for _, ev := range events {
+		assert.Equal(t, ev.Type, types.EventAdd)
+		switch obj := ev.Object.(type) {
+		case *apisix.Upstream:
+			upstreams[obj.Name] = obj
+		case *apisix.Route:
+			route = obj
+		}
+	}
+	assert.Equal(t, route.Name, "<anon>#httpbin#rc1")
+	assert.Equal(t, route.UpstreamId, upstreams["httpbin"].Id)
+	assert.Equal(t, route.Vars[0].Vars, []string{"connection_original_dst", "~~", "8080$"})
+	assert.Equal(t, upstreams["httpbin"].Nodes[0].Host, "10.0.3.11")
+	assert.Equal(t, upstreams["kubernetes"].Nodes[0].Host, "10.0.0.1")
+
+	assert.Equal(t, cs.lastRequest(types.ClusterLoadAssignmentUrl).ResourceNames, []string{"httpbin"})
+	assert.Equal(t, cs.lastRequest(types.RouteConfigurationUrl).ResourceNames, []string{"rc1"})
+	assert.Equal(t, cs.lastRequest(types.ClusterUrl).Node.Id, "sidecar~1.1.1.1~12345.default~default.svc.cluster.local")
+
+	// Nothing changed.
+	assert.Nil(t, p.poll(context.Background()))
+	for _, typeUrl := range _pollOrder {
+		dr := cs.lastRequest(typeUrl)
+		assert.Equal(t, dr.VersionInfo, "1")
+		assert.Equal(t, dr.ResponseNonce, "nonce-1")
+	}
+
+	// Endpoints changed.
+	cs.set(types.ClusterLoadAssignmentUrl, "2", newClusterLoadAssignment("httpbin", "10.0.3.12"))
+	events = p.poll(context.Background())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.12")
+
+	// The cluster was removed, so the endpoints are no longer subscribed.
+	cs.set(types.ClusterUrl, "2", newStaticCluster("kubernetes", "10.0.0.1"))
+	count := cs.requestCount(types.ClusterLoadAssignmentUrl)
+	events = p.poll(context.Background())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventDelete)
+	assert.Equal(t, events[0].Tombstone.(*apisix.Upstream).Name, "httpbin")
+	assert.Equal(t, cs.requestCount(types.ClusterLoadAssignmentUrl), count)
+}
+
+func TestPollReject(t *testing.T) {
+	cs := newFakeConfigSource(t)
+	cs.set(types.ClusterUrl, "1", newStaticCluster("httpbin", "10.0.3.11"))
+	srv := httptest.NewServer(cs)
+	defer srv.Close()
+
+	p := newTestProvisioner(t, srv.URL)
+	assert.Len(t, p.poll(context.Background()), 1)
+
+	// A route configuration in CDS response.
+	cs.set(types.ClusterUrl, "2", newRouteConfiguration("rc1", "httpbin"))
+	assert.Nil(t, p.poll(context.Background()))
+	assert.Nil(t, p.poll(context.Background()))
+	dr := cs.lastRequest(types.ClusterUrl)
+	assert.Equal(t, dr.VersionInfo, "1")
+	assert.Equal(t, dr.ResponseNonce, "nonce-2")
+	assert.Equal(t, dr.ErrorDetail.Code, int32(code.Code_INVALID_ARGUMENT))
+	assert.Contains(t, dr.ErrorDetail.Message, "unexpected resource type")
+
+	cs.set(types.ClusterUrl, "3", newStaticCluster("httpbin", "10.0.3.12"))
+	events := p.poll(context.Background())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Nil(t, p.poll(context.Background()))
+	dr = cs.lastRequest(types.ClusterUrl)
+	assert.Equal(t, dr.VersionInfo, "3")
+	assert.Nil(t, dr.ErrorDetail)
+}
+
+func TestPollFailure(t *testing.T) {
+	cs := newFakeConfigSource(t)
+	cs.set(types.ClusterUrl, "1", newStaticCluster("httpbin", "10.0.3.11"))
+	srv := httptest.NewServer(cs)
+	defer srv.Close()
+
+	p := newTestProvisioner(t, srv.URL)
+	assert.Len(t, p.poll(context.Background()), 1)
+
+	// The last state is kept.
+	cs.set(types.ClusterUrl, "2", newStaticCluster("httpbin", "10.0.3.12"))
+	cs.fail(http.StatusServiceUnavailable, 0)
+	assert.Nil(t, p.poll(context.Background()))
+	assert.Equal(t, p.versions[types.ClusterUrl], "1")
+
+	cs.fail(0, 300*time.Millisecond)
+	p.requestTimeout = 100 * time.Millisecond
+	assert.Nil(t, p.poll(context.Background()))
+	assert.Equal(t, p.versions[types.ClusterUrl], "1")
+
+	cs.fail(0, 0)
+	events := p.poll(context.Background())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.12")
+}
+
+func TestPollFailureAfterSubscriptionChange(t *testing.T) {
+	cs := newFakeConfigSource(t)
+	cs.set(types.ClusterUrl, "1", newStaticCluster("httpbin", "10.0.3.11"))
+	cs.set(types.ListenerUrl, "1", newRDSListener(t, 8080, "rc1"))
+	cs.set(types.RouteConfigurationUrl, "1", newRouteConfiguration("rc1", "httpbin"))
+	srv := httptest.NewServer(cs)
+	defer srv.Close()
+
+	p := newTestProvisioner(t, srv.URL)
+	assert.Len(t, p.poll(context.Background()), 2)
+
+	// The subscription changed but the request failed.
+	p.rdsNames = []string{"rc2"}
+	cs.fail(http.StatusServiceUnavailable, 0)
+	assert.Nil(t, p.poll(context.Background()))
+	assert.Equal(t, p.resourceNames[types.RouteConfigurationUrl], []string{"rc1"})
+
+	// The full state of the new subscription is asked again.
+	cs.fail(0, 0)
+	cs.set(types.RouteConfigurationUrl, "1", newRouteConfiguration("rc2", "httpbin"))
+	events := p.poll(context.Background())
+	dr := cs.lastRequest(types.RouteConfigurationUrl)
+	assert.Equal(t, dr.ResourceNames, []string{"rc2"})
+	assert.Equal(t, dr.VersionInfo, "")
+	assert.Len(t, events, 2)
+	assert.Equal(t, p.resourceNames[types.RouteConfigurationUrl], []string{"rc2"})
+}
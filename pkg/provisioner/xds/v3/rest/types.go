@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/status"
+
+	xdsv3 "github.com/api7/apisix-mesh-agent/pkg/adaptor/xds/v3"
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/log"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/util"
+	"github.com/api7/apisix-mesh-agent/pkg/set"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+var (
+	_errBadConfigSource = errors.New("bad xds config source, it should be started with \"http://\" or \"https://\"")
+)
+
+type restProvisioner struct {
+	node            *corev3.Node
+	configSource    string
+	client          *http.Client
+	refreshInterval time.Duration
+	requestTimeout  time.Duration
+	logger          *log.Logger
+	evChan          chan []types.Event
+	v3Adaptor       xdsv3.Adaptor
+
+	// The last accepted versions, the last received nonces, the last
+	// requested resource names and the error details of the last rejected
+	// responses, the key is the type url.
+	versions      map[string]string
+	nonces        map[string]string
+	resourceNames map[string][]string
+	errorDetails  map[string]*status.Status
+
+	// upstreams are translated from clusters, nodes from EDS are kept in
+	// assignments, so that they're not lost when clusters are updated.
+	upstreams           map[string]*apisix.Upstream
+	edsRequiredClusters set.StringSet
+	assignments         map[string][]*apisix.Node
+	listeners           []*listenerv3.Listener
+	// routeOwnership maps the route configuration name to the address
+	// of the listener which refers it.
+	routeOwnership            map[string]string
+	routeHTTPFilters          map[string][]*hcmv3.HttpFilter
+	routeUpgradeConfigs       map[string][]*hcmv3.HttpConnectionManager_UpgradeConfig
+	routeFilterChains         map[string][]*xdsv3.FilterChain
+	inboundPort               int
+	inboundRoutes             set.StringSet
+	rdsNames                  []string
+	staticRouteConfigurations []*routev3.RouteConfiguration
+	routeConfigurations       []*routev3.RouteConfiguration
+	// manifest is the last translated state.
+	manifest *util.Manifest
+}
+
+// NewXDSProvisioner creates a provisioner which polls the config source
+// with the xDS REST protocol (e.g. POST /v3/discovery:clusters)
+// periodically.
+func NewXDSProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
+	if !strings.HasPrefix(cfg.XDSConfigSource, "http://") && !strings.HasPrefix(cfg.XDSConfigSource, "https://") {
+		return nil, _errBadConfigSource
+	}
+	logger, err := log.NewLogger(
+		log.WithOutputFile(cfg.LogOutput),
+		log.WithLogLevel(cfg.LogLevel),
+		log.WithContext("xds-rest-provisioner"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	adaptor, err := xdsv3.NewAdaptor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	node, err := util.NewNode(cfg, os.Environ(), logger)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.XDSRefreshInterval
+	if interval == 0 {
+		interval = config.DefaultXDSRefreshInterval
+	}
+	timeout := cfg.XDSRequestTimeout
+	if timeout == 0 {
+		timeout = config.DefaultXDSRequestTimeout
+	}
+	return &restProvisioner{
+		node:                node,
+		configSource:        strings.TrimSuffix(cfg.XDSConfigSource, "/"),
+		client:              &http.Client{},
+		refreshInterval:     interval,
+		requestTimeout:      timeout,
+		logger:              logger,
+		evChan:              make(chan []types.Event),
+		v3Adaptor:           adaptor,
+		versions:            make(map[string]string),
+		nonces:              make(map[string]string),
+		resourceNames:       make(map[string][]string),
+		errorDetails:        make(map[string]*status.Status),
+		upstreams:           make(map[string]*apisix.Upstream),
+		edsRequiredClusters: set.StringSet{},
+		assignments:         make(map[string][]*apisix.Node),
+		routeOwnership:      make(map[string]string),
+		inboundPort:         cfg.InboundPort,
+	}, nil
+}
+
+func (p *restProvisioner) Channel() <-chan []types.Event {
+	return p.evChan
+}
+
+// Run polls the config source once it's started and then periodically,
+// the last translated state is kept if the config source is unavailable.
+func (p *restProvisioner) Run(stop chan struct{}) error {
+	p.logger.Infow("xds v3 rest provisioner started",
+		zap.String("config_source", p.configSource),
+		zap.Duration("refresh_interval", p.refreshInterval),
+	)
+	defer p.logger.Infow("xds v3 rest provisioner exited")
+	defer close(p.evChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(p.refreshInterval)
+	defer ticker.Stop()
+	for {
+		if events := p.poll(ctx); len(events) > 0 {
+			select {
+			case <-stop:
+				return nil
+			case p.evChan <- events:
+			}
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/api7/apisix-mesh-agent/pkg/config"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func TestNewXDSProvisioner(t *testing.T) {
+	cfg := &config.Config{
+		RunId:           "12345",
+		LogLevel:        "info",
+		LogOutput:       "stderr",
+		Provisioner:     config.XDSV3RESTProvisioner,
+		XDSConfigSource: "grpc://127.0.0.1:11111",
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	}
+	p, err := NewXDSProvisioner(cfg)
+	assert.Nil(t, p)
+	assert.Equal(t, err, _errBadConfigSource)
+
+	cfg.XDSConfigSource = "http://127.0.0.1:11111/"
+	p, err = NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	assert.NotNil(t, p.Channel())
+
+	rp := p.(*restProvisioner)
+	assert.Equal(t, rp.configSource, "http://127.0.0.1:11111")
+	assert.Equal(t, rp.node.Id, "sidecar~1.1.1.1~12345.default~default.svc.cluster.local")
+	assert.Equal(t, rp.refreshInterval, config.DefaultXDSRefreshInterval)
+	assert.Equal(t, rp.requestTimeout, config.DefaultXDSRequestTimeout)
+
+	cfg.XDSRefreshInterval = time.Second
+	cfg.XDSRequestTimeout = 2 * time.Second
+	p, err = NewXDSProvisioner(cfg)
+	assert.Nil(t, err)
+	rp = p.(*restProvisioner)
+	assert.Equal(t, rp.refreshInterval, time.Second)
+	assert.Equal(t, rp.requestTimeout, 2*time.Second)
+}
+
+func TestRESTProvisionerRun(t *testing.T) {
+	cs := newFakeConfigSource(t)
+	cs.set(types.ClusterUrl, "1", newStaticCluster("httpbin", "10.0.3.11"))
+	srv := httptest.NewServer(cs)
+	defer srv.Close()
+
+	p, err := NewXDSProvisioner(&config.Config{
+		RunId:              "12345",
+		LogLevel:           "debug",
+		LogOutput:          "stderr",
+		Provisioner:        config.XDSV3RESTProvisioner,
+		XDSConfigSource:    srv.URL,
+		XDSRefreshInterval: 50 * time.Millisecond,
+		RunningContext: &config.RunningContext{
+			PodNamespace: "default",
+			IPAddress:    "1.1.1.1",
+		},
+	})
+	assert.Nil(t, err)
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		assert.Nil(t, p.Run(stopCh))
+		close(done)
+	}()
+
+	events := receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventAdd)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.11")
+
+	cs.set(types.ClusterUrl, "2", newStaticCluster("httpbin", "10.0.3.12"))
+	events = receiveEvents(t, p.Channel())
+	assert.Len(t, events, 1)
+	assert.Equal(t, events[0].Type, types.EventUpdate)
+	assert.Equal(t, events[0].Object.(*apisix.Upstream).Nodes[0].Host, "10.0.3.12")
+
+	close(stopCh)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "provisioner didn't exit in time")
+	}
+	_, ok := <-p.Channel()
+	assert.False(t, ok)
+}
+
+func receiveEvents(t *testing.T, ch <-chan []types.Event) []types.Event {
+	select {
+	case events := <-ch:
+		return events
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "no event arrived in time")
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package recorder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+const (
+	_kindRoute       = "route"
+	_kindUpstream    = "upstream"
+	_kindStreamRoute = "stream_route"
+	_kindSSL         = "ssl"
+)
+
+var (
+	_errUnknownObject = errors.New("unknown event object type")
+)
+
+// Record is a batch of events emitted by the provisioner, it's encoded
+// as one line in the recording file.
+type Record struct {
+	// Timestamp is the time that the batch arrived.
+	Timestamp time.Time
+	// Revision is the revision of the last event in the batch.
+	Revision int64
+	// Redacted means the private keys of SSLs are removed, such SSLs
+	// cannot be used.
+	Redacted bool
+	Events   []types.Event
+}
+
+type record struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Revision  int64            `json:"revision"`
+	Redacted  bool             `json:"redacted,omitempty"`
+	Events    []*recordedEvent `json:"events"`
+}
+
+type recordedEvent struct {
+	Type     types.EventType `json:"type"`
+	Kind     string          `json:"kind"`
+	Revision int64           `json:"revision"`
+	Source   string          `json:"source,omitempty"`
+	// Object is the object of add and update events, or the tombstone
+	// of delete events, it's encoded by protojson so that it can be
+	// decoded losslessly.
+	Object json.RawMessage `json:"object"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. The private keys
+// of SSL objects are not recorded if the record is redacted, so that the
+// recording files can be shared safely.
+func (r *Record) MarshalJSON() ([]byte, error) {
+	rec := &record{
+		Timestamp: r.Timestamp,
+		Revision:  r.Revision,
+		Redacted:  r.Redacted,
+		Events:    make([]*recordedEvent, 0, len(r.Events)),
+	}
+	for _, ev := range r.Events {
+		obj := ev.Object
+		if ev.Type == types.EventDelete {
+			obj = ev.Tombstone
+		}
+		var kind string
+		switch o := obj.(type) {
+		case *apisix.Route:
+			kind = _kindRoute
+		case *apisix.Upstream:
+			kind = _kindUpstream
+		case *apisix.StreamRoute:
+			kind = _kindStreamRoute
+		case *apisix.SSL:
+			kind = _kindSSL
+			if r.Redacted && o.Key != "" {
+				o = proto.Clone(o).(*apisix.SSL)
+				o.Key = ""
+				obj = o
+			}
+		default:
+			return nil, _errUnknownObject
+		}
+		data, err := protojson.Marshal(obj.(proto.Message))
+		if err != nil {
+			return nil, err
+		}
+		rec.Events = append(rec.Events, &recordedEvent{
+			Type:     ev.Type,
+			Kind:     kind,
+			Revision: ev.Revision,
+			Source:   ev.Source,
+			Object:   data,
+		})
+	}
+	return json.Marshal(rec)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+	r.Timestamp = rec.Timestamp
+	r.Revision = rec.Revision
+	r.Redacted = rec.Redacted
+	r.Events = make([]types.Event, 0, len(rec.Events))
+	for i, rev := range rec.Events {
+		var obj proto.Message
+		switch rev.Kind {
+		case _kindRoute:
+			obj = &apisix.Route{}
+		case _kindUpstream:
+			obj = &apisix.Upstream{}
+		case _kindStreamRoute:
+			obj = &apisix.StreamRoute{}
+		case _kindSSL:
+			obj = &apisix.SSL{}
+		default:
+			return fmt.Errorf("event #%d: unknown kind %q", i, rev.Kind)
+		}
+		if err := protojson.Unmarshal(rev.Object, obj); err != nil {
+			return fmt.Errorf("event #%d: %s", i, err)
+		}
+		ev := types.Event{
+			Type:     rev.Type,
+			Revision: rev.Revision,
+			Source:   rev.Source,
+		}
+		switch rev.Type {
+		case types.EventAdd, types.EventUpdate:
+			ev.Object = obj
+		case types.EventDelete:
+			ev.Tombstone = obj
+		default:
+			return fmt.Errorf("event #%d: unknown type %q", i, rev.Type)
+		}
+		r.Events = append(r.Events, ev)
+	}
+	return nil
+}
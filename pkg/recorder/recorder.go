@@ -0,0 +1,66 @@
+package recorder
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+)
+
+// Recorder writes the event batches emitted by the provisioner to a
+// JSON-lines file, one Record per line, so that they can be replayed by
+// the replay provisioner.
+type Recorder struct {
+	mu         sync.Mutex
+	file       *os.File
+	redactKeys bool
+	now        func() time.Time
+}
+
+// NewRecorder creates a Recorder, the file will be truncated if it exists.
+// The private keys of SSLs are not recorded if redactKeys is true.
+func NewRecorder(filename string, redactKeys bool) (*Recorder, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		file:       file,
+		redactKeys: redactKeys,
+		now:        time.Now,
+	}, nil
+}
+
+// Record writes the events as one line, the events should have been
+// assigned revisions.
+func (r *Recorder) Record(events []types.Event) error {
+	rec := &Record{
+		Timestamp: r.now(),
+		Redacted:  r.redactKeys,
+		Events:    events,
+	}
+	for _, ev := range events {
+		if ev.Revision > rec.Revision {
+			rec.Revision = ev.Revision
+		}
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(data)
+	return err
+}
+
+// Close closes the recording file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,111 @@
+package recorder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/apisix"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "events.jsonl")
+
+	r, err := NewRecorder(file, false)
+	assert.Nil(t, err)
+	ts := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	r.now = func() time.Time {
+		ts = ts.Add(time.Second)
+		return ts
+	}
+
+	route := &apisix.Route{
+		Id:       "1",
+		Name:     "reviews",
+		Priority: 999,
+		Status:   apisix.Route_Enable,
+		Uris:     []string{"/*"},
+		Vars: []*apisix.Var{
+			{Vars: []string{"connection_original_dst", "~~", "9080$"}},
+		},
+	}
+	ups := &apisix.Upstream{
+		Id:    "2",
+		Name:  "reviews",
+		Nodes: []*apisix.Node{{Host: "10.0.3.11", Port: 9080, Weight: 100}},
+	}
+	ssl := &apisix.SSL{Id: "3", Cert: "cert", Key: "private key", Snis: []string{"*.example.com"}}
+	assert.Nil(t, r.Record([]types.Event{
+		{Type: types.EventAdd, Object: route, Revision: 1},
+		{Type: types.EventAdd, Object: ups, Revision: 2, Source: "xds"},
+		{Type: types.EventAdd, Object: ssl, Revision: 3},
+	}))
+	assert.Nil(t, r.Record([]types.Event{
+		{Type: types.EventDelete, Tombstone: ups, Revision: 4},
+	}))
+	assert.NotNil(t, r.Record([]types.Event{
+		{Type: types.EventAdd, Object: "unknown"},
+	}))
+	assert.Nil(t, r.Close())
+
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+
+	var rec Record
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, rec.Timestamp, time.Date(2021, 3, 1, 8, 0, 1, 0, time.UTC))
+	assert.Equal(t, rec.Revision, int64(3))
+	assert.Len(t, rec.Events, 3)
+	assert.Equal(t, rec.Events[0].Type, types.EventAdd)
+	assert.Equal(t, rec.Events[0].Revision, int64(1))
+	assert.True(t, proto.Equal(rec.Events[0].Object.(*apisix.Route), route))
+	assert.True(t, proto.Equal(rec.Events[1].Object.(*apisix.Upstream), ups))
+	assert.Equal(t, rec.Events[0].Source, "")
+	assert.Equal(t, rec.Events[1].Source, "xds")
+	assert.False(t, rec.Redacted)
+	assert.True(t, proto.Equal(rec.Events[2].Object.(*apisix.SSL), ssl))
+
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &rec))
+	assert.Equal(t, rec.Revision, int64(4))
+	assert.Len(t, rec.Events, 1)
+	assert.Equal(t, rec.Events[0].Type, types.EventDelete)
+	assert.Nil(t, rec.Events[0].Object)
+	assert.True(t, proto.Equal(rec.Events[0].Tombstone.(*apisix.Upstream), ups))
+
+	// Private keys are removed if keys are redacted.
+	r, err = NewRecorder(file, true)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Record([]types.Event{
+		{Type: types.EventAdd, Object: ssl, Revision: 5},
+	}))
+	assert.Nil(t, r.Close())
+	// The original object is not changed.
+	assert.Equal(t, ssl.Key, "private key")
+	data, err = ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(data, &rec))
+	assert.True(t, rec.Redacted)
+	assert.Equal(t, rec.Events[0].Object.(*apisix.SSL).Key, "")
+	assert.Equal(t, rec.Events[0].Object.(*apisix.SSL).Cert, "cert")
+
+	for _, line := range []string{
+		`{"events": [{"type": "add", "kind": "consumer", "object": {}}]}`,
+		`{"events": [{"type": "patch", "kind": "route", "object": {}}]}`,
+		`{"events": [{"type": "add", "kind": "route", "object": {"unknown": 1}}]}`,
+	} {
+		assert.NotNil(t, json.Unmarshal([]byte(line), &rec), line)
+	}
+}
@@ -33,10 +33,13 @@ type apisixRunner struct {
 }
 
 type apisixConfig struct {
-	SSLPort       int
-	NodeListen    int
-	GRPCListen    string
-	EtcdKeyPrefix string
+	SSLPort         int
+	NodeListen      int
+	InboundPort     int
+	PassthroughPort int
+	GRPCListen      string
+	EtcdKeyPrefix   string
+	StreamPorts     []int
 }
 
 func (ar *apisixRunner) run(wg *sync.WaitGroup) error {
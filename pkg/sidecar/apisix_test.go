@@ -18,10 +18,11 @@ func TestConfigRender(t *testing.T) {
 	}()
 	ar := &apisixRunner{
 		config: &apisixConfig{
-			SSLPort:       9443,
-			NodeListen:    9080,
-			GRPCListen:    "127.0.0.1:2379",
-			EtcdKeyPrefix: "/apisix",
+			SSLPort:         9443,
+			NodeListen:      9080,
+			PassthroughPort: 9081,
+			GRPCListen:      "127.0.0.1:2379",
+			EtcdKeyPrefix:   "/apisix",
 		},
 		runArgs: []string{"start"},
 		home:    "./testdata",
@@ -34,6 +35,29 @@ func TestConfigRender(t *testing.T) {
 	assert.Contains(t, string(data), "node_listen: 9080")
 	assert.Contains(t, string(data), "prefix: \"/apisix\"")
 	assert.Contains(t, string(data), "- \"http://127.0.0.1:2379\"")
+	assert.NotContains(t, string(data), "stream_proxy")
+
+	ar.config.StreamPorts = []int{3306, 6379}
+	err = ar.renderConfig()
+	assert.Nil(t, err)
+
+	data, err = ioutil.ReadFile("./testdata/conf/config.yaml")
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "  stream_proxy:\n    only: false\n    tcp:\n      - 3306\n      - 6379\n")
+	assert.Contains(t, string(data), "listen 9081 reuseport;")
+	assert.Contains(t, string(data), "proxy_pass http://$connection_original_dst;")
+
+	// Inbound traffic to the inbound port is handled by APISIX routes,
+	// the passthrough server is kept.
+	ar.config.InboundPort = 9082
+	err = ar.renderConfig()
+	assert.Nil(t, err)
+
+	data, err = ioutil.ReadFile("./testdata/conf/config.yaml")
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "  node_listen:\n    - 9080\n    - 9082\n")
+	assert.Contains(t, string(data), "listen 9081 reuseport;")
+	assert.Contains(t, string(data), "proxy_pass http://$connection_original_dst;")
 }
 
 func TestApisixRunner(t *testing.T) {
@@ -44,10 +68,11 @@ func TestApisixRunner(t *testing.T) {
 	ar := &apisixRunner{
 		logger: log.DefaultLogger,
 		config: &apisixConfig{
-			SSLPort:       9443,
-			NodeListen:    9080,
-			GRPCListen:    "127.0.0.1:2379",
-			EtcdKeyPrefix: "/apisix",
+			SSLPort:         9443,
+			NodeListen:      9080,
+			PassthroughPort: 9081,
+			GRPCListen:      "127.0.0.1:2379",
+			EtcdKeyPrefix:   "/apisix",
 		},
 		runArgs: []string{"3600"},
 		home:    "./testdata",
@@ -32,6 +32,19 @@ func (s *Sidecar) reflectToCache(events []types.Event) {
 					zap.String("event", string(ev.Type)),
 				)
 				err = s.cache.Upstream().Insert(obj)
+			case *apisix.StreamRoute:
+				s.logger.Debugw("insert stream route cache",
+					zap.Any("stream_route", obj),
+					zap.String("event", string(ev.Type)),
+				)
+				err = s.cache.StreamRoute().Insert(obj)
+			case *apisix.SSL:
+				s.logger.Debugw("insert ssl cache",
+					zap.String("ssl_id", obj.GetId()),
+					zap.Strings("snis", obj.GetSnis()),
+					zap.String("event", string(ev.Type)),
+				)
+				err = s.cache.SSL().Insert(obj)
 			default:
 				err = _errUnknownEventObject
 			}
@@ -49,6 +62,18 @@ func (s *Sidecar) reflectToCache(events []types.Event) {
 					zap.String("event", string(ev.Type)),
 				)
 				err = s.cache.Upstream().Delete(obj.GetId())
+			case *apisix.StreamRoute:
+				s.logger.Debugw("delete stream route cache",
+					zap.Any("stream_route", obj),
+					zap.String("event", string(ev.Type)),
+				)
+				err = s.cache.StreamRoute().Delete(obj.GetId())
+			case *apisix.SSL:
+				s.logger.Debugw("delete ssl cache",
+					zap.String("ssl_id", obj.GetId()),
+					zap.String("event", string(ev.Type)),
+				)
+				err = s.cache.SSL().Delete(obj.GetId())
 			default:
 				err = _errUnknownEventObject
 			}
@@ -44,9 +44,29 @@ func TestReflectToCache(t *testing.T) {
 				Id: "21",
 			},
 		},
+		{
+			Type: types.EventAdd,
+			Object: &apisix.StreamRoute{
+				Id: "3",
+			},
+		},
+		{
+			Type: types.EventDelete,
+			Tombstone: &apisix.StreamRoute{
+				Id: "4",
+			},
+		},
+		{
+			Type: types.EventAdd,
+			Object: &apisix.SSL{
+				Id: "5",
+			},
+		},
 	}
 	err = s.cache.Upstream().Insert(&apisix.Upstream{Id: "21"})
 	assert.Nil(t, err)
+	err = s.cache.StreamRoute().Insert(&apisix.StreamRoute{Id: "4"})
+	assert.Nil(t, err)
 	s.reflectToCache(events)
 	r1, err := s.cache.Route().Get("1")
 	assert.NotNil(t, r1)
@@ -63,4 +83,16 @@ func TestReflectToCache(t *testing.T) {
 	u2, err := s.cache.Upstream().Get("21")
 	assert.Nil(t, u2)
 	assert.Equal(t, err, cache.ErrObjectNotFound)
+
+	sr1, err := s.cache.StreamRoute().Get("3")
+	assert.NotNil(t, sr1)
+	assert.Nil(t, err)
+
+	sr2, err := s.cache.StreamRoute().Get("4")
+	assert.Nil(t, sr2)
+	assert.Equal(t, err, cache.ErrObjectNotFound)
+
+	ssl, err := s.cache.SSL().Get("5")
+	assert.NotNil(t, ssl)
+	assert.Nil(t, err)
 }
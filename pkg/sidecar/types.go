@@ -14,8 +14,15 @@ import (
 	"github.com/api7/apisix-mesh-agent/pkg/etcdv3"
 	"github.com/api7/apisix-mesh-agent/pkg/log"
 	"github.com/api7/apisix-mesh-agent/pkg/provisioner"
+	apisixfile "github.com/api7/apisix-mesh-agent/pkg/provisioner/apisix/file"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/composite"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/kubernetes"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/patch"
+	"github.com/api7/apisix-mesh-agent/pkg/provisioner/replay"
 	xdsv3file "github.com/api7/apisix-mesh-agent/pkg/provisioner/xds/v3/file"
 	xdsv3grpc "github.com/api7/apisix-mesh-agent/pkg/provisioner/xds/v3/grpc"
+	xdsv3rest "github.com/api7/apisix-mesh-agent/pkg/provisioner/xds/v3/rest"
+	"github.com/api7/apisix-mesh-agent/pkg/recorder"
 	"github.com/api7/apisix-mesh-agent/pkg/types"
 )
 
@@ -30,6 +37,7 @@ type Sidecar struct {
 	etcdSrv      etcdv3.EtcdV3
 	revision     int64
 	apisixRunner *apisixRunner
+	recorder     *recorder.Recorder
 	waitGroup    sync.WaitGroup
 }
 
@@ -62,13 +70,24 @@ func NewSidecar(cfg *config.Config) (*Sidecar, error) {
 			logger:  logger,
 			runArgs: []string{"start"},
 			config: &apisixConfig{
-				NodeListen:    9080,
-				GRPCListen:    cfg.GRPCListen,
-				EtcdKeyPrefix: cfg.EtcdKeyPrefix,
+				NodeListen:      9080,
+				InboundPort:     cfg.InboundPort,
+				PassthroughPort: config.InboundPassthroughPort,
+				GRPCListen:      cfg.GRPCListen,
+				EtcdKeyPrefix:   cfg.EtcdKeyPrefix,
+				StreamPorts:     cfg.StreamPorts,
 			},
 		}
 	}
 
+	var rec *recorder.Recorder
+	if cfg.RecordFile != "" {
+		rec, err = recorder.NewRecorder(cfg.RecordFile, cfg.RecordRedactKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	s := &Sidecar{
 		runId:        cfg.RunId,
 		grpcListener: li,
@@ -76,6 +95,7 @@ func NewSidecar(cfg *config.Config) (*Sidecar, error) {
 		provisioner:  p,
 		cache:        cache.NewInMemoryCache(),
 		apisixRunner: ar,
+		recorder:     rec,
 	}
 	etcd, err := etcdv3.NewEtcdV3Server(cfg, s.cache, s)
 	if err != nil {
@@ -128,6 +148,7 @@ loop:
 		s.reflectToLog(events)
 		// TODO may reflect to etcd after cache one by one.
 		s.reflectToCache(events)
+		s.reflectToRecorder(events)
 		s.reflectToEtcd(events)
 		// sidecar goroutine doesn't need to watch on stop channel,
 		// since it can receive the quit signal from the provisioner.
@@ -136,6 +157,13 @@ loop:
 	if s.apisixRunner != nil {
 		s.apisixRunner.shutdown()
 	}
+	if s.recorder != nil {
+		if err := s.recorder.Close(); err != nil {
+			s.logger.Errorw("failed to close recorder",
+				zap.Error(err),
+			)
+		}
+	}
 
 	shutCtx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
 	defer cancel()
@@ -155,6 +183,19 @@ func (s *Sidecar) reflectToLog(events []types.Event) {
 	)
 }
 
+// reflectToRecorder records the events, they should have been assigned
+// revisions.
+func (s *Sidecar) reflectToRecorder(events []types.Event) {
+	if s.recorder == nil {
+		return
+	}
+	if err := s.recorder.Record(events); err != nil {
+		s.logger.Errorw("failed to record events",
+			zap.Error(err),
+		)
+	}
+}
+
 func (s *Sidecar) reflectToEtcd(events []types.Event) {
 	s.etcdSrv.PushEvents(events)
 }
@@ -165,11 +206,48 @@ func (s *Sidecar) Revision() int64 {
 }
 
 func newProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
-	switch cfg.Provisioner {
+	p, err := newBaseProvisioner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.PatchFiles) > 0 {
+		return patch.NewPatchProvisioner(cfg, p)
+	}
+	return p, nil
+}
+
+func newBaseProvisioner(cfg *config.Config) (provisioner.Provisioner, error) {
+	if len(cfg.Provisioners) == 0 {
+		return newSingleProvisioner(cfg, cfg.Provisioner)
+	}
+	var sources []composite.Source
+	for _, name := range cfg.Provisioners {
+		p, err := newSingleProvisioner(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, composite.Source{
+			Name:        name,
+			Provisioner: p,
+		})
+	}
+	return composite.NewCompositeProvisioner(cfg, sources)
+}
+
+func newSingleProvisioner(cfg *config.Config, name string) (provisioner.Provisioner, error) {
+	switch name {
 	case config.XDSV3FileProvisioner:
 		return xdsv3file.NewXDSProvisioner(cfg)
 	case config.XDSV3GRPCProvisioner:
 		return xdsv3grpc.NewXDSProvisioner(cfg)
+	case config.XDSV3RESTProvisioner:
+		return xdsv3rest.NewXDSProvisioner(cfg)
+	case config.APISIXFileProvisioner:
+		return apisixfile.NewAPISIXProvisioner(cfg)
+	case config.KubernetesProvisioner:
+		return kubernetes.NewKubernetesProvisioner(cfg)
+	case config.ReplayProvisioner:
+		return replay.NewReplayProvisioner(cfg)
 	default:
 		return nil, config.ErrUnknownProvisioner
 	}
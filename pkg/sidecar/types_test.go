@@ -1,13 +1,39 @@
 package sidecar
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
 	"testing"
 	"time"
 
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdswellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"golang.org/x/net/nettest"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/api7/apisix-mesh-agent/pkg/config"
 	"github.com/api7/apisix-mesh-agent/pkg/id"
+	"github.com/api7/apisix-mesh-agent/pkg/types"
+	"github.com/api7/apisix-mesh-agent/pkg/types/istio"
 )
 
 func TestSidecarRun(t *testing.T) {
@@ -26,7 +52,12 @@ func TestSidecarRun(t *testing.T) {
 		close(finishCh)
 	}()
 
-	time.Sleep(time.Second)
+	// The provisioner doesn't deliver the pending events once it's
+	// stopped, so wait for the events before stopping.
+	assert.Eventually(t, func() bool {
+		_, err := s.cache.Upstream().Get(id.GenID("httpbin.default.svc.cluster.local"))
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
 	close(stop)
 	<-finishCh
 
@@ -35,3 +66,250 @@ func TestSidecarRun(t *testing.T) {
 	assert.Equal(t, ups.Name, "httpbin.default.svc.cluster.local")
 	assert.Len(t, ups.Nodes, 0)
 }
+
+// fakeIstiod serves the listeners and clusters through ADS, and signs
+// the workload certificate with a self-signed CA.
+type fakeIstiod struct {
+	istio.UnimplementedIstioCertificateServiceServer
+
+	t         *testing.T
+	caCert    *x509.Certificate
+	caKey     *ecdsa.PrivateKey
+	resources map[string][]proto.Message
+}
+
+func (srv *fakeIstiod) StreamAggregatedResources(stream discoveryv3.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		// Only the initial requests are responded, ACKs are ignored.
+		if req.ResponseNonce != "" {
+			continue
+		}
+		resp := &discoveryv3.DiscoveryResponse{
+			VersionInfo: "1",
+			Nonce:       req.TypeUrl,
+			TypeUrl:     req.TypeUrl,
+		}
+		for _, m := range srv.resources[req.TypeUrl] {
+			var res any.Any
+			assert.Nil(srv.t, anypb.MarshalFrom(&res, m, proto.MarshalOptions{}))
+			resp.Resources = append(resp.Resources, &res)
+		}
+		if err := stream.Send(resp); err != nil {
+			return nil
+		}
+	}
+}
+
+func (srv *fakeIstiod) DeltaAggregatedResources(_ discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	return errors.New("not yet implemented")
+}
+
+func (srv *fakeIstiod) CreateCertificate(_ context.Context, req *istio.IstioCertificateRequest) (*istio.IstioCertificateResponse, error) {
+	block, _ := pem.Decode([]byte(req.Csr))
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	assert.Nil(srv.t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		URIs:         csr.URIs,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Duration(req.ValidityDuration) * time.Second),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, srv.caCert, csr.PublicKey, srv.caKey)
+	assert.Nil(srv.t, err)
+	return &istio.IstioCertificateResponse{
+		CertChain: []string{
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.caCert.Raw})),
+		},
+	}, nil
+}
+
+func newFakeIstiod(t *testing.T, resources map[string][]proto.Message) (string, func()) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	srv := &fakeIstiod{
+		t:         t,
+		caCert:    cert,
+		caKey:     key,
+		resources: resources,
+	}
+	ln, err := nettest.NewLocalListener("tcp")
+	assert.Nil(t, err)
+	grpcSrv := grpc.NewServer()
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(grpcSrv, srv)
+	istio.RegisterIstioCertificateServiceServer(grpcSrv, srv)
+	go func() {
+		err := grpcSrv.Serve(ln)
+		assert.Nil(t, err)
+	}()
+	return ln.Addr().String(), grpcSrv.Stop
+}
+
+func newTestTransportSocket(t *testing.T, ctx proto.Message) *corev3.TransportSocket {
+	var opaque anypb.Any
+	assert.Nil(t, anypb.MarshalFrom(&opaque, ctx, proto.MarshalOptions{}))
+	return &corev3.TransportSocket{
+		Name: xdswellknown.TransportSocketTls,
+		ConfigType: &corev3.TransportSocket_TypedConfig{
+			TypedConfig: &opaque,
+		},
+	}
+}
+
+// rangeValues queries the etcd v3 server, the values are decoded to maps
+// so that the raw fields that Apache APISIX sees can be asserted.
+func rangeValues(t *testing.T, client etcdserverpb.KVClient, key, rangeEnd string) []map[string]interface{} {
+	resp, err := client.Range(context.Background(), &etcdserverpb.RangeRequest{
+		Key:      []byte(key),
+		RangeEnd: []byte(rangeEnd),
+	})
+	assert.Nil(t, err)
+	var values []map[string]interface{}
+	for _, kv := range resp.Kvs {
+		var value map[string]interface{}
+		assert.Nil(t, json.Unmarshal(kv.Value, &value))
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestSidecarServeWorkloadCertificate(t *testing.T) {
+	common := &tlsv3.CommonTlsContext{
+		TlsCertificateSdsSecretConfigs: []*tlsv3.SdsSecretConfig{
+			{Name: "default"},
+		},
+		ValidationContextType: &tlsv3.CommonTlsContext_CombinedValidationContext{
+			CombinedValidationContext: &tlsv3.CommonTlsContext_CombinedCertificateValidationContext{
+				DefaultValidationContext:         &tlsv3.CertificateValidationContext{},
+				ValidationContextSdsSecretConfig: &tlsv3.SdsSecretConfig{Name: "ROOTCA"},
+			},
+		},
+	}
+	listener := &listenerv3.Listener{
+		Name: "0.0.0.0_443",
+		Address: &corev3.Address{
+			Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{
+					Address: "0.0.0.0",
+					PortSpecifier: &corev3.SocketAddress_PortValue{
+						PortValue: 443,
+					},
+				},
+			},
+		},
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				FilterChainMatch: &listenerv3.FilterChainMatch{
+					ServerNames: []string{"httpbin.org"},
+				},
+				TransportSocket: newTestTransportSocket(t, &tlsv3.DownstreamTlsContext{
+					CommonTlsContext: common,
+				}),
+			},
+			{
+				// Not restricted by server names, like the Istio inbound
+				// filter chains.
+				TransportSocket: newTestTransportSocket(t, &tlsv3.DownstreamTlsContext{
+					CommonTlsContext: common,
+				}),
+			},
+		},
+	}
+	cluster := &clusterv3.Cluster{
+		Name: "httpbin.default.svc.cluster.local",
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{
+			Type: clusterv3.Cluster_STATIC,
+		},
+		LoadAssignment: &endpointv3.ClusterLoadAssignment{
+			ClusterName: "httpbin.default.svc.cluster.local",
+		},
+		TransportSocket: newTestTransportSocket(t, &tlsv3.UpstreamTlsContext{
+			CommonTlsContext: common,
+		}),
+	}
+	addr, stopIstiod := newFakeIstiod(t, map[string][]proto.Message{
+		types.ListenerUrl: {listener},
+		types.ClusterUrl:  {cluster},
+	})
+	defer stopIstiod()
+
+	cfg := config.NewDefaultConfig()
+	cfg.Provisioner = config.XDSV3GRPCProvisioner
+	cfg.XDSConfigSource = "grpc://" + addr
+	cfg.CAAddress = "grpc://" + addr
+	cfg.GRPCListen = "127.0.0.1:0"
+	cfg.RunningContext = &config.RunningContext{
+		PodNamespace: "default",
+		IPAddress:    "10.0.5.3",
+	}
+	s, err := NewSidecar(cfg)
+	assert.Nil(t, err)
+
+	stop := make(chan struct{})
+	finishCh := make(chan struct{})
+	go func() {
+		err := s.Run(stop)
+		assert.Nil(t, err)
+		close(finishCh)
+	}()
+	defer func() {
+		close(stop)
+		<-finishCh
+	}()
+
+	dialCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(dialCtx, s.grpcListener.Addr().String(),
+		grpc.WithBlock(),
+		grpc.WithInsecure(),
+	)
+	assert.Nil(t, err)
+	defer conn.Close()
+	client := etcdserverpb.NewKVClient(conn)
+
+	var upstreams []map[string]interface{}
+	assert.Eventually(t, func() bool {
+		upstreams = rangeValues(t, client, "/apisix/upstreams", "/apisix/upstreamt")
+		return len(upstreams) == 1 && upstreams[0]["tls"] != nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// The workload certificate is served inline as the client certificate.
+	assert.Equal(t, upstreams[0]["scheme"], "https")
+	tlsConf := upstreams[0]["tls"].(map[string]interface{})
+	assert.Len(t, tlsConf, 2)
+	certPEM := tlsConf["client_cert"].(string)
+	keyPEM := tlsConf["client_key"].(string)
+	pair, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	assert.Nil(t, err)
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	assert.Nil(t, err)
+	assert.Equal(t, leaf.URIs[0].String(), "spiffe://cluster.local/ns/default/sa/default")
+
+	// Only the filter chain with server names has the server SSL.
+	ssls := rangeValues(t, client, "/apisix/ssl", "/apisix/ssm")
+	assert.Len(t, ssls, 1)
+	assert.Len(t, ssls[0], 4)
+	assert.Equal(t, ssls[0]["snis"], []interface{}{"httpbin.org"})
+	assert.Equal(t, ssls[0]["cert"], certPEM)
+	assert.Equal(t, ssls[0]["key"], keyPEM)
+}
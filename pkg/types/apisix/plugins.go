@@ -0,0 +1,39 @@
+package apisix
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// ForwardAuthPlugin is the name of the forward-auth plugin.
+	ForwardAuthPlugin = "forward-auth"
+	// FaultInjectionPlugin is the name of the fault-injection plugin.
+	FaultInjectionPlugin = "fault-injection"
+)
+
+// NewPluginConfig encodes the typed plugin configuration (e.g. ForwardAuth)
+// to the form used in the plugins of Route, field names are kept same to the
+// ones in Apache APISIX.
+func NewPluginConfig(conf proto.Message) (*structpb.Struct, error) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	var s structpb.Struct
+	if err := protojson.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DecodePluginConfig decodes the plugin configuration to the typed one, fields
+// which are not in the typed configuration are discarded.
+func DecodePluginConfig(conf *structpb.Struct, typed proto.Message) error {
+	data, err := protojson.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, typed)
+}
@@ -177,14 +177,21 @@ func (m *Route) Validate() error {
 
 	}
 
-	if v, ok := interface{}(m.GetPlugins()).(interface{ Validate() error }); ok {
-		if err := v.Validate(); err != nil {
-			return RouteValidationError{
-				field:  "Plugins",
-				reason: "embedded message failed validation",
-				cause:  err,
+	for key, val := range m.GetPlugins() {
+		_ = val
+
+		// no validation rules for Plugins[key]
+
+		if v, ok := interface{}(val).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return RouteValidationError{
+					field:  fmt.Sprintf("Plugins[%v]", key),
+					reason: "embedded message failed validation",
+					cause:  err,
+				}
 			}
 		}
+
 	}
 
 	// no validation rules for ServiceId
@@ -193,6 +200,8 @@ func (m *Route) Validate() error {
 
 	// no validation rules for Status
 
+	// no validation rules for EnableWebsocket
+
 	return nil
 }
 
@@ -0,0 +1,181 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.25.0-devel
+// 	protoc        v3.12.3
+// source: ssl.proto
+
+package apisix
+
+import (
+	_ "github.com/envoyproxy/protoc-gen-validate/validate"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// [#protodoc-title: The Apache APISIX SSL configuration]
+// An SSL object contains the certificate and the private key, it's selected
+// by the TLS server name indication when terminating the downstream TLS
+// connections.
+type SSL struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The SSL id.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The PEM encoded certificate (chain).
+	Cert string `protobuf:"bytes,2,opt,name=cert,proto3" json:"cert,omitempty"`
+	// The PEM encoded private key.
+	Key string `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	// The TLS server names that this SSL object is used for,
+	// wildcard server name like "*.apache.org" is also supported.
+	Snis []string `protobuf:"bytes,4,rep,name=snis,proto3" json:"snis,omitempty"`
+}
+
+func (x *SSL) Reset() {
+	*x = SSL{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_ssl_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SSL) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SSL) ProtoMessage() {}
+
+func (x *SSL) ProtoReflect() protoreflect.Message {
+	mi := &file_ssl_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SSL.ProtoReflect.Descriptor instead.
+func (*SSL) Descriptor() ([]byte, []int) {
+	return file_ssl_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SSL) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SSL) GetCert() string {
+	if x != nil {
+		return x.Cert
+	}
+	return ""
+}
+
+func (x *SSL) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SSL) GetSnis() []string {
+	if x != nil {
+		return x.Snis
+	}
+	return nil
+}
+
+var File_ssl_proto protoreflect.FileDescriptor
+
+var file_ssl_proto_rawDesc = []byte{
+	0x0a, 0x09, 0x73, 0x73, 0x6c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x17, 0x76, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x65, 0x2f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x6b, 0x0a, 0x03, 0x53, 0x53, 0x4c, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1b, 0x0a, 0x04, 0x63,
+	0x65, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x42, 0x07, 0xfa, 0x42, 0x04, 0x72, 0x02,
+	0x10, 0x01, 0x52, 0x04, 0x63, 0x65, 0x72, 0x74, 0x12, 0x19, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x42, 0x07, 0xfa, 0x42, 0x04, 0x72, 0x02, 0x10, 0x01, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x04, 0x73, 0x6e, 0x69, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x09, 0x42, 0x08, 0xfa, 0x42, 0x05, 0x92, 0x01, 0x02, 0x18, 0x01, 0x52, 0x04, 0x73, 0x6e, 0x69,
+	0x73, 0x42, 0x0a, 0x5a, 0x08, 0x2e, 0x3b, 0x61, 0x70, 0x69, 0x73, 0x69, 0x78, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_ssl_proto_rawDescOnce sync.Once
+	file_ssl_proto_rawDescData = file_ssl_proto_rawDesc
+)
+
+func file_ssl_proto_rawDescGZIP() []byte {
+	file_ssl_proto_rawDescOnce.Do(func() {
+		file_ssl_proto_rawDescData = protoimpl.X.CompressGZIP(file_ssl_proto_rawDescData)
+	})
+	return file_ssl_proto_rawDescData
+}
+
+var file_ssl_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_ssl_proto_goTypes = []interface{}{
+	(*SSL)(nil), // 0: SSL
+}
+var file_ssl_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_ssl_proto_init() }
+func file_ssl_proto_init() {
+	if File_ssl_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_ssl_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SSL); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_ssl_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_ssl_proto_goTypes,
+		DependencyIndexes: file_ssl_proto_depIdxs,
+		MessageInfos:      file_ssl_proto_msgTypes,
+	}.Build()
+	File_ssl_proto = out.File
+	file_ssl_proto_rawDesc = nil
+	file_ssl_proto_goTypes = nil
+	file_ssl_proto_depIdxs = nil
+}
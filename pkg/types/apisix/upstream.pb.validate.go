@@ -138,6 +138,16 @@ func (m *Upstream) Validate() error {
 
 	}
 
+	if v, ok := interface{}(m.GetTls()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return UpstreamValidationError{
+				field:  "Tls",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -1221,3 +1231,72 @@ var _ interface {
 	Cause() error
 	ErrorName() string
 } = Upstream_TimeoutValidationError{}
+
+// Validate checks the field values on Upstream_TLS with the rules defined in
+// the proto definition for this message. If any rules are violated, an error
+// is returned.
+func (m *Upstream_TLS) Validate() error {
+	if m == nil {
+		return nil
+	}
+
+	// no validation rules for ClientCert
+
+	// no validation rules for ClientKey
+
+	return nil
+}
+
+// Upstream_TLSValidationError is the validation error returned by
+// Upstream_TLS.Validate if the designated constraints aren't met.
+type Upstream_TLSValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e Upstream_TLSValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e Upstream_TLSValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e Upstream_TLSValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e Upstream_TLSValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e Upstream_TLSValidationError) ErrorName() string { return "Upstream_TLSValidationError" }
+
+// Error satisfies the builtin error interface
+func (e Upstream_TLSValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sUpstream_TLS.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = Upstream_TLSValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = Upstream_TLSValidationError{}
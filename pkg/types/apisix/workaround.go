@@ -1,6 +1,9 @@
 package apisix
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+)
 
 // MarshalJSON implements the json.Marshaler interface.
 func (v *Var) MarshalJSON() ([]byte, error) {
@@ -9,3 +12,26 @@ func (v *Var) MarshalJSON() ([]byte, error) {
 	}
 	return json.Marshal(v.Vars)
 }
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (v *Var) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &v.Vars)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r *Route) MarshalJSON() ([]byte, error) {
+	type route Route
+	data, err := json.Marshal((*route)(r))
+	if err != nil {
+		return nil, err
+	}
+	// The disabled status is the zero value so it's omitted, but Apache
+	// APISIX treats the missing status as enabled.
+	if r.Status == Route_Disable && bytes.HasPrefix(data, []byte("{")) {
+		if bytes.Equal(data, []byte("{}")) {
+			return []byte(`{"status":0}`), nil
+		}
+		return append([]byte(`{"status":0,`), data[1:]...), nil
+	}
+	return data, nil
+}
@@ -38,4 +38,7 @@ type Event struct {
 
 	// Revision is the revision that the event happened
 	Revision int64
+	// Source is the name of the source that the object comes from, it's
+	// only set by the composite provisioner.
+	Source string
 }
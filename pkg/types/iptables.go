@@ -7,4 +7,8 @@ var (
 	InboundRedirectChain = "APISIX_INBOUND_REDIRECT"
 	OutputChain          = "OUTPUT"
 	PreRoutingChain      = "PREROUTING"
+
+	// The chain redirects TCP traffic to APISIX stream proxy, the destination
+	// port is kept so that APISIX can find the stream route by the server port.
+	StreamRedirectChain = "APISIX_STREAM_REDIRECT"
 )